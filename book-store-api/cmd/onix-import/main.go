@@ -0,0 +1,137 @@
+// Command onix-import sends a publisher's ONIX 3.0 feed to the API's import
+// endpoint and prints the report: what was created and updated, the records
+// that couldn't be imported and the data that was left out of the rest.
+//
+//	onix-import [-api http://localhost:8080] [-dry-run] [-user id] [feed.xml]
+//
+// The user is only trusted by the API when GATEWAY_SECRET holds its gateway
+// secret.
+//
+// The feed is read from standard input if no file is given. The command
+// exits with status 1 if any record failed.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func main() {
+	api := flag.String("api", "http://localhost:8080", "base URL of the API")
+	dryRun := flag.Bool("dry-run", false, "check the feed without saving anything")
+	user := flag.String("user", "", "user ID to record as the actor for price and stock changes")
+	flag.Parse()
+
+	log.SetFlags(0)
+	log.SetPrefix("onix-import: ")
+
+	var feed io.Reader = os.Stdin
+	if path := flag.Arg(0); path != "" {
+		file, err := os.Open(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		feed = file
+	}
+
+	result, err := send(*api, feed, *dryRun, *user, os.Getenv("GATEWAY_SECRET"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	report(os.Stdout, result)
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
+
+// send posts the feed to POST /books/import and decodes the report
+func send(api string, feed io.Reader, dryRun bool, user, gatewaySecret string) (models.ImportResult, error) {
+	var result models.ImportResult
+
+	query := url.Values{"format": {"onix"}}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(api, "/")+"/books/import?"+query.Encode(), feed)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/xml")
+	if user != "" {
+		req.Header.Set("X-User-Id", user)
+		req.Header.Set("X-Gateway-Secret", gatewaySecret)
+	}
+
+	// Large feeds take a while, but a server that has stopped answering shouldn't hang the command
+	client := &http.Client{Timeout: 30 * time.Minute}
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var body struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.Error == "" {
+			return result, fmt.Errorf("import failed: %s", resp.Status)
+		}
+		return result, fmt.Errorf("import failed: %s", body.Error)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf("reading the report: %w", err)
+	}
+	return result, nil
+}
+
+// report prints the import report
+func report(w io.Writer, result models.ImportResult) {
+	verb := "imported"
+	if result.DryRun {
+		verb = "checked (dry run)"
+	}
+	fmt.Fprintf(w, "%d records %s: %d created, %d updated, %d failed\n",
+		result.Rows, verb, result.Created, result.Updated, result.Failed)
+
+	if len(result.Errors) > 0 {
+		fmt.Fprintln(w, "\nInvalid records:")
+		for _, e := range result.Errors {
+			fmt.Fprintf(w, "  %s\n", describe(e))
+		}
+		if hidden := result.Failed - len(result.Errors); hidden > 0 {
+			fmt.Fprintf(w, "  ... and %d more\n", hidden)
+		}
+	}
+
+	if len(result.Unmapped) > 0 {
+		fmt.Fprintln(w, "\nUnmapped data:")
+		for _, e := range result.Unmapped {
+			fmt.Fprintf(w, "  %s\n", describe(e))
+		}
+	}
+}
+
+// describe formats a report entry as "line N, record R, ISBN I: message"
+func describe(e models.ImportError) string {
+	where := []string{fmt.Sprintf("line %d", e.Line)}
+	if e.Record != "" {
+		where = append(where, "record "+e.Record)
+	}
+	if e.ISBN != "" {
+		where = append(where, "ISBN "+e.ISBN)
+	}
+	return strings.Join(where, ", ") + ": " + e.Error
+}
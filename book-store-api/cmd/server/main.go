@@ -6,14 +6,20 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/bisac"
 	"github.com/godwin/book-store-api/internal/database"
 	"github.com/godwin/book-store-api/internal/handlers"
+	"github.com/godwin/book-store-api/internal/isbn"
 	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+	"github.com/godwin/book-store-api/internal/pricing"
+	"github.com/godwin/book-store-api/internal/storage"
 	"github.com/joho/godotenv"
 )
 
@@ -31,11 +37,28 @@ func main() {
 	// Set up the store
 	store := database.NewMockStore()
 
+	// Load the ranges used to hyphenate ISBNs before any books are stored
+	loadISBNRanges()
+
+	// Import a subject scheme to classify books with, if one is configured
+	importCategories(store)
+
 	// Add some sample data to the store
 	addSampleBooks(store)
 
+	// Expire abandoned reservations in the background
+	sweepCtx, stopSweeper := context.WithCancel(context.Background())
+	defer stopSweeper()
+	go database.SweepReservations(sweepCtx, store, reservationSweepInterval())
+
+	// Load the exchange rates used to price books in other currencies
+	pricer := pricing.NewPricer(loadExchangeRates())
+
+	// Set up the storage for cover images
+	covers := coverStore()
+
 	// Set up the router
-	r := setupRouter(store)
+	r := setupRouter(store, pricer, covers)
 
 	// Determine port for HTTP service
 	port := os.Getenv("PORT")
@@ -76,6 +99,131 @@ func main() {
 	log.Println("Server exited gracefully")
 }
 
+// gatewaySecret reads GATEWAY_SECRET, which the gateway sends with the
+// identity headers of the callers it has authenticated
+func gatewaySecret() string {
+	secret := os.Getenv("GATEWAY_SECRET")
+	if secret == "" {
+		log.Println("GATEWAY_SECRET is not set; X-User-Id and X-User-Role headers will be ignored")
+	}
+	return secret
+}
+
+// reservationSweepInterval reads RESERVATION_SWEEP_INTERVAL, defaulting to a minute
+func reservationSweepInterval() time.Duration {
+	if value := os.Getenv("RESERVATION_SWEEP_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err == nil && interval > 0 {
+			return interval
+		}
+		log.Printf("Invalid RESERVATION_SWEEP_INTERVAL %q, defaulting to 1m", value)
+	}
+	return time.Minute
+}
+
+// loadExchangeRates reads the rate table from EXCHANGE_RATES_FILE, defaulting
+// to data/exchange_rates.json. Without one, books can only be shown in their
+// own currency.
+func loadExchangeRates() *money.Rates {
+	path := os.Getenv("EXCHANGE_RATES_FILE")
+	if path == "" {
+		path = "data/exchange_rates.json"
+	}
+
+	rates, err := money.LoadRates(path)
+	if err != nil {
+		log.Printf("Could not load exchange rates from %s: %v", path, err)
+		rates, _ = money.NewRates(money.DefaultCurrency)
+		return rates
+	}
+
+	log.Printf("Loaded exchange rates for %d currencies", len(rates.Currencies()))
+	return rates
+}
+
+// loadISBNRanges reads the ISBN hyphenation ranges from ISBN_RANGES_FILE,
+// defaulting to the sample in data/isbn_ranges.sample.xml, which only covers
+// a few registration groups. ISBNs outside the ranges are shown without
+// hyphens.
+func loadISBNRanges() {
+	path := os.Getenv("ISBN_RANGES_FILE")
+	if path == "" {
+		path = "data/isbn_ranges.sample.xml"
+		log.Printf("ISBN_RANGES_FILE is not set; using the sample ranges in %s, which only hyphenate 978-0, 978-1, 978-3, 978-4 and 979-8 ISBNs", path)
+	}
+
+	ranges, err := isbn.LoadRanges(path)
+	if err != nil {
+		log.Printf("Could not load ISBN ranges from %s: %v", path, err)
+		return
+	}
+
+	isbn.SetRanges(ranges)
+}
+
+// coverStore returns the blob storage for cover images: an S3 bucket if
+// COVER_BUCKET is set, otherwise the COVER_DIR directory (default
+// data/covers)
+func coverStore() storage.BlobStore {
+	if bucket := os.Getenv("COVER_BUCKET"); bucket != "" {
+		pathStyle, _ := strconv.ParseBool(os.Getenv("S3_PATH_STYLE"))
+		store, err := storage.NewS3Store(storage.S3Config{
+			Endpoint:        os.Getenv("S3_ENDPOINT"),
+			Region:          os.Getenv("AWS_REGION"),
+			Bucket:          bucket,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+			PathStyle:       pathStyle,
+		})
+		if err != nil {
+			log.Fatalf("Failed to set up cover storage: %v", err)
+		}
+		log.Printf("Storing covers in bucket %s", bucket)
+		return store
+	}
+
+	dir := os.Getenv("COVER_DIR")
+	if dir == "" {
+		dir = "data/covers"
+	}
+	store, err := storage.NewLocalStore(dir)
+	if err != nil {
+		log.Fatalf("Failed to set up cover storage: %v", err)
+	}
+	return store
+}
+
+// importCategories imports BISAC subject headings from CATEGORIES_FILE, if
+// it is set
+func importCategories(store database.Store) {
+	path := os.Getenv("CATEGORIES_FILE")
+	if path == "" {
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Could not open categories file %s: %v", path, err)
+		return
+	}
+	defer f.Close()
+
+	entries, err := bisac.Parse(f)
+	if err != nil {
+		log.Printf("Could not read categories from %s: %v", path, err)
+		return
+	}
+
+	result, err := store.ImportCategories(entries)
+	if err != nil {
+		log.Printf("Could not import categories from %s: %v", path, err)
+		return
+	}
+
+	log.Printf("Imported %d categories (%d created, %d updated)", result.Total, result.Created, result.Updated)
+}
+
 // setupRouter configures the Gin router with routes and middleware
 // addSampleBooks adds some sample data to the store for demonstration purposes
 func addSampleBooks(store database.Store) {
@@ -85,7 +233,7 @@ func addSampleBooks(store database.Store) {
 			Author:      "Robert C. Martin",
 			ISBN:        "978-0132350884",
 			PublishedAt: time.Date(2008, 8, 1, 0, 0, 0, 0, time.UTC),
-			Price:       37.49,
+			Price:       money.New(3749, "USD"),
 			Quantity:    15,
 		},
 		{
@@ -93,7 +241,7 @@ func addSampleBooks(store database.Store) {
 			Author:      "Erich Gamma, Richard Helm, Ralph Johnson, John Vlissides",
 			ISBN:        "978-0201633610",
 			PublishedAt: time.Date(1994, 11, 10, 0, 0, 0, 0, time.UTC),
-			Price:       44.99,
+			Price:       money.New(4499, "USD"),
 			Quantity:    12,
 		},
 		{
@@ -101,47 +249,136 @@ func addSampleBooks(store database.Store) {
 			Author:      "Andrew Hunt, David Thomas",
 			ISBN:        "978-0201616224",
 			PublishedAt: time.Date(1999, 10, 30, 0, 0, 0, 0, time.UTC),
-			Price:       34.99,
+			Price:       money.New(3499, "USD"),
 			Quantity:    10,
 		},
 	}
 
 	for _, book := range sampleBooks {
-		_, err := store.CreateBook(book)
+		_, err := store.CreateBook(book, "system")
 		if err != nil {
 			log.Printf("Error adding sample book: %v", err)
 		}
 	}
 }
 
-func setupRouter(store database.Store) *gin.Engine {
+func setupRouter(store database.Store, pricer *pricing.Pricer, covers storage.BlobStore) *gin.Engine {
 	r := gin.Default()
 
 	// Create handler with store dependency
-	h := handlers.NewHandler(store)
+	h := handlers.NewHandler(store, []byte(os.Getenv("CURSOR_SECRET")), pricer, covers, os.Getenv("COVER_BASE_URL"))
 
 	// Middleware
 	r.Use(gin.Logger())
 	r.Use(gin.Recovery())
+	r.Use(handlers.GatewayIdentity(gatewaySecret()))
 
 	// Setup CORS
 	config := cors.DefaultConfig()
 	config.AllowAllOrigins = true
 	config.AllowMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
-	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization"}
+	config.AllowHeaders = []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Cart-Session", "Accept-Currency"}
+	config.ExposeHeaders = []string{"Link", "X-Cart-Session"}
 	r.Use(cors.New(config))
 
 	// Routes
 	r.GET("/status", h.GetStatus)
+	r.GET("/exchange-rates", h.GetExchangeRates)
+	r.PUT("/exchange-rates", h.UpdateExchangeRates)
+	r.GET("/covers/*key", h.GetCoverImage)
 
 	// Book routes
 	books := r.Group("/books")
 	{
 		books.GET("", h.GetBooks)
+		books.GET("/search", h.SearchBooks)
+		books.GET("/facets", h.GetBookFacets)
+		books.GET("/export", h.ExportBooks)
+		books.POST("/import", h.ImportBooks)
+		books.GET("/isbn/:isbn", h.GetBookByISBN)
 		books.GET("/:id", h.GetBook)
 		books.POST("", h.CreateBook)
 		books.PUT("/:id", h.UpdateBook)
 		books.DELETE("/:id", h.DeleteBook)
+		books.GET("/:id/price-history", h.GetPriceHistory)
+		books.POST("/:id/cover", h.UploadCover)
+		books.DELETE("/:id/cover", h.DeleteCover)
+		books.GET("/:id/stock-movements", h.GetStockMovements)
+		books.POST("/:id/stock-movements", h.RecordStockMovement)
+		books.POST("/:id/reservations", h.CreateReservation)
+		books.GET("/:id/reviews", h.GetBookReviews)
+		books.POST("/:id/reviews", h.CreateReview)
+	}
+
+	// Author routes
+	authors := r.Group("/authors")
+	{
+		authors.GET("", h.GetAuthors)
+		authors.POST("", h.CreateAuthor)
+		authors.GET("/:id", h.GetAuthor)
+		authors.PUT("/:id", h.UpdateAuthor)
+		authors.DELETE("/:id", h.DeleteAuthor)
+		authors.GET("/:id/books", h.GetAuthorBooks)
+	}
+
+	// Category routes
+	categories := r.Group("/categories")
+	{
+		categories.GET("", h.GetCategories)
+		categories.POST("", h.CreateCategory)
+		categories.POST("/import", h.ImportCategories)
+		categories.GET("/:slug", h.GetCategory)
+		categories.PUT("/:slug", h.UpdateCategory)
+		categories.DELETE("/:slug", h.DeleteCategory)
+		categories.GET("/:slug/books", h.GetCategoryBooks)
+	}
+
+	// Promotion routes
+	promotions := r.Group("/promotions")
+	{
+		promotions.GET("", h.GetPromotions)
+		promotions.POST("", h.CreatePromotion)
+		promotions.GET("/:id", h.GetPromotion)
+		promotions.PUT("/:id", h.UpdatePromotion)
+		promotions.DELETE("/:id", h.DeletePromotion)
+	}
+
+	// Cart routes
+	cart := r.Group("/cart")
+	{
+		cart.GET("", h.GetCart)
+		cart.DELETE("", h.ClearCart)
+		cart.POST("/items", h.AddCartItem)
+		cart.PUT("/items/:bookId", h.UpdateCartItem)
+		cart.DELETE("/items/:bookId", h.RemoveCartItem)
+		cart.POST("/checkout", h.Checkout)
+	}
+
+	// Order routes
+	orders := r.Group("/orders")
+	{
+		orders.GET("", h.GetOrders)
+		orders.POST("", h.CreateOrder)
+		orders.GET("/:id", h.GetOrder)
+		orders.PUT("/:id/status", h.UpdateOrderStatus)
+	}
+
+	// Review routes
+	reviews := r.Group("/reviews")
+	{
+		reviews.GET("", h.GetReviews)
+		reviews.GET("/:id", h.GetReview)
+		reviews.PUT("/:id", h.UpdateReview)
+		reviews.DELETE("/:id", h.DeleteReview)
+		reviews.PUT("/:id/status", h.ModerateReview)
+	}
+
+	// Reservation routes
+	reservations := r.Group("/reservations")
+	{
+		reservations.GET("/:id", h.GetReservation)
+		reservations.POST("/:id/confirm", h.ConfirmReservation)
+		reservations.POST("/:id/release", h.ReleaseReservation)
 	}
 
 	return r
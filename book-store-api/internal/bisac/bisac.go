@@ -0,0 +1,86 @@
+// Package bisac reads BISAC subject headings, the subject scheme used by the
+// North American book trade, so they can be imported as categories.
+package bisac
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// ErrInvalid is returned for a file that isn't a list of subject headings
+var ErrInvalid = errors.New("bisac: invalid subject headings")
+
+// codePattern matches a BISAC code: three letters for the section and six digits
+var codePattern = regexp.MustCompile(`^[A-Z]{3}[0-9]{6}$`)
+
+// ValidCode reports whether s is a well-formed BISAC code, e.g. COM051000
+func ValidCode(s string) bool {
+	return codePattern.MatchString(s)
+}
+
+// Parse reads subject headings as CSV rows of code and heading, e.g.
+//
+//	Code,Literal
+//	COM000000,COMPUTERS / General
+//	COM051000,COMPUTERS / Programming / General
+//
+// A header row is skipped. Headings are split on " / " into a path from the
+// section down. BISAC uses "General" for a heading's own code, so
+// "COMPUTERS / Programming / General" is imported as the code of
+// "COMPUTERS / Programming".
+func Parse(r io.Reader) ([]models.CategoryImport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []models.CategoryImport
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+		}
+		if len(record) < 2 {
+			return nil, fmt.Errorf("%w: line %d needs a code and a heading", ErrInvalid, line)
+		}
+
+		code := strings.ToUpper(strings.TrimSpace(record[0]))
+		if !ValidCode(code) {
+			if line == 1 {
+				continue // header
+			}
+			return nil, fmt.Errorf("%w: line %d has invalid code %q", ErrInvalid, line, record[0])
+		}
+
+		path := splitHeading(record[1])
+		if len(path) > 1 && strings.EqualFold(path[len(path)-1], "General") {
+			path = path[:len(path)-1]
+		}
+		if len(path) == 0 {
+			return nil, fmt.Errorf("%w: line %d has no heading", ErrInvalid, line)
+		}
+
+		entries = append(entries, models.CategoryImport{Code: code, Path: path})
+	}
+
+	return entries, nil
+}
+
+// splitHeading splits "COMPUTERS / Programming / Object Oriented" into its parts
+func splitHeading(heading string) []string {
+	var path []string
+	for _, part := range strings.Split(strings.Join(strings.Fields(heading), " "), " / ") {
+		if part = strings.TrimSpace(part); part != "" {
+			path = append(path, part)
+		}
+	}
+	return path
+}
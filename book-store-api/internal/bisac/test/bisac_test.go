@@ -0,0 +1,110 @@
+package bisac_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/bisac"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []models.CategoryImport
+	}{
+		{
+			name:  "header is skipped",
+			input: "Code,Literal\nCOM000000,COMPUTERS / General\n",
+			want:  []models.CategoryImport{{Code: "COM000000", Path: []string{"COMPUTERS"}}},
+		},
+		{
+			name:  "General names the parent heading",
+			input: "COM051000,COMPUTERS / Programming / General\n",
+			want:  []models.CategoryImport{{Code: "COM051000", Path: []string{"COMPUTERS", "Programming"}}},
+		},
+		{
+			name:  "nested heading",
+			input: "COM051210,COMPUTERS / Programming / Object Oriented\n",
+			want:  []models.CategoryImport{{Code: "COM051210", Path: []string{"COMPUTERS", "Programming", "Object Oriented"}}},
+		},
+		{
+			name:  "spacing and code case are tidied",
+			input: ` com051000 , "COMPUTERS  /   Programming / general"` + "\n",
+			want:  []models.CategoryImport{{Code: "COM051000", Path: []string{"COMPUTERS", "Programming"}}},
+		},
+		{
+			name:  "extra columns are ignored",
+			input: "FIC000000,FICTION / General,2024\n",
+			want:  []models.CategoryImport{{Code: "FIC000000", Path: []string{"FICTION"}}},
+		},
+		{
+			name:  "a lone General heading is kept",
+			input: "GEN000000,General\n",
+			want:  []models.CategoryImport{{Code: "GEN000000", Path: []string{"General"}}},
+		},
+		{
+			name:  "empty file",
+			input: "",
+			want:  nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bisac.Parse(strings.NewReader(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		msg   string
+	}{
+		{"bad code after the header", "Code,Literal\nCOM51000,COMPUTERS\n", "line 2 has invalid code"},
+		{"no heading", "COM000000\n", "line 1 needs a code and a heading"},
+		{"blank heading", "COM000000,   \n", "line 1 has no heading"},
+		{"unbalanced quotes", "COM000000,\"COMPUTERS\n", "bisac"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := bisac.Parse(strings.NewReader(tt.input))
+			if !errors.Is(err, bisac.ErrInvalid) || !strings.Contains(err.Error(), tt.msg) {
+				t.Errorf("Expected ErrInvalid with %q, got %v", tt.msg, err)
+			}
+		})
+	}
+}
+
+func TestValidCode(t *testing.T) {
+	tests := []struct {
+		code string
+		want bool
+	}{
+		{"COM051000", true},
+		{"FIC000000", true},
+		{"com051000", false},
+		{"COM05100", false},
+		{"CO0051000", false},
+		{"COM0510000", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if got := bisac.ValidCode(tt.code); got != tt.want {
+			t.Errorf("%q: expected %v, got %v", tt.code, tt.want, got)
+		}
+	}
+}
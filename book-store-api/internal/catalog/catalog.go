@@ -0,0 +1,141 @@
+// Package catalog reads and writes books in bulk, one book per row, as CSV
+// or newline-delimited JSON (NDJSON). Both are read and written as streams,
+// so files of any size can be handled a row at a time. ONIX messages from
+// publishers can be read too, a product per row.
+package catalog
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/onix"
+)
+
+// Formats. ONIX can only be read.
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatONIX   = "onix"
+)
+
+// ErrUnknownFormat is returned for a format that can't be read or written
+var ErrUnknownFormat = errors.New("unknown format")
+
+// RowError is a problem with one row of an import file. Reading can go on
+// with the next row.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads the rows of an import file. Next returns io.EOF after the
+// last row, and a *RowError for a row that can't be read; any other error
+// means the rest of the file can't be read.
+type Reader interface {
+	Next() (models.ImportRow, error)
+}
+
+// Writer writes books to an export file. Flush must be called after the
+// last book.
+type Writer interface {
+	Write(book models.Book) error
+	Flush() error
+}
+
+// NewReader returns a reader for an import file in the format. CSV files
+// must start with a header row naming their columns.
+func NewReader(format string, r io.Reader) (Reader, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVReader(r)
+	case FormatNDJSON:
+		return newNDJSONReader(r), nil
+	case FormatONIX:
+		return onixReader{onix.NewReader(r)}, nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// NewWriter returns a writer for an export file in the format. Files written
+// in either format can be imported again.
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return newCSVWriter(w)
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	}
+	return nil, ErrUnknownFormat
+}
+
+// FormatOf returns the format of a Content-Type, or "" if it isn't one of
+// the supported formats
+func FormatOf(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	switch mediaType {
+	case "text/csv", "application/csv":
+		return FormatCSV
+	case "application/x-ndjson", "application/ndjson", "application/jsonl", "application/x-jsonlines":
+		return FormatNDJSON
+	case "application/xml", "text/xml":
+		return FormatONIX
+	}
+	return ""
+}
+
+// ContentType returns the Content-Type an export in the format is sent with
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// onixReader reads the products of an ONIX message as rows
+type onixReader struct {
+	r *onix.Reader
+}
+
+func (r onixReader) Next() (models.ImportRow, error) {
+	row, err := r.r.Next()
+	var recordErr *onix.RecordError
+	if errors.As(err, &recordErr) {
+		return row, &RowError{Line: recordErr.Line, Err: recordErr.Err}
+	}
+	return row, err
+}
+
+// parseDate reads a publication date as YYYY-MM-DD or an RFC 3339 time
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, errors.New("published_at must be YYYY-MM-DD or an RFC 3339 time")
+}
+
+// formatDate writes a date at midnight UTC as YYYY-MM-DD and any other time
+// in RFC 3339
+func formatDate(t time.Time) string {
+	if t.Location() == time.UTC && t.Equal(t.Truncate(24*time.Hour)) {
+		return t.Format(time.DateOnly)
+	}
+	return t.Format(time.RFC3339)
+}
@@ -0,0 +1,205 @@
+package catalog
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// Columns are the columns of an exported CSV file, in order. Imports need
+// isbn, title, author, published_at and price, in any order; id is ignored
+// and other columns are optional. Categories and prices hold several values
+// separated by "|".
+var Columns = []string{"id", "isbn", "title", "author", "categories", "published_at", "price", "currency", "prices", "quantity"}
+
+var requiredColumns = []string{"isbn", "title", "author", "published_at", "price"}
+
+// listSeparator separates the values of a multi-valued cell
+const listSeparator = "|"
+
+type csvReader struct {
+	r       *csv.Reader
+	columns map[string]int
+}
+
+func newCSVReader(r io.Reader) (*csvReader, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff") // Spreadsheets often start files with a byte order mark
+		}
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	var missing []string
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing columns: %s", strings.Join(missing, ", "))
+	}
+
+	return &csvReader{r: cr, columns: columns}, nil
+}
+
+func (r *csvReader) Next() (models.ImportRow, error) {
+	record, err := r.r.Read()
+	if err == io.EOF {
+		return models.ImportRow{}, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return models.ImportRow{}, &RowError{Line: parseErr.StartLine, Err: parseErr.Err}
+	}
+	if err != nil {
+		return models.ImportRow{}, err
+	}
+
+	line, _ := r.r.FieldPos(0)
+	row := models.ImportRow{Line: line, Omitted: make(map[string]bool)}
+	book, err := r.book(record, row.Omitted)
+	if err != nil {
+		row.Book.ISBN = strings.TrimSpace(record[r.columns["isbn"]])
+		return row, &RowError{Line: line, Err: err}
+	}
+	row.Book = book
+	return row, nil
+}
+
+// book reads a record, noting the optional fields it leaves out
+func (r *csvReader) book(record []string, omitted map[string]bool) (models.Book, error) {
+	value := func(column string) (string, bool) {
+		i, ok := r.columns[column]
+		if !ok {
+			return "", false
+		}
+		return strings.TrimSpace(record[i]), true
+	}
+	get := func(column string) string {
+		v, _ := value(column)
+		return v
+	}
+
+	book := models.Book{
+		ISBN:   get("isbn"),
+		Title:  get("title"),
+		Author: get("author"),
+	}
+
+	published, err := parseDate(get("published_at"))
+	if err != nil {
+		return models.Book{}, err
+	}
+	book.PublishedAt = published
+
+	currency := get("currency")
+	if currency == "" {
+		currency = money.DefaultCurrency
+	}
+	if book.Price, err = money.Parse(get("price"), currency); err != nil {
+		return models.Book{}, fmt.Errorf("invalid price %q: %w", get("price"), err)
+	}
+
+	if prices, ok := value("prices"); !ok {
+		omitted[models.ImportPrices] = true
+	} else {
+		for _, p := range splitList(prices) {
+			price, err := money.ParseString(p)
+			if err != nil {
+				return models.Book{}, fmt.Errorf("invalid price %q in prices: %w", p, err)
+			}
+			book.Prices = append(book.Prices, price)
+		}
+	}
+
+	if categories, ok := value("categories"); !ok {
+		omitted[models.ImportCategories] = true
+	} else {
+		for _, c := range splitList(categories) {
+			// Slugs are lower case; anything else is taken for a subject code
+			if models.ValidSlug(c) {
+				book.Categories = append(book.Categories, models.CategoryRef{Slug: c})
+			} else {
+				book.Categories = append(book.Categories, models.CategoryRef{Code: c})
+			}
+		}
+	}
+
+	if quantity, ok := value("quantity"); !ok || quantity == "" {
+		omitted[models.ImportQuantity] = true
+	} else if book.Quantity, err = strconv.Atoi(quantity); err != nil {
+		return models.Book{}, fmt.Errorf("quantity must be a whole number, not %q", quantity)
+	}
+
+	return book, nil
+}
+
+// splitList splits a multi-valued cell, dropping empty values
+func splitList(s string) []string {
+	var values []string
+	for _, v := range strings.Split(s, listSeparator) {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+type csvWriter struct {
+	w *csv.Writer
+}
+
+func newCSVWriter(w io.Writer) (*csvWriter, error) {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(Columns); err != nil {
+		return nil, err
+	}
+	return &csvWriter{w: cw}, nil
+}
+
+func (w *csvWriter) Write(book models.Book) error {
+	categories := make([]string, len(book.Categories))
+	for i, category := range book.Categories {
+		categories[i] = category.Slug
+	}
+	prices := make([]string, len(book.Prices))
+	for i, price := range book.Prices {
+		prices[i] = price.String()
+	}
+
+	return w.w.Write([]string{
+		book.ID,
+		book.ISBN,
+		book.Title,
+		book.Author,
+		strings.Join(categories, listSeparator),
+		formatDate(book.PublishedAt),
+		book.Price.Decimal(),
+		book.Price.Currency,
+		strings.Join(prices, listSeparator),
+		strconv.Itoa(book.Quantity),
+	})
+}
+
+func (w *csvWriter) Flush() error {
+	w.w.Flush()
+	return w.w.Error()
+}
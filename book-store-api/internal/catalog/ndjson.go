@@ -0,0 +1,80 @@
+package catalog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// maxLineSize is the longest NDJSON line accepted
+const maxLineSize = 1 << 20
+
+type ndjsonReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func newNDJSONReader(r io.Reader) *ndjsonReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), maxLineSize)
+	return &ndjsonReader{scanner: scanner}
+}
+
+// Next reads the next non-blank line, which holds a book in the same form as
+// the body of POST /books
+func (r *ndjsonReader) Next() (models.ImportRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		data := bytes.TrimSpace(r.scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		row := models.ImportRow{Line: r.line, Omitted: make(map[string]bool)}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return row, &RowError{Line: r.line, Err: errors.New("not a JSON object")}
+		}
+		for _, field := range []string{models.ImportPrices, models.ImportCategories, models.ImportQuantity} {
+			if value, ok := fields[field]; !ok || string(value) == "null" {
+				row.Omitted[field] = true
+			}
+		}
+		if err := json.Unmarshal(data, &row.Book); err != nil {
+			return row, &RowError{Line: r.line, Err: fmt.Errorf("invalid book: %w", err)}
+		}
+		return row, nil
+	}
+
+	if errors.Is(r.scanner.Err(), bufio.ErrTooLong) {
+		return models.ImportRow{}, fmt.Errorf("line %d is longer than %d bytes", r.line+1, maxLineSize)
+	}
+	if err := r.scanner.Err(); err != nil {
+		return models.ImportRow{}, err
+	}
+	return models.ImportRow{}, io.EOF
+}
+
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	bw := bufio.NewWriter(w)
+	return &ndjsonWriter{w: bw, enc: json.NewEncoder(bw)}
+}
+
+// Write writes the book as a line of JSON, as GET /books/:id shows it
+func (w *ndjsonWriter) Write(book models.Book) error {
+	return w.enc.Encode(book)
+}
+
+func (w *ndjsonWriter) Flush() error {
+	return w.w.Flush()
+}
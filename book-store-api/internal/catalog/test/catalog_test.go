@@ -0,0 +1,535 @@
+package catalog_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/catalog"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+func price(s string) money.Money {
+	m, err := money.ParseString(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func date(year int) time.Time {
+	return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// result is what reading one row gave
+type result struct {
+	row  models.ImportRow
+	line int // Line of the RowError, or 0 for a row read cleanly
+}
+
+// readAll reads every row of an import file, stopping at the first error
+// that isn't a RowError
+func readAll(t *testing.T, format, data string) ([]result, error) {
+	t.Helper()
+	r, err := catalog.NewReader(format, strings.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	var results []result
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			return results, nil
+		}
+		var rowErr *catalog.RowError
+		if errors.As(err, &rowErr) {
+			results = append(results, result{row: row, line: rowErr.Line})
+			continue
+		}
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result{row: row})
+	}
+}
+
+// omitted lists the fields a row omits, in a fixed order
+func omitted(row models.ImportRow) []string {
+	var fields []string
+	for _, field := range []string{models.ImportPrices, models.ImportCategories, models.ImportQuantity} {
+		if row.Omitted[field] {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+	}{
+		{"text/csv", catalog.FormatCSV},
+		{"text/csv; charset=utf-8", catalog.FormatCSV},
+		{"application/csv", catalog.FormatCSV},
+		{"application/x-ndjson", catalog.FormatNDJSON},
+		{"application/jsonl", catalog.FormatNDJSON},
+		{"Application/X-NDJSON", catalog.FormatNDJSON},
+		{"application/xml", catalog.FormatONIX},
+		{"text/xml; charset=utf-8", catalog.FormatONIX},
+		{"application/json", ""},
+		{"", ""},
+		{"text/csv; charset", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if got := catalog.FormatOf(tt.contentType); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestUnknownFormat(t *testing.T) {
+	if _, err := catalog.NewReader("xlsx", strings.NewReader("")); !errors.Is(err, catalog.ErrUnknownFormat) {
+		t.Errorf("Expected %v, got %v", catalog.ErrUnknownFormat, err)
+	}
+	for _, format := range []string{"xlsx", catalog.FormatONIX} {
+		if _, err := catalog.NewWriter(format, io.Discard); !errors.Is(err, catalog.ErrUnknownFormat) {
+			t.Errorf("%s: expected %v, got %v", format, catalog.ErrUnknownFormat, err)
+		}
+	}
+}
+
+func TestCSVReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		book    models.Book
+		omitted []string
+		line    int
+	}{
+		{
+			name: "every column",
+			data: "id,isbn,title,author,categories,published_at,price,currency,prices,quantity\n" +
+				"ignored,978-0-13-235088-4,Clean Code,Robert C. Martin,software|FIC000000,2008-08-01,37.49,USD,34.99 EUR| 29.99 GBP,15\n",
+			book: models.Book{
+				ISBN:        "978-0-13-235088-4",
+				Title:       "Clean Code",
+				Author:      "Robert C. Martin",
+				Categories:  []models.CategoryRef{{Slug: "software"}, {Code: "FIC000000"}},
+				PublishedAt: time.Date(2008, 8, 1, 0, 0, 0, 0, time.UTC),
+				Price:       price("37.49 USD"),
+				Prices:      money.Prices{price("34.99 EUR"), price("29.99 GBP")},
+				Quantity:    15,
+			},
+		},
+		{
+			name: "required columns only, in any order",
+			data: "\ufeffTitle, Price ,ISBN,Published_At,Author\n" +
+				"Refactoring,9.99,9780201485677,1999-07-08T12:00:00Z,Martin Fowler\n",
+			book: models.Book{
+				ISBN:        "9780201485677",
+				Title:       "Refactoring",
+				Author:      "Martin Fowler",
+				PublishedAt: time.Date(1999, 7, 8, 12, 0, 0, 0, time.UTC),
+				Price:       price("9.99 USD"),
+			},
+			omitted: []string{models.ImportPrices, models.ImportCategories, models.ImportQuantity},
+		},
+		{
+			name: "empty cells clear lists but a blank quantity is omitted",
+			data: "isbn,title,author,published_at,price,currency,prices,categories,quantity\n" +
+				"9780201485677,Refactoring,Martin Fowler,1999-07-08,1500,JPY,,,\n",
+			book: models.Book{
+				ISBN:        "9780201485677",
+				Title:       "Refactoring",
+				Author:      "Martin Fowler",
+				PublishedAt: time.Date(1999, 7, 8, 0, 0, 0, 0, time.UTC),
+				Price:       price("1500 JPY"),
+			},
+			omitted: []string{models.ImportQuantity},
+		},
+		{
+			name: "invalid date",
+			data: "isbn,title,author,published_at,price\n9780201485677,Refactoring,Martin Fowler,08/07/1999,9.99\n",
+			book: models.Book{ISBN: "9780201485677"},
+			line: 2,
+		},
+		{
+			name: "invalid price",
+			data: "isbn,title,author,published_at,price\n9780201485677,Refactoring,Martin Fowler,1999-07-08,9.999\n",
+			book: models.Book{ISBN: "9780201485677"},
+			line: 2,
+		},
+		{
+			name: "invalid entry in prices",
+			data: "isbn,title,author,published_at,price,prices\n9780201485677,Refactoring,Martin Fowler,1999-07-08,9.99,8.99 XYZ\n",
+			book: models.Book{ISBN: "9780201485677"},
+			line: 2,
+		},
+		{
+			name: "invalid quantity",
+			data: "isbn,title,author,published_at,price,quantity\n9780201485677,Refactoring,Martin Fowler,1999-07-08,9.99,two\n",
+			book: models.Book{ISBN: "9780201485677"},
+			line: 2,
+		},
+		{
+			name: "wrong number of fields",
+			data: "isbn,title,author,published_at,price\n9780201485677,Refactoring\n",
+			line: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := readAll(t, catalog.FormatCSV, tt.data)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 row, got %d", len(results))
+			}
+			got := results[0]
+
+			if got.line != tt.line {
+				t.Errorf("Expected error on line %d, got %d", tt.line, got.line)
+			}
+			if !reflect.DeepEqual(got.row.Book, tt.book) {
+				t.Errorf("Expected %+v, got %+v", tt.book, got.row.Book)
+			}
+			if tt.line == 0 && !reflect.DeepEqual(omitted(got.row), tt.omitted) {
+				t.Errorf("Expected omitted %v, got %v", tt.omitted, omitted(got.row))
+			}
+		})
+	}
+}
+
+func TestCSVReader_Lines(t *testing.T) {
+	data := "isbn,title,author,published_at,price\n" +
+		"9780132350884,Clean Code,Robert C. Martin,2008-08-01,37.49\n" +
+		"9780201485677,\"Refactoring:\nImproving the Design of Existing Code\",Martin Fowler,1999-07-08,9.99\n" +
+		"9780201633610,Design Patterns,Erich Gamma,1994,44.99\n" +
+		"9780201896831,The Art of Computer Programming,Donald Knuth,1968-01-01,199.00\n"
+
+	results, err := readAll(t, catalog.FormatCSV, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// A quoted line break moves the rows after it down a line, and a bad
+	// row doesn't stop the rest being read
+	want := []struct{ line, errLine int }{{2, 0}, {3, 0}, {5, 5}, {6, 0}}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d rows, got %d", len(want), len(results))
+	}
+	for i, w := range want {
+		if results[i].row.Line != w.line || results[i].line != w.errLine {
+			t.Errorf("Row %d: expected line %d and error line %d, got %d and %d",
+				i, w.line, w.errLine, results[i].row.Line, results[i].line)
+		}
+	}
+	if results[1].row.Book.Title != "Refactoring:\nImproving the Design of Existing Code" {
+		t.Errorf("Expected the quoted title, got %q", results[1].row.Book.Title)
+	}
+}
+
+func TestCSVReader_Header(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty file", "", "the file is empty"},
+		{"missing columns", "isbn,title,price\n", "missing columns: author, published_at"},
+		{"malformed header", "isbn,\"title\n", "reading header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAll(t, catalog.FormatCSV, tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNDJSONReader(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		book    models.Book
+		omitted []string
+		failed  bool
+	}{
+		{
+			name: "every field",
+			line: `{"isbn":"9780132350884","title":"Clean Code","author":"Robert C. Martin","published_at":"2008-08-01T00:00:00Z",` +
+				`"price":{"amount":"37.49","currency":"USD"},"prices":[{"amount":"34.99","currency":"EUR"}],"categories":[{"slug":"software"}],"quantity":15}`,
+			book: models.Book{
+				ISBN:        "9780132350884",
+				Title:       "Clean Code",
+				Author:      "Robert C. Martin",
+				PublishedAt: time.Date(2008, 8, 1, 0, 0, 0, 0, time.UTC),
+				Price:       price("37.49 USD"),
+				Prices:      money.Prices{price("34.99 EUR")},
+				Categories:  []models.CategoryRef{{Slug: "software"}},
+				Quantity:    15,
+			},
+		},
+		{
+			name: "optional fields left out or null",
+			line: `{"isbn":"9780201485677","title":"Refactoring","published_at":"1999-07-08T00:00:00Z","price":"9.99","prices":null}`,
+			book: models.Book{
+				ISBN:        "9780201485677",
+				Title:       "Refactoring",
+				PublishedAt: time.Date(1999, 7, 8, 0, 0, 0, 0, time.UTC),
+				Price:       price("9.99 USD"),
+			},
+			omitted: []string{models.ImportPrices, models.ImportCategories, models.ImportQuantity},
+		},
+		{
+			name:    "empty lists aren't omitted",
+			line:    `{"isbn":"9780201485677","prices":[],"categories":[],"quantity":0}`,
+			book:    models.Book{ISBN: "9780201485677", Prices: money.Prices{}, Categories: []models.CategoryRef{}},
+			omitted: nil,
+		},
+		{"not an object", `["9780201485677"]`, models.Book{}, nil, true},
+		{"not JSON", `isbn=9780201485677`, models.Book{}, nil, true},
+		{"invalid price", `{"isbn":"9780201485677","price":"9.999"}`, models.Book{}, nil, true},
+		{"wrong type", `{"isbn":"9780201485677","quantity":"two"}`, models.Book{}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := readAll(t, catalog.FormatNDJSON, tt.line+"\n")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("Expected 1 row, got %d", len(results))
+			}
+			got := results[0]
+
+			if failed := got.line != 0; failed != tt.failed {
+				t.Fatalf("Expected failure %v, got error line %d", tt.failed, got.line)
+			}
+			if tt.failed {
+				return
+			}
+			if !reflect.DeepEqual(got.row.Book, tt.book) {
+				t.Errorf("Expected %+v, got %+v", tt.book, got.row.Book)
+			}
+			if !reflect.DeepEqual(omitted(got.row), tt.omitted) {
+				t.Errorf("Expected omitted %v, got %v", tt.omitted, omitted(got.row))
+			}
+		})
+	}
+}
+
+func TestNDJSONReader_Lines(t *testing.T) {
+	data := "{\"isbn\":\"9780132350884\"}\n" +
+		"\n" +
+		"   \r\n" +
+		"{\"isbn\":\"9780201485677\"\n" +
+		"{\"isbn\":\"9780201633610\"}\r\n" +
+		"{\"isbn\":\"9780201896831\"}" // No final newline
+
+	results, err := readAll(t, catalog.FormatNDJSON, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []struct {
+		line   int
+		failed bool
+		isbn   string
+	}{
+		{1, false, "9780132350884"},
+		{4, true, ""},
+		{5, false, "9780201633610"},
+		{6, false, "9780201896831"},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d rows, got %d", len(want), len(results))
+	}
+	for i, w := range want {
+		got := results[i]
+		if got.row.Line != w.line || (got.line != 0) != w.failed || got.row.Book.ISBN != w.isbn {
+			t.Errorf("Row %d: expected line %d failed %v ISBN %q, got line %d error line %d ISBN %q",
+				i, w.line, w.failed, w.isbn, got.row.Line, got.line, got.row.Book.ISBN)
+		}
+	}
+}
+
+func TestNDJSONReader_LineTooLong(t *testing.T) {
+	data := "{\"isbn\":\"9780132350884\"}\n{\"title\":\"" + strings.Repeat("x", 1<<20) + "\"}\n"
+
+	results, err := readAll(t, catalog.FormatNDJSON, data)
+	if len(results) != 1 {
+		t.Errorf("Expected 1 row before the long line, got %d", len(results))
+	}
+	var rowErr *catalog.RowError
+	if err == nil || errors.As(err, &rowErr) {
+		t.Errorf("Expected an error ending the import, got %v", err)
+	}
+}
+
+// exported are books as an export sees them, with IDs and store-set fields
+func exported() []models.Book {
+	return []models.Book{
+		{
+			ID:          "b1",
+			ISBN:        "9780132350884",
+			Title:       "Clean Code",
+			Author:      "Robert C. Martin",
+			Categories:  []models.CategoryRef{{ID: "c1", Slug: "software", Name: "Software"}, {ID: "c2", Slug: "agile", Name: "Agile"}},
+			PublishedAt: time.Date(2008, 8, 1, 0, 0, 0, 0, time.UTC),
+			Price:       price("37.49 USD"),
+			Prices:      money.Prices{price("34.99 EUR"), price("5000 JPY")},
+			Quantity:    15,
+		},
+		{
+			ID:          "b2",
+			ISBN:        "9780201485677",
+			Title:       "Refactoring, \"Improving\" Code",
+			Author:      "Martin Fowler, Kent Beck",
+			PublishedAt: time.Date(1999, 7, 8, 9, 30, 0, 0, time.FixedZone("EST", -5*60*60)),
+			Price:       price("9.99 GBP"),
+		},
+	}
+}
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := catalog.NewWriter(catalog.FormatCSV, &buf)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, book := range exported() {
+		if err := w.Write(book); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := "id,isbn,title,author,categories,published_at,price,currency,prices,quantity\n" +
+		"b1,9780132350884,Clean Code,Robert C. Martin,software|agile,2008-08-01,37.49,USD,34.99 EUR|5000 JPY,15\n" +
+		"b2,9780201485677,\"Refactoring, \"\"Improving\"\" Code\",\"Martin Fowler, Kent Beck\",,1999-07-08T09:30:00-05:00,9.99,GBP,,0\n"
+	if buf.String() != want {
+		t.Errorf("Expected:\n%s\ngot:\n%s", want, buf.String())
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, format := range []string{catalog.FormatCSV, catalog.FormatNDJSON} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := catalog.NewWriter(format, &buf)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			books := exported()
+			for _, book := range books {
+				if err := w.Write(book); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			results, err := readAll(t, format, buf.String())
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(results) != len(books) {
+				t.Fatalf("Expected %d rows, got %d", len(books), len(results))
+			}
+
+			for i, got := range results {
+				want := books[i]
+				book := got.row.Book
+				if got.line != 0 {
+					t.Errorf("Row %d: unexpected error on line %d", i, got.line)
+				}
+				// NDJSON leaves out empty lists, which an import keeps as they are
+				if len(want.Prices) > 0 && got.row.Omitted[models.ImportPrices] {
+					t.Errorf("Row %d: expected prices not to be omitted", i)
+				}
+				if len(want.Categories) > 0 && got.row.Omitted[models.ImportCategories] {
+					t.Errorf("Row %d: expected categories not to be omitted", i)
+				}
+				if got.row.Omitted[models.ImportQuantity] {
+					t.Errorf("Row %d: expected quantity not to be omitted", i)
+				}
+				if book.ISBN != want.ISBN || book.Title != want.Title || book.Author != want.Author ||
+					book.Price != want.Price || book.Quantity != want.Quantity || !book.PublishedAt.Equal(want.PublishedAt) {
+					t.Errorf("Row %d: expected %+v, got %+v", i, want, book)
+				}
+				if len(book.Prices) != len(want.Prices) {
+					t.Errorf("Row %d: expected prices %v, got %v", i, want.Prices, book.Prices)
+				}
+				for j := range min(len(book.Prices), len(want.Prices)) {
+					if book.Prices[j] != want.Prices[j] {
+						t.Errorf("Row %d: expected prices %v, got %v", i, want.Prices, book.Prices)
+					}
+				}
+				if len(book.Categories) != len(want.Categories) {
+					t.Errorf("Row %d: expected categories %v, got %v", i, want.Categories, book.Categories)
+				}
+				for j := range min(len(book.Categories), len(want.Categories)) {
+					if book.Categories[j].Slug != want.Categories[j].Slug {
+						t.Errorf("Row %d: expected categories %v, got %v", i, want.Categories, book.Categories)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestONIXReader(t *testing.T) {
+	product := func(reference, notification string) string {
+		return "<Product><RecordReference>" + reference + "</RecordReference><NotificationType>" + notification + "</NotificationType>" +
+			"<ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>9780306406157</IDValue></ProductIdentifier>" +
+			"<DescriptiveDetail><TitleDetail><TitleType>01</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel>" +
+			"<TitleText>Thinking in Systems</TitleText></TitleElement></TitleDetail></DescriptiveDetail>" +
+			"<PublishingDetail><PublishingDate><PublishingDateRole>01</PublishingDateRole><Date>20081203</Date></PublishingDate></PublishingDetail>" +
+			"<ProductSupply><SupplyDetail><Price><PriceType>02</PriceType><PriceAmount>19.95</PriceAmount><CurrencyCode>USD</CurrencyCode></Price></SupplyDetail></ProductSupply>" +
+			"</Product>\n"
+	}
+	data := "<ONIXMessage release=\"3.0\">\n" + product("acme-1", "03") + product("acme-2", "05") + "</ONIXMessage>"
+
+	results, err := readAll(t, catalog.FormatONIX, data)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Products that can't be mapped are row errors, like bad rows of other formats
+	want := []struct {
+		record  string
+		errLine int
+	}{{"acme-1", 0}, {"acme-2", 3}}
+	if len(results) != len(want) {
+		t.Fatalf("Expected %d rows, got %d", len(want), len(results))
+	}
+	for i, w := range want {
+		if results[i].row.Record != w.record || results[i].line != w.errLine {
+			t.Errorf("Row %d: expected %s with error line %d, got %s with %d",
+				i, w.record, w.errLine, results[i].row.Record, results[i].line)
+		}
+	}
+	if results[0].row.Book.Title != "Thinking in Systems" {
+		t.Errorf("Expected Thinking in Systems, got %q", results[0].row.Book.Title)
+	}
+}
@@ -0,0 +1,171 @@
+// Package covers checks uploaded cover images and makes their thumbnails.
+// Only the standard library's image codecs are used, so covers can be JPEG,
+// PNG or GIF.
+package covers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"net/http"
+
+	// Register the decoders for the accepted formats
+	_ "image/gif"
+	_ "image/png"
+)
+
+const (
+	// MaxSize is the largest cover file accepted, in bytes
+	MaxSize = 10 << 20
+	// MaxPixels is the largest cover accepted, in pixels, so a small file
+	// can't decode into an enormous image
+	MaxPixels = 50_000_000
+
+	thumbnailQuality = 85
+)
+
+var (
+	// ErrUnsupportedType is returned for files that aren't JPEG, PNG or GIF images
+	ErrUnsupportedType = errors.New("cover must be a JPEG, PNG or GIF image")
+	// ErrTooLarge is returned for files over MaxSize or images over MaxPixels
+	ErrTooLarge = errors.New("cover image is too large")
+	// ErrInvalidImage is returned for files that look like images but can't be decoded
+	ErrInvalidImage = errors.New("cover image can't be read")
+)
+
+// ThumbnailSize is a named thumbnail width. Heights keep the cover's aspect ratio.
+type ThumbnailSize struct {
+	Name  string
+	Width int
+}
+
+// ThumbnailSizes are the thumbnails made for every cover, smallest first
+var ThumbnailSizes = []ThumbnailSize{
+	{Name: "small", Width: 120},
+	{Name: "medium", Width: 300},
+	{Name: "large", Width: 600},
+}
+
+// extensions maps the accepted content types to file extensions
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image is an uploaded cover with its thumbnails
+type Image struct {
+	Data        []byte
+	ContentType string // Sniffed from the data; what the client claimed is ignored
+	Extension   string
+	Width       int
+	Height      int
+	Thumbnails  []Thumbnail
+}
+
+// Thumbnail is a JPEG copy of a cover scaled down to one of ThumbnailSizes.
+// Covers narrower than the size aren't scaled up.
+type Thumbnail struct {
+	Name   string
+	Data   []byte
+	Width  int
+	Height int
+}
+
+// Process checks an uploaded file and makes its thumbnails
+func Process(data []byte) (Image, error) {
+	if len(data) > MaxSize {
+		return Image{}, ErrTooLarge
+	}
+
+	contentType := http.DetectContentType(data)
+	extension, ok := extensions[contentType]
+	if !ok {
+		return Image{}, ErrUnsupportedType
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 {
+		return Image{}, ErrInvalidImage
+	}
+	if config.Width*config.Height > MaxPixels {
+		return Image{}, ErrTooLarge
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrInvalidImage
+	}
+
+	// Thumbnails are JPEGs, so transparent covers are laid on white
+	flat := image.NewRGBA(image.Rect(0, 0, config.Width, config.Height))
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, src.Bounds().Min, draw.Over)
+
+	cover := Image{
+		Data:        data,
+		ContentType: contentType,
+		Extension:   extension,
+		Width:       config.Width,
+		Height:      config.Height,
+	}
+	for _, size := range ThumbnailSizes {
+		thumbnail := scale(flat, size.Width)
+
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, thumbnail, &jpeg.Options{Quality: thumbnailQuality}); err != nil {
+			return Image{}, fmt.Errorf("encoding %s thumbnail: %w", size.Name, err)
+		}
+		cover.Thumbnails = append(cover.Thumbnails, Thumbnail{
+			Name:   size.Name,
+			Data:   buf.Bytes(),
+			Width:  thumbnail.Bounds().Dx(),
+			Height: thumbnail.Bounds().Dy(),
+		})
+	}
+
+	return cover, nil
+}
+
+// scale shrinks an image to the width, keeping its aspect ratio, by
+// averaging the source pixels under each target pixel. Images no wider than
+// the width are returned as they are.
+func scale(src *image.RGBA, width int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	if sw <= width {
+		return src
+	}
+	height := max(1, (sh*width+sw/2)/sw)
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0, y1 := y*sh/height, max((y+1)*sh/height, y*sh/height+1)
+		for x := 0; x < width; x++ {
+			x0, x1 := x*sw/width, max((x+1)*sw/width, x*sw/width+1)
+
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					p := row[sx*4 : sx*4+4]
+					r += int(p[0])
+					g += int(p[1])
+					b += int(p[2])
+					a += int(p[3])
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
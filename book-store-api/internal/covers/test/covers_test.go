@@ -0,0 +1,218 @@
+package covers_test
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/covers"
+)
+
+// cover returns a width x height image filled with c
+func cover(width, height int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeGIF(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode GIF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("Failed to encode JPEG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// near reports whether two 8-bit channel values are within JPEG's rounding
+func near(a, b uint32) bool {
+	a, b = a>>8, b>>8
+	return a+8 >= b && b+8 >= a
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name        string
+		data        func(t *testing.T) []byte
+		contentType string
+		extension   string
+		width       int
+		height      int
+		thumbnails  [][2]int
+	}{
+		{
+			name:        "PNG",
+			data:        func(t *testing.T) []byte { return encodePNG(t, cover(1000, 1500, color.Black)) },
+			contentType: "image/png",
+			extension:   ".png",
+			width:       1000,
+			height:      1500,
+			thumbnails:  [][2]int{{120, 180}, {300, 450}, {600, 900}},
+		},
+		{
+			name:        "JPEG",
+			data:        func(t *testing.T) []byte { return encodeJPEG(t, cover(400, 601, color.Black)) },
+			contentType: "image/jpeg",
+			extension:   ".jpg",
+			width:       400,
+			height:      601,
+			thumbnails:  [][2]int{{120, 180}, {300, 451}, {400, 601}}, // Heights round to the nearest pixel
+		},
+		{
+			name:        "GIF narrower than every size",
+			data:        func(t *testing.T) []byte { return encodeGIF(t, cover(100, 160, color.Black)) },
+			contentType: "image/gif",
+			extension:   ".gif",
+			width:       100,
+			height:      160,
+			thumbnails:  [][2]int{{100, 160}, {100, 160}, {100, 160}},
+		},
+		{
+			name:        "very wide banner keeps a height of one pixel",
+			data:        func(t *testing.T) []byte { return encodePNG(t, cover(2000, 2, color.Black)) },
+			contentType: "image/png",
+			extension:   ".png",
+			width:       2000,
+			height:      2,
+			thumbnails:  [][2]int{{120, 1}, {300, 1}, {600, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := covers.Process(tt.data(t))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.ContentType != tt.contentType || got.Extension != tt.extension {
+				t.Errorf("Expected %s %s, got %s %s", tt.contentType, tt.extension, got.ContentType, got.Extension)
+			}
+			if got.Width != tt.width || got.Height != tt.height {
+				t.Errorf("Expected %dx%d, got %dx%d", tt.width, tt.height, got.Width, got.Height)
+			}
+			if len(got.Thumbnails) != len(covers.ThumbnailSizes) {
+				t.Fatalf("Expected %d thumbnails, got %d", len(covers.ThumbnailSizes), len(got.Thumbnails))
+			}
+
+			for i, thumbnail := range got.Thumbnails {
+				if thumbnail.Name != covers.ThumbnailSizes[i].Name {
+					t.Errorf("Expected thumbnail %s, got %s", covers.ThumbnailSizes[i].Name, thumbnail.Name)
+				}
+				if thumbnail.Width != tt.thumbnails[i][0] || thumbnail.Height != tt.thumbnails[i][1] {
+					t.Errorf("Expected %s thumbnail %dx%d, got %dx%d", thumbnail.Name,
+						tt.thumbnails[i][0], tt.thumbnails[i][1], thumbnail.Width, thumbnail.Height)
+				}
+
+				config, format, err := image.DecodeConfig(bytes.NewReader(thumbnail.Data))
+				if err != nil {
+					t.Fatalf("Failed to decode %s thumbnail: %v", thumbnail.Name, err)
+				}
+				if format != "jpeg" || config.Width != thumbnail.Width || config.Height != thumbnail.Height {
+					t.Errorf("Expected a %dx%d JPEG, got a %dx%d %s", thumbnail.Width, thumbnail.Height,
+						config.Width, config.Height, format)
+				}
+			}
+		})
+	}
+}
+
+func TestProcess_Rejected(t *testing.T) {
+	// A GIF's logical screen size is all DecodeConfig reads, so a tiny file
+	// can claim to be enormous
+	huge := encodeGIF(t, cover(1, 1, color.Black))
+	huge[6], huge[7], huge[8], huge[9] = 0xff, 0xff, 0xff, 0xff
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"text", []byte("not an image"), covers.ErrUnsupportedType},
+		{"empty", nil, covers.ErrUnsupportedType},
+		{"WebP", append([]byte("RIFF\x00\x00\x00\x00WEBPVP8 "), make([]byte, 32)...), covers.ErrUnsupportedType},
+		{"truncated PNG", encodePNG(t, cover(10, 10, color.Black))[:20], covers.ErrInvalidImage},
+		{"too many pixels", huge, covers.ErrTooLarge},
+		{"over the size limit", append(encodePNG(t, cover(1, 1, color.Black)), make([]byte, covers.MaxSize)...), covers.ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := covers.Process(tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestProcess_Thumbnails(t *testing.T) {
+	// Stripes one pixel wide average to grey when halved
+	stripes := image.NewRGBA(image.Rect(0, 0, 1200, 40))
+	for y := 0; y < 40; y++ {
+		for x := 0; x < 1200; x++ {
+			if x%2 == 0 {
+				stripes.Set(x, y, color.Black)
+			} else {
+				stripes.Set(x, y, color.White)
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+		want color.Color
+	}{
+		{"solid colour is kept", cover(1200, 40, color.RGBA{R: 200, G: 40, B: 40, A: 255}), color.RGBA{R: 200, G: 40, B: 40, A: 255}},
+		{"pixels are averaged", stripes, color.RGBA{R: 127, G: 127, B: 127, A: 255}},
+		{"transparency is laid on white", cover(1200, 40, color.Transparent), color.White},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := covers.Process(encodePNG(t, tt.img))
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			large := got.Thumbnails[len(got.Thumbnails)-1]
+			thumbnail, err := jpeg.Decode(bytes.NewReader(large.Data))
+			if err != nil {
+				t.Fatalf("Failed to decode thumbnail: %v", err)
+			}
+
+			r, g, b, _ := thumbnail.At(large.Width/2, large.Height/2).RGBA()
+			wr, wg, wb, _ := tt.want.RGBA()
+			if !near(r, wr) || !near(g, wg) || !near(b, wb) {
+				t.Errorf("Expected %v, got %v", tt.want, thumbnail.At(large.Width/2, large.Height/2))
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package cursor_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/cursor"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+var secret = []byte("test-secret")
+
+// signed builds a token for an arbitrary payload, as a holder of the secret could
+func signed(payload string) string {
+	body := base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(body))
+	return body + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestCodec_RoundTrip(t *testing.T) {
+	codec := cursor.NewCodec(secret)
+	book := models.Book{
+		ID:          "b1",
+		Title:       "Clean Code",
+		Price:       money.New(3749, "USD"),
+		PublishedAt: time.Date(2008, 8, 1, 0, 0, 0, 0, time.UTC),
+		Rating:      models.Rating{Average: 4.5, Count: 2},
+	}
+
+	tests := []struct {
+		name     string
+		keys     []models.SortKey
+		backward bool
+	}{
+		{"default sort", models.DefaultSort, false},
+		{"several keys", []models.SortKey{{Field: models.SortByPrice, Descending: true}, {Field: models.SortByTitle}}, false},
+		{"backward", []models.SortKey{{Field: models.SortByPublishedAt}}, true},
+		{"rating", []models.SortKey{{Field: models.SortByRating, Descending: true}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := models.NewCursor(book, tt.keys, tt.backward)
+			token, err := codec.Encode(want)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.ContainsAny(token, "+/=") {
+				t.Errorf("Expected a URL-safe token, got %s", token)
+			}
+
+			got, err := codec.Decode(token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestCodec_Decode_Invalid(t *testing.T) {
+	codec := cursor.NewCodec(secret)
+	token, err := codec.Encode(models.NewCursor(models.Book{ID: "b1", Title: "Clean Code"}, []models.SortKey{{Field: models.SortByTitle}}, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, sig, _ := strings.Cut(token, ".")
+
+	other, err := cursor.NewCodec([]byte("other-secret")).Encode(models.NewCursor(models.Book{ID: "b1"}, models.DefaultSort, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"empty", ""},
+		{"no signature", body},
+		{"tampered body", strings.Replace(body, body[:4], "AAAA", 1) + "." + sig},
+		{"tampered signature", body + "." + sig[:len(sig)-2] + "AA"},
+		{"signature not base64", body + ".!!!"},
+		{"signed with another secret", other},
+		{"signed but not JSON", signed("not json")},
+		{"signed with an unknown sort field", signed(`{"s":"isbn","v":["x"],"id":"b1"}`)},
+		{"signed with too few values", signed(`{"s":"title,price","v":["x"],"id":"b1"}`)},
+		{"signed with a bad value", signed(`{"s":"price","v":["cheap"],"id":"b1"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := codec.Decode(tt.token); !errors.Is(err, models.ErrInvalidCursor) {
+				t.Errorf("Expected ErrInvalidCursor, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewCodec_RandomSecret(t *testing.T) {
+	token, err := cursor.NewCodec(nil).Encode(models.NewCursor(models.Book{ID: "b1"}, models.DefaultSort, false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Each codec without a secret gets its own, so tokens don't carry over
+	if _, err := cursor.NewCodec(nil).Decode(token); !errors.Is(err, models.ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+}
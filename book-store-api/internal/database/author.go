@@ -0,0 +1,265 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAuthorNotFound is returned when no author has the requested ID
+	ErrAuthorNotFound = errors.New("author not found")
+	// ErrDuplicateAuthor is returned when saving an author with another author's name
+	ErrDuplicateAuthor = errors.New("an author with this name already exists")
+	// ErrAuthorInUse is returned when deleting an author that is credited on books
+	ErrAuthorInUse = errors.New("author is credited on books")
+	// ErrInvalidContributors is returned when a book's contributors can't be saved
+	ErrInvalidContributors = errors.New("invalid contributors")
+)
+
+// AuthorStore keeps the authors books are linked to. Author names are
+// unique, ignoring case.
+type AuthorStore interface {
+	ListAuthors() ([]models.Author, error)
+	GetAuthor(id string) (models.Author, error)
+	CreateAuthor(author models.Author) (models.Author, error)
+	UpdateAuthor(id string, author models.Author) (models.Author, error)
+	DeleteAuthor(id string) error
+	GetAuthorBooks(id string) ([]models.Book, error)
+}
+
+// ListAuthors returns all authors sorted by name
+func (m *MockStore) ListAuthors() ([]models.Author, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	authors := make([]models.Author, 0, len(m.authors))
+	for _, author := range m.authors {
+		authors = append(authors, m.withBookCount(author))
+	}
+	sort.Slice(authors, func(i, j int) bool {
+		return strings.ToLower(authors[i].Name) < strings.ToLower(authors[j].Name)
+	})
+
+	return authors, nil
+}
+
+// GetAuthor retrieves an author by ID
+func (m *MockStore) GetAuthor(id string) (models.Author, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	author, exists := m.authors[id]
+	if !exists {
+		return models.Author{}, ErrAuthorNotFound
+	}
+
+	return m.withBookCount(author), nil
+}
+
+// CreateAuthor adds an author
+func (m *MockStore) CreateAuthor(author models.Author) (models.Author, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.authorNames[authorKey(author.Name)]; exists {
+		return models.Author{}, ErrDuplicateAuthor
+	}
+
+	return m.createAuthor(author), nil
+}
+
+// UpdateAuthor replaces an author's details. A new name is shown on every
+// book crediting the author.
+func (m *MockStore) UpdateAuthor(id string, author models.Author) (models.Author, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.authors[id]
+	if !exists {
+		return models.Author{}, ErrAuthorNotFound
+	}
+	if otherID, exists := m.authorNames[authorKey(author.Name)]; exists && otherID != id {
+		return models.Author{}, ErrDuplicateAuthor
+	}
+
+	author.ID = existing.ID
+	author.Name = strings.TrimSpace(author.Name)
+	author.CreatedAt = existing.CreatedAt
+	author.UpdatedAt = time.Now()
+	author.BookCount = 0
+
+	delete(m.authorNames, authorKey(existing.Name))
+	m.authorNames[authorKey(author.Name)] = id
+	m.authors[id] = author
+
+	if author.Name != existing.Name {
+		for bookID, book := range m.books {
+			if !book.HasContributor(id) {
+				continue
+			}
+			book.Contributors = slices.Clone(book.Contributors)
+			for i := range book.Contributors {
+				if book.Contributors[i].AuthorID == id {
+					book.Contributors[i].Name = author.Name
+				}
+			}
+			book.Author = models.JoinAuthors(book.Contributors)
+			m.books[bookID] = book
+			m.index.Add(bookID, searchFields(book))
+		}
+	}
+
+	return m.withBookCount(author), nil
+}
+
+// DeleteAuthor removes an author that isn't credited on any book
+func (m *MockStore) DeleteAuthor(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	author, exists := m.authors[id]
+	if !exists {
+		return ErrAuthorNotFound
+	}
+	if m.withBookCount(author).BookCount > 0 {
+		return ErrAuthorInUse
+	}
+
+	delete(m.authors, id)
+	delete(m.authorNames, authorKey(author.Name))
+	return nil
+}
+
+// GetAuthorBooks returns the books crediting an author, newest first
+func (m *MockStore) GetAuthorBooks(id string) ([]models.Book, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.authors[id]; !exists {
+		return nil, ErrAuthorNotFound
+	}
+
+	var books []models.Book
+	for _, book := range m.books {
+		if book.HasContributor(id) {
+			books = append(books, book)
+		}
+	}
+	sort.Slice(books, func(i, j int) bool {
+		return books[i].PublishedAt.After(books[j].PublishedAt)
+	})
+
+	return books, nil
+}
+
+// createAuthor stores a new author; the caller must hold m.mu and have
+// checked the name is free
+func (m *MockStore) createAuthor(author models.Author) models.Author {
+	now := time.Now()
+	author.ID = uuid.New().String()
+	author.Name = strings.TrimSpace(author.Name)
+	author.BookCount = 0
+	author.CreatedAt = now
+	author.UpdatedAt = now
+
+	m.authors[author.ID] = author
+	m.authorNames[authorKey(author.Name)] = author.ID
+	return author
+}
+
+// setContributors links the book to its authors, creating authors that are
+// named but don't exist yet. A book saved with only an author string has it
+// split into authors, which migrates books from before authors were kept
+// separately. Nothing is created unless every contributor is valid. The
+// caller must hold m.mu.
+func (m *MockStore) setContributors(book *models.Book) error {
+	contributors, err := m.checkContributors(*book)
+	if err != nil {
+		return err
+	}
+
+	type credit struct {
+		authorID string
+		role     models.ContributorRole
+	}
+	seen := make(map[credit]bool)
+
+	linked := make([]models.Contributor, 0, len(contributors))
+	for _, c := range contributors {
+		author, exists := m.authors[c.AuthorID]
+		if !exists {
+			if id, named := m.authorNames[authorKey(c.Name)]; named {
+				author = m.authors[id]
+			} else {
+				author = m.createAuthor(models.Author{Name: c.Name})
+			}
+		}
+
+		if key := (credit{author.ID, c.Role}); !seen[key] {
+			seen[key] = true
+			linked = append(linked, models.Contributor{AuthorID: author.ID, Name: author.Name, Role: c.Role})
+		}
+	}
+
+	book.Contributors = linked
+	book.Author = models.JoinAuthors(linked)
+	return nil
+}
+
+// checkContributors returns the book's contributors, from its author string
+// if it has none, with default roles, or an error if any of them is invalid.
+// The caller must hold m.mu.
+func (m *MockStore) checkContributors(book models.Book) ([]models.Contributor, error) {
+	contributors := slices.Clone(book.Contributors)
+	if len(contributors) == 0 {
+		for _, name := range models.SplitAuthors(book.Author) {
+			contributors = append(contributors, models.Contributor{Name: name})
+		}
+	}
+
+	hasAuthor := false
+	for i, c := range contributors {
+		if c.Role == "" {
+			contributors[i].Role = models.RoleAuthor
+		}
+		if contributors[i].Role == models.RoleAuthor {
+			hasAuthor = true
+		}
+		if c.AuthorID != "" {
+			if _, exists := m.authors[c.AuthorID]; !exists {
+				return nil, fmt.Errorf("%w: author %s not found", ErrInvalidContributors, c.AuthorID)
+			}
+		} else if strings.TrimSpace(c.Name) == "" {
+			return nil, fmt.Errorf("%w: each contributor needs an author_id or name", ErrInvalidContributors)
+		}
+	}
+	if !hasAuthor {
+		return nil, fmt.Errorf("%w: a book needs at least one author", ErrInvalidContributors)
+	}
+
+	return contributors, nil
+}
+
+// withBookCount sets the number of books crediting the author; the caller
+// must hold m.mu
+func (m *MockStore) withBookCount(author models.Author) models.Author {
+	author.BookCount = 0
+	for _, book := range m.books {
+		if book.HasContributor(author.ID) {
+			author.BookCount++
+		}
+	}
+	return author
+}
+
+// authorKey is the form author names are compared in
+func authorKey(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
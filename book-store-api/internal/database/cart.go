@@ -0,0 +1,189 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+var (
+	// ErrCartItemNotFound is returned when the cart has no line for the book
+	ErrCartItemNotFound = errors.New("cart item not found")
+	// ErrCartEmpty is returned when checking out a cart with no items
+	ErrCartEmpty = errors.New("cart is empty")
+)
+
+// CartStore keeps shopping carts and turns them into orders. GetCart returns
+// an empty cart for an owner that has none. Checkout must place the order and
+// empty the cart in one atomic step, with the same guarantees as CreateOrder.
+type CartStore interface {
+	GetCart(owner string) (models.Cart, error)
+	AddCartItem(owner, bookID string, quantity int) (models.Cart, error)
+	UpdateCartItem(owner, bookID string, quantity int) (models.Cart, error)
+	RemoveCartItem(owner, bookID string) (models.Cart, error)
+	ClearCart(owner string) error
+	Checkout(owner string, order models.Order, reservationTTL time.Duration) (models.Order, error)
+}
+
+// GetCart returns the owner's cart
+func (m *MockStore) GetCart(owner string) (models.Cart, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	cart := m.cart(owner)
+	if err := m.priceCart(&cart); err != nil {
+		return models.Cart{}, err
+	}
+
+	return cart, nil
+}
+
+// AddCartItem adds copies of a book to the cart, snapshotting its price if
+// the book isn't in the cart yet
+func (m *MockStore) AddCartItem(owner, bookID string, quantity int) (models.Cart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, exists := m.books[bookID]
+	if !exists {
+		return models.Cart{}, ErrBookNotFound
+	}
+
+	cart := m.cart(owner)
+	for i, item := range cart.Items {
+		if item.BookID == bookID {
+			quantity += item.Quantity
+			if quantity > book.Available() {
+				return models.Cart{}, ErrInsufficientStock
+			}
+			cart.Items[i].Quantity = quantity
+			return m.saveCart(cart)
+		}
+	}
+
+	if quantity > book.Available() {
+		return models.Cart{}, ErrInsufficientStock
+	}
+	cart.Items = append(cart.Items, models.CartItem{
+		BookID:    book.ID,
+		Title:     book.Title,
+		UnitPrice: book.Price,
+		Quantity:  quantity,
+		AddedAt:   time.Now(),
+	})
+
+	return m.saveCart(cart)
+}
+
+// UpdateCartItem sets the quantity of a cart line, keeping its price snapshot
+func (m *MockStore) UpdateCartItem(owner, bookID string, quantity int) (models.Cart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cart := m.cart(owner)
+	for i, item := range cart.Items {
+		if item.BookID != bookID {
+			continue
+		}
+		if book, exists := m.books[bookID]; exists && quantity > book.Available() {
+			return models.Cart{}, ErrInsufficientStock
+		}
+		cart.Items[i].Quantity = quantity
+		return m.saveCart(cart)
+	}
+
+	return models.Cart{}, ErrCartItemNotFound
+}
+
+// RemoveCartItem removes a book from the cart
+func (m *MockStore) RemoveCartItem(owner, bookID string) (models.Cart, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cart := m.cart(owner)
+	for i, item := range cart.Items {
+		if item.BookID == bookID {
+			cart.Items = append(cart.Items[:i], cart.Items[i+1:]...)
+			return m.saveCart(cart)
+		}
+	}
+
+	return models.Cart{}, ErrCartItemNotFound
+}
+
+// ClearCart removes every item from the cart
+func (m *MockStore) ClearCart(owner string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.carts, owner)
+	return nil
+}
+
+// Checkout places an order for the items in the cart at the cart's prices
+// and empties the cart. order supplies the addresses; its customer and items
+// come from the cart. If any book is missing or short of stock nothing is
+// reserved and the error names the book.
+func (m *MockStore) Checkout(owner string, order models.Order, reservationTTL time.Duration) (models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cart := m.cart(owner)
+	if len(cart.Items) == 0 {
+		return models.Order{}, ErrCartEmpty
+	}
+
+	order.CustomerID = owner
+	order.Items = nil
+	for _, item := range cart.Items {
+		order.Items = append(order.Items, models.OrderItem{
+			ProductID: item.BookID,
+			Title:     item.Title,
+			Quantity:  item.Quantity,
+			UnitPrice: item.UnitPrice,
+		})
+	}
+
+	order, err := m.placeOrder(order, reservationTTL)
+	if err != nil {
+		return models.Order{}, err
+	}
+	delete(m.carts, owner)
+
+	return order, nil
+}
+
+// priceCart applies the promotions running now and recalculates the
+// cart's totals; the caller must hold m.mu
+func (m *MockStore) priceCart(cart *models.Cart) error {
+	lines := make([]models.OrderItem, len(cart.Items))
+	for i, item := range cart.Items {
+		lines[i] = models.OrderItem{ProductID: item.BookID, UnitPrice: item.UnitPrice, Quantity: item.Quantity}
+	}
+	cart.Discounts = m.lineDiscounts(lines, time.Now())
+	return cart.Recalculate()
+}
+
+// cart returns a copy of the owner's cart, or a new empty one; the caller must hold m.mu
+func (m *MockStore) cart(owner string) models.Cart {
+	cart, exists := m.carts[owner]
+	if !exists {
+		now := time.Now()
+		return models.Cart{Owner: owner, Items: []models.CartItem{}, CreatedAt: now, UpdatedAt: now}
+	}
+
+	cart.Items = append([]models.CartItem{}, cart.Items...)
+	return cart
+}
+
+// saveCart recalculates the totals and stores the cart. It fails without
+// storing anything if the items are in different currencies. The caller must hold m.mu.
+func (m *MockStore) saveCart(cart models.Cart) (models.Cart, error) {
+	if err := m.priceCart(&cart); err != nil {
+		return models.Cart{}, err
+	}
+	cart.UpdatedAt = time.Now()
+	m.carts[cart.Owner] = cart
+	return cart, nil
+}
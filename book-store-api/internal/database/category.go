@@ -0,0 +1,422 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrCategoryNotFound is returned when no category has the requested slug
+	ErrCategoryNotFound = errors.New("category not found")
+	// ErrInvalidCategory is returned for a category with a bad slug or parent
+	ErrInvalidCategory = errors.New("invalid category")
+	// ErrDuplicateCategory is returned when saving a category with another category's slug or code
+	ErrDuplicateCategory = errors.New("a category with this slug or code already exists")
+	// ErrCategoryInUse is returned when deleting a category that has subcategories or books
+	ErrCategoryInUse = errors.New("category has subcategories or books")
+	// ErrInvalidCategories is returned when a book's categories can't be found
+	ErrInvalidCategories = errors.New("invalid categories")
+)
+
+// CategoryStore keeps the category tree. Categories are addressed by slug,
+// and slugs and codes are unique.
+type CategoryStore interface {
+	ListCategories() ([]models.Category, error)
+	GetCategory(slug string) (models.Category, error)
+	CreateCategory(category models.Category) (models.Category, error)
+	UpdateCategory(slug string, category models.Category) (models.Category, error)
+	DeleteCategory(slug string) error
+	CategoryDescendants(slug string) (map[string]bool, error)
+	ImportCategories(entries []models.CategoryImport) (models.CategoryImportResult, error)
+}
+
+// ListCategories returns the category tree: the top-level categories with
+// their descendants nested under them
+func (m *MockStore) ListCategories() ([]models.Category, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.subtrees(""), nil
+}
+
+// GetCategory returns a category with its ancestors and descendants
+func (m *MockStore) GetCategory(slug string) (models.Category, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	category, exists := m.categoryBySlug(slug)
+	if !exists {
+		return models.Category{}, ErrCategoryNotFound
+	}
+
+	for parentID := category.ParentID; parentID != ""; parentID = m.categories[parentID].ParentID {
+		category.Ancestors = append([]models.CategoryRef{m.categories[parentID].Ref()}, category.Ancestors...)
+	}
+	category.Children = m.subtrees(category.ID)
+
+	return category, nil
+}
+
+// CreateCategory adds a category
+func (m *MockStore) CreateCategory(category models.Category) (models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	category.ID = uuid.New().String()
+	if err := m.checkCategory(&category); err != nil {
+		return models.Category{}, err
+	}
+
+	now := time.Now()
+	category.CreatedAt = now
+	category.UpdatedAt = now
+	m.saveCategory(category, models.Category{})
+
+	return category, nil
+}
+
+// UpdateCategory replaces a category's details. It can be moved under
+// another parent, but not under itself or its descendants. Books in the
+// category show its new name and slug.
+func (m *MockStore) UpdateCategory(slug string, category models.Category) (models.Category, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.categoryBySlug(slug)
+	if !exists {
+		return models.Category{}, ErrCategoryNotFound
+	}
+
+	for parentID := category.ParentID; parentID != ""; parentID = m.categories[parentID].ParentID {
+		if parentID == existing.ID {
+			return models.Category{}, fmt.Errorf("%w: a category can't be moved under itself", ErrInvalidCategory)
+		}
+		if _, exists := m.categories[parentID]; !exists {
+			break // reported by checkCategory
+		}
+	}
+
+	category.ID = existing.ID
+	if category.Slug == "" {
+		category.Slug = existing.Slug
+	}
+	if err := m.checkCategory(&category); err != nil {
+		return models.Category{}, err
+	}
+
+	category.CreatedAt = existing.CreatedAt
+	category.UpdatedAt = time.Now()
+	m.saveCategory(category, existing)
+
+	return category, nil
+}
+
+// DeleteCategory removes a category with no subcategories and no books
+func (m *MockStore) DeleteCategory(slug string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	category, exists := m.categoryBySlug(slug)
+	if !exists {
+		return ErrCategoryNotFound
+	}
+
+	if len(m.childCategories(category.ID)) > 0 {
+		return ErrCategoryInUse
+	}
+	for _, book := range m.books {
+		if book.InCategories(map[string]bool{category.ID: true}) {
+			return ErrCategoryInUse
+		}
+	}
+
+	delete(m.categories, category.ID)
+	delete(m.categorySlugs, category.Slug)
+	delete(m.categoryCodes, category.Code)
+	m.unlinkCategory(category)
+	return nil
+}
+
+// CategoryDescendants returns the IDs of a category and every category below it
+func (m *MockStore) CategoryDescendants(slug string) (map[string]bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	category, exists := m.categoryBySlug(slug)
+	if !exists {
+		return nil, ErrCategoryNotFound
+	}
+
+	ids := map[string]bool{category.ID: true}
+	queue := []string{category.ID}
+	for len(queue) > 0 {
+		for _, child := range m.childCategories(queue[0]) {
+			ids[child.ID] = true
+			queue = append(queue, child.ID)
+		}
+		queue = queue[1:]
+	}
+
+	return ids, nil
+}
+
+// ImportCategories adds the categories of a subject scheme, creating the
+// headings on each path that don't exist yet and giving the last one the
+// entry's code. Headings are matched to existing categories by name under
+// the same parent, so importing a newer edition of a scheme updates it.
+func (m *MockStore) ImportCategories(entries []models.CategoryImport) (models.CategoryImportResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	result := models.CategoryImportResult{Total: len(entries)}
+	now := time.Now()
+
+	for _, entry := range entries {
+		var category models.Category
+		created := false
+		for _, name := range entry.Path {
+			child, exists := m.childCategoryNamed(category.ID, name)
+			created = !exists
+			if !exists {
+				child = models.Category{
+					ID:        uuid.New().String(),
+					Name:      name,
+					ParentID:  category.ID,
+					CreatedAt: now,
+					UpdatedAt: now,
+				}
+				child.Slug = m.uniqueSlug(m.defaultSlug(child))
+				m.saveCategory(child, models.Category{})
+				result.Created++
+			}
+			category = child
+		}
+
+		if category.Code == entry.Code {
+			continue
+		}
+
+		// The code moves here from wherever it was before
+		if otherID, exists := m.categoryCodes[entry.Code]; exists {
+			other := m.categories[otherID]
+			other.Code = ""
+			other.UpdatedAt = now
+			m.saveCategory(other, m.categories[otherID])
+		}
+		updated := category
+		updated.Code = entry.Code
+		updated.UpdatedAt = now
+		m.saveCategory(updated, category)
+		if !created {
+			result.Updated++
+		}
+	}
+
+	return result, nil
+}
+
+// checkCategory fills in a default slug and checks the category's slug,
+// code and parent; the caller must hold m.mu
+func (m *MockStore) checkCategory(category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	category.Code = strings.TrimSpace(category.Code)
+
+	if category.ParentID != "" {
+		if _, exists := m.categories[category.ParentID]; !exists {
+			return fmt.Errorf("%w: parent %s not found", ErrInvalidCategory, category.ParentID)
+		}
+	}
+
+	if category.Slug == "" {
+		category.Slug = m.uniqueSlug(m.defaultSlug(*category))
+	}
+	if !models.ValidSlug(category.Slug) {
+		return fmt.Errorf("%w: slug must be lower-case letters and digits separated by hyphens", ErrInvalidCategory)
+	}
+	if id, exists := m.categorySlugs[category.Slug]; exists && id != category.ID {
+		return ErrDuplicateCategory
+	}
+	if id, exists := m.categoryCodes[category.Code]; exists && category.Code != "" && id != category.ID {
+		return ErrDuplicateCategory
+	}
+
+	return nil
+}
+
+// saveCategory stores a category, replacing previous, and updates the books
+// in it and its place among its parent's children; the caller must hold m.mu
+func (m *MockStore) saveCategory(category, previous models.Category) {
+	delete(m.categorySlugs, previous.Slug)
+	delete(m.categoryCodes, previous.Code)
+
+	m.categories[category.ID] = category
+	m.categorySlugs[category.Slug] = category.ID
+	if category.Code != "" {
+		m.categoryCodes[category.Code] = category.ID
+	}
+
+	if previous.ID == "" || previous.ParentID != category.ParentID || compareCategories(previous, category) != 0 {
+		if previous.ID != "" {
+			m.unlinkCategory(previous)
+		}
+		siblings := m.subcategories[category.ParentID]
+		i, _ := slices.BinarySearchFunc(siblings, category, func(id string, target models.Category) int {
+			return compareCategories(m.categories[id], target)
+		})
+		m.subcategories[category.ParentID] = slices.Insert(siblings, i, category.ID)
+	}
+
+	if previous.ID == "" || category.Ref() == previous.Ref() {
+		return
+	}
+	for id, book := range m.books {
+		if !book.InCategories(map[string]bool{category.ID: true}) {
+			continue
+		}
+		book.Categories = slices.Clone(book.Categories)
+		for i := range book.Categories {
+			if book.Categories[i].ID == category.ID {
+				book.Categories[i] = category.Ref()
+			}
+		}
+		m.books[id] = book
+	}
+}
+
+// setCategories links the book to the categories it names by ID, slug or
+// code; the caller must hold m.mu
+func (m *MockStore) setCategories(book *models.Book) error {
+	seen := make(map[string]bool)
+	linked := make([]models.CategoryRef, 0, len(book.Categories))
+
+	for _, ref := range book.Categories {
+		id, exists := ref.ID, false
+		switch {
+		case ref.ID != "":
+			_, exists = m.categories[ref.ID]
+		case ref.Slug != "":
+			id, exists = m.categorySlugs[ref.Slug]
+		case ref.Code != "":
+			id, exists = m.categoryCodes[ref.Code]
+		}
+		if !exists {
+			return fmt.Errorf("%w: category %s not found", ErrInvalidCategories, firstNonEmpty(ref.ID, ref.Slug, ref.Code))
+		}
+
+		if !seen[id] {
+			seen[id] = true
+			linked = append(linked, m.categories[id].Ref())
+		}
+	}
+
+	book.Categories = linked
+	return nil
+}
+
+// categoryBySlug looks up a category; the caller must hold m.mu
+func (m *MockStore) categoryBySlug(slug string) (models.Category, bool) {
+	id, exists := m.categorySlugs[slug]
+	if !exists {
+		return models.Category{}, false
+	}
+	return m.categories[id], true
+}
+
+// unlinkCategory removes a category from its parent's children; the caller
+// must hold m.mu
+func (m *MockStore) unlinkCategory(category models.Category) {
+	siblings := m.subcategories[category.ParentID]
+	if i := slices.Index(siblings, category.ID); i >= 0 {
+		siblings = slices.Delete(siblings, i, i+1)
+	}
+	if len(siblings) == 0 {
+		delete(m.subcategories, category.ParentID)
+		return
+	}
+	m.subcategories[category.ParentID] = siblings
+}
+
+// childCategories returns a category's children in order, or the top-level
+// categories for an empty ID; the caller must hold m.mu
+func (m *MockStore) childCategories(parentID string) []models.Category {
+	ids := m.subcategories[parentID]
+	children := make([]models.Category, len(ids))
+	for i, id := range ids {
+		children[i] = m.categories[id]
+	}
+	return children
+}
+
+// compareCategories orders siblings by position, then by name ignoring
+// case, then by ID so the order is stable
+func compareCategories(a, b models.Category) int {
+	if a.Position != b.Position {
+		return a.Position - b.Position
+	}
+	if c := strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name)); c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+// childCategoryNamed finds a child by name, ignoring case; the caller must hold m.mu
+func (m *MockStore) childCategoryNamed(parentID, name string) (models.Category, bool) {
+	for _, id := range m.subcategories[parentID] {
+		if child := m.categories[id]; strings.EqualFold(child.Name, name) {
+			return child, true
+		}
+	}
+	return models.Category{}, false
+}
+
+// subtrees returns a category's children with their descendants nested;
+// the caller must hold m.mu
+func (m *MockStore) subtrees(parentID string) []models.Category {
+	children := m.childCategories(parentID)
+	for i := range children {
+		children[i].Children = m.subtrees(children[i].ID)
+	}
+	return children
+}
+
+// defaultSlug makes a slug from the category's name, prefixed with its
+// parent's slug; the caller must hold m.mu
+func (m *MockStore) defaultSlug(category models.Category) string {
+	slug := models.Slugify(category.Name)
+	if slug == "" {
+		slug = "category"
+	}
+	if parent, exists := m.categories[category.ParentID]; exists {
+		slug = strings.Trim(parent.Slug+"-"+slug, "-")
+	}
+	return slug
+}
+
+// uniqueSlug adds a number to a slug that is already taken; the caller must hold m.mu
+func (m *MockStore) uniqueSlug(slug string) string {
+	if _, taken := m.categorySlugs[slug]; !taken {
+		return slug
+	}
+	for n := 2; ; n++ {
+		candidate := slug + "-" + strconv.Itoa(n)
+		if _, taken := m.categorySlugs[candidate]; !taken {
+			return candidate
+		}
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,43 @@
+package database
+
+import (
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// CoverStore links books to their cover images. The images themselves are
+// kept in blob storage.
+type CoverStore interface {
+	SetBookCover(id string, cover *models.Cover) (*models.Cover, error)
+}
+
+// SetBookCover replaces a book's cover, or removes it for a nil cover, and
+// returns the previous one so its images can be deleted
+func (m *MockStore) SetBookCover(id string, cover *models.Cover) (*models.Cover, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book, exists := m.books[id]
+	if !exists {
+		return nil, ErrBookNotFound
+	}
+
+	previous := book.Cover
+	setCover(&book, cover)
+	book.UpdatedAt = time.Now()
+	m.books[id] = book
+
+	return previous, nil
+}
+
+// setCover sets a book's cover and the URLs shown for it
+func setCover(book *models.Book, cover *models.Cover) {
+	book.Cover = cover
+	book.CoverURL = ""
+	book.CoverThumbnailURLs = nil
+	if cover != nil {
+		book.CoverURL = cover.URL
+		book.CoverThumbnailURLs = cover.ThumbnailURLs()
+	}
+}
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"github.com/godwin/book-store-api/internal/isbn"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// ImportStore saves books from bulk imports, matching them to existing
+// books by ISBN
+type ImportStore interface {
+	ImportBook(row models.ImportRow, actor string, dryRun bool) (models.ImportAction, error)
+}
+
+// ImportBook creates the row's book, or updates the book with its ISBN. An
+// existing book keeps its own values for the fields the row omits, and its
+// contributors if the row's author string hasn't changed. In a dry run the
+// row is checked as it would be saved, but nothing is saved and no authors
+// are created.
+func (m *MockStore) ImportBook(row models.ImportRow, actor string, dryRun bool) (models.ImportAction, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	book := row.Book
+	book.ID = ""
+	code, err := isbn.Normalize(book.ISBN)
+	if err != nil {
+		return "", err
+	}
+
+	id, exists := m.isbns[code]
+	if !exists {
+		if dryRun {
+			return models.ImportCreated, m.checkBook(book)
+		}
+		_, err := m.createBook(book, actor)
+		return models.ImportCreated, err
+	}
+
+	existing := m.books[id]
+	if row.Omitted[models.ImportPrices] {
+		book.Prices = existing.Prices
+	}
+	if row.Omitted[models.ImportCategories] {
+		book.Categories = existing.Categories
+	}
+	if row.Omitted[models.ImportQuantity] {
+		book.Quantity = existing.Quantity
+	}
+	if len(book.Contributors) == 0 && book.Author == existing.Author {
+		book.Contributors = existing.Contributors
+	}
+
+	if dryRun {
+		book.ID = id
+		if err := m.checkBook(book); err != nil {
+			return models.ImportUpdated, err
+		}
+		if book.Quantity < existing.Reserved {
+			return models.ImportUpdated, ErrInsufficientStock
+		}
+		return models.ImportUpdated, nil
+	}
+	_, err = m.updateBook(id, book, actor)
+	return models.ImportUpdated, err
+}
+
+// checkBook runs the checks saving the book would, without saving anything;
+// the caller must hold m.mu
+func (m *MockStore) checkBook(book models.Book) error {
+	if err := m.setISBN(&book); err != nil {
+		return err
+	}
+	if _, err := m.checkContributors(book); err != nil {
+		return err
+	}
+	return m.setCategories(&book)
+}
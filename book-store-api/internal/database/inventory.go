@@ -0,0 +1,69 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/google/uuid"
+)
+
+// ErrInsufficientStock is returned when a movement or reservation needs more
+// copies than are available
+var ErrInsufficientStock = errors.New("insufficient stock")
+
+// InventoryStore records changes to book stock as a ledger of movements
+type InventoryStore interface {
+	RecordStockMovement(movement models.StockMovement) (models.StockMovement, error)
+	GetStockMovements(bookID string) ([]models.StockMovement, error)
+}
+
+// RecordStockMovement appends a movement to the book's ledger and updates its
+// quantity. The check against going negative and the update happen under the
+// store lock, so concurrent movements can't oversell.
+func (m *MockStore) RecordStockMovement(movement models.StockMovement) (models.StockMovement, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.recordStockMovement(movement)
+}
+
+// recordStockMovement does the work of RecordStockMovement; the caller must hold m.mu
+func (m *MockStore) recordStockMovement(movement models.StockMovement) (models.StockMovement, error) {
+	book, exists := m.books[movement.BookID]
+	if !exists {
+		return models.StockMovement{}, ErrBookNotFound
+	}
+
+	// Copies held by reservations can't be sold or adjusted away
+	balance := book.Quantity + movement.Delta
+	if balance < book.Reserved {
+		return models.StockMovement{}, ErrInsufficientStock
+	}
+
+	movement.ID = uuid.New().String()
+	movement.BalanceAfter = balance
+	movement.CreatedAt = time.Now()
+	m.movements[book.ID] = append(m.movements[book.ID], movement)
+
+	book.Quantity = balance
+	book.UpdatedAt = movement.CreatedAt
+	m.books[book.ID] = book
+
+	return movement, nil
+}
+
+// GetStockMovements returns a book's ledger, oldest first
+func (m *MockStore) GetStockMovements(bookID string) ([]models.StockMovement, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.books[bookID]; !exists {
+		return nil, ErrBookNotFound
+	}
+
+	movements := make([]models.StockMovement, len(m.movements[bookID]))
+	copy(movements, m.movements[bookID])
+
+	return movements, nil
+}
@@ -0,0 +1,241 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrOrderNotFound is returned when no order has the requested ID
+	ErrOrderNotFound = errors.New("order not found")
+	// ErrInvalidTransition is returned when an order can't move to the requested status
+	ErrInvalidTransition = errors.New("invalid order status transition")
+)
+
+// OrderStore keeps orders and drives their stock through the order's
+// lifecycle. CreateOrder must reserve stock for every item and save the order
+// in one atomic step: either all items are reserved or nothing changes.
+// Completing an order sells its copies and cancelling it releases them.
+type OrderStore interface {
+	CreateOrder(order models.Order, reservationTTL time.Duration) (models.Order, error)
+	GetOrder(id string) (models.Order, error)
+	ListOrders(filter models.OrderFilter) ([]models.Order, int, error)
+	UpdateOrderStatus(id string, status models.OrderStatus, actor string) (models.Order, error)
+}
+
+// CreateOrder places a pending order at the books' current prices
+func (m *MockStore) CreateOrder(order models.Order, reservationTTL time.Duration) (models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, item := range order.Items {
+		book, exists := m.books[item.ProductID]
+		if !exists {
+			return models.Order{}, fmt.Errorf("%w: %s", ErrBookNotFound, item.ProductID)
+		}
+		order.Items[i].Title = book.Title
+		order.Items[i].UnitPrice = book.Price
+	}
+
+	return m.placeOrder(order, reservationTTL)
+}
+
+// placeOrder reserves stock for every item, applies the promotions running
+// now and saves the order as pending.
+// Everything is checked before anything is reserved so a failure leaves no
+// holds behind. The caller must hold m.mu.
+func (m *MockStore) placeOrder(order models.Order, reservationTTL time.Duration) (models.Order, error) {
+	wanted := make(map[string]int)
+	for _, item := range order.Items {
+		wanted[item.ProductID] += item.Quantity
+	}
+	for _, item := range order.Items {
+		book, exists := m.books[item.ProductID]
+		if !exists {
+			return models.Order{}, fmt.Errorf("%w: %s", ErrBookNotFound, itemName(item))
+		}
+		if wanted[item.ProductID] > book.Available() {
+			return models.Order{}, fmt.Errorf("%w: %s", ErrInsufficientStock, itemName(item))
+		}
+	}
+
+	now := time.Now()
+	order.Discounts = m.lineDiscounts(order.Items, now)
+	if err := order.Recalculate(); err != nil {
+		return models.Order{}, err
+	}
+
+	order.ID = uuid.New().String()
+	order.Status = models.OrderPending
+	order.CreatedAt = now
+	order.UpdatedAt = now
+	if order.BillingAddress == "" {
+		order.BillingAddress = order.ShippingAddress
+	}
+
+	for i, item := range order.Items {
+		reservation, err := m.createReservation(models.Reservation{
+			BookID:    item.ProductID,
+			Quantity:  item.Quantity,
+			Actor:     order.CustomerID,
+			ExpiresAt: now.Add(reservationTTL),
+		})
+		if err != nil {
+			return models.Order{}, err
+		}
+		order.Items[i].ID = uuid.New().String()
+		order.Items[i].ReservationID = reservation.ID
+	}
+
+	m.orders[order.ID] = order
+
+	return order, nil
+}
+
+// GetOrder retrieves an order by its ID
+func (m *MockStore) GetOrder(id string) (models.Order, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	order, exists := m.orders[id]
+	if !exists {
+		return models.Order{}, ErrOrderNotFound
+	}
+
+	return order, nil
+}
+
+// ListOrders returns a page of the orders matching the filter, newest first,
+// and the number of matching orders
+func (m *MockStore) ListOrders(filter models.OrderFilter) ([]models.Order, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	orders := []models.Order{}
+	for _, order := range m.orders {
+		if filter.CustomerID != "" && order.CustomerID != filter.CustomerID {
+			continue
+		}
+		if filter.Status != "" && order.Status != filter.Status {
+			continue
+		}
+		orders = append(orders, order)
+	}
+
+	sort.Slice(orders, func(i, j int) bool {
+		if !orders[i].CreatedAt.Equal(orders[j].CreatedAt) {
+			return orders[i].CreatedAt.After(orders[j].CreatedAt)
+		}
+		return orders[i].ID < orders[j].ID
+	})
+
+	total := len(orders)
+	start := min(filter.Skip, total)
+	end := total
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, total)
+	}
+
+	return orders[start:end], total, nil
+}
+
+// UpdateOrderStatus moves an order to a new status if the transition is
+// allowed. Processing keeps the order's reservations until the order ends,
+// so the sweeper can't release copies being fulfilled. Completing sells the
+// order's copies, using its reservations where they are still active;
+// cancelling returns reserved copies to stock.
+func (m *MockStore) UpdateOrderStatus(id string, status models.OrderStatus, actor string) (models.Order, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	order, exists := m.orders[id]
+	if !exists {
+		return models.Order{}, ErrOrderNotFound
+	}
+	if !order.Status.CanTransition(status) {
+		return models.Order{}, fmt.Errorf("%w: %s to %s", ErrInvalidTransition, order.Status, status)
+	}
+
+	now := time.Now()
+	switch status {
+	case models.OrderProcessing:
+		for _, item := range order.Items {
+			if reservation, ok := m.reservations[item.ReservationID]; ok && reservation.Status == models.ReservationActive {
+				reservation.ExpiresAt = time.Time{}
+				reservation.UpdatedAt = now
+				m.reservations[reservation.ID] = reservation
+			}
+		}
+	case models.OrderCompleted:
+		if err := m.completeOrder(order, actor, now); err != nil {
+			return models.Order{}, err
+		}
+	case models.OrderCancelled:
+		for _, item := range order.Items {
+			if reservation, ok := m.reservations[item.ReservationID]; ok && reservation.Status == models.ReservationActive {
+				m.endReservation(reservation, models.ReservationReleased, now)
+			}
+		}
+	}
+
+	order.Status = status
+	order.UpdatedAt = now
+	m.orders[id] = order
+
+	return order, nil
+}
+
+// completeOrder records a sale for every item. Items whose reservation has
+// lapsed are sold from available stock, which is checked for all items
+// before anything is sold. The caller must hold m.mu.
+func (m *MockStore) completeOrder(order models.Order, actor string, now time.Time) error {
+	unreserved := make(map[string]int)
+	for _, item := range order.Items {
+		reservation, ok := m.reservations[item.ReservationID]
+		if ok && reservation.Expired(now) {
+			reservation = m.endReservation(reservation, models.ReservationExpired, now)
+		}
+		if !ok || reservation.Status != models.ReservationActive {
+			unreserved[item.ProductID] += item.Quantity
+		}
+	}
+	for _, item := range order.Items {
+		book, exists := m.books[item.ProductID]
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrBookNotFound, itemName(item))
+		}
+		if unreserved[item.ProductID] > book.Available() {
+			return fmt.Errorf("%w: %s", ErrInsufficientStock, itemName(item))
+		}
+	}
+
+	for _, item := range order.Items {
+		if reservation, ok := m.reservations[item.ReservationID]; ok && reservation.Status == models.ReservationActive {
+			m.endReservation(reservation, models.ReservationConfirmed, now)
+		}
+		if _, err := m.recordStockMovement(models.StockMovement{
+			BookID: item.ProductID,
+			Type:   models.MovementSale,
+			Delta:  -item.Quantity,
+			Reason: "order " + order.ID,
+			Actor:  actor,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// itemName names an order item in errors
+func itemName(item models.OrderItem) string {
+	if item.Title != "" {
+		return item.Title
+	}
+	return item.ProductID
+}
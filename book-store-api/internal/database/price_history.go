@@ -0,0 +1,50 @@
+package database
+
+import (
+	"slices"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/google/uuid"
+)
+
+// PriceHistoryStore keeps a record of every change to book prices
+type PriceHistoryStore interface {
+	GetPriceHistory(bookID string) ([]models.PriceChange, error)
+}
+
+// GetPriceHistory returns a book's price changes, oldest first
+func (m *MockStore) GetPriceHistory(bookID string) ([]models.PriceChange, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if _, exists := m.books[bookID]; !exists {
+		return nil, ErrBookNotFound
+	}
+
+	history := make([]models.PriceChange, len(m.priceHistory[bookID]))
+	copy(history, m.priceHistory[bookID])
+
+	return history, nil
+}
+
+// recordPriceChange appends the book's current prices to its history if they
+// differ from previous; previous is nil for a new book. The caller must hold m.mu.
+func (m *MockStore) recordPriceChange(book models.Book, previous *models.Book, actor string, at time.Time) {
+	change := models.PriceChange{
+		ID:        uuid.New().String(),
+		BookID:    book.ID,
+		Price:     book.Price,
+		Prices:    slices.Clone(book.Prices),
+		Actor:     actor,
+		ChangedAt: at,
+	}
+	if previous != nil {
+		if book.SamePrices(*previous) {
+			return
+		}
+		change.PreviousPrice = &previous.Price
+	}
+
+	m.priceHistory[book.ID] = append(m.priceHistory[book.ID], change)
+}
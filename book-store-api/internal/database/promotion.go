@@ -0,0 +1,116 @@
+package database
+
+import (
+	"errors"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/promotions"
+	"github.com/google/uuid"
+)
+
+// ErrPromotionNotFound is returned when no promotion has the requested ID
+var ErrPromotionNotFound = errors.New("promotion not found")
+
+// PromotionStore keeps promotion rules. Carts and orders are priced with the
+// promotions active when they are read or placed.
+type PromotionStore interface {
+	ListPromotions() ([]models.Promotion, error)
+	GetPromotion(id string) (models.Promotion, error)
+	CreatePromotion(promo models.Promotion) (models.Promotion, error)
+	UpdatePromotion(id string, promo models.Promotion) (models.Promotion, error)
+	DeletePromotion(id string) error
+}
+
+// ListPromotions returns all promotions in the order they are applied
+func (m *MockStore) ListPromotions() ([]models.Promotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.sortedPromotions(), nil
+}
+
+// GetPromotion retrieves a promotion by its ID
+func (m *MockStore) GetPromotion(id string) (models.Promotion, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	promo, exists := m.promotions[id]
+	if !exists {
+		return models.Promotion{}, ErrPromotionNotFound
+	}
+
+	return promo, nil
+}
+
+// CreatePromotion adds a promotion
+func (m *MockStore) CreatePromotion(promo models.Promotion) (models.Promotion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	promo.ID = uuid.New().String()
+	promo.CreatedAt = now
+	promo.UpdatedAt = now
+	m.promotions[promo.ID] = promo
+
+	return promo, nil
+}
+
+// UpdatePromotion replaces a promotion's rule
+func (m *MockStore) UpdatePromotion(id string, promo models.Promotion) (models.Promotion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.promotions[id]
+	if !exists {
+		return models.Promotion{}, ErrPromotionNotFound
+	}
+
+	promo.ID = existing.ID
+	promo.CreatedAt = existing.CreatedAt
+	promo.UpdatedAt = time.Now()
+	m.promotions[id] = promo
+
+	return promo, nil
+}
+
+// DeletePromotion removes a promotion
+func (m *MockStore) DeletePromotion(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.promotions[id]; !exists {
+		return ErrPromotionNotFound
+	}
+
+	delete(m.promotions, id)
+	return nil
+}
+
+// sortedPromotions returns the promotions in application order; the caller must hold m.mu
+func (m *MockStore) sortedPromotions() []models.Promotion {
+	promos := make([]models.Promotion, 0, len(m.promotions))
+	for _, promo := range m.promotions {
+		promos = append(promos, promo)
+	}
+	promotions.Sort(promos)
+	return promos
+}
+
+// lineDiscounts works out the promotions for a set of lines at the given
+// time. Lines for books that no longer exist get no discount. The caller
+// must hold m.mu.
+func (m *MockStore) lineDiscounts(lines []models.OrderItem, now time.Time) []models.AppliedPromotion {
+	promos := m.sortedPromotions()
+	discounts := []models.AppliedPromotion{}
+	for _, line := range lines {
+		book, exists := m.books[line.ProductID]
+		if !exists {
+			continue
+		}
+		_, applied := promotions.PriceLine(book, line.UnitPrice, line.Quantity, promos, now)
+		discounts = append(discounts, applied...)
+	}
+	return discounts
+}
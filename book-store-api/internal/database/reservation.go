@@ -0,0 +1,184 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrReservationNotFound is returned when no reservation has the requested ID
+	ErrReservationNotFound = errors.New("reservation not found")
+	// ErrReservationNotActive is returned when confirming or releasing a
+	// reservation that was already confirmed, released or expired
+	ErrReservationNotActive = errors.New("reservation is not active")
+	// ErrReservationExpired is returned when confirming a reservation past its expiry
+	ErrReservationExpired = errors.New("reservation has expired")
+)
+
+// ReservationStore holds copies of books for checkouts. Implementations must
+// check availability and hold the copies in one atomic step (a conditional
+// update in a database) so two checkouts can't reserve the same copy.
+type ReservationStore interface {
+	CreateReservation(reservation models.Reservation) (models.Reservation, error)
+	GetReservation(id string) (models.Reservation, error)
+	ConfirmReservation(id string) (models.Reservation, error)
+	ReleaseReservation(id string) (models.Reservation, error)
+	ExpireReservations(now time.Time) (int, error)
+}
+
+// CreateReservation holds reservation.Quantity copies of the book until
+// reservation.ExpiresAt. It fails with ErrInsufficientStock if not enough
+// copies are available.
+func (m *MockStore) CreateReservation(reservation models.Reservation) (models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.createReservation(reservation)
+}
+
+// createReservation does the work of CreateReservation; the caller must hold m.mu
+func (m *MockStore) createReservation(reservation models.Reservation) (models.Reservation, error) {
+	book, exists := m.books[reservation.BookID]
+	if !exists {
+		return models.Reservation{}, ErrBookNotFound
+	}
+	if book.Available() < reservation.Quantity {
+		return models.Reservation{}, ErrInsufficientStock
+	}
+
+	now := time.Now()
+	reservation.ID = uuid.New().String()
+	reservation.Status = models.ReservationActive
+	reservation.CreatedAt = now
+	reservation.UpdatedAt = now
+	m.reservations[reservation.ID] = reservation
+
+	book.Reserved += reservation.Quantity
+	m.books[book.ID] = book
+
+	return reservation, nil
+}
+
+// GetReservation retrieves a reservation by its ID
+func (m *MockStore) GetReservation(id string) (models.Reservation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	reservation, exists := m.reservations[id]
+	if !exists {
+		return models.Reservation{}, ErrReservationNotFound
+	}
+
+	return reservation, nil
+}
+
+// ConfirmReservation turns an active reservation into a sale in the stock ledger
+func (m *MockStore) ConfirmReservation(id string) (models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reservation, exists := m.reservations[id]
+	if !exists {
+		return models.Reservation{}, ErrReservationNotFound
+	}
+
+	now := time.Now()
+	if reservation.Expired(now) {
+		m.endReservation(reservation, models.ReservationExpired, now)
+		return models.Reservation{}, ErrReservationExpired
+	}
+	if reservation.Status != models.ReservationActive {
+		return models.Reservation{}, ErrReservationNotActive
+	}
+
+	reservation = m.endReservation(reservation, models.ReservationConfirmed, now)
+
+	// The held copies were excluded from available stock, so the sale can't fail
+	if _, err := m.recordStockMovement(models.StockMovement{
+		BookID: reservation.BookID,
+		Type:   models.MovementSale,
+		Delta:  -reservation.Quantity,
+		Reason: "reservation " + reservation.ID,
+		Actor:  reservation.Actor,
+	}); err != nil {
+		return models.Reservation{}, err
+	}
+
+	return reservation, nil
+}
+
+// ReleaseReservation gives the held copies back to available stock
+func (m *MockStore) ReleaseReservation(id string) (models.Reservation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reservation, exists := m.reservations[id]
+	if !exists {
+		return models.Reservation{}, ErrReservationNotFound
+	}
+	if reservation.Status != models.ReservationActive {
+		return models.Reservation{}, ErrReservationNotActive
+	}
+
+	return m.endReservation(reservation, models.ReservationReleased, time.Now()), nil
+}
+
+// ExpireReservations releases every active reservation that expired before
+// now and returns how many there were
+func (m *MockStore) ExpireReservations(now time.Time) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for _, reservation := range m.reservations {
+		if reservation.Expired(now) {
+			m.endReservation(reservation, models.ReservationExpired, now)
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// endReservation moves an active reservation to a final status and stops
+// holding its copies; the caller must hold m.mu
+func (m *MockStore) endReservation(reservation models.Reservation, status models.ReservationStatus, now time.Time) models.Reservation {
+	reservation.Status = status
+	reservation.UpdatedAt = now
+	m.reservations[reservation.ID] = reservation
+
+	if book, exists := m.books[reservation.BookID]; exists {
+		book.Reserved -= reservation.Quantity
+		m.books[book.ID] = book
+	}
+
+	return reservation
+}
+
+// SweepReservations expires overdue reservations every interval until ctx is
+// cancelled
+func SweepReservations(ctx context.Context, store ReservationStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			count, err := store.ExpireReservations(now)
+			if err != nil {
+				log.Printf("Error expiring reservations: %v", err)
+				continue
+			}
+			if count > 0 {
+				log.Printf("Expired %d reservations", count)
+			}
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package database
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrReviewNotFound is returned when no review has the requested ID
+	ErrReviewNotFound = errors.New("review not found")
+	// ErrDuplicateReview is returned when a customer reviews a book twice
+	ErrDuplicateReview = errors.New("book already reviewed by this user")
+)
+
+// ReviewStore keeps customer reviews. A book's Rating summarizes its approved
+// reviews and is kept up to date as reviews are moderated, edited and deleted.
+type ReviewStore interface {
+	CreateReview(review models.Review) (models.Review, error)
+	GetReview(id string) (models.Review, error)
+	ListReviews(filter models.ReviewFilter) ([]models.Review, int, error)
+	UpdateReview(id string, review models.Review) (models.Review, error)
+	ModerateReview(id string, status models.ReviewStatus, moderator, note string) (models.Review, error)
+	DeleteReview(id string) error
+}
+
+// CreateReview adds a review, pending moderation
+func (m *MockStore) CreateReview(review models.Review) (models.Review, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.books[review.BookID]; !exists {
+		return models.Review{}, ErrBookNotFound
+	}
+	for _, existing := range m.reviews {
+		if existing.BookID == review.BookID && existing.UserID == review.UserID {
+			return models.Review{}, ErrDuplicateReview
+		}
+	}
+
+	now := time.Now()
+	review.ID = uuid.New().String()
+	review.Status = models.ReviewPending
+	review.ModeratedBy = ""
+	review.ModerationNote = ""
+	review.CreatedAt = now
+	review.UpdatedAt = now
+	m.reviews[review.ID] = review
+
+	return review, nil
+}
+
+// GetReview retrieves a review by its ID
+func (m *MockStore) GetReview(id string) (models.Review, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	review, exists := m.reviews[id]
+	if !exists {
+		return models.Review{}, ErrReviewNotFound
+	}
+
+	return review, nil
+}
+
+// ListReviews returns the page of reviews matching the filter, newest first,
+// and the total number of matches
+func (m *MockStore) ListReviews(filter models.ReviewFilter) ([]models.Review, int, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if filter.BookID != "" {
+		if _, exists := m.books[filter.BookID]; !exists {
+			return nil, 0, ErrBookNotFound
+		}
+	}
+
+	reviews := []models.Review{}
+	for _, review := range m.reviews {
+		if filter.BookID != "" && review.BookID != filter.BookID {
+			continue
+		}
+		if filter.UserID != "" && review.UserID != filter.UserID {
+			continue
+		}
+		if filter.Status != "" && review.Status != filter.Status {
+			continue
+		}
+		reviews = append(reviews, review)
+	}
+
+	sort.Slice(reviews, func(i, j int) bool {
+		if !reviews[i].CreatedAt.Equal(reviews[j].CreatedAt) {
+			return reviews[i].CreatedAt.After(reviews[j].CreatedAt)
+		}
+		return reviews[i].ID < reviews[j].ID
+	})
+
+	total := len(reviews)
+	start := min(filter.Skip, total)
+	end := total
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, total)
+	}
+
+	return reviews[start:end], total, nil
+}
+
+// UpdateReview replaces a review's rating and comments. The changed review
+// goes back to pending moderation.
+func (m *MockStore) UpdateReview(id string, review models.Review) (models.Review, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	existing, exists := m.reviews[id]
+	if !exists {
+		return models.Review{}, ErrReviewNotFound
+	}
+
+	existing.Rating = review.Rating
+	existing.Title = review.Title
+	existing.Body = review.Body
+	m.saveReview(existing, models.ReviewPending, "", "")
+
+	return m.reviews[id], nil
+}
+
+// ModerateReview moves a review to a new status on behalf of a moderator
+func (m *MockStore) ModerateReview(id string, status models.ReviewStatus, moderator, note string) (models.Review, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	review, exists := m.reviews[id]
+	if !exists {
+		return models.Review{}, ErrReviewNotFound
+	}
+
+	m.saveReview(review, status, moderator, note)
+
+	return m.reviews[id], nil
+}
+
+// DeleteReview removes a review
+func (m *MockStore) DeleteReview(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	review, exists := m.reviews[id]
+	if !exists {
+		return ErrReviewNotFound
+	}
+
+	m.rate(review, -1)
+	delete(m.reviews, review.ID)
+	return nil
+}
+
+// saveReview stores a review in a new status and moves its rating in or out
+// of the book's summary; the caller must hold m.mu
+func (m *MockStore) saveReview(review models.Review, status models.ReviewStatus, moderator, note string) {
+	m.rate(m.reviews[review.ID], -1)
+
+	review.Status = status
+	review.ModeratedBy = moderator
+	review.ModerationNote = note
+	review.UpdatedAt = time.Now()
+	m.reviews[review.ID] = review
+
+	m.rate(review, 1)
+}
+
+// rate adds an approved review to its book's rating, or takes it away for a
+// negative delta. Other reviews don't count. The caller must hold m.mu.
+func (m *MockStore) rate(review models.Review, delta int) {
+	if review.Status != models.ReviewApproved {
+		return
+	}
+	book, exists := m.books[review.BookID]
+	if !exists {
+		return
+	}
+	book.Rating = book.Rating.Add(review.Rating, delta)
+	m.books[book.ID] = book
+}
@@ -1,33 +1,95 @@
 package database
 
 import (
+	"context"
 	"errors"
+	"slices"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/godwin/book-store-api/internal/isbn"
 	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/search"
 	"github.com/google/uuid"
 )
 
+// ErrBookNotFound is returned when no book has the requested ID
+var ErrBookNotFound = errors.New("book not found")
+
+// ErrDuplicateISBN is returned when saving a book with another book's ISBN
+var ErrDuplicateISBN = errors.New("a book with this ISBN already exists")
+
 // Store defines the methods for interacting with our data store
 type Store interface {
+	InventoryStore
+	ReservationStore
+	CartStore
+	OrderStore
+	PromotionStore
+	PriceHistoryStore
+	AuthorStore
+	CategoryStore
+	ReviewStore
+	CoverStore
+	ImportStore
+
 	GetBooks() ([]models.Book, error)
+	ListBooks(ctx context.Context, query models.BookQuery) (models.BookList, error)
 	GetBookByID(id string) (models.Book, error)
-	CreateBook(book models.Book) (models.Book, error)
-	UpdateBook(id string, book models.Book) (models.Book, error)
+	GetBookByISBN(isbn string) (models.Book, error)
+	CreateBook(book models.Book, actor string) (models.Book, error)
+	UpdateBook(id string, book models.Book, actor string) (models.Book, error)
 	DeleteBook(id string) error
+	SearchBooks(query string) ([]models.BookSearchResult, error)
+	GetBookFacets(filter models.BookFilter) (models.BookFacets, error)
 }
 
 // MockStore is an in-memory implementation of the Store interface
 type MockStore struct {
-	books map[string]models.Book
-	mu    sync.RWMutex
+	books         map[string]models.Book
+	isbns         map[string]string // canonical ISBN -> book ID
+	authors       map[string]models.Author
+	authorNames   map[string]string // lower-case name -> author ID
+	categories    map[string]models.Category
+	categorySlugs map[string]string                 // slug -> category ID
+	categoryCodes map[string]string                 // subject code -> category ID
+	subcategories map[string][]string               // parent ID, "" for the top level -> child IDs in order
+	movements     map[string][]models.StockMovement // book ID -> ledger
+	reservations  map[string]models.Reservation
+	carts         map[string]models.Cart // owner -> cart
+	orders        map[string]models.Order
+	promotions    map[string]models.Promotion
+	reviews       map[string]models.Review
+	priceHistory  map[string][]models.PriceChange // book ID -> changes
+	index         *search.Index
+	mu            sync.RWMutex
 }
 
 // NewMockStore returns a new instance of MockStore
 func NewMockStore() *MockStore {
 	return &MockStore{
-		books: make(map[string]models.Book),
+		books:         make(map[string]models.Book),
+		isbns:         make(map[string]string),
+		authors:       make(map[string]models.Author),
+		authorNames:   make(map[string]string),
+		categories:    make(map[string]models.Category),
+		categorySlugs: make(map[string]string),
+		categoryCodes: make(map[string]string),
+		subcategories: make(map[string][]string),
+		movements:     make(map[string][]models.StockMovement),
+		reservations:  make(map[string]models.Reservation),
+		carts:         make(map[string]models.Cart),
+		orders:        make(map[string]models.Order),
+		promotions:    make(map[string]models.Promotion),
+		reviews:       make(map[string]models.Review),
+		priceHistory:  make(map[string][]models.PriceChange),
+		index: search.NewIndex(map[string]float64{
+			"title":  2.0,
+			"author": 1.5,
+			"isbn":   1.0,
+		}),
 	}
 }
 
@@ -44,6 +106,89 @@ func (m *MockStore) GetBooks() ([]models.Book, error) {
 	return books, nil
 }
 
+// ListBooks returns the page of books matching the query and the total
+// number of matches
+func (m *MockStore) ListBooks(ctx context.Context, query models.BookQuery) (models.BookList, error) {
+	if err := ctx.Err(); err != nil {
+		return models.BookList{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches := make([]models.Book, 0, len(m.books))
+	for _, book := range m.books {
+		if book, ok := query.Filter.Apply(book); ok {
+			matches = append(matches, book)
+		}
+	}
+
+	sortKeys := query.Sort
+	if query.Cursor != nil {
+		sortKeys = query.Cursor.Sort
+	}
+	if len(sortKeys) == 0 {
+		sortKeys = models.DefaultSort
+	}
+	slices.SortFunc(matches, func(a, b models.Book) int {
+		return models.CompareBooks(a, b, sortKeys)
+	})
+
+	result := models.BookList{Total: len(matches)}
+
+	if query.Cursor != nil {
+		boundary, err := query.Cursor.Boundary()
+		if err != nil {
+			return models.BookList{}, err
+		}
+
+		// Index of the first book sorting after the cursor position
+		split, _ := slices.BinarySearchFunc(matches, boundary, func(book, target models.Book) int {
+			if models.CompareBooks(book, target, sortKeys) <= 0 {
+				return -1
+			}
+			return 1
+		})
+
+		if query.Cursor.Backward {
+			// The page is the last Limit books before the position
+			end := split
+			if end > 0 && models.CompareBooks(matches[end-1], boundary, sortKeys) == 0 {
+				end--
+			}
+			start := 0
+			if query.Page.Limit > 0 {
+				start = max(end-query.Page.Limit, 0)
+			}
+			result.Items = matches[start:end]
+			result.HasPrev = start > 0
+			result.HasNext = end < len(matches)
+		} else {
+			end := len(matches)
+			if query.Page.Limit > 0 {
+				end = min(split+query.Page.Limit, len(matches))
+			}
+			result.Items = matches[split:end]
+			result.HasPrev = split > 0
+			result.HasNext = end < len(matches)
+		}
+
+		return result, nil
+	}
+
+	start := min(max(query.Page.Offset, 0), len(matches))
+	end := len(matches)
+	if query.Page.Limit > 0 {
+		end = min(start+query.Page.Limit, len(matches))
+	}
+
+	result.Items = matches[start:end]
+	result.HasPrev = start > 0
+	result.HasNext = end < len(matches)
+
+	return result, nil
+}
+
 // GetBookByID retrieves a book by its ID
 func (m *MockStore) GetBookByID(id string) (models.Book, error) {
 	m.mu.RLock()
@@ -51,40 +196,108 @@ func (m *MockStore) GetBookByID(id string) (models.Book, error) {
 
 	book, exists := m.books[id]
 	if !exists {
-		return models.Book{}, errors.New("book not found")
+		return models.Book{}, ErrBookNotFound
 	}
 
 	return book, nil
 }
 
-// CreateBook adds a new book to the store
-func (m *MockStore) CreateBook(book models.Book) (models.Book, error) {
+// GetBookByISBN retrieves a book by its ISBN, given in any ISBN-10 or
+// ISBN-13 form
+func (m *MockStore) GetBookByISBN(code string) (models.Book, error) {
+	code, err := isbn.Normalize(code)
+	if err != nil {
+		return models.Book{}, err
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	id, exists := m.isbns[code]
+	if !exists {
+		return models.Book{}, ErrBookNotFound
+	}
+
+	return m.books[id], nil
+}
+
+// CreateBook adds a new book to the store. Its ISBN is stored in canonical
+// ISBN-13 form and must not belong to another book. Its opening price and
+// stock are recorded against the actor.
+func (m *MockStore) CreateBook(book models.Book, actor string) (models.Book, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.createBook(book, actor)
+}
+
+// createBook adds a book; the caller must hold m.mu
+func (m *MockStore) createBook(book models.Book, actor string) (models.Book, error) {
 	// Generate a new ID if one wasn't provided
 	if book.ID == "" {
 		book.ID = uuid.New().String()
 	}
 
+	// Everything is checked before setContributors creates any new authors
+	if err := m.setISBN(&book); err != nil {
+		return models.Book{}, err
+	}
+	if err := m.setCategories(&book); err != nil {
+		return models.Book{}, err
+	}
+	if err := m.setContributors(&book); err != nil {
+		return models.Book{}, err
+	}
+
 	// Set timestamps
 	now := time.Now()
 	book.CreatedAt = now
 	book.UpdatedAt = now
 
+	// Response-only prices aren't stored
+	book.ListPrice, book.SalePrice, book.LowestPrice = nil, nil, nil
+	book.Rating = models.NewRating()
+	setCover(&book, nil)
+
+	// Opening stock goes through the ledger as a receipt
+	opening := book.Quantity
+	book.Quantity = 0
+	book.Reserved = 0
+
 	m.books[book.ID] = book
+	m.isbns[book.ISBN] = book.ID
+	m.index.Add(book.ID, searchFields(book))
+	m.recordPriceChange(book, nil, actor, now)
 
-	return book, nil
+	if opening > 0 {
+		if _, err := m.recordStockMovement(models.StockMovement{
+			BookID: book.ID,
+			Type:   models.MovementReceipt,
+			Delta:  opening,
+			Reason: "opening stock",
+			Actor:  actor,
+		}); err != nil {
+			return models.Book{}, err
+		}
+	}
+
+	return m.books[book.ID], nil
 }
 
-// UpdateBook updates an existing book in the store
-func (m *MockStore) UpdateBook(id string, book models.Book) (models.Book, error) {
+// UpdateBook updates an existing book in the store. A change of price is
+// recorded in the book's price history against the actor.
+func (m *MockStore) UpdateBook(id string, book models.Book, actor string) (models.Book, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.updateBook(id, book, actor)
+}
+
+// updateBook replaces a book; the caller must hold m.mu
+func (m *MockStore) updateBook(id string, book models.Book, actor string) (models.Book, error) {
 	existingBook, exists := m.books[id]
 	if !exists {
-		return models.Book{}, errors.New("book not found")
+		return models.Book{}, ErrBookNotFound
 	}
 
 	// Keep original ID, CreatedAt
@@ -92,9 +305,50 @@ func (m *MockStore) UpdateBook(id string, book models.Book) (models.Book, error)
 	book.CreatedAt = existingBook.CreatedAt
 	book.UpdatedAt = time.Now()
 
+	// Copies held by reservations can't be taken out of stock
+	requested := book.Quantity
+	if requested < existingBook.Reserved {
+		return models.Book{}, ErrInsufficientStock
+	}
+
+	// Everything is checked before setContributors creates any new authors
+	if err := m.setISBN(&book); err != nil {
+		return models.Book{}, err
+	}
+	if err := m.setCategories(&book); err != nil {
+		return models.Book{}, err
+	}
+	if err := m.setContributors(&book); err != nil {
+		return models.Book{}, err
+	}
+
+	book.ListPrice, book.SalePrice, book.LowestPrice = nil, nil, nil
+
+	// Quantity is owned by the ledger; a different value is recorded as an adjustment
+	book.Quantity = existingBook.Quantity
+	book.Reserved = existingBook.Reserved
+	book.Rating = existingBook.Rating
+	setCover(&book, existingBook.Cover)
+
 	m.books[id] = book
+	delete(m.isbns, existingBook.ISBN)
+	m.isbns[book.ISBN] = id
+	m.index.Add(id, searchFields(book))
+	m.recordPriceChange(book, &existingBook, actor, book.UpdatedAt)
 
-	return book, nil
+	if requested != existingBook.Quantity {
+		if _, err := m.recordStockMovement(models.StockMovement{
+			BookID: id,
+			Type:   models.MovementAdjustment,
+			Delta:  requested - existingBook.Quantity,
+			Reason: "quantity set by book update",
+			Actor:  actor,
+		}); err != nil {
+			return models.Book{}, err
+		}
+	}
+
+	return m.books[id], nil
 }
 
 // DeleteBook removes a book from the store
@@ -102,10 +356,168 @@ func (m *MockStore) DeleteBook(id string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if _, exists := m.books[id]; !exists {
-		return errors.New("book not found")
+	book, exists := m.books[id]
+	if !exists {
+		return ErrBookNotFound
 	}
 
 	delete(m.books, id)
+	delete(m.isbns, book.ISBN)
+	delete(m.movements, id)
+	delete(m.priceHistory, id)
+	for resID, reservation := range m.reservations {
+		if reservation.BookID == id {
+			delete(m.reservations, resID)
+		}
+	}
+	for reviewID, review := range m.reviews {
+		if review.BookID == id {
+			delete(m.reviews, reviewID)
+		}
+	}
+	m.index.Remove(id)
 	return nil
 }
+
+// SearchBooks runs a full-text query over book titles, authors and ISBNs and
+// returns matches ordered by relevance
+func (m *MockStore) SearchBooks(query string) ([]models.BookSearchResult, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	matches, err := m.index.Search(query)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]models.BookSearchResult, 0, len(matches))
+	for _, match := range matches {
+		book, exists := m.books[match.ID]
+		if !exists {
+			continue
+		}
+		results = append(results, models.BookSearchResult{
+			Book:       book,
+			Score:      match.Score,
+			Highlights: match.Highlights,
+		})
+	}
+
+	return results, nil
+}
+
+// GetBookFacets counts the books matching the filter by author, publication
+// decade, price range and availability
+func (m *MockStore) GetBookFacets(filter models.BookFilter) (models.BookFacets, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	authors := make(map[string]int)
+	decades := make(map[string]int)
+	prices := make([]int, len(models.PriceBuckets))
+	inStock, outOfStock := 0, 0
+	total := 0
+
+	currency := filter.PriceCurrency()
+	for _, book := range m.books {
+		book, ok := filter.Apply(book)
+		if !ok {
+			continue
+		}
+		total++
+
+		for _, author := range book.Authors() {
+			authors[author]++
+		}
+		if decade := book.Decade(); decade != "" {
+			decades[decade]++
+		}
+		for i, bucket := range models.PriceBuckets {
+			if bucket.Contains(book.Price, currency) {
+				prices[i]++
+				break
+			}
+		}
+		if book.Available() > 0 {
+			inStock++
+		} else {
+			outOfStock++
+		}
+	}
+
+	facets := models.BookFacets{
+		Total:    total,
+		Currency: currency,
+		Authors:  facetValues(authors),
+		Decades:  facetValues(decades),
+		Availability: []models.FacetValue{
+			{Value: models.AvailabilityInStock, Count: inStock},
+			{Value: models.AvailabilityOutOfStock, Count: outOfStock},
+		},
+	}
+
+	// Authors are most common first; decades are chronological
+	sort.SliceStable(facets.Authors, func(i, j int) bool {
+		return facets.Authors[i].Count > facets.Authors[j].Count
+	})
+
+	for i, bucket := range models.PriceBuckets {
+		min, max := bucket.Range(currency)
+		facets.PriceRanges = append(facets.PriceRanges, models.FacetValue{
+			Value: bucket.Label,
+			Count: prices[i],
+			Min:   &min,
+			Max:   max,
+		})
+	}
+
+	return facets, nil
+}
+
+// facetValues converts counts to facet values sorted by value
+func facetValues(counts map[string]int) []models.FacetValue {
+	values := make([]models.FacetValue, 0, len(counts))
+	for value, count := range counts {
+		values = append(values, models.FacetValue{Value: value, Count: count})
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return values[i].Value < values[j].Value
+	})
+	return values
+}
+
+// setISBN normalizes the book's ISBN, fills in its other forms and checks
+// that no other book has it. The caller must hold m.mu.
+func (m *MockStore) setISBN(book *models.Book) error {
+	code, err := isbn.Normalize(book.ISBN)
+	if err != nil {
+		return err
+	}
+	if id, exists := m.isbns[code]; exists && id != book.ID {
+		return ErrDuplicateISBN
+	}
+
+	book.ISBN = code
+	book.ISBN10, _ = isbn.To10(code)
+	book.Hyphenated, _ = isbn.Hyphenate(code)
+	return nil
+}
+
+// searchFields returns the text of a book that is indexed for search. All
+// forms of the ISBN are indexed so it can be searched for as printed.
+func searchFields(book models.Book) map[string]string {
+	return map[string]string{
+		"title":  book.Title,
+		"author": contributorNames(book),
+		"isbn":   strings.Join([]string{book.ISBN, book.ISBN10, book.Hyphenated}, " "),
+	}
+}
+
+// contributorNames returns the names of everyone credited on the book
+func contributorNames(book models.Book) string {
+	names := make([]string, len(book.Contributors))
+	for i, c := range book.Contributors {
+		names[i] = c.Name
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,255 @@
+package database_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func names(contributors []models.Contributor) []string {
+	found := make([]string, len(contributors))
+	for i, c := range contributors {
+		found[i] = c.Name + "/" + string(c.Role)
+	}
+	return found
+}
+
+func TestCreateBook_Contributors(t *testing.T) {
+	store, _ := newStore(t)
+	fowler, err := store.CreateAuthor(models.Author{Name: "Martin Fowler"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		author       string
+		contributors []models.Contributor
+		want         []string
+		joined       string
+	}{
+		{
+			name:   "legacy author string is split",
+			author: "Andrew Hunt, David Thomas",
+			want:   []string{"Andrew Hunt/author", "David Thomas/author"},
+			joined: "Andrew Hunt, David Thomas",
+		},
+		{
+			name:         "existing authors by ID and by name in any case",
+			contributors: []models.Contributor{{AuthorID: fowler.ID}, {Name: "  andrew hunt "}},
+			want:         []string{"Martin Fowler/author", "Andrew Hunt/author"},
+			joined:       "Martin Fowler, Andrew Hunt",
+		},
+		{
+			name: "roles keep credit order",
+			contributors: []models.Contributor{
+				{Name: "Kent Beck", Role: models.RoleEditor},
+				{Name: "Martin Fowler"},
+				{Name: "Ana Trad", Role: models.RoleTranslator},
+			},
+			want:   []string{"Kent Beck/editor", "Martin Fowler/author", "Ana Trad/translator"},
+			joined: "Martin Fowler",
+		},
+		{
+			name: "repeated credits are dropped",
+			contributors: []models.Contributor{
+				{Name: "Martin Fowler"},
+				{AuthorID: fowler.ID},
+				{AuthorID: fowler.ID, Role: models.RoleEditor},
+			},
+			want:   []string{"Martin Fowler/author", "Martin Fowler/editor"},
+			joined: "Martin Fowler",
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book, err := store.CreateBook(models.Book{Title: tt.name, Author: tt.author, Contributors: tt.contributors, ISBN: isbn13(i + 1), Price: usd("10.00")}, "system")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := names(book.Contributors); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			if book.Author != tt.joined {
+				t.Errorf("Expected author %q, got %q", tt.joined, book.Author)
+			}
+		})
+	}
+
+	// Authors named on books are created once
+	authors, _ := store.ListAuthors()
+	counts := make(map[string]int)
+	for _, author := range authors {
+		counts[author.Name] = author.BookCount
+	}
+	want := map[string]int{"Andrew Hunt": 2, "David Thomas": 1, "Martin Fowler": 3, "Kent Beck": 1, "Ana Trad": 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("Expected %v, got %v", want, counts)
+	}
+}
+
+func TestCreateBook_InvalidContributors(t *testing.T) {
+	tests := []struct {
+		name         string
+		author       string
+		contributors []models.Contributor
+	}{
+		{"nobody", "", nil},
+		{"only separators", " , & ", nil},
+		{"unknown author ID", "", []models.Contributor{{AuthorID: "missing"}}},
+		{"no ID or name", "", []models.Contributor{{Name: "Martin Fowler"}, {Name: "  "}}},
+		{"no author role", "", []models.Contributor{{Name: "Kent Beck", Role: models.RoleEditor}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, _ := newStore(t)
+			_, err := store.CreateBook(models.Book{Title: "Refactoring", Author: tt.author, Contributors: tt.contributors, ISBN: isbn13(1), Price: usd("10.00")}, "system")
+			if !errors.Is(err, database.ErrInvalidContributors) {
+				t.Fatalf("Expected ErrInvalidContributors, got %v", err)
+			}
+			// Nothing is created for a book that isn't saved
+			if authors, _ := store.ListAuthors(); len(authors) != 0 {
+				t.Errorf("Expected no authors, got %d", len(authors))
+			}
+		})
+	}
+}
+
+func TestBookWrites_RejectedWithoutNewAuthors(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(store *database.MockStore, book models.Book) error
+		book  func(book models.Book) models.Book
+		err   error
+	}{
+		{
+			name: "create with an unknown category",
+			write: func(store *database.MockStore, book models.Book) error {
+				_, err := store.CreateBook(book, "system")
+				return err
+			},
+			book: func(book models.Book) models.Book {
+				book.ID, book.ISBN = "", isbn13(2)
+				book.Categories = []models.CategoryRef{{Slug: "missing"}}
+				return book
+			},
+			err: database.ErrInvalidCategories,
+		},
+		{
+			name: "create with a taken ISBN",
+			write: func(store *database.MockStore, book models.Book) error {
+				_, err := store.CreateBook(book, "system")
+				return err
+			},
+			book: func(book models.Book) models.Book {
+				book.ID = ""
+				return book
+			},
+			err: database.ErrDuplicateISBN,
+		},
+		{
+			name: "update with an unknown category",
+			write: func(store *database.MockStore, book models.Book) error {
+				_, err := store.UpdateBook(book.ID, book, "42")
+				return err
+			},
+			book: func(book models.Book) models.Book {
+				book.Categories = []models.CategoryRef{{Code: "FIC999999"}}
+				return book
+			},
+			err: database.ErrInvalidCategories,
+		},
+		{
+			name: "update below the reserved copies",
+			write: func(store *database.MockStore, book models.Book) error {
+				_, err := store.UpdateBook(book.ID, book, "42")
+				return err
+			},
+			book: func(book models.Book) models.Book {
+				book.Quantity = 0
+				return book
+			},
+			err: database.ErrInsufficientStock,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, books := newStore(t, catalogBooks()[0])
+			if _, err := store.CreateReservation(models.Reservation{BookID: books[0].ID, Quantity: 1}); err != nil {
+				t.Fatal(err)
+			}
+			before, _ := store.ListAuthors()
+
+			book := tt.book(books[0])
+			book.Author, book.Contributors = "", []models.Contributor{{Name: "Dean Wampler"}}
+			if err := tt.write(store, book); !errors.Is(err, tt.err) {
+				t.Fatalf("Expected %v, got %v", tt.err, err)
+			}
+			if after, _ := store.ListAuthors(); len(after) != len(before) {
+				t.Errorf("Expected %d authors, got %d", len(before), len(after))
+			}
+		})
+	}
+}
+
+func TestAuthors(t *testing.T) {
+	store, books := newStore(t, catalogBooks()...)
+	martin := books[0].Contributors[0].AuthorID
+
+	// Renaming shows the new name on every book, and in search
+	if _, err := store.UpdateAuthor(martin, models.Author{Name: "Uncle Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{books[0].ID, books[1].ID} {
+		if book, _ := store.GetBookByID(id); book.Author != "Uncle Bob" || book.Contributors[0].Name != "Uncle Bob" {
+			t.Errorf("Expected the new name on %s, got %q", book.Title, book.Author)
+		}
+	}
+	if found, _ := store.SearchBooks("uncle"); len(found) != 2 {
+		t.Errorf("Expected 2 books to match the new name, got %d", len(found))
+	}
+
+	// Most recently published first
+	authorBooks, err := store.GetAuthorBooks(martin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(authorBooks), []string{"Clean Architecture", "Clean Code"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	unused, err := store.CreateAuthor(models.Author{Name: "Grace Hopper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		call func() error
+		err  error
+	}{
+		{"duplicate name ignoring case", func() error { _, err := store.CreateAuthor(models.Author{Name: "donald knuth"}); return err }, database.ErrDuplicateAuthor},
+		{"rename to a taken name", func() error { _, err := store.UpdateAuthor(unused.ID, models.Author{Name: "Uncle Bob"}); return err }, database.ErrDuplicateAuthor},
+		{"rename keeping the name", func() error { _, err := store.UpdateAuthor(martin, models.Author{Name: "UNCLE BOB"}); return err }, nil},
+		{"update unknown", func() error { _, err := store.UpdateAuthor("missing", models.Author{Name: "x"}); return err }, database.ErrAuthorNotFound},
+		{"delete credited", func() error { return store.DeleteAuthor(martin) }, database.ErrAuthorInUse},
+		{"delete unused", func() error { return store.DeleteAuthor(unused.ID) }, nil},
+		{"delete twice", func() error { return store.DeleteAuthor(unused.ID) }, database.ErrAuthorNotFound},
+		{"name free after delete", func() error { _, err := store.CreateAuthor(models.Author{Name: "Grace Hopper"}); return err }, nil},
+		{"books of unknown", func() error { _, err := store.GetAuthorBooks("missing"); return err }, database.ErrAuthorNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
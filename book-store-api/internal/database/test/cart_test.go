@@ -0,0 +1,156 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func TestCart_Items(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2], catalogBooks()[3]) // 3 and 2 copies
+	patterns, refactoring := books[0].ID, books[1].ID
+	const owner = "user:1"
+
+	tests := []struct {
+		name     string
+		call     func() (models.Cart, error)
+		err      error
+		items    int
+		subtotal string
+	}{
+		{"add", func() (models.Cart, error) { return store.AddCartItem(owner, patterns, 1) }, nil, 1, "44.99 USD"},
+		{"add the same book again", func() (models.Cart, error) { return store.AddCartItem(owner, patterns, 1) }, nil, 2, "89.98 USD"},
+		{"add another book", func() (models.Cart, error) { return store.AddCartItem(owner, refactoring, 2) }, nil, 4, "109.96 USD"},
+		{"add past the stock", func() (models.Cart, error) { return store.AddCartItem(owner, patterns, 2) }, database.ErrInsufficientStock, 4, "109.96 USD"},
+		{"add an unknown book", func() (models.Cart, error) { return store.AddCartItem(owner, "missing", 1) }, database.ErrBookNotFound, 4, "109.96 USD"},
+		{"update", func() (models.Cart, error) { return store.UpdateCartItem(owner, patterns, 3) }, nil, 5, "154.95 USD"},
+		{"update past the stock", func() (models.Cart, error) { return store.UpdateCartItem(owner, refactoring, 3) }, database.ErrInsufficientStock, 5, "154.95 USD"},
+		{"update a book not in the cart", func() (models.Cart, error) { return store.UpdateCartItem(owner, "missing", 1) }, database.ErrCartItemNotFound, 5, "154.95 USD"},
+		{"remove", func() (models.Cart, error) { return store.RemoveCartItem(owner, patterns) }, nil, 2, "19.98 USD"},
+		{"remove twice", func() (models.Cart, error) { return store.RemoveCartItem(owner, patterns) }, database.ErrCartItemNotFound, 2, "19.98 USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.call(); !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			cart, err := store.GetCart(owner)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cart.ItemCount != tt.items || cart.Subtotal.String() != tt.subtotal || cart.Total.String() != tt.subtotal {
+				t.Errorf("Expected %d items for %s, got %d for %s (total %s)", tt.items, tt.subtotal, cart.ItemCount, cart.Subtotal, cart.Total)
+			}
+		})
+	}
+}
+
+func TestCart_KeepsPriceSnapshot(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+	book := books[0]
+	const owner = "session:abc"
+
+	if _, err := store.AddCartItem(owner, book.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	book.Price = usd("10.00")
+	if _, err := store.UpdateBook(book.ID, book, "clerk"); err != nil {
+		t.Fatal(err)
+	}
+
+	cart, err := store.GetCart(owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cart.Items[0].UnitPrice.String(); got != "37.49 USD" {
+		t.Errorf("Expected the price when added, got %s", got)
+	}
+	if got := cart.Total.String(); got != "74.98 USD" {
+		t.Errorf("Expected 74.98 USD, got %s", got)
+	}
+
+	// Other owners have their own, empty carts
+	other, err := store.GetCart("session:other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(other.Items) != 0 || other.ItemCount != 0 {
+		t.Errorf("Expected an empty cart, got %+v", other.Items)
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2], catalogBooks()[3])
+	patterns, refactoring := books[0], books[1]
+	const owner = "user:7"
+
+	if _, err := store.Checkout(owner, models.Order{ShippingAddress: "1 Main St"}, time.Minute); !errors.Is(err, database.ErrCartEmpty) {
+		t.Fatalf("Expected ErrCartEmpty, got %v", err)
+	}
+
+	if _, err := store.AddCartItem(owner, patterns.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddCartItem(owner, refactoring.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := store.Checkout(owner, models.Order{CustomerID: "someone else", ShippingAddress: "1 Main St"}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.CustomerID != owner || order.Status != models.OrderPending || order.BillingAddress != "1 Main St" {
+		t.Errorf("Expected a pending order for %s billed to the shipping address, got %+v", owner, order)
+	}
+	if len(order.Items) != 2 || order.Total.String() != "99.97 USD" {
+		t.Errorf("Expected 2 items totalling 99.97 USD, got %d for %s", len(order.Items), order.Total)
+	}
+
+	// Every item is held by a reservation and the cart is emptied
+	for _, item := range order.Items {
+		reservation, err := store.GetReservation(item.ReservationID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if reservation.Status != models.ReservationActive || reservation.Quantity != item.Quantity {
+			t.Errorf("Expected an active hold on %d copies, got %+v", item.Quantity, reservation)
+		}
+	}
+	if book, _ := store.GetBookByID(patterns.ID); book.Reserved != 2 {
+		t.Errorf("Expected 2 copies reserved, got %d", book.Reserved)
+	}
+	if cart, _ := store.GetCart(owner); len(cart.Items) != 0 {
+		t.Errorf("Expected an empty cart, got %+v", cart.Items)
+	}
+}
+
+func TestCheckout_ShortStockReservesNothing(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2], catalogBooks()[3])
+	patterns, refactoring := books[0], books[1]
+
+	if _, err := store.AddCartItem("user:1", patterns.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.AddCartItem("user:1", refactoring.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	// Another customer takes a copy after it went into the cart
+	if _, err := store.CreateReservation(models.Reservation{BookID: refactoring.ID, Quantity: 1, ExpiresAt: time.Now().Add(time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := store.Checkout("user:1", models.Order{ShippingAddress: "1 Main St"}, time.Minute)
+	if !errors.Is(err, database.ErrInsufficientStock) {
+		t.Fatalf("Expected ErrInsufficientStock, got %v", err)
+	}
+	if book, _ := store.GetBookByID(patterns.ID); book.Reserved != 0 {
+		t.Errorf("Expected nothing reserved, got %d", book.Reserved)
+	}
+	if cart, _ := store.GetCart("user:1"); cart.ItemCount != 3 {
+		t.Errorf("Expected the cart to be kept, got %d items", cart.ItemCount)
+	}
+}
@@ -0,0 +1,276 @@
+package database_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func createCategory(t *testing.T, store *database.MockStore, category models.Category) models.Category {
+	t.Helper()
+	created, err := store.CreateCategory(category)
+	if err != nil {
+		t.Fatalf("Creating %q: %v", category.Name, err)
+	}
+	return created
+}
+
+// tree flattens categories into "slug" entries, indented by depth
+func tree(categories []models.Category, depth int) []string {
+	var lines []string
+	for _, c := range categories {
+		lines = append(lines, fmt.Sprintf("%*s%s", depth*2, "", c.Slug))
+		lines = append(lines, tree(c.Children, depth+1)...)
+	}
+	return lines
+}
+
+func TestCategories_Tree(t *testing.T) {
+	store, _ := newStore(t)
+	fiction := createCategory(t, store, models.Category{Name: "Fiction"})
+	computers := createCategory(t, store, models.Category{Name: "Computers", Position: 1})
+	createCategory(t, store, models.Category{Name: "Science Fiction", ParentID: fiction.ID})
+	createCategory(t, store, models.Category{Name: "Fantasy", ParentID: fiction.ID})
+	programming := createCategory(t, store, models.Category{Name: "Programming", ParentID: computers.ID})
+	createCategory(t, store, models.Category{Name: "Go", ParentID: programming.ID})
+	createCategory(t, store, models.Category{Name: "fiction", Slug: "fiction-classics", ParentID: fiction.ID, Position: -1})
+
+	categories, err := store.ListCategories()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Siblings by position, then name
+	want := []string{
+		"fiction",
+		"  fiction-classics",
+		"  fiction-fantasy",
+		"  fiction-science-fiction",
+		"computers",
+		"  computers-programming",
+		"    computers-programming-go",
+	}
+	if got := tree(categories, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	category, err := store.GetCategory("computers-programming-go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ancestors []string
+	for _, a := range category.Ancestors {
+		ancestors = append(ancestors, a.Slug)
+	}
+	if want := []string{"computers", "computers-programming"}; !reflect.DeepEqual(ancestors, want) {
+		t.Errorf("Expected ancestors %v, got %v", want, ancestors)
+	}
+
+	descendants, err := store.CategoryDescendants("computers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(descendants) != 3 || !descendants[programming.ID] {
+		t.Errorf("Expected computers and its 2 descendants, got %v", descendants)
+	}
+}
+
+func TestCategories_Update(t *testing.T) {
+	store, _ := newStore(t)
+	fiction := createCategory(t, store, models.Category{Name: "Fiction"})
+	computers := createCategory(t, store, models.Category{Name: "Computers"})
+	programming := createCategory(t, store, models.Category{Name: "Programming", ParentID: computers.ID})
+	book, err := store.CreateBook(models.Book{Title: "The Go Programming Language", Author: "Alan Donovan", ISBN: isbn13(1), Price: usd("30.00"), Categories: []models.CategoryRef{{Slug: programming.Slug}}}, "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		slug     string
+		category models.Category
+		err      error
+	}{
+		{"under itself", "computers", models.Category{Name: "Computers", ParentID: computers.ID}, database.ErrInvalidCategory},
+		{"under a descendant", "computers", models.Category{Name: "Computers", ParentID: programming.ID}, database.ErrInvalidCategory},
+		{"under a missing parent", "computers", models.Category{Name: "Computers", ParentID: "missing"}, database.ErrInvalidCategory},
+		{"invalid slug", "computers", models.Category{Name: "Computers", Slug: "Computers!"}, database.ErrInvalidCategory},
+		{"taken slug", "computers", models.Category{Name: "Computers", Slug: "fiction"}, database.ErrDuplicateCategory},
+		{"unknown", "missing", models.Category{Name: "Missing"}, database.ErrCategoryNotFound},
+		{"move and rename", "computers-programming", models.Category{Name: "Coding", Slug: "coding", ParentID: fiction.ID}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.UpdateCategory(tt.slug, tt.category); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	// The moved category left its old parent and the book shows its new name
+	categories, _ := store.ListCategories()
+	if got, want := tree(categories, 0), []string{"computers", "fiction", "  coding"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	book, _ = store.GetBookByID(book.ID)
+	if ref := book.Categories[0]; ref.Slug != "coding" || ref.Name != "Coding" {
+		t.Errorf("Expected the book in coding, got %+v", ref)
+	}
+	if descendants, _ := store.CategoryDescendants("computers"); len(descendants) != 1 {
+		t.Errorf("Expected computers to have no descendants, got %d", len(descendants)-1)
+	}
+}
+
+func TestCategories_Delete(t *testing.T) {
+	store, _ := newStore(t)
+	computers := createCategory(t, store, models.Category{Name: "Computers"})
+	programming := createCategory(t, store, models.Category{Name: "Programming", ParentID: computers.ID})
+	book, err := store.CreateBook(models.Book{Title: "Clean Code", Author: "Robert C. Martin", ISBN: isbn13(1), Price: usd("30.00"), Categories: []models.CategoryRef{{ID: programming.ID}}}, "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		before func()
+		slug   string
+		err    error
+	}{
+		{"has subcategories", nil, "computers", database.ErrCategoryInUse},
+		{"has books", nil, "computers-programming", database.ErrCategoryInUse},
+		{"empty", func() {
+			book.Categories = nil
+			if _, err := store.UpdateBook(book.ID, book, "clerk"); err != nil {
+				t.Fatal(err)
+			}
+		}, "computers-programming", nil},
+		{"no longer has subcategories", nil, "computers", nil},
+		{"twice", nil, "computers", database.ErrCategoryNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.before != nil {
+				tt.before()
+			}
+			if err := store.DeleteCategory(tt.slug); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	if categories, _ := store.ListCategories(); len(categories) != 0 {
+		t.Errorf("Expected no categories, got %d", len(categories))
+	}
+}
+
+func TestCategories_Slugs(t *testing.T) {
+	store, _ := newStore(t)
+	fiction := createCategory(t, store, models.Category{Name: "Fiction"})
+
+	tests := []struct {
+		category models.Category
+		want     string
+	}{
+		{models.Category{Name: "Science Fiction", ParentID: fiction.ID}, "fiction-science-fiction"},
+		{models.Category{Name: "Science Fiction!", ParentID: fiction.ID}, "fiction-science-fiction-2"},
+		{models.Category{Name: "Fiction"}, "fiction-2"},
+		{models.Category{Name: "???"}, "category"},
+		{models.Category{Name: "Poetry", Slug: "verse"}, "verse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := createCategory(t, store, tt.category); got.Slug != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got.Slug)
+			}
+		})
+	}
+}
+
+func TestImportCategories(t *testing.T) {
+	store, _ := newStore(t)
+	entries := []models.CategoryImport{
+		{Code: "COM000000", Path: []string{"COMPUTERS"}},
+		{Code: "COM051000", Path: []string{"COMPUTERS", "Programming"}},
+		{Code: "COM051210", Path: []string{"COMPUTERS", "Programming", "Object Oriented"}},
+		{Code: "FIC028000", Path: []string{"FICTION", "Science Fiction"}},
+	}
+
+	result, err := store.ImportCategories(entries)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// FICTION is created along the way, without a code
+	if want := (models.CategoryImportResult{Created: 5, Updated: 0, Total: 4}); result != want {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+
+	// A newer edition matches headings by name and moves a code
+	result, err = store.ImportCategories([]models.CategoryImport{
+		{Code: "COM051000", Path: []string{"Computers", "programming"}},
+		{Code: "COM051210", Path: []string{"COMPUTERS", "Programming", "Object-Oriented"}},
+		{Code: "FIC000000", Path: []string{"FICTION"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (models.CategoryImportResult{Created: 1, Updated: 1, Total: 3}); result != want {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+
+	codes := map[string]string{
+		"computers-programming-object-oriented":   "",
+		"computers-programming-object-oriented-2": "COM051210",
+		"fiction":               "FIC000000",
+		"computers-programming": "COM051000",
+	}
+	for slug, want := range codes {
+		category, err := store.GetCategory(slug)
+		if err != nil {
+			t.Fatalf("%s: %v", slug, err)
+		}
+		if category.Code != want {
+			t.Errorf("%s: expected code %q, got %q", slug, want, category.Code)
+		}
+	}
+
+	// Books can be filed by code
+	book, err := store.CreateBook(models.Book{Title: "Dune", Author: "Frank Herbert", ISBN: isbn13(1), Price: usd("9.99"), Categories: []models.CategoryRef{{Code: "FIC028000"}, {Slug: "fiction-science-fiction"}}}, "system")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(book.Categories) != 1 || book.Categories[0].Name != "Science Fiction" {
+		t.Errorf("Expected one category, got %+v", book.Categories)
+	}
+	if _, err := store.CreateBook(models.Book{Title: "Neuromancer", Author: "William Gibson", ISBN: isbn13(2), Price: usd("9.99"), Categories: []models.CategoryRef{{Code: "FIC999999"}}}, "system"); !errors.Is(err, database.ErrInvalidCategories) {
+		t.Errorf("Expected ErrInvalidCategories, got %v", err)
+	}
+}
+
+func TestImportCategories_ManySiblings(t *testing.T) {
+	store, _ := newStore(t)
+
+	// Import in reverse so every heading is inserted before the ones already there
+	var entries []models.CategoryImport
+	for i := 2000; i > 0; i-- {
+		entries = append(entries, models.CategoryImport{Code: fmt.Sprintf("COM%06d", i), Path: []string{"COMPUTERS", fmt.Sprintf("Topic %04d", i)}})
+	}
+	if _, err := store.ImportCategories(entries); err != nil {
+		t.Fatal(err)
+	}
+
+	categories, _ := store.ListCategories()
+	if len(categories) != 1 || len(categories[0].Children) != 2000 {
+		t.Fatalf("Expected one section with 2000 headings, got %d", len(categories))
+	}
+	for i, child := range categories[0].Children {
+		if want := fmt.Sprintf("Topic %04d", i+1); child.Name != want {
+			t.Fatalf("Expected %s at %d, got %s", want, i, child.Name)
+		}
+	}
+}
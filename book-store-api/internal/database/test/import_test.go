@@ -0,0 +1,266 @@
+package database_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/isbn"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+func slugs(categories []models.CategoryRef) []string {
+	var found []string
+	for _, c := range categories {
+		found = append(found, c.Slug)
+	}
+	return found
+}
+
+func TestImportBook(t *testing.T) {
+	eur, _ := money.Parse("34.99", "EUR")
+	all := map[string]bool{}
+	none := map[string]bool{models.ImportPrices: true, models.ImportCategories: true, models.ImportQuantity: true}
+
+	// Every case starts from Clean Code, 15 copies with 2 reserved, filed
+	// under computers and priced in euros too
+	tests := []struct {
+		name       string
+		book       models.Book
+		omitted    map[string]bool
+		dryRun     bool
+		action     models.ImportAction
+		err        error
+		isbn       string // Book to look up afterwards
+		title      string // Its title, or "" if it shouldn't exist
+		quantity   int
+		prices     int
+		categories []string
+	}{
+		{
+			name:    "new ISBN is created",
+			book:    models.Book{ISBN: "978-0-00-000002-6", Title: "Clean Architecture", Author: "Robert C. Martin", Price: usd("29.99"), Quantity: 4},
+			omitted: all,
+			action:  models.ImportCreated,
+			isbn:    isbn13(2), title: "Clean Architecture", quantity: 4,
+		},
+		{
+			name:    "known ISBN is updated",
+			book:    models.Book{ISBN: isbn13(1), Title: "Clean Code, 2nd Edition", Author: "Robert C. Martin", Price: usd("39.99"), Quantity: 20},
+			omitted: all,
+			action:  models.ImportUpdated,
+			isbn:    isbn13(1), title: "Clean Code, 2nd Edition", quantity: 20,
+		},
+		{
+			name:    "omitted fields keep their values",
+			book:    models.Book{ISBN: isbn13(1), Title: "Clean Code, 2nd Edition", Author: "Robert C. Martin", Price: usd("39.99")},
+			omitted: none,
+			action:  models.ImportUpdated,
+			isbn:    isbn13(1), title: "Clean Code, 2nd Edition", quantity: 15, prices: 1, categories: []string{"computers"},
+		},
+		{
+			name:    "row IDs are ignored",
+			book:    models.Book{ID: "someone-else", ISBN: isbn13(3), Title: "Refactoring", Author: "Martin Fowler", Price: usd("9.99")},
+			omitted: all,
+			action:  models.ImportCreated,
+			isbn:    isbn13(3), title: "Refactoring",
+		},
+		{
+			name:    "dry run create saves nothing",
+			book:    models.Book{ISBN: isbn13(2), Title: "Clean Architecture", Author: "Robert C. Martin", Price: usd("29.99")},
+			omitted: all,
+			dryRun:  true,
+			action:  models.ImportCreated,
+			isbn:    isbn13(2),
+		},
+		{
+			name:    "dry run update saves nothing",
+			book:    models.Book{ISBN: isbn13(1), Title: "Clean Code, 2nd Edition", Author: "Robert C. Martin", Price: usd("39.99"), Quantity: 20},
+			omitted: all,
+			dryRun:  true,
+			action:  models.ImportUpdated,
+			isbn:    isbn13(1), title: "Clean Code", quantity: 15, prices: 1, categories: []string{"computers"},
+		},
+		{
+			name:    "dry run checks categories",
+			book:    models.Book{ISBN: isbn13(2), Title: "Clean Architecture", Author: "Robert C. Martin", Price: usd("29.99"), Categories: []models.CategoryRef{{Slug: "missing"}}},
+			omitted: all,
+			dryRun:  true,
+			action:  models.ImportCreated,
+			err:     database.ErrInvalidCategories,
+			isbn:    isbn13(2),
+		},
+		{
+			name:    "dry run checks stock against reservations",
+			book:    models.Book{ISBN: isbn13(1), Title: "Clean Code", Author: "Robert C. Martin", Price: usd("37.49"), Quantity: 1},
+			omitted: all,
+			dryRun:  true,
+			action:  models.ImportUpdated,
+			err:     database.ErrInsufficientStock,
+			isbn:    isbn13(1), title: "Clean Code", quantity: 15, prices: 1, categories: []string{"computers"},
+		},
+		{
+			name:    "stock below reservations",
+			book:    models.Book{ISBN: isbn13(1), Title: "Clean Code", Author: "Robert C. Martin", Price: usd("37.49"), Quantity: 1},
+			omitted: all,
+			action:  models.ImportUpdated,
+			err:     database.ErrInsufficientStock,
+			isbn:    isbn13(1), title: "Clean Code", quantity: 15, prices: 1, categories: []string{"computers"},
+		},
+		{
+			name:    "invalid ISBN",
+			book:    models.Book{ISBN: "978-0-00-000002-7", Title: "Clean Architecture", Author: "Robert C. Martin", Price: usd("29.99")},
+			omitted: all,
+			err:     isbn.ErrInvalid,
+			isbn:    isbn13(2),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := database.NewMockStore()
+			computers := createCategory(t, store, models.Category{Name: "Computers"})
+			book := catalogBooks()[0]
+			book.ISBN = isbn13(1)
+			book.Prices = []money.Money{eur}
+			book.Categories = []models.CategoryRef{{ID: computers.ID}}
+			created, err := store.CreateBook(book, "system")
+			if err != nil {
+				t.Fatal(err)
+			}
+			reserve(t, store, created.ID, 2, time.Minute)
+
+			action, err := store.ImportBook(models.ImportRow{Line: 2, Book: tt.book, Omitted: tt.omitted}, "import", tt.dryRun)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if action != tt.action {
+				t.Errorf("Expected %q, got %q", tt.action, action)
+			}
+
+			got, err := store.GetBookByISBN(tt.isbn)
+			if tt.title == "" {
+				if !errors.Is(err, database.ErrBookNotFound) {
+					t.Errorf("Expected %v, got %v", database.ErrBookNotFound, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got.Title != tt.title || got.Quantity != tt.quantity || len(got.Prices) != tt.prices {
+				t.Errorf("Expected %q with %d copies and %d prices, got %q with %d and %d",
+					tt.title, tt.quantity, tt.prices, got.Title, got.Quantity, len(got.Prices))
+			}
+			if !reflect.DeepEqual(slugs(got.Categories), tt.categories) {
+				t.Errorf("Expected categories %v, got %v", tt.categories, slugs(got.Categories))
+			}
+			if got.ID == "someone-else" {
+				t.Error("Expected the row's ID to be ignored")
+			}
+			if got.ID == created.ID && got.Reserved != 2 {
+				t.Errorf("Expected reservations to be kept, got %d reserved", got.Reserved)
+			}
+		})
+	}
+}
+
+func TestImportBook_Contributors(t *testing.T) {
+	store, books := newStore(t, models.Book{
+		Title:        "Design Patterns",
+		Contributors: []models.Contributor{{Name: "Erich Gamma"}, {Name: "Grady Booch", Role: models.RoleEditor}},
+		PublishedAt:  date(1994),
+		Price:        usd("44.99"),
+	})
+	original := books[0]
+
+	tests := []struct {
+		name   string
+		author string
+		want   []string
+	}{
+		{"same author string keeps contributors", original.Author, names(original.Contributors)},
+		{"new author string replaces them", "Erich Gamma, Richard Helm", []string{"Erich Gamma/author", "Richard Helm/author"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row := models.ImportRow{
+				Book:    models.Book{ISBN: original.ISBN, Title: original.Title, Author: tt.author, PublishedAt: original.PublishedAt, Price: original.Price},
+				Omitted: map[string]bool{},
+			}
+			if _, err := store.ImportBook(row, "import", false); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			got, _ := store.GetBookByID(original.ID)
+			if !reflect.DeepEqual(names(got.Contributors), tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, names(got.Contributors))
+			}
+		})
+	}
+}
+
+func TestImportBook_PriceHistory(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+	book := books[0]
+
+	rows := []struct {
+		price   money.Money
+		dryRun  bool
+		changes int
+	}{
+		{usd("37.49"), false, 1}, // Unchanged prices aren't recorded
+		{usd("39.99"), true, 1},  // Dry runs change nothing
+		{usd("39.99"), false, 2},
+	}
+
+	for i, r := range rows {
+		row := models.ImportRow{
+			Book:    models.Book{ISBN: book.ISBN, Title: book.Title, Author: book.Author, PublishedAt: book.PublishedAt, Price: r.price},
+			Omitted: map[string]bool{models.ImportPrices: true, models.ImportCategories: true, models.ImportQuantity: true},
+		}
+		if _, err := store.ImportBook(row, "nightly-import", r.dryRun); err != nil {
+			t.Fatalf("Row %d: unexpected error: %v", i, err)
+		}
+
+		history, _ := store.GetPriceHistory(book.ID)
+		if len(history) != r.changes {
+			t.Fatalf("Row %d: expected %d price changes, got %d", i, r.changes, len(history))
+		}
+		if last := history[len(history)-1]; r.changes > 1 && last.Actor != "nightly-import" {
+			t.Errorf("Row %d: expected the change by nightly-import, got %q", i, last.Actor)
+		}
+	}
+}
+
+func TestImportBook_RecordsTheImporter(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+	existing := books[0]
+
+	rows := []models.ImportRow{
+		{Book: models.Book{ISBN: isbn13(2), Title: "Refactoring", Author: "Martin Fowler", PublishedAt: date(1999), Price: usd("9.99"), Quantity: 4}},
+		{Book: models.Book{ISBN: existing.ISBN, Title: existing.Title, Author: existing.Author, PublishedAt: existing.PublishedAt, Price: usd("39.99"), Quantity: 20}},
+	}
+	for i, row := range rows {
+		if _, err := store.ImportBook(row, "nightly-import", false); err != nil {
+			t.Fatalf("Row %d: unexpected error: %v", i, err)
+		}
+
+		book, err := store.GetBookByISBN(row.Book.ISBN)
+		if err != nil {
+			t.Fatal(err)
+		}
+		movements, _ := store.GetStockMovements(book.ID)
+		if last := movements[len(movements)-1]; last.Actor != "nightly-import" {
+			t.Errorf("Row %d: expected the stock change by nightly-import, got %+v", i, last)
+		}
+		history, _ := store.GetPriceHistory(book.ID)
+		if last := history[len(history)-1]; last.Actor != "nightly-import" {
+			t.Errorf("Row %d: expected the price change by nightly-import, got %+v", i, last)
+		}
+	}
+}
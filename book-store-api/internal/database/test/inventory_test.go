@@ -0,0 +1,121 @@
+package database_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func TestRecordStockMovement(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0]) // Opens with 15 copies
+	id := books[0].ID
+
+	tests := []struct {
+		name    string
+		typ     models.MovementType
+		delta   int
+		balance int
+		err     error
+	}{
+		{"receipt", models.MovementReceipt, 5, 20, nil},
+		{"sale", models.MovementSale, -8, 12, nil},
+		{"return", models.MovementReturn, 1, 13, nil},
+		{"adjustment down", models.MovementAdjustment, -3, 10, nil},
+		{"sale beyond stock", models.MovementSale, -11, 10, database.ErrInsufficientStock},
+		{"sale of every copy", models.MovementSale, -10, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			movement, err := store.RecordStockMovement(models.StockMovement{BookID: id, Type: tt.typ, Delta: tt.delta, Actor: "clerk"})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && movement.BalanceAfter != tt.balance {
+				t.Errorf("Expected balance %d, got %d", tt.balance, movement.BalanceAfter)
+			}
+			book, _ := store.GetBookByID(id)
+			if book.Quantity != tt.balance {
+				t.Errorf("Expected quantity %d, got %d", tt.balance, book.Quantity)
+			}
+		})
+	}
+
+	// The ledger opens with the book's stock and sums to its quantity
+	movements, err := store.GetStockMovements(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movements) != 6 {
+		t.Fatalf("Expected 6 movements, got %d", len(movements))
+	}
+	if movements[0].Type != models.MovementReceipt || movements[0].Delta != 15 || movements[0].Reason != "opening stock" {
+		t.Errorf("Expected an opening receipt of 15, got %+v", movements[0])
+	}
+	sum := 0
+	for _, m := range movements {
+		sum += m.Delta
+		if m.BalanceAfter != sum {
+			t.Errorf("Expected running balance %d, got %d", sum, m.BalanceAfter)
+		}
+	}
+}
+
+func TestRecordStockMovement_UnknownBook(t *testing.T) {
+	store, _ := newStore(t)
+	if _, err := store.RecordStockMovement(models.StockMovement{BookID: "missing", Delta: 1}); !errors.Is(err, database.ErrBookNotFound) {
+		t.Errorf("Expected ErrBookNotFound, got %v", err)
+	}
+	if _, err := store.GetStockMovements("missing"); !errors.Is(err, database.ErrBookNotFound) {
+		t.Errorf("Expected ErrBookNotFound, got %v", err)
+	}
+}
+
+func TestRecordStockMovement_ConcurrentSalesDontOversell(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+	id := books[0].ID
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sold := 0
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := store.RecordStockMovement(models.StockMovement{BookID: id, Type: models.MovementSale, Delta: -1}); err == nil {
+				mu.Lock()
+				sold++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	book, _ := store.GetBookByID(id)
+	if sold != 15 || book.Quantity != 0 {
+		t.Errorf("Expected 15 sales leaving 0 copies, got %d sales leaving %d", sold, book.Quantity)
+	}
+}
+
+func TestUpdateBook_QuantityChangeIsAnAdjustment(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+	book := books[0]
+
+	book.Quantity = 9
+	updated, err := store.UpdateBook(book.ID, book, "auditor")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Quantity != 9 {
+		t.Errorf("Expected quantity 9, got %d", updated.Quantity)
+	}
+
+	movements, _ := store.GetStockMovements(book.ID)
+	last := movements[len(movements)-1]
+	if last.Type != models.MovementAdjustment || last.Delta != -6 || last.BalanceAfter != 9 || last.Actor != "auditor" {
+		t.Errorf("Expected an adjustment of -6 by auditor, got %+v", last)
+	}
+}
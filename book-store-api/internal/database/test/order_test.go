@@ -0,0 +1,230 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// placeOrder orders two copies of the first book and one of the second
+func placeOrder(t *testing.T, store *database.MockStore, books []models.Book, ttl time.Duration) models.Order {
+	t.Helper()
+	order, err := store.CreateOrder(models.Order{
+		CustomerID:      "user:1",
+		ShippingAddress: "1 Main St",
+		Items: []models.OrderItem{
+			{ProductID: books[0].ID, Quantity: 2},
+			{ProductID: books[1].ID, Quantity: 1},
+		},
+	}, ttl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order
+}
+
+func TestCreateOrder(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2], catalogBooks()[3])
+
+	// Prices come from the catalog, not the request
+	order, err := store.CreateOrder(models.Order{
+		CustomerID: "user:1",
+		Items:      []models.OrderItem{{ProductID: books[0].ID, Quantity: 2, UnitPrice: usd("0.01")}},
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.Items[0].Title != "Design Patterns" || order.Items[0].Subtotal.String() != "89.98 USD" || order.Total.String() != "89.98 USD" {
+		t.Errorf("Expected catalog prices, got %+v totalling %s", order.Items[0], order.Total)
+	}
+
+	tests := []struct {
+		name  string
+		items []models.OrderItem
+		err   error
+	}{
+		{"unknown book", []models.OrderItem{{ProductID: "missing", Quantity: 1}}, database.ErrBookNotFound},
+		{"short of stock", []models.OrderItem{{ProductID: books[0].ID, Quantity: 2}}, database.ErrInsufficientStock},
+		{"lines for one book add up", []models.OrderItem{{ProductID: books[1].ID, Quantity: 1}, {ProductID: books[1].ID, Quantity: 2}}, database.ErrInsufficientStock},
+		{"one short line fails the order", []models.OrderItem{{ProductID: books[1].ID, Quantity: 1}, {ProductID: books[0].ID, Quantity: 2}}, database.ErrInsufficientStock},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := store.CreateOrder(models.Order{CustomerID: "user:2", Items: tt.items}, time.Minute); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+			if book, _ := store.GetBookByID(books[1].ID); book.Reserved != 0 {
+				t.Errorf("Expected nothing reserved, got %d", book.Reserved)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    []models.OrderStatus
+		err      error
+		quantity int // Copies of the first book left on hand
+		reserved int
+	}{
+		{"pending", nil, nil, 3, 2},
+		{"processing", []models.OrderStatus{models.OrderProcessing}, nil, 3, 2},
+		{"completed", []models.OrderStatus{models.OrderProcessing, models.OrderCompleted}, nil, 1, 0},
+		{"cancelled while pending", []models.OrderStatus{models.OrderCancelled}, nil, 3, 0},
+		{"cancelled while processing", []models.OrderStatus{models.OrderProcessing, models.OrderCancelled}, nil, 3, 0},
+		{"pending can't complete", []models.OrderStatus{models.OrderCompleted}, database.ErrInvalidTransition, 3, 2},
+		{"completed can't cancel", []models.OrderStatus{models.OrderProcessing, models.OrderCompleted, models.OrderCancelled}, database.ErrInvalidTransition, 1, 0},
+		{"cancelled can't resume", []models.OrderStatus{models.OrderCancelled, models.OrderProcessing}, database.ErrInvalidTransition, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, books := newStore(t, catalogBooks()[2], catalogBooks()[3])
+			order := placeOrder(t, store, books, time.Minute)
+
+			var err error
+			for _, status := range tt.steps {
+				if _, err = store.UpdateOrderStatus(order.ID, status, "admin"); err != nil {
+					break
+				}
+			}
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+
+			book, _ := store.GetBookByID(books[0].ID)
+			if book.Quantity != tt.quantity || book.Reserved != tt.reserved {
+				t.Errorf("Expected %d on hand and %d reserved, got %d and %d", tt.quantity, tt.reserved, book.Quantity, book.Reserved)
+			}
+		})
+	}
+
+	store, _ := newStore(t)
+	if _, err := store.UpdateOrderStatus("missing", models.OrderCancelled, "admin"); !errors.Is(err, database.ErrOrderNotFound) {
+		t.Errorf("Expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestUpdateOrderStatus_ProcessingKeepsReservations(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2], catalogBooks()[3])
+	order := placeOrder(t, store, books, time.Minute)
+
+	if _, err := store.UpdateOrderStatus(order.ID, models.OrderProcessing, "admin"); err != nil {
+		t.Fatal(err)
+	}
+
+	// The sweeper leaves the holds of an order being fulfilled alone
+	if count, _ := store.ExpireReservations(time.Now().Add(time.Hour)); count != 0 {
+		t.Errorf("Expected no reservations to expire, got %d", count)
+	}
+	for _, item := range order.Items {
+		reservation, _ := store.GetReservation(item.ReservationID)
+		if reservation.Status != models.ReservationActive || !reservation.ExpiresAt.IsZero() {
+			t.Errorf("Expected an active hold without expiry, got %s until %v", reservation.Status, reservation.ExpiresAt)
+		}
+	}
+
+	if _, err := store.UpdateOrderStatus(order.ID, models.OrderCompleted, "admin"); err != nil {
+		t.Fatal(err)
+	}
+	for _, item := range order.Items {
+		if reservation, _ := store.GetReservation(item.ReservationID); reservation.Status != models.ReservationConfirmed {
+			t.Errorf("Expected the hold to be confirmed, got %s", reservation.Status)
+		}
+	}
+	movements, _ := store.GetStockMovements(books[0].ID)
+	if sale := movements[len(movements)-1]; sale.Delta != -2 || sale.Reason != "order "+order.ID || sale.Actor != "admin" {
+		t.Errorf("Expected the order's sale, got %+v", sale)
+	}
+}
+
+func TestUpdateOrderStatus_CompletesLapsedReservations(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken int // Copies of the first book reserved by someone else after the holds lapsed
+		err   error
+	}{
+		{"sold from available stock", 0, nil},
+		{"not enough left", 2, database.ErrInsufficientStock},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, books := newStore(t, catalogBooks()[2], catalogBooks()[3])
+			order := placeOrder(t, store, books, -time.Second)
+			if _, err := store.ExpireReservations(time.Now()); err != nil {
+				t.Fatal(err)
+			}
+			if tt.taken > 0 {
+				if _, err := store.CreateReservation(models.Reservation{BookID: books[0].ID, Quantity: tt.taken, ExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			// Processing doesn't revive lapsed holds, so completion sells from stock
+			if _, err := store.UpdateOrderStatus(order.ID, models.OrderProcessing, "admin"); err != nil {
+				t.Fatal(err)
+			}
+			_, err := store.UpdateOrderStatus(order.ID, models.OrderCompleted, "admin")
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected %v, got %v", tt.err, err)
+			}
+
+			got, _ := store.GetOrder(order.ID)
+			want := models.OrderCompleted
+			if tt.err != nil {
+				want = models.OrderProcessing
+			}
+			if got.Status != want {
+				t.Errorf("Expected %s, got %s", want, got.Status)
+			}
+		})
+	}
+}
+
+func TestListOrders(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+	for _, customer := range []string{"user:1", "user:2", "user:1"} {
+		if _, err := store.CreateOrder(models.Order{CustomerID: customer, Items: []models.OrderItem{{ProductID: books[0].ID, Quantity: 1}}}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, _, _ := store.ListOrders(models.OrderFilter{})
+	if _, err := store.UpdateOrderStatus(all[0].ID, models.OrderCancelled, "user:1"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter models.OrderFilter
+		count  int
+		total  int
+	}{
+		{"all", models.OrderFilter{}, 3, 3},
+		{"by customer", models.OrderFilter{CustomerID: "user:1"}, 2, 2},
+		{"by status", models.OrderFilter{Status: models.OrderPending}, 2, 2},
+		{"page", models.OrderFilter{Skip: 1, Limit: 1}, 1, 3},
+		{"skip past the end", models.OrderFilter{Skip: 5}, 0, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orders, total, err := store.ListOrders(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(orders) != tt.count || total != tt.total {
+				t.Errorf("Expected %d of %d, got %d of %d", tt.count, tt.total, len(orders), total)
+			}
+			for i := 1; i < len(orders); i++ {
+				if orders[i].CreatedAt.After(orders[i-1].CreatedAt) {
+					t.Error("Expected the newest orders first")
+				}
+			}
+		})
+	}
+}
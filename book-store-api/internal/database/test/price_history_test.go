@@ -0,0 +1,72 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+func TestGetPriceHistory(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+	book := books[0]
+
+	updates := []struct {
+		name    string
+		price   string
+		prices  []money.Money
+		actor   string
+		entries int
+	}{
+		{"price cut", "29.99", nil, "42", 2},
+		{"same price", "29.99", nil, "43", 2},
+		{"fixed price added", "29.99", []money.Money{money.New(2500, "GBP")}, "44", 3},
+		{"same fixed price", "29.99", []money.Money{money.New(2500, "GBP")}, "45", 3},
+		{"fixed price changed", "29.99", []money.Money{money.New(2400, "GBP")}, "46", 4},
+	}
+	for _, u := range updates {
+		book.Price = usd(u.price)
+		book.Prices = u.prices
+		if _, err := store.UpdateBook(book.ID, book, u.actor); err != nil {
+			t.Fatalf("%s: %v", u.name, err)
+		}
+		history, err := store.GetPriceHistory(book.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(history) != u.entries {
+			t.Errorf("%s: expected %d entries, got %d", u.name, u.entries, len(history))
+		}
+	}
+
+	history, _ := store.GetPriceHistory(book.ID)
+	opening, cut := history[0], history[1]
+	if opening.Actor != "system" || opening.PreviousPrice != nil || opening.Price.String() != "37.49 USD" {
+		t.Errorf("Expected the opening price by system, got %+v", opening)
+	}
+	if cut.Actor != "42" || cut.PreviousPrice == nil || cut.PreviousPrice.String() != "37.49 USD" || cut.Price.String() != "29.99 USD" {
+		t.Errorf("Expected the cut from 37.49 USD by 42, got %+v", cut)
+	}
+	for i := 1; i < len(history); i++ {
+		if history[i].ChangedAt.Before(history[i-1].ChangedAt) {
+			t.Error("Expected the history oldest first")
+		}
+	}
+
+	if _, err := store.GetPriceHistory("missing"); !errors.Is(err, database.ErrBookNotFound) {
+		t.Errorf("Expected ErrBookNotFound, got %v", err)
+	}
+}
+
+func TestGetPriceHistory_IsACopy(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+
+	history, _ := store.GetPriceHistory(books[0].ID)
+	history[0].Actor = "changed"
+
+	again, _ := store.GetPriceHistory(books[0].ID)
+	if again[0].Actor != "system" {
+		t.Errorf("Expected the stored history to be unchanged, got %s", again[0].Actor)
+	}
+}
@@ -0,0 +1,191 @@
+package database_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func reserve(t *testing.T, store *database.MockStore, bookID string, quantity int, ttl time.Duration) models.Reservation {
+	t.Helper()
+	reservation, err := store.CreateReservation(models.Reservation{
+		BookID:    bookID,
+		Quantity:  quantity,
+		Actor:     "checkout",
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reservation
+}
+
+func TestCreateReservation(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2]) // Opens with 3 copies
+	id := books[0].ID
+
+	tests := []struct {
+		name      string
+		bookID    string
+		quantity  int
+		err       error
+		reserved  int
+		available int
+	}{
+		{"holds copies", id, 2, nil, 2, 1},
+		{"more than available", id, 2, database.ErrInsufficientStock, 2, 1},
+		{"the last copy", id, 1, nil, 3, 0},
+		{"none left", id, 1, database.ErrInsufficientStock, 3, 0},
+		{"unknown book", "missing", 1, database.ErrBookNotFound, 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reservation, err := store.CreateReservation(models.Reservation{BookID: tt.bookID, Quantity: tt.quantity, ExpiresAt: time.Now().Add(time.Minute)})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && reservation.Status != models.ReservationActive {
+				t.Errorf("Expected an active reservation, got %s", reservation.Status)
+			}
+			book, _ := store.GetBookByID(id)
+			if book.Reserved != tt.reserved || book.Available() != tt.available || book.Quantity != 3 {
+				t.Errorf("Expected %d reserved and %d available of 3, got %d and %d of %d", tt.reserved, tt.available, book.Reserved, book.Available(), book.Quantity)
+			}
+		})
+	}
+}
+
+func TestReservation_ConfirmAndRelease(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2])
+	id := books[0].ID
+
+	confirmed := reserve(t, store, id, 2, time.Minute)
+	released := reserve(t, store, id, 1, time.Minute)
+
+	got, err := store.ConfirmReservation(confirmed.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Status != models.ReservationConfirmed {
+		t.Errorf("Expected confirmed, got %s", got.Status)
+	}
+	if _, err := store.ReleaseReservation(released.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Confirming sells the held copies; releasing gives them back
+	book, _ := store.GetBookByID(id)
+	if book.Quantity != 1 || book.Reserved != 0 {
+		t.Errorf("Expected 1 copy and none reserved, got %d and %d", book.Quantity, book.Reserved)
+	}
+	movements, _ := store.GetStockMovements(id)
+	sale := movements[len(movements)-1]
+	if sale.Type != models.MovementSale || sale.Delta != -2 || sale.Actor != "checkout" {
+		t.Errorf("Expected a sale of 2 by checkout, got %+v", sale)
+	}
+
+	// Finished reservations can't be confirmed or released again
+	tests := []struct {
+		name string
+		call func(string) (models.Reservation, error)
+		id   string
+		err  error
+	}{
+		{"confirm twice", store.ConfirmReservation, confirmed.ID, database.ErrReservationNotActive},
+		{"release confirmed", store.ReleaseReservation, confirmed.ID, database.ErrReservationNotActive},
+		{"confirm released", store.ConfirmReservation, released.ID, database.ErrReservationNotActive},
+		{"release twice", store.ReleaseReservation, released.ID, database.ErrReservationNotActive},
+		{"confirm unknown", store.ConfirmReservation, "missing", database.ErrReservationNotFound},
+		{"release unknown", store.ReleaseReservation, "missing", database.ErrReservationNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.call(tt.id); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestReservation_Expiry(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2])
+	id := books[0].ID
+
+	overdue := reserve(t, store, id, 1, -time.Second)
+	late := reserve(t, store, id, 1, -time.Second)
+	current := reserve(t, store, id, 1, time.Hour)
+
+	// Confirming an overdue reservation expires it instead
+	if _, err := store.ConfirmReservation(late.ID); !errors.Is(err, database.ErrReservationExpired) {
+		t.Fatalf("Expected ErrReservationExpired, got %v", err)
+	}
+	if got, _ := store.GetReservation(late.ID); got.Status != models.ReservationExpired {
+		t.Errorf("Expected expired, got %s", got.Status)
+	}
+
+	count, err := store.ExpireReservations(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 reservation to expire, got %d", count)
+	}
+	if got, _ := store.GetReservation(overdue.ID); got.Status != models.ReservationExpired {
+		t.Errorf("Expected expired, got %s", got.Status)
+	}
+	if got, _ := store.GetReservation(current.ID); got.Status != models.ReservationActive {
+		t.Errorf("Expected the current reservation to stay active, got %s", got.Status)
+	}
+
+	book, _ := store.GetBookByID(id)
+	if book.Reserved != 1 || book.Quantity != 3 {
+		t.Errorf("Expected 1 reserved of 3, got %d of %d", book.Reserved, book.Quantity)
+	}
+}
+
+func TestReservation_StockBelowReserved(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[2])
+	book := books[0]
+	reserve(t, store, book.ID, 2, time.Minute)
+
+	// Held copies can't be sold, adjusted or updated away
+	if _, err := store.RecordStockMovement(models.StockMovement{BookID: book.ID, Type: models.MovementSale, Delta: -2}); !errors.Is(err, database.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock, got %v", err)
+	}
+	book.Quantity = 1
+	if _, err := store.UpdateBook(book.ID, book, "clerk"); !errors.Is(err, database.ErrInsufficientStock) {
+		t.Errorf("Expected ErrInsufficientStock, got %v", err)
+	}
+}
+
+func TestGetBookFacets_ReservedCopies(t *testing.T) {
+	store, books := newStore(t, catalogBooks()...)
+	knuth := reserve(t, store, books[4].ID, 1, time.Minute) // Its only copy
+	reserve(t, store, books[3].ID, 1, time.Minute)          // One of two copies
+
+	availability := func() map[string]int {
+		facets, err := store.GetBookFacets(models.BookFilter{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return counts(facets.Availability)
+	}
+
+	want := map[string]int{models.AvailabilityInStock: 3, models.AvailabilityOutOfStock: 2}
+	if got := availability(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v while reserved, got %v", want, got)
+	}
+
+	if _, err := store.ReleaseReservation(knuth.ID); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]int{models.AvailabilityInStock: 4, models.AvailabilityOutOfStock: 1}
+	if got := availability(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v after release, got %v", want, got)
+	}
+}
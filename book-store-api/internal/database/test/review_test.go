@@ -0,0 +1,157 @@
+package database_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func review(t *testing.T, store *database.MockStore, bookID, userID string, stars int) models.Review {
+	t.Helper()
+	created, err := store.CreateReview(models.Review{BookID: bookID, UserID: userID, Rating: stars})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return created
+}
+
+func TestReviews_Rating(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0])
+	id := books[0].ID
+	first := review(t, store, id, "1", 5)
+	second := review(t, store, id, "2", 2)
+
+	tests := []struct {
+		name      string
+		change    func() error
+		average   float64
+		count     int
+		histogram map[int]int
+	}{
+		{"pending reviews don't count", func() error { return nil }, 0, 0, map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}},
+		{"approved", func() error {
+			_, err := store.ModerateReview(first.ID, models.ReviewApproved, "mod", "")
+			return err
+		}, 5, 1, map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 1}},
+		{"approved again changes nothing", func() error {
+			_, err := store.ModerateReview(first.ID, models.ReviewApproved, "mod", "still fine")
+			return err
+		}, 5, 1, map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 1}},
+		{"second approved", func() error {
+			_, err := store.ModerateReview(second.ID, models.ReviewApproved, "mod", "")
+			return err
+		}, 3.5, 2, map[int]int{1: 0, 2: 1, 3: 0, 4: 0, 5: 1}},
+		{"edit goes back to pending", func() error {
+			_, err := store.UpdateReview(second.ID, models.Review{Rating: 4, Title: "Grew on me"})
+			return err
+		}, 5, 1, map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 1}},
+		{"edit approved", func() error {
+			_, err := store.ModerateReview(second.ID, models.ReviewApproved, "mod", "")
+			return err
+		}, 4.5, 2, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}},
+		{"rejected", func() error {
+			_, err := store.ModerateReview(first.ID, models.ReviewRejected, "mod", "spoilers")
+			return err
+		}, 4, 1, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 0}},
+		{"deleted", func() error { return store.DeleteReview(second.ID) }, 0, 0, map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatal(err)
+			}
+			book, _ := store.GetBookByID(id)
+			if book.Rating.Average != tt.average || book.Rating.Count != tt.count || !reflect.DeepEqual(book.Rating.Histogram, tt.histogram) {
+				t.Errorf("Expected %v from %d %v, got %+v", tt.average, tt.count, tt.histogram, book.Rating)
+			}
+		})
+	}
+
+	rejected, _ := store.GetReview(first.ID)
+	if rejected.ModeratedBy != "mod" || rejected.ModerationNote != "spoilers" {
+		t.Errorf("Expected the moderator and note to be kept, got %+v", rejected)
+	}
+}
+
+func TestReviews_Errors(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0], catalogBooks()[1])
+	existing := review(t, store, books[0].ID, "1", 4)
+
+	tests := []struct {
+		name string
+		call func() error
+		err  error
+	}{
+		{"second review of a book", func() error {
+			_, err := store.CreateReview(models.Review{BookID: books[0].ID, UserID: "1", Rating: 1})
+			return err
+		}, database.ErrDuplicateReview},
+		{"same user, another book", func() error {
+			_, err := store.CreateReview(models.Review{BookID: books[1].ID, UserID: "1", Rating: 1})
+			return err
+		}, nil},
+		{"unknown book", func() error {
+			_, err := store.CreateReview(models.Review{BookID: "missing", UserID: "1", Rating: 1})
+			return err
+		}, database.ErrBookNotFound},
+		{"get unknown", func() error { _, err := store.GetReview("missing"); return err }, database.ErrReviewNotFound},
+		{"update unknown", func() error { _, err := store.UpdateReview("missing", existing); return err }, database.ErrReviewNotFound},
+		{"moderate unknown", func() error {
+			_, err := store.ModerateReview("missing", models.ReviewApproved, "mod", "")
+			return err
+		}, database.ErrReviewNotFound},
+		{"delete unknown", func() error { return store.DeleteReview("missing") }, database.ErrReviewNotFound},
+		{"list for unknown book", func() error {
+			_, _, err := store.ListReviews(models.ReviewFilter{BookID: "missing"})
+			return err
+		}, database.ErrBookNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.call(); !errors.Is(err, tt.err) {
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestListReviews(t *testing.T) {
+	store, books := newStore(t, catalogBooks()[0], catalogBooks()[1])
+	review(t, store, books[0].ID, "1", 5)
+	approved := review(t, store, books[0].ID, "2", 3)
+	review(t, store, books[1].ID, "1", 4)
+	if _, err := store.ModerateReview(approved.ID, models.ReviewApproved, "mod", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		filter models.ReviewFilter
+		count  int
+		total  int
+	}{
+		{"all", models.ReviewFilter{}, 3, 3},
+		{"by book", models.ReviewFilter{BookID: books[0].ID}, 2, 2},
+		{"by user", models.ReviewFilter{UserID: "1"}, 2, 2},
+		{"approved for a book", models.ReviewFilter{BookID: books[0].ID, Status: models.ReviewApproved}, 1, 1},
+		{"pending", models.ReviewFilter{Status: models.ReviewPending}, 2, 2},
+		{"page", models.ReviewFilter{Skip: 1, Limit: 1}, 1, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reviews, total, err := store.ListReviews(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(reviews) != tt.count || total != tt.total {
+				t.Errorf("Expected %d of %d, got %d of %d", tt.count, tt.total, len(reviews), total)
+			}
+		})
+	}
+}
@@ -0,0 +1,315 @@
+package database_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// isbn13 returns the nth valid ISBN-13 in the 978-0 group
+func isbn13(n int) string {
+	digits := fmt.Sprintf("9780%08d", n)
+	sum := 0
+	for i, d := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(d-'0') * weight
+	}
+	return digits + fmt.Sprint((10-sum%10)%10)
+}
+
+func usd(amount string) money.Money {
+	m, err := money.Parse(amount, "USD")
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func date(year int) time.Time {
+	return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// newStore returns a store holding the books, and the books as stored
+func newStore(t *testing.T, books ...models.Book) (*database.MockStore, []models.Book) {
+	t.Helper()
+	store := database.NewMockStore()
+	created := make([]models.Book, len(books))
+	for i, book := range books {
+		if book.ISBN == "" {
+			book.ISBN = isbn13(i + 1)
+		}
+		var err error
+		if created[i], err = store.CreateBook(book, "system"); err != nil {
+			t.Fatalf("Creating %q: %v", book.Title, err)
+		}
+	}
+	return store, created
+}
+
+// catalogBooks is a small catalog spanning every facet
+func catalogBooks() []models.Book {
+	return []models.Book{
+		{Title: "Clean Code", Author: "Robert C. Martin", PublishedAt: date(2008), Price: usd("37.49"), Quantity: 15},
+		{Title: "Clean Architecture", Author: "Robert C. Martin", PublishedAt: date(2017), Price: usd("29.99")},
+		{Title: "Design Patterns", Author: "Erich Gamma, Richard Helm", PublishedAt: date(1994), Price: usd("44.99"), Quantity: 3},
+		{Title: "Refactoring", Author: "Martin Fowler", PublishedAt: date(1999), Price: usd("9.99"), Quantity: 2},
+		{Title: "The Art of Computer Programming", Author: "Donald Knuth", PublishedAt: date(1968), Price: usd("199.00"), Quantity: 1},
+	}
+}
+
+func counts(values []models.FacetValue) map[string]int {
+	found := make(map[string]int)
+	for _, v := range values {
+		found[v.Value] = v.Count
+	}
+	return found
+}
+
+func TestGetBookFacets(t *testing.T) {
+	store, _ := newStore(t, catalogBooks()...)
+
+	tests := []struct {
+		name         string
+		filter       models.BookFilter
+		total        int
+		authors      map[string]int
+		decades      map[string]int
+		prices       map[string]int
+		availability map[string]int
+	}{
+		{
+			name:         "all books",
+			total:        5,
+			authors:      map[string]int{"Robert C. Martin": 2, "Erich Gamma": 1, "Richard Helm": 1, "Martin Fowler": 1, "Donald Knuth": 1},
+			decades:      map[string]int{"1960s": 1, "1990s": 2, "2000s": 1, "2010s": 1},
+			prices:       map[string]int{"0-10": 1, "10-20": 0, "20-30": 1, "30-50": 2, "50+": 1},
+			availability: map[string]int{models.AvailabilityInStock: 4, models.AvailabilityOutOfStock: 1},
+		},
+		{
+			name:         "filtered by author",
+			filter:       models.BookFilter{Author: "martin"},
+			total:        3,
+			authors:      map[string]int{"Robert C. Martin": 2, "Martin Fowler": 1},
+			decades:      map[string]int{"1990s": 1, "2000s": 1, "2010s": 1},
+			prices:       map[string]int{"0-10": 1, "10-20": 0, "20-30": 1, "30-50": 1, "50+": 0},
+			availability: map[string]int{models.AvailabilityInStock: 2, models.AvailabilityOutOfStock: 1},
+		},
+		{
+			name:         "prices in another currency fall in no range",
+			filter:       models.BookFilter{Currency: "EUR", Title: "clean"},
+			total:        2,
+			authors:      map[string]int{"Robert C. Martin": 2},
+			decades:      map[string]int{"2000s": 1, "2010s": 1},
+			prices:       map[string]int{"0-10": 0, "10-20": 0, "20-30": 0, "30-50": 0, "50+": 0},
+			availability: map[string]int{models.AvailabilityInStock: 1, models.AvailabilityOutOfStock: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			facets, err := store.GetBookFacets(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if facets.Total != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, facets.Total)
+			}
+			if got := counts(facets.Authors); !reflect.DeepEqual(got, tt.authors) {
+				t.Errorf("Expected authors %v, got %v", tt.authors, got)
+			}
+			if got := counts(facets.Decades); !reflect.DeepEqual(got, tt.decades) {
+				t.Errorf("Expected decades %v, got %v", tt.decades, got)
+			}
+			if got := counts(facets.PriceRanges); !reflect.DeepEqual(got, tt.prices) {
+				t.Errorf("Expected prices %v, got %v", tt.prices, got)
+			}
+			if got := counts(facets.Availability); !reflect.DeepEqual(got, tt.availability) {
+				t.Errorf("Expected availability %v, got %v", tt.availability, got)
+			}
+		})
+	}
+}
+
+func TestGetBookFacets_Order(t *testing.T) {
+	store, _ := newStore(t, catalogBooks()...)
+
+	facets, err := store.GetBookFacets(models.BookFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Authors are most common first, then by name; decades are chronological
+	wantAuthors := []string{"Robert C. Martin", "Donald Knuth", "Erich Gamma", "Martin Fowler", "Richard Helm"}
+	for i, v := range facets.Authors {
+		if v.Value != wantAuthors[i] {
+			t.Errorf("Expected author %d to be %s, got %s", i, wantAuthors[i], v.Value)
+		}
+	}
+	wantDecades := []string{"1960s", "1990s", "2000s", "2010s"}
+	for i, v := range facets.Decades {
+		if v.Value != wantDecades[i] {
+			t.Errorf("Expected decade %d to be %s, got %s", i, wantDecades[i], v.Value)
+		}
+	}
+
+	// Price ranges carry their bounds in the facet currency
+	last := facets.PriceRanges[len(facets.PriceRanges)-1]
+	if last.Min == nil || last.Min.String() != "50.00 USD" || last.Max != nil {
+		t.Errorf("Expected the last range to be 50.00 USD and up, got %v-%v", last.Min, last.Max)
+	}
+}
+
+func titles(books []models.Book) []string {
+	found := make([]string, len(books))
+	for i, book := range books {
+		found[i] = book.Title
+	}
+	return found
+}
+
+func TestListBooks(t *testing.T) {
+	store, _ := newStore(t, catalogBooks()...)
+	min, max := usd("20.00"), usd("50.00")
+
+	tests := []struct {
+		name    string
+		query   models.BookQuery
+		want    []string
+		total   int
+		hasNext bool
+		hasPrev bool
+	}{
+		{
+			name:  "default order is creation order",
+			want:  []string{"Clean Code", "Clean Architecture", "Design Patterns", "Refactoring", "The Art of Computer Programming"},
+			total: 5,
+		},
+		{
+			name:  "filter and sort",
+			query: models.BookQuery{Filter: models.BookFilter{Title: "clean"}, Sort: []models.SortKey{{Field: models.SortByTitle}}},
+			want:  []string{"Clean Architecture", "Clean Code"},
+			total: 2,
+		},
+		{
+			name:  "price bounds",
+			query: models.BookQuery{Filter: models.BookFilter{MinPrice: &min, MaxPrice: &max}, Sort: []models.SortKey{{Field: models.SortByPrice, Descending: true}}},
+			want:  []string{"Design Patterns", "Clean Code", "Clean Architecture"},
+			total: 3,
+		},
+		{
+			name:    "first page",
+			query:   models.BookQuery{Sort: []models.SortKey{{Field: models.SortByPublishedAt}}, Page: models.Page{Limit: 2}},
+			want:    []string{"The Art of Computer Programming", "Design Patterns"},
+			total:   5,
+			hasNext: true,
+		},
+		{
+			name:    "middle page",
+			query:   models.BookQuery{Sort: []models.SortKey{{Field: models.SortByPublishedAt}}, Page: models.Page{Limit: 2, Offset: 2}},
+			want:    []string{"Refactoring", "Clean Code"},
+			total:   5,
+			hasNext: true,
+			hasPrev: true,
+		},
+		{
+			name:    "last page",
+			query:   models.BookQuery{Sort: []models.SortKey{{Field: models.SortByPublishedAt}}, Page: models.Page{Limit: 2, Offset: 4}},
+			want:    []string{"Clean Architecture"},
+			total:   5,
+			hasPrev: true,
+		},
+		{
+			name:    "offset past the end",
+			query:   models.BookQuery{Page: models.Page{Limit: 2, Offset: 10}},
+			want:    []string{},
+			total:   5,
+			hasPrev: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			list, err := store.ListBooks(context.Background(), tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := titles(list.Items); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+			if list.Total != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, list.Total)
+			}
+			if list.HasNext != tt.hasNext || list.HasPrev != tt.hasPrev {
+				t.Errorf("Expected next/prev %v/%v, got %v/%v", tt.hasNext, tt.hasPrev, list.HasNext, list.HasPrev)
+			}
+		})
+	}
+}
+
+func TestListBooks_CanceledContext(t *testing.T) {
+	store, _ := newStore(t, catalogBooks()...)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := store.ListBooks(ctx, models.BookQuery{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestListBooks_Cursor(t *testing.T) {
+	store, _ := newStore(t, catalogBooks()...)
+	keys := []models.SortKey{{Field: models.SortByPublishedAt}}
+
+	first, err := store.ListBooks(context.Background(), models.BookQuery{Sort: keys, Page: models.Page{Limit: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Forward from the last book of the first page
+	next := models.NewCursor(first.Items[1], keys, false)
+	second, err := store.ListBooks(context.Background(), models.BookQuery{Cursor: &next, Page: models.Page{Limit: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(second.Items), []string{"Refactoring", "Clean Code"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if !second.HasNext || !second.HasPrev {
+		t.Errorf("Expected a middle page, got next/prev %v/%v", second.HasNext, second.HasPrev)
+	}
+
+	// Backward from the first book of the second page returns the first page
+	prev := models.NewCursor(second.Items[0], keys, true)
+	back, err := store.ListBooks(context.Background(), models.BookQuery{Cursor: &prev, Page: models.Page{Limit: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(back.Items), titles(first.Items); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if back.HasPrev || !back.HasNext {
+		t.Errorf("Expected the first page, got next/prev %v/%v", back.HasNext, back.HasPrev)
+	}
+
+	// A book added before the cursor position doesn't shift the next page
+	if _, err := store.CreateBook(models.Book{Title: "Structure and Interpretation", Author: "Harold Abelson", ISBN: isbn13(99), PublishedAt: date(1985), Price: usd("55.00")}, "system"); err != nil {
+		t.Fatal(err)
+	}
+	again, err := store.ListBooks(context.Background(), models.BookQuery{Cursor: &next, Page: models.Page{Limit: 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := titles(again.Items), titles(second.Items); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
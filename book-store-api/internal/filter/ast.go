@@ -0,0 +1,144 @@
+package filter
+
+import (
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// Op is a comparison operator
+type Op string
+
+const (
+	OpEq       Op = "eq"
+	OpNe       Op = "ne"
+	OpLt       Op = "lt"
+	OpLe       Op = "le"
+	OpGt       Op = "gt"
+	OpGe       Op = "ge"
+	OpIn       Op = "in"
+	OpContains Op = "contains"
+)
+
+// Expr is a node of a parsed filter expression. Stores can evaluate it with
+// Matches or walk the tree to translate it into their own query language.
+type Expr interface {
+	Matches(book models.Book) bool
+}
+
+// And matches when both sides match
+type And struct {
+	Left, Right Expr
+}
+
+// Or matches when either side matches
+type Or struct {
+	Left, Right Expr
+}
+
+// Not matches when the inner expression does not
+type Not struct {
+	Expr Expr
+}
+
+// Comparison compares a book field against one value, or a list for OpIn
+type Comparison struct {
+	Field  string
+	Op     Op
+	Values []Value
+}
+
+// Value is a typed literal from the expression
+type Value struct {
+	Type   FieldType
+	String string
+	Number float64
+	Bool   bool
+	Time   time.Time
+	Money  money.Money
+}
+
+func (e And) Matches(book models.Book) bool {
+	return e.Left.Matches(book) && e.Right.Matches(book)
+}
+
+func (e Or) Matches(book models.Book) bool {
+	return e.Left.Matches(book) || e.Right.Matches(book)
+}
+
+func (e Not) Matches(book models.Book) bool {
+	return !e.Expr.Matches(book)
+}
+
+func (e Comparison) Matches(book models.Book) bool {
+	field, ok := Fields[e.Field]
+	if !ok {
+		return false
+	}
+	actual := field.Get(book)
+
+	switch e.Op {
+	case OpIn:
+		for _, v := range e.Values {
+			if compare(actual, v) == 0 {
+				return true
+			}
+		}
+		return false
+	case OpContains:
+		return strings.Contains(strings.ToLower(actual.String), strings.ToLower(e.Values[0].String))
+	}
+
+	// Prices in another currency than the literal can't be compared, so only ne matches
+	if actual.Type == TypeMoney && actual.Money.Currency != e.Values[0].Money.Currency {
+		return e.Op == OpNe
+	}
+
+	c := compare(actual, e.Values[0])
+	switch e.Op {
+	case OpEq:
+		return c == 0
+	case OpNe:
+		return c != 0
+	case OpLt:
+		return c < 0
+	case OpLe:
+		return c <= 0
+	case OpGt:
+		return c > 0
+	case OpGe:
+		return c >= 0
+	}
+	return false
+}
+
+// compare orders two values of the same type. Strings compare case-insensitively.
+func compare(a, b Value) int {
+	switch a.Type {
+	case TypeString:
+		return strings.Compare(strings.ToLower(a.String), strings.ToLower(b.String))
+	case TypeNumber:
+		switch {
+		case a.Number < b.Number:
+			return -1
+		case a.Number > b.Number:
+			return 1
+		}
+		return 0
+	case TypeTime:
+		return a.Time.Compare(b.Time)
+	case TypeMoney:
+		return a.Money.Cmp(b.Money)
+	case TypeBool:
+		if a.Bool == b.Bool {
+			return 0
+		}
+		if !a.Bool {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,82 @@
+package filter
+
+import (
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// FieldType is the type of a filterable field
+type FieldType int
+
+const (
+	TypeString FieldType = iota
+	TypeNumber
+	TypeTime
+	TypeBool
+	TypeMoney
+)
+
+// Field describes a filterable book field
+type Field struct {
+	Type FieldType
+	Get  func(book models.Book) Value
+}
+
+// Fields are the book fields that can be used in filter expressions, keyed
+// by their JSON name. in_stock is derived from the copies available, so a
+// book whose every copy is reserved isn't in stock.
+var Fields = map[string]Field{
+	"id":           stringField(func(b models.Book) string { return b.ID }),
+	"title":        stringField(func(b models.Book) string { return b.Title }),
+	"author":       stringField(func(b models.Book) string { return b.Author }),
+	"isbn":         stringField(func(b models.Book) string { return b.ISBN }),
+	"price":        moneyField(func(b models.Book) money.Money { return b.Price }),
+	"quantity":     numberField(func(b models.Book) float64 { return float64(b.Quantity) }),
+	"rating":       numberField(func(b models.Book) float64 { return b.Rating.Average }),
+	"published_at": timeField(func(b models.Book) time.Time { return b.PublishedAt }),
+	"created_at":   timeField(func(b models.Book) time.Time { return b.CreatedAt }),
+	"updated_at":   timeField(func(b models.Book) time.Time { return b.UpdatedAt }),
+	"in_stock": {
+		Type: TypeBool,
+		Get:  func(b models.Book) Value { return Value{Type: TypeBool, Bool: b.Available() > 0} },
+	},
+}
+
+// allowedOps lists the operators that make sense for each field type
+var allowedOps = map[FieldType]map[Op]bool{
+	TypeString: {OpEq: true, OpNe: true, OpIn: true, OpContains: true},
+	TypeNumber: {OpEq: true, OpNe: true, OpLt: true, OpLe: true, OpGt: true, OpGe: true, OpIn: true},
+	TypeTime:   {OpEq: true, OpNe: true, OpLt: true, OpLe: true, OpGt: true, OpGe: true},
+	TypeBool:   {OpEq: true, OpNe: true},
+	TypeMoney:  {OpEq: true, OpNe: true, OpLt: true, OpLe: true, OpGt: true, OpGe: true, OpIn: true},
+}
+
+func stringField(get func(models.Book) string) Field {
+	return Field{
+		Type: TypeString,
+		Get:  func(b models.Book) Value { return Value{Type: TypeString, String: get(b)} },
+	}
+}
+
+func numberField(get func(models.Book) float64) Field {
+	return Field{
+		Type: TypeNumber,
+		Get:  func(b models.Book) Value { return Value{Type: TypeNumber, Number: get(b)} },
+	}
+}
+
+func moneyField(get func(models.Book) money.Money) Field {
+	return Field{
+		Type: TypeMoney,
+		Get:  func(b models.Book) Value { return Value{Type: TypeMoney, Money: get(b)} },
+	}
+}
+
+func timeField(get func(models.Book) time.Time) Field {
+	return Field{
+		Type: TypeTime,
+		Get:  func(b models.Book) Value { return Value{Type: TypeTime, Time: get(b)} },
+	}
+}
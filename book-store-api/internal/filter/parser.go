@@ -0,0 +1,249 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// maxDepth limits nesting so hostile input can't exhaust the stack
+const maxDepth = 32
+
+// Parse parses a filter expression such as
+//
+//	in_stock eq true and (author contains 'Gamma' or author contains 'Fowler') and published_at gt '2000-01-01'
+//
+// Grammar, lowest precedence first:
+//
+//	expr       = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | primary
+//	primary    = "(" expr ")" | comparison
+//	comparison = field op value | field "in" "(" value { "," value } ")"
+//
+// Operators are eq, ne, lt, le, gt, ge, in and contains. Strings are quoted
+// with ' or ", times are quoted dates (2006-01-02) or RFC 3339 timestamps,
+// prices are exact decimals in the default currency or quoted with a
+// currency ('12.50 EUR'), and booleans are true or false. Keywords are
+// case-insensitive.
+func Parse(input string) (Expr, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	expr, err := p.parseOr(0)
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, &SyntaxError{Pos: tok.pos, Msg: fmt.Sprintf("unexpected %q", tok.text)}
+	}
+
+	return expr, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// keyword reports whether the next token is the given keyword, consuming it if so
+func (p *parser) keyword(word string) bool {
+	tok := p.peek()
+	if tok.kind == tokIdent && strings.EqualFold(tok.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr(depth int) (Expr, error) {
+	left, err := p.parseAnd(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("or") {
+		right, err := p.parseAnd(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = Or{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd(depth int) (Expr, error) {
+	left, err := p.parseUnary(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("and") {
+		right, err := p.parseUnary(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = And{Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary(depth int) (Expr, error) {
+	if depth > maxDepth {
+		return nil, &SyntaxError{Pos: p.peek().pos, Msg: "expression is nested too deeply"}
+	}
+	if p.keyword("not") {
+		expr, err := p.parseUnary(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return Not{Expr: expr}, nil
+	}
+	return p.parsePrimary(depth)
+}
+
+func (p *parser) parsePrimary(depth int) (Expr, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+		expr, err := p.parseOr(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "expected )"}
+		}
+		return expr, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (Expr, error) {
+	fieldTok := p.next()
+	if fieldTok.kind != tokIdent {
+		return nil, &SyntaxError{Pos: fieldTok.pos, Msg: "expected field name"}
+	}
+	name := strings.ToLower(fieldTok.text)
+	field, ok := Fields[name]
+	if !ok {
+		return nil, &SyntaxError{Pos: fieldTok.pos, Msg: fmt.Sprintf("unknown field %q", fieldTok.text)}
+	}
+
+	opTok := p.next()
+	if opTok.kind != tokIdent {
+		return nil, &SyntaxError{Pos: opTok.pos, Msg: "expected operator"}
+	}
+	op := Op(strings.ToLower(opTok.text))
+	if !allowedOps[field.Type][op] {
+		return nil, &SyntaxError{Pos: opTok.pos, Msg: fmt.Sprintf("operator %q is not supported for field %q", opTok.text, name)}
+	}
+
+	cmp := Comparison{Field: name, Op: op}
+
+	if op == OpIn {
+		if tok := p.next(); tok.kind != tokLParen {
+			return nil, &SyntaxError{Pos: tok.pos, Msg: "expected ( after in"}
+		}
+		for {
+			value, err := p.parseValue(field.Type)
+			if err != nil {
+				return nil, err
+			}
+			cmp.Values = append(cmp.Values, value)
+
+			tok := p.next()
+			if tok.kind == tokRParen {
+				break
+			}
+			if tok.kind != tokComma {
+				return nil, &SyntaxError{Pos: tok.pos, Msg: "expected , or )"}
+			}
+		}
+		return cmp, nil
+	}
+
+	value, err := p.parseValue(field.Type)
+	if err != nil {
+		return nil, err
+	}
+	cmp.Values = []Value{value}
+
+	return cmp, nil
+}
+
+func (p *parser) parseValue(typ FieldType) (Value, error) {
+	tok := p.next()
+
+	switch typ {
+	case TypeString:
+		if tok.kind == tokString {
+			return Value{Type: TypeString, String: tok.text}, nil
+		}
+		return Value{}, &SyntaxError{Pos: tok.pos, Msg: "expected quoted string"}
+
+	case TypeNumber:
+		if tok.kind == tokNumber {
+			n, err := strconv.ParseFloat(tok.text, 64)
+			if err == nil {
+				return Value{Type: TypeNumber, Number: n}, nil
+			}
+		}
+		return Value{}, &SyntaxError{Pos: tok.pos, Msg: "expected number"}
+
+	case TypeMoney:
+		// A bare number is in the default currency; a string may name one, e.g. '12.50 EUR'
+		var m money.Money
+		var err error
+		switch tok.kind {
+		case tokNumber:
+			m, err = money.Parse(tok.text, money.DefaultCurrency)
+		case tokString:
+			m, err = money.ParseString(tok.text)
+		default:
+			err = money.ErrInvalidAmount
+		}
+		if err != nil {
+			return Value{}, &SyntaxError{Pos: tok.pos, Msg: "expected price"}
+		}
+		return Value{Type: TypeMoney, Money: m}, nil
+
+	case TypeTime:
+		if tok.kind == tokString {
+			for _, layout := range []string{time.RFC3339, "2006-01-02"} {
+				if t, err := time.Parse(layout, tok.text); err == nil {
+					return Value{Type: TypeTime, Time: t}, nil
+				}
+			}
+		}
+		return Value{}, &SyntaxError{Pos: tok.pos, Msg: "expected quoted date"}
+
+	case TypeBool:
+		if tok.kind == tokIdent {
+			switch strings.ToLower(tok.text) {
+			case "true":
+				return Value{Type: TypeBool, Bool: true}, nil
+			case "false":
+				return Value{Type: TypeBool, Bool: false}, nil
+			}
+		}
+		return Value{}, &SyntaxError{Pos: tok.pos, Msg: "expected true or false"}
+	}
+
+	return Value{}, &SyntaxError{Pos: tok.pos, Msg: "unexpected value"}
+}
@@ -0,0 +1,214 @@
+package filter_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/filter"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+var books = []models.Book{
+	{
+		ID:          "1",
+		Title:       "Design Patterns",
+		Author:      "Erich Gamma, Richard Helm",
+		ISBN:        "9780201633610",
+		Price:       money.New(4499, "USD"),
+		Quantity:    12,
+		PublishedAt: time.Date(1994, 11, 10, 0, 0, 0, 0, time.UTC),
+		Rating:      models.Rating{Average: 4.5, Count: 10},
+	},
+	{
+		ID:          "2",
+		Title:       "Refactoring",
+		Author:      "Martin Fowler",
+		ISBN:        "9780201485677",
+		Price:       money.New(3999, "USD"),
+		PublishedAt: time.Date(1999, 7, 8, 0, 0, 0, 0, time.UTC),
+		Rating:      models.Rating{Average: 4, Count: 3},
+	},
+	{
+		ID:          "3",
+		Title:       "Clean Code",
+		Author:      "Robert C. Martin",
+		ISBN:        "9780132350884",
+		Price:       money.New(3499, "EUR"),
+		Quantity:    5,
+		PublishedAt: time.Date(2008, 8, 1, 0, 0, 0, 0, time.UTC),
+	},
+}
+
+// matching returns the IDs of the books the expression matches
+func matching(expr filter.Expr) []string {
+	var ids []string
+	for _, book := range books {
+		if expr.Matches(book) {
+			ids = append(ids, book.ID)
+		}
+	}
+	return ids
+}
+
+func TestParse_Matches(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{`title eq 'refactoring'`, []string{"2"}},
+		{`title ne "Refactoring"`, []string{"1", "3"}},
+		{`author contains 'MARTIN'`, []string{"2", "3"}},
+		{`title in ('Clean Code', 'Design Patterns')`, []string{"1", "3"}},
+		{`quantity gt 0`, []string{"1", "3"}},
+		{`quantity le 5`, []string{"2", "3"}},
+		{`rating ge 4.5`, []string{"1"}},
+		{`in_stock eq false`, []string{"2"}},
+		{`published_at lt '2000-01-01'`, []string{"1", "2"}},
+		{`published_at ge '2008-08-01T00:00:00Z'`, []string{"3"}},
+		{`price lt 40`, []string{"2"}},
+		{`price eq '34.99 EUR'`, []string{"3"}},
+		{`price in (39.99, 44.99)`, []string{"1", "2"}},
+		{`price ne 10`, []string{"1", "2", "3"}}, // Prices in another currency only match ne
+		{`price gt 0`, []string{"1", "2"}},
+		{`not in_stock eq true`, []string{"2"}},
+		{`NOT (quantity gt 0 OR rating gt 0)`, nil},
+		{`in_stock eq true and author contains 'Gamma' or author contains 'Fowler'`, []string{"1", "2"}},
+		{`in_stock eq true and (author contains 'Gamma' or author contains 'Fowler')`, []string{"1"}},
+		{`title eq 'Design Patterns' or title eq 'Refactoring' and quantity gt 0`, []string{"1"}},
+		{`title eq 'It\'s' or isbn eq "9780132350884"`, []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := filter.Parse(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := matching(expr); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse_InStockCountsReservations(t *testing.T) {
+	expr, err := filter.Parse(`in_stock eq true`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		quantity int
+		reserved int
+		want     bool
+	}{
+		{"unreserved copies", 3, 0, true},
+		{"some copies reserved", 3, 2, true},
+		{"every copy reserved", 3, 3, false},
+		{"no copies", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			book := models.Book{Quantity: tt.quantity, Reserved: tt.reserved}
+			if got := expr.Matches(book); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParse_Precedence(t *testing.T) {
+	expr, err := filter.Parse(`quantity eq 1 or quantity eq 2 and quantity eq 3`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	or, ok := expr.(filter.Or)
+	if !ok {
+		t.Fatalf("Expected or at the root, got %T", expr)
+	}
+	if _, ok := or.Right.(filter.And); !ok {
+		t.Errorf("Expected and to bind tighter than or, got %T on the right", or.Right)
+	}
+
+	// A list comparison keeps every value, typed for its field
+	expr, err = filter.Parse(`price in (10, '12.50 EUR')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := filter.Comparison{Field: "price", Op: filter.OpIn, Values: []filter.Value{
+		{Type: filter.TypeMoney, Money: money.New(1000, "USD")},
+		{Type: filter.TypeMoney, Money: money.New(1250, "EUR")},
+	}}
+	if !reflect.DeepEqual(expr, want) {
+		t.Errorf("Expected %+v, got %+v", want, expr)
+	}
+}
+
+func TestParse_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{``, 0, "expected field name"},
+		{`title eq 'open`, 9, "unterminated string"},
+		{`title eq 'a' $`, 13, "unexpected character"},
+		{`pages gt 100`, 0, `unknown field "pages"`},
+		{`title`, 5, "expected operator"},
+		{`title gt 'a'`, 6, `operator "gt" is not supported`},
+		{`in_stock contains 'x'`, 9, `operator "contains" is not supported`},
+		{`title eq 42`, 9, "expected quoted string"},
+		{`quantity eq 'many'`, 12, "expected number"},
+		{`quantity eq 1.2.3`, 12, "expected number"},
+		{`price lt 'cheap'`, 9, "expected price"},
+		{`published_at gt '01/02/2000'`, 16, "expected quoted date"},
+		{`in_stock eq yes`, 12, "expected true or false"},
+		{`title in 'a'`, 9, "expected ( after in"},
+		{`title in ('a' 'b')`, 14, "expected , or )"},
+		{`(title eq 'a'`, 13, "expected )"},
+		{`title eq 'a' title eq 'b'`, 13, `unexpected "title"`},
+		{`title eq 'a' and`, 16, "expected field name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := filter.Parse(tt.expr)
+			var syntaxErr *filter.SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("Expected a syntax error, got %v", err)
+			}
+			if syntaxErr.Pos != tt.pos || !strings.Contains(syntaxErr.Msg, tt.msg) {
+				t.Errorf("Expected %q at %d, got %q at %d", tt.msg, tt.pos, syntaxErr.Msg, syntaxErr.Pos)
+			}
+		})
+	}
+}
+
+func TestParse_DepthLimit(t *testing.T) {
+	nested := func(depth int) string {
+		return strings.Repeat("(", depth) + "quantity gt 0" + strings.Repeat(")", depth)
+	}
+
+	if _, err := filter.Parse(nested(30)); err != nil {
+		t.Errorf("Expected 30 levels of parentheses to parse, got %v", err)
+	}
+
+	tests := []string{
+		nested(1000),
+		strings.Repeat("not ", 1000) + "quantity gt 0",
+	}
+	for _, expr := range tests {
+		_, err := filter.Parse(expr)
+		var syntaxErr *filter.SyntaxError
+		if !errors.As(err, &syntaxErr) || !strings.Contains(syntaxErr.Msg, "nested too deeply") {
+			t.Errorf("Expected a nesting error, got %v", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// GetAuthors handles GET /authors endpoint
+func (h *Handler) GetAuthors(c *gin.Context) {
+	authors, err := h.store.ListAuthors()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list authors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":   len(authors),
+		"authors": authors,
+	})
+}
+
+// GetAuthor handles GET /authors/:id endpoint
+func (h *Handler) GetAuthor(c *gin.Context) {
+	author, err := h.store.GetAuthor(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+// GetAuthorBooks handles GET /authors/:id/books endpoint
+func (h *Handler) GetAuthorBooks(c *gin.Context) {
+	currency, err := h.displayCurrency(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+
+	books, err := h.store.GetAuthorBooks(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	}
+
+	for i := range books {
+		books[i] = h.localize(books[i], currency)
+	}
+	if err := h.setSalePrices(books); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply promotions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total": len(books),
+		"books": books,
+	})
+}
+
+// CreateAuthor handles POST /authors endpoint
+func (h *Handler) CreateAuthor(c *gin.Context) {
+	var req models.AuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author data"})
+		return
+	}
+
+	author, err := h.store.CreateAuthor(models.Author{Name: req.Name, Bio: req.Bio})
+	if errors.Is(err, database.ErrDuplicateAuthor) {
+		c.JSON(http.StatusConflict, gin.H{"error": "An author with this name already exists"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create author"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, author)
+}
+
+// UpdateAuthor handles PUT /authors/:id endpoint
+func (h *Handler) UpdateAuthor(c *gin.Context) {
+	var req models.AuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid author data"})
+		return
+	}
+
+	author, err := h.store.UpdateAuthor(c.Param("id"), models.Author{Name: req.Name, Bio: req.Bio})
+	switch {
+	case errors.Is(err, database.ErrAuthorNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	case errors.Is(err, database.ErrDuplicateAuthor):
+		c.JSON(http.StatusConflict, gin.H{"error": "An author with this name already exists"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update author"})
+		return
+	}
+
+	c.JSON(http.StatusOK, author)
+}
+
+// DeleteAuthor handles DELETE /authors/:id endpoint. Authors still credited
+// on books can't be deleted.
+func (h *Handler) DeleteAuthor(c *gin.Context) {
+	err := h.store.DeleteAuthor(c.Param("id"))
+	switch {
+	case errors.Is(err, database.ErrAuthorNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Author not found"})
+		return
+	case errors.Is(err, database.ErrAuthorInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Author is credited on books"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete author"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+	"github.com/google/uuid"
+)
+
+// cartSessionHeader carries the ID of an anonymous customer's cart
+const cartSessionHeader = "X-Cart-Session"
+
+// GetCart handles GET /cart endpoint
+func (h *Handler) GetCart(c *gin.Context) {
+	cart, err := h.store.GetCart(cartOwner(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// AddCartItem handles POST /cart/items endpoint
+func (h *Handler) AddCartItem(c *gin.Context) {
+	var req models.AddCartItemRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item"})
+		return
+	}
+
+	cart, err := h.store.AddCartItem(cartOwner(c), req.BookID, req.Quantity)
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// UpdateCartItem handles PUT /cart/items/:bookId endpoint
+func (h *Handler) UpdateCartItem(c *gin.Context) {
+	var req models.UpdateCartItemRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cart item"})
+		return
+	}
+
+	cart, err := h.store.UpdateCartItem(cartOwner(c), c.Param("bookId"), req.Quantity)
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// RemoveCartItem handles DELETE /cart/items/:bookId endpoint
+func (h *Handler) RemoveCartItem(c *gin.Context) {
+	cart, err := h.store.RemoveCartItem(cartOwner(c), c.Param("bookId"))
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, cart)
+}
+
+// ClearCart handles DELETE /cart endpoint
+func (h *Handler) ClearCart(c *gin.Context) {
+	if err := h.store.ClearCart(cartOwner(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Checkout handles POST /cart/checkout endpoint
+func (h *Handler) Checkout(c *gin.Context) {
+	var req models.CheckoutRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid checkout data"})
+		return
+	}
+
+	order, err := h.store.Checkout(cartOwner(c), models.Order{
+		ShippingAddress: req.ShippingAddress,
+		BillingAddress:  req.BillingAddress,
+	}, DefaultReservationTTL)
+	if err != nil {
+		cartError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, order)
+}
+
+// cartOwner identifies the customer: the signed-in user from the gateway's
+// X-User-Id header, or else the anonymous session in X-Cart-Session. A new
+// session is started, and returned in the response header, if there is neither.
+func cartOwner(c *gin.Context) string {
+	if userID := c.GetHeader("X-User-Id"); userID != "" {
+		return "user:" + userID
+	}
+
+	session := c.GetHeader(cartSessionHeader)
+	if session == "" {
+		session = uuid.New().String()
+	}
+	c.Header(cartSessionHeader, session)
+
+	return "session:" + session
+}
+
+// cartError maps a store error from a cart operation to a response
+func cartError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, database.ErrBookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrCartItemNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book is not in the cart"})
+	case errors.Is(err, database.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, money.ErrCurrencyMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": "All items in a cart must be priced in the same currency"})
+	case errors.Is(err, database.ErrCartEmpty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart"})
+	}
+}
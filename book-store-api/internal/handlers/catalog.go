@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/godwin/book-store-api/internal/catalog"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/isbn"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// exportPageSize is the number of books an export reads from the store at a time
+const exportPageSize = 500
+
+// ImportBooks handles POST /books/import endpoint. The body is a CSV or
+// NDJSON file or an ONIX message, by its Content-Type or the format
+// parameter, and is read a row at a time. Each row creates a book or updates
+// the book with its ISBN; rows that fail are reported and the rest are still
+// imported, and so is data a row has that books can't hold. With
+// dry_run=true the rows are only checked.
+func (h *Handler) ImportBooks(c *gin.Context) {
+	format := c.Query("format")
+	if format == "" {
+		format = catalog.FormatOf(c.ContentType())
+	}
+	if format == "" {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "Send text/csv, application/x-ndjson or application/xml (ONIX), or set format to csv, ndjson or onix"})
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+			return
+		}
+	}
+
+	reader, err := catalog.NewReader(format, c.Request.Body)
+	if errors.Is(err, catalog.ErrUnknownFormat) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, ndjson or onix"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	actor := requestActor(c, "import")
+
+	result := models.ImportResult{DryRun: dryRun, Errors: []models.ImportError{}}
+	// A dry run saves nothing, so a repeated ISBN would be created twice
+	planned := make(map[string]bool)
+
+	for {
+		row, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *catalog.RowError
+		if errors.As(err, &rowErr) {
+			result.Rows++
+			result.AddError(models.ImportError{Line: rowErr.Line, Record: row.Record, ISBN: row.Book.ISBN, Error: rowErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error(), "result": result})
+			return
+		}
+		if err := c.Request.Context().Err(); err != nil {
+			return
+		}
+
+		result.Rows++
+		if err := checkImportRow(row.Book); err != nil {
+			result.AddError(models.ImportError{Line: row.Line, Record: row.Record, ISBN: row.Book.ISBN, Error: err.Error()})
+			continue
+		}
+
+		action, err := h.store.ImportBook(row, actor, dryRun)
+		if err != nil {
+			result.AddError(models.ImportError{Line: row.Line, Record: row.Record, ISBN: row.Book.ISBN, Error: importErrorMessage(err)})
+			continue
+		}
+		for _, note := range row.Unmapped {
+			result.AddUnmapped(models.ImportError{Line: row.Line, Record: row.Record, ISBN: row.Book.ISBN, Error: note})
+		}
+
+		if dryRun {
+			code, _ := isbn.Normalize(row.Book.ISBN)
+			if planned[code] {
+				action = models.ImportUpdated
+			}
+			planned[code] = true
+		}
+		if action == models.ImportCreated {
+			result.Created++
+		} else {
+			result.Updated++
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExportBooks handles GET /books/export endpoint. It streams the books
+// matching the same filters as GET /books, in the sort order asked for, as
+// CSV (the default) or NDJSON by the format parameter.
+func (h *Handler) ExportBooks(c *gin.Context) {
+	format := c.DefaultQuery("format", catalog.FormatCSV)
+	if format != catalog.FormatCSV && format != catalog.FormatNDJSON {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or ndjson"})
+		return
+	}
+
+	filter, err := h.parseBookFilter(c)
+	if errors.Is(err, database.ErrCategoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sortKeys, err := models.ParseSort(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort parameter"})
+		return
+	}
+
+	query := models.BookQuery{
+		Filter: filter,
+		Sort:   sortKeys,
+		Page:   models.Page{Limit: exportPageSize},
+	}
+	page, err := h.store.ListBooks(c.Request.Context(), query)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve books"})
+		return
+	}
+
+	c.Header("Content-Type", catalog.ContentType(format))
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="books.%s"`, format))
+	c.Status(http.StatusOK)
+
+	writer, err := catalog.NewWriter(format, c.Writer)
+	if err != nil {
+		log.Printf("Exporting books: %v", err)
+		return
+	}
+
+	// Pages follow on from the last book of the one before, so books saved
+	// during the export can't shift the rest along
+	for {
+		for _, book := range page.Items {
+			if err := writer.Write(book); err != nil {
+				log.Printf("Exporting books: %v", err)
+				return
+			}
+		}
+		if err := writer.Flush(); err != nil {
+			log.Printf("Exporting books: %v", err)
+			return
+		}
+		c.Writer.Flush()
+
+		if !page.HasNext || len(page.Items) == 0 {
+			return
+		}
+		next := models.NewCursor(page.Items[len(page.Items)-1], sortKeys, false)
+		query.Cursor = &next
+
+		if page, err = h.store.ListBooks(c.Request.Context(), query); err != nil {
+			// The response has started, so all that can be done is to cut it short
+			log.Printf("Exporting books: %v", err)
+			return
+		}
+	}
+}
+
+// checkImportRow applies the checks POST /books makes to a book
+func checkImportRow(book models.Book) error {
+	if err := binding.Validator.ValidateStruct(&book); err != nil {
+		var invalid validator.ValidationErrors
+		if errors.As(err, &invalid) {
+			return fmt.Errorf("invalid %s (%s)", invalid[0].Field(), invalid[0].Tag())
+		}
+		return err
+	}
+	if !validPrice(book.Price) {
+		return errors.New("price is required and must not be negative")
+	}
+	if !validPrices(book) {
+		return errors.New("prices must be non-negative and in distinct currencies")
+	}
+	return nil
+}
+
+// importErrorMessage describes why the store didn't save an imported row
+func importErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, isbn.ErrInvalid):
+		return "invalid ISBN"
+	case errors.Is(err, database.ErrInsufficientStock):
+		return "quantity is below the number of reserved copies"
+	case errors.Is(err, database.ErrInvalidContributors), errors.Is(err, database.ErrInvalidCategories):
+		return err.Error()
+	}
+	return "failed to save book"
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/bisac"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// GetCategories handles GET /categories endpoint, returning the whole tree
+func (h *Handler) GetCategories(c *gin.Context) {
+	categories, err := h.store.ListCategories()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"categories": categories,
+	})
+}
+
+// GetCategory handles GET /categories/:slug endpoint. The category comes with
+// its ancestors, for breadcrumbs, and its subcategories.
+func (h *Handler) GetCategory(c *gin.Context) {
+	category, err := h.store.GetCategory(c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// GetCategoryBooks handles GET /categories/:slug/books endpoint. It lists the
+// books in the category and its subcategories, and takes the same filtering,
+// sorting and paging parameters as GET /books.
+func (h *Handler) GetCategoryBooks(c *gin.Context) {
+	h.GetBooks(c)
+}
+
+// CreateCategory handles POST /categories endpoint
+func (h *Handler) CreateCategory(c *gin.Context) {
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category data"})
+		return
+	}
+
+	category, err := h.store.CreateCategory(categoryFromRequest(req))
+	if err != nil {
+		categoryError(c, err, "Failed to create category")
+		return
+	}
+
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory handles PUT /categories/:slug endpoint
+func (h *Handler) UpdateCategory(c *gin.Context) {
+	var req models.CategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid category data"})
+		return
+	}
+
+	category, err := h.store.UpdateCategory(c.Param("slug"), categoryFromRequest(req))
+	if err != nil {
+		categoryError(c, err, "Failed to update category")
+		return
+	}
+
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory handles DELETE /categories/:slug endpoint. Only categories
+// without subcategories or books can be deleted.
+func (h *Handler) DeleteCategory(c *gin.Context) {
+	if err := h.store.DeleteCategory(c.Param("slug")); err != nil {
+		categoryError(c, err, "Failed to delete category")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ImportCategories handles POST /categories/import endpoint. The body is a
+// CSV file of BISAC codes and headings.
+func (h *Handler) ImportCategories(c *gin.Context) {
+	entries, err := bisac.Parse(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	result, err := h.store.ImportCategories(entries)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func categoryFromRequest(req models.CategoryRequest) models.Category {
+	return models.Category{
+		Name:     req.Name,
+		Slug:     req.Slug,
+		ParentID: req.ParentID,
+		Position: req.Position,
+		Code:     req.Code,
+	}
+}
+
+// categoryError writes the response for an error from a category change
+func categoryError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, database.ErrCategoryNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+	case errors.Is(err, database.ErrInvalidCategory):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrDuplicateCategory):
+		c.JSON(http.StatusConflict, gin.H{"error": "A category with this slug or code already exists"})
+	case errors.Is(err, database.ErrCategoryInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": "Category has subcategories or books"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/covers"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/storage"
+	"github.com/google/uuid"
+)
+
+// coverKeyPrefix is the blob storage prefix of cover images, which are
+// served under the same path
+const coverKeyPrefix = "covers"
+
+// multipartOverhead is allowed on top of covers.MaxSize for the rest of an
+// upload's multipart body
+const multipartOverhead = 64 << 10
+
+// UploadCover handles POST /books/:id/cover endpoint. The image is sent as
+// the "cover" field of a multipart form; its type is sniffed from the data.
+// The cover and its thumbnails replace any previous cover.
+func (h *Handler) UploadCover(c *gin.Context) {
+	id := c.Param("id")
+	if _, err := h.store.GetBookByID(id); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, covers.MaxSize+multipartOverhead)
+	file, header, err := c.Request.FormFile("cover")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Cover image must be at most 10 MB"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send the image as the cover field of a multipart form"})
+		return
+	}
+	defer file.Close()
+	if header.Size > covers.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "Cover image must be at most 10 MB"})
+		return
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, covers.MaxSize+1))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read cover image"})
+		return
+	}
+
+	image, err := covers.Process(data)
+	switch {
+	case errors.Is(err, covers.ErrUnsupportedType):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, covers.ErrTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, covers.ErrInvalidImage):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process cover image"})
+		return
+	}
+
+	cover, err := h.storeCover(c.Request.Context(), id, image)
+	if err != nil {
+		log.Printf("Storing cover of book %s: %v", id, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to store cover image"})
+		return
+	}
+
+	previous, err := h.store.SetBookCover(id, cover)
+	if err != nil {
+		h.deleteCover(cover)
+		if errors.Is(err, database.ErrBookNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save cover"})
+		return
+	}
+	h.deleteCover(previous)
+
+	c.JSON(http.StatusCreated, cover)
+}
+
+// DeleteCover handles DELETE /books/:id/cover endpoint
+func (h *Handler) DeleteCover(c *gin.Context) {
+	previous, err := h.store.SetBookCover(c.Param("id"), nil)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+	if previous == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book has no cover"})
+		return
+	}
+	h.deleteCover(previous)
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetCoverImage handles GET /covers/*key endpoint, serving cover images and
+// thumbnails from blob storage. A new upload gets new keys, so images can be
+// cached for good.
+func (h *Handler) GetCoverImage(c *gin.Context) {
+	reader, object, err := h.covers.Get(c.Request.Context(), coverKeyPrefix+c.Param("key"))
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("Reading cover image %s: %v", c.Param("key"), err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read image"})
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, object.Size, object.ContentType, reader, map[string]string{
+		"Cache-Control":          "public, max-age=31536000, immutable",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// storeCover puts a processed cover and its thumbnails in blob storage under
+// new keys. If any of them fails, those already stored are deleted.
+func (h *Handler) storeCover(ctx context.Context, bookID string, image covers.Image) (*models.Cover, error) {
+	prefix := coverKeyPrefix + "/" + bookID + "/" + uuid.New().String() + "/"
+	cover := &models.Cover{
+		Key:         prefix + "original" + image.Extension,
+		ContentType: image.ContentType,
+		Size:        int64(len(image.Data)),
+		Width:       image.Width,
+		Height:      image.Height,
+		UploadedAt:  time.Now(),
+	}
+	cover.URL = h.coverURL(cover.Key)
+
+	if err := h.covers.Put(ctx, cover.Key, image.Data, image.ContentType); err != nil {
+		return nil, err
+	}
+	for _, thumbnail := range image.Thumbnails {
+		key := prefix + thumbnail.Name + ".jpg"
+		if err := h.covers.Put(ctx, key, thumbnail.Data, "image/jpeg"); err != nil {
+			h.deleteCover(cover)
+			return nil, err
+		}
+		cover.Thumbnails = append(cover.Thumbnails, models.Thumbnail{
+			Name:   thumbnail.Name,
+			URL:    h.coverURL(key),
+			Key:    key,
+			Width:  thumbnail.Width,
+			Height: thumbnail.Height,
+		})
+	}
+
+	return cover, nil
+}
+
+// deleteCover removes a cover's images from blob storage. Failures only
+// leave unused images behind, so they are logged rather than returned.
+func (h *Handler) deleteCover(cover *models.Cover) {
+	if cover == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, key := range cover.Keys() {
+		if err := h.covers.Delete(ctx, key); err != nil {
+			log.Printf("Deleting cover image %s: %v", key, err)
+		}
+	}
+}
+
+// coverURL returns the URL a cover image is shown at
+func (h *Handler) coverURL(key string) string {
+	return h.coverBaseURL + "/" + key
+}
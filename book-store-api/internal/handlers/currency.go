@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// errUnsupportedCurrency is returned for a currency= parameter the rate table can't price in
+var errUnsupportedCurrency = errors.New("unsupported currency")
+
+// GetExchangeRates handles GET /exchange-rates endpoint
+func (h *Handler) GetExchangeRates(c *gin.Context) {
+	c.JSON(http.StatusOK, h.pricer.Rates())
+}
+
+// UpdateExchangeRates handles PUT /exchange-rates endpoint. The body replaces
+// the whole rate table and uses the same format as the rates file.
+func (h *Handler) UpdateExchangeRates(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can change exchange rates"})
+		return
+	}
+
+	rates, err := money.ParseRates(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid exchange rates: " + err.Error()})
+		return
+	}
+	h.pricer.SetRates(rates)
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// displayCurrency returns the currency the caller wants prices in, from the
+// currency query parameter or else the Accept-Currency header. The parameter
+// must name a supported currency; the header may list several, and the first
+// supported one is used. An empty result means each book's own price.
+func (h *Handler) displayCurrency(c *gin.Context) (string, error) {
+	c.Header("Vary", "Accept-Currency")
+
+	if param := c.Query("currency"); param != "" {
+		code, err := money.NormalizeCurrency(param)
+		if err != nil || !h.pricer.Supports(code) {
+			return "", errUnsupportedCurrency
+		}
+		return code, nil
+	}
+
+	// e.g. "EUR, GBP;q=0.8"; preference order is taken as written
+	for _, part := range strings.Split(c.GetHeader("Accept-Currency"), ",") {
+		part, _, _ = strings.Cut(part, ";")
+		if code, err := money.NormalizeCurrency(part); err == nil && h.pricer.Supports(code) {
+			return code, nil
+		}
+	}
+
+	return "", nil
+}
+
+// localize prices a book in the display currency, if there is one
+func (h *Handler) localize(book models.Book, currency string) models.Book {
+	if currency == "" {
+		return book
+	}
+	return h.pricer.Localize(book, currency)
+}
+
+// validPrices reports whether a book's fixed prices are valid: each in a
+// different currency from the others and from Price, and none negative
+func validPrices(book models.Book) bool {
+	seen := map[string]bool{book.Price.Currency: true}
+	for _, price := range book.Prices {
+		if !validPrice(price) || seen[price.Currency] {
+			return false
+		}
+		seen[price.Currency] = true
+	}
+	return true
+}
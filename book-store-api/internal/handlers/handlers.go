@@ -1,25 +1,46 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/cursor"
 	"github.com/godwin/book-store-api/internal/database"
+	bookfilter "github.com/godwin/book-store-api/internal/filter"
+	"github.com/godwin/book-store-api/internal/isbn"
 	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+	"github.com/godwin/book-store-api/internal/pricing"
+	"github.com/godwin/book-store-api/internal/search"
+	"github.com/godwin/book-store-api/internal/storage"
 )
 
 // Handler holds dependencies for API handlers
 type Handler struct {
-	store database.Store
+	store        database.Store
+	cursors      *cursor.Codec
+	pricer       *pricing.Pricer
+	covers       storage.BlobStore
+	coverBaseURL string
 }
 
-// NewHandler returns a new instance of Handler
-func NewHandler(store database.Store) *Handler {
+// NewHandler returns a new instance of Handler. cursorSecret signs pagination
+// cursors; if empty a random secret is used. pricer prices books in the
+// currency the caller asks for. Cover images are kept in covers and linked
+// from coverBaseURL, or served by the API itself if it is empty.
+func NewHandler(store database.Store, cursorSecret []byte, pricer *pricing.Pricer, covers storage.BlobStore, coverBaseURL string) *Handler {
 	return &Handler{
-		store: store,
+		store:        store,
+		cursors:      cursor.NewCodec(cursorSecret),
+		pricer:       pricer,
+		covers:       covers,
+		coverBaseURL: strings.TrimSuffix(coverBaseURL, "/"),
 	}
 }
 
@@ -32,22 +53,18 @@ func (h *Handler) GetStatus(c *gin.Context) {
 	})
 }
 
-// GetBooks handles GET /books endpoint with optional pagination and filtering
+// GetBooks handles GET /books endpoint with optional pagination, filtering and
+// sorting. Pages can be requested by limit and offset, or by the opaque cursor
+// returned as next_cursor/prev_cursor and in the Link header. Passing
+// facets=true also returns facet counts for the filtered books.
 func (h *Handler) GetBooks(c *gin.Context) {
 	// Get pagination parameters
 	limitStr := c.DefaultQuery("limit", "10")
 	offsetStr := c.DefaultQuery("offset", "0")
 
-	// Get filtering parameters
-	titleFilter := c.Query("title")
-	authorFilter := c.Query("author")
-	minPriceStr := c.Query("min_price")
-	maxPriceStr := c.Query("max_price")
-
 	// Convert string parameters to integers with validation
 	limit := 10
 	offset := 0
-	var minPrice, maxPrice float64
 
 	if limitStr != "" {
 		if val, err := strconv.Atoi(limitStr); err == nil && val > 0 {
@@ -61,73 +78,289 @@ func (h *Handler) GetBooks(c *gin.Context) {
 		}
 	}
 
-	if minPriceStr != "" {
-		if val, err := strconv.ParseFloat(minPriceStr, 64); err == nil && val >= 0 {
-			minPrice = val
-		}
+	filter, err := h.parseBookFilter(c)
+	if errors.Is(err, database.ErrCategoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sortKeys, err := models.ParseSort(c.Query("sort"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid sort parameter"})
+		return
 	}
 
-	if maxPriceStr != "" {
-		if val, err := strconv.ParseFloat(maxPriceStr, 64); err == nil && val >= 0 {
-			maxPrice = val
+	query := models.BookQuery{
+		Filter: filter,
+		Sort:   sortKeys,
+		Page:   models.Page{Limit: limit, Offset: offset},
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		cur, err := h.cursors.Decode(token)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		if c.Query("sort") != "" && models.FormatSort(cur.Sort) != models.FormatSort(sortKeys) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Cursor does not match sort parameter"})
+			return
 		}
+		query.Cursor = &cur
+		query.Sort = cur.Sort
+		query.Page.Offset = 0
+		offset = 0
 	}
 
-	books, err := h.store.GetBooks()
+	result, err := h.store.ListBooks(c.Request.Context(), query)
 	if err != nil {
+		if errors.Is(err, models.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve books"})
 		return
 	}
 
-	// Apply filters
-	var filteredBooks []models.Book
-	for _, book := range books {
-		// Title filter
-		if titleFilter != "" && !strings.Contains(strings.ToLower(book.Title), strings.ToLower(titleFilter)) {
-			continue
+	if err := h.setSalePrices(result.Items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply promotions"})
+		return
+	}
+	if err := h.setLowestPrices(result.Items); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read price history"})
+		return
+	}
+
+	nextCursor, prevCursor, err := h.pageCursors(result, query.Sort)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create cursor"})
+		return
+	}
+	setLinkHeader(c, nextCursor, prevCursor)
+
+	response := gin.H{
+		"total":       result.Total,
+		"limit":       limit,
+		"offset":      offset,
+		"books":       result.Items,
+		"next_cursor": nullableString(nextCursor),
+		"prev_cursor": nullableString(prevCursor),
+	}
+
+	if c.Query("facets") == "true" {
+		facets, err := h.store.GetBookFacets(filter)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute facets"})
+			return
+		}
+		response["facets"] = facets
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// pageCursors returns tokens for the pages after and before a result, or
+// empty strings when there is no such page
+func (h *Handler) pageCursors(result models.BookList, sortKeys []models.SortKey) (string, string, error) {
+	if len(result.Items) == 0 {
+		return "", "", nil
+	}
+
+	var next, prev string
+	var err error
+
+	if result.HasNext {
+		last := result.Items[len(result.Items)-1]
+		next, err = h.cursors.Encode(models.NewCursor(last, sortKeys, false))
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if result.HasPrev {
+		first := result.Items[0]
+		prev, err = h.cursors.Encode(models.NewCursor(first, sortKeys, true))
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	return next, prev, nil
+}
+
+// setLinkHeader adds RFC 8288 next and prev links that repeat the current
+// query with the cursor replaced
+func setLinkHeader(c *gin.Context, nextCursor, prevCursor string) {
+	var links []string
+
+	link := func(token, rel string) string {
+		params := url.Values{}
+		for key, values := range c.Request.URL.Query() {
+			if key != "cursor" && key != "offset" {
+				params[key] = values
+			}
+		}
+		params.Set("cursor", token)
+		return fmt.Sprintf("<%s?%s>; rel=\"%s\"", c.Request.URL.Path, params.Encode(), rel)
+	}
+
+	if nextCursor != "" {
+		links = append(links, link(nextCursor, "next"))
+	}
+	if prevCursor != "" {
+		links = append(links, link(prevCursor, "prev"))
+	}
+
+	if len(links) > 0 {
+		c.Header("Link", strings.Join(links, ", "))
+	}
+}
+
+// nullableString returns nil for an empty string so it is encoded as JSON null
+func nullableString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// GetBookFacets handles GET /books/facets endpoint, returning counts by
+// author, decade, price range and availability for the current filters
+func (h *Handler) GetBookFacets(c *gin.Context) {
+	filter, err := h.parseBookFilter(c)
+	if errors.Is(err, database.ErrCategoryNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	facets, err := h.store.GetBookFacets(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute facets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, facets)
+}
+
+// parseBookFilter reads the title, author, author_id, category, min_price,
+// max_price and filter query parameters, and the display currency. The
+// category is the :slug path parameter if there is one, and matches books
+// in its subcategories too. Books are priced in the display
+// currency before filtering, and price bounds are exact decimals in it (USD
+// if there is none). Invalid or negative prices are ignored; an invalid
+// filter expression or currency is an error.
+func (h *Handler) parseBookFilter(c *gin.Context) (models.BookFilter, error) {
+	filter := models.BookFilter{
+		Title:    c.Query("title"),
+		Author:   c.Query("author"),
+		AuthorID: c.Query("author_id"),
+	}
+
+	slug := c.Param("slug")
+	if slug == "" {
+		slug = c.Query("category")
+	}
+	if slug != "" {
+		ids, err := h.store.CategoryDescendants(slug)
+		if err != nil {
+			return models.BookFilter{}, err
 		}
+		filter.Categories = ids
+	}
 
-		// Author filter
-		if authorFilter != "" && !strings.Contains(strings.ToLower(book.Author), strings.ToLower(authorFilter)) {
-			continue
+	currency, err := h.displayCurrency(c)
+	if err != nil {
+		return models.BookFilter{}, err
+	}
+	if currency != "" {
+		filter.Currency = currency
+		filter.Localize = func(book models.Book) models.Book {
+			return h.pricer.Localize(book, currency)
 		}
+	}
+	currency = filter.PriceCurrency()
+
+	if minPriceStr := c.Query("min_price"); minPriceStr != "" {
+		if val, err := money.Parse(minPriceStr, currency); err == nil && !val.IsNegative() {
+			filter.MinPrice = &val
+		}
+	}
 
-		// Price range filter
-		if minPriceStr != "" && book.Price < minPrice {
-			continue
+	if maxPriceStr := c.Query("max_price"); maxPriceStr != "" {
+		if val, err := money.Parse(maxPriceStr, currency); err == nil && !val.IsNegative() {
+			filter.MaxPrice = &val
 		}
+	}
 
-		if maxPriceStr != "" && book.Price < maxPrice {
-			continue
+	if expr := strings.TrimSpace(c.Query("filter")); expr != "" {
+		parsed, err := bookfilter.Parse(expr)
+		if err != nil {
+			return models.BookFilter{}, err
 		}
+		filter.Expr = parsed
+	}
 
-		filteredBooks = append(filteredBooks, book)
+	return filter, nil
+}
+
+// SearchBooks handles GET /books/search endpoint. The q parameter supports
+// plain words, "quoted phrases" and prefix* queries.
+func (h *Handler) SearchBooks(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Query parameter q is required"})
+		return
 	}
 
-	// Apply pagination
-	start := offset
-	end := offset + limit
+	limit := 10
+	offset := 0
 
-	if start > len(filteredBooks) {
-		start = len(filteredBooks)
+	if val, err := strconv.Atoi(c.DefaultQuery("limit", "10")); err == nil && val > 0 {
+		limit = val
 	}
-	if end > len(filteredBooks) {
-		end = len(filteredBooks)
+	if val, err := strconv.Atoi(c.DefaultQuery("offset", "0")); err == nil && val >= 0 {
+		offset = val
 	}
 
-	pagedBooks := filteredBooks[start:end]
+	results, err := h.store.SearchBooks(query)
+	if err != nil {
+		if errors.Is(err, search.ErrEmptyQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Query has no searchable terms"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search books"})
+		return
+	}
+
+	start := min(offset, len(results))
+	end := min(offset+limit, len(results))
 
 	c.JSON(http.StatusOK, gin.H{
-		"total":  len(filteredBooks),
-		"limit":  limit,
-		"offset": offset,
-		"books":  pagedBooks,
+		"query":   query,
+		"total":   len(results),
+		"limit":   limit,
+		"offset":  offset,
+		"results": results[start:end],
 	})
 }
 
 // GetBook handles GET /books/:id endpoint
 func (h *Handler) GetBook(c *gin.Context) {
-	id := c.Param("bookId")
+	id := c.Param("id")
+
+	currency, err := h.displayCurrency(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
 
 	book, err := h.store.GetBookByID(id)
 	if err != nil {
@@ -135,7 +368,49 @@ func (h *Handler) GetBook(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, book)
+	books := []models.Book{h.localize(book, currency)}
+	if err := h.setSalePrices(books); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply promotions"})
+		return
+	}
+	if err := h.setLowestPrices(books); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read price history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, books[0])
+}
+
+// GetBookByISBN handles GET /books/isbn/:isbn endpoint. The ISBN can be an
+// ISBN-10 or ISBN-13, with or without hyphens.
+func (h *Handler) GetBookByISBN(c *gin.Context) {
+	currency, err := h.displayCurrency(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+
+	book, err := h.store.GetBookByISBN(c.Param("isbn"))
+	if errors.Is(err, isbn.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ISBN"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	books := []models.Book{h.localize(book, currency)}
+	if err := h.setSalePrices(books); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply promotions"})
+		return
+	}
+	if err := h.setLowestPrices(books); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read price history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, books[0])
 }
 
 // CreateBook handles POST /books endpoint
@@ -146,8 +421,29 @@ func (h *Handler) CreateBook(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book data"})
 		return
 	}
+	if !validPrice(book.Price) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price is required and must not be negative"})
+		return
+	}
+	if !validPrices(book) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prices must be non-negative and in distinct currencies"})
+		return
+	}
 
-	createdBook, err := h.store.CreateBook(book)
+	// The opening price and stock are recorded against the caller
+	createdBook, err := h.store.CreateBook(book, requestActor(c, "anonymous"))
+	if errors.Is(err, isbn.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ISBN"})
+		return
+	}
+	if errors.Is(err, database.ErrDuplicateISBN) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A book with this ISBN already exists"})
+		return
+	}
+	if errors.Is(err, database.ErrInvalidContributors) || errors.Is(err, database.ErrInvalidCategories) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create book"})
 		return
@@ -165,24 +461,68 @@ func (h *Handler) UpdateBook(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid book data"})
 		return
 	}
+	if !validPrice(book.Price) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "price is required and must not be negative"})
+		return
+	}
+	if !validPrices(book) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "prices must be non-negative and in distinct currencies"})
+		return
+	}
 
-	updatedBook, err := h.store.UpdateBook(id, models.Book{})
-	if err != nil {
+	// Price changes are recorded against the caller; the gateway identifies them
+	actor := requestActor(c, "anonymous")
+
+	updatedBook, err := h.store.UpdateBook(id, book, actor)
+	if errors.Is(err, database.ErrBookNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
+	if errors.Is(err, database.ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Quantity is below the number of reserved copies"})
+		return
+	}
+	if errors.Is(err, isbn.ErrInvalid) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ISBN"})
+		return
+	}
+	if errors.Is(err, database.ErrDuplicateISBN) {
+		c.JSON(http.StatusConflict, gin.H{"error": "A book with this ISBN already exists"})
+		return
+	}
+	if errors.Is(err, database.ErrInvalidContributors) || errors.Is(err, database.ErrInvalidCategories) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update book"})
+		return
+	}
 
 	c.JSON(http.StatusOK, updatedBook)
 }
 
-// DeleteBook handles DELETE /books/:id endpoint
+// DeleteBook handles DELETE /books/:id endpoint. The book's cover images
+// are deleted with it.
 func (h *Handler) DeleteBook(c *gin.Context) {
 	id := c.Param("id")
 
+	book, err := h.store.GetBookByID(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
 	if err := h.store.DeleteBook(id); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
 		return
 	}
+	h.deleteCover(book.Cover)
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// validPrice reports whether a bound price was given and isn't negative.
+// Binding tags can't check struct values, so book handlers call this instead.
+func validPrice(price money.Money) bool {
+	return price.Currency != "" && !price.IsNegative()
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"crypto/subtle"
+
+	"github.com/gin-gonic/gin"
+)
+
+// identityHeaders name the caller. The gateway sets them after
+// authenticating the request, so a client must not be able to send them.
+var identityHeaders = []string{"X-User-Id", "X-User-Role"}
+
+// GatewayIdentity trusts the identity headers only on requests that carry
+// secret in X-Gateway-Secret, which only the gateway knows. On any other
+// request they are removed, so the caller is treated as anonymous. With an
+// empty secret no request is trusted.
+func GatewayIdentity(secret string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		sent := c.GetHeader("X-Gateway-Secret")
+		c.Request.Header.Del("X-Gateway-Secret")
+
+		if secret != "" && subtle.ConstantTimeCompare([]byte(sent), []byte(secret)) == 1 {
+			c.Next()
+			return
+		}
+		for _, header := range identityHeaders {
+			c.Request.Header.Del(header)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// RecordStockMovement handles POST /books/:id/stock-movements endpoint
+func (h *Handler) RecordStockMovement(c *gin.Context) {
+	id := c.Param("id")
+	var req models.StockMovementRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stock movement"})
+		return
+	}
+
+	movement, err := h.store.RecordStockMovement(models.StockMovement{
+		BookID: id,
+		Type:   req.Type,
+		Delta:  req.Delta(),
+		Reason: req.Reason,
+		Actor:  requestActor(c, internalActor),
+	})
+	switch {
+	case errors.Is(err, database.ErrBookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	case errors.Is(err, database.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record stock movement"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, movement)
+}
+
+// GetStockMovements handles GET /books/:id/stock-movements endpoint
+func (h *Handler) GetStockMovements(c *gin.Context) {
+	movements, err := h.store.GetStockMovements(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":     len(movements),
+		"movements": movements,
+	})
+}
+
+// internalActor is recorded for stock changes made without a gateway
+// identity, such as calls from other services inside the network
+const internalActor = "internal"
+
+// requestActor returns who is making a change: the user the gateway
+// authenticated, or fallback for a request without one. Clients can't name
+// the actor themselves.
+func requestActor(c *gin.Context, fallback string) string {
+	if actor := c.GetHeader("X-User-Id"); actor != "" {
+		return actor
+	}
+	return fallback
+}
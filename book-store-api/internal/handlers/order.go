@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// maxOrderPageSize caps the limit parameter of GET /orders
+const maxOrderPageSize = 100
+
+// CreateOrder handles POST /orders endpoint. Customers place orders for
+// themselves; admins may place them for any customer_id.
+func (h *Handler) CreateOrder(c *gin.Context) {
+	var req models.CreateOrderRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order data"})
+		return
+	}
+
+	customerID := cartOwner(c)
+	if isAdmin(c) && req.CustomerID != "" {
+		customerID = req.CustomerID
+	}
+
+	order := models.Order{
+		CustomerID:      customerID,
+		ShippingAddress: req.ShippingAddress,
+		BillingAddress:  req.BillingAddress,
+	}
+	// One line per book, so quantity-based promotions see every copy
+	lines := make(map[string]int)
+	for _, item := range req.Items {
+		if _, seen := lines[item.ProductID]; !seen {
+			lines[item.ProductID] = len(order.Items)
+			order.Items = append(order.Items, models.OrderItem{ProductID: item.ProductID})
+		}
+		order.Items[lines[item.ProductID]].Quantity += item.Quantity
+	}
+
+	created, err := h.store.CreateOrder(order, DefaultReservationTTL)
+	if err != nil {
+		orderError(c, err, "Failed to create order")
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// GetOrders handles GET /orders endpoint. It supports skip, limit, status
+// and, for admins, customer_id. Other callers only see their own orders.
+func (h *Handler) GetOrders(c *gin.Context) {
+	skip, err := strconv.Atoi(c.DefaultQuery("skip", "0"))
+	if err != nil || skip < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "skip must be a non-negative integer"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(maxOrderPageSize)))
+	if err != nil || limit < 1 || limit > maxOrderPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return
+	}
+
+	filter := models.OrderFilter{
+		CustomerID: c.Query("customer_id"),
+		Status:     models.OrderStatus(c.Query("status")),
+		Skip:       skip,
+		Limit:      limit,
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order status"})
+		return
+	}
+	if !isAdmin(c) {
+		filter.CustomerID = cartOwner(c)
+	}
+
+	orders, total, err := h.store.ListOrders(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list orders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":  total,
+		"skip":   skip,
+		"limit":  limit,
+		"orders": orders,
+	})
+}
+
+// GetOrder handles GET /orders/:id endpoint. Customers can only see their own orders.
+func (h *Handler) GetOrder(c *gin.Context) {
+	order, err := h.store.GetOrder(c.Param("id"))
+	if err != nil || !canAccessOrder(c, order) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, order)
+}
+
+// UpdateOrderStatus handles PUT /orders/:id/status endpoint. The status can
+// be given as a query parameter, like the orders service, or in the body.
+// Admins drive the whole lifecycle; customers can only cancel their own orders.
+func (h *Handler) UpdateOrderStatus(c *gin.Context) {
+	status := models.OrderStatus(c.Query("status"))
+	if status == "" {
+		var req models.UpdateOrderStatusRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status is required"})
+			return
+		}
+		status = req.Status
+	}
+	if !status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid order status"})
+		return
+	}
+
+	order, err := h.store.GetOrder(c.Param("id"))
+	if err != nil || !canAccessOrder(c, order) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+		return
+	}
+	if !isAdmin(c) && status != models.OrderCancelled {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can move orders to " + string(status)})
+		return
+	}
+
+	actor := requestActor(c, order.CustomerID)
+
+	updated, err := h.store.UpdateOrderStatus(order.ID, status, actor)
+	if err != nil {
+		orderError(c, err, "Failed to update order")
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// isAdmin reports whether the gateway authenticated the caller as an admin
+func isAdmin(c *gin.Context) bool {
+	return c.GetHeader("X-User-Role") == "admin"
+}
+
+// canAccessOrder reports whether the caller may see the order
+func canAccessOrder(c *gin.Context, order models.Order) bool {
+	return isAdmin(c) || order.CustomerID == cartOwner(c)
+}
+
+// orderError maps a store error from an order operation to a response
+func orderError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, database.ErrOrderNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
+	case errors.Is(err, database.ErrBookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrInsufficientStock), errors.Is(err, database.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, money.ErrCurrencyMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": "All items in an order must be priced in the same currency"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
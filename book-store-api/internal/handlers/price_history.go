@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// GetPriceHistory handles GET /books/:id/price-history endpoint. Changes are
+// in the book's own currencies; lowest_price_30d is in the display currency,
+// given by currency.
+func (h *Handler) GetPriceHistory(c *gin.Context) {
+	currency, err := h.displayCurrency(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported currency"})
+		return
+	}
+
+	book, err := h.store.GetBookByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	history, err := h.store.GetPriceHistory(book.ID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	book = h.localize(book, currency)
+	lowest, ok := h.pricer.LowestPrice(book, history, book.Price.Currency, time.Now().Add(-models.LowestPriceWindow))
+
+	response := gin.H{
+		"total":            len(history),
+		"changes":          history,
+		"lowest_price_30d": nil,
+		"currency":         book.Price.Currency,
+	}
+	if ok {
+		response["lowest_price_30d"] = lowest
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// setLowestPrices sets the lowest price of each book over the last 30 days,
+// in the currency of its shown price
+func (h *Handler) setLowestPrices(books []models.Book) error {
+	since := time.Now().Add(-models.LowestPriceWindow)
+	for i := range books {
+		history, err := h.store.GetPriceHistory(books[i].ID)
+		if err != nil {
+			return err
+		}
+		if lowest, ok := h.pricer.LowestPrice(books[i], history, books[i].Price.Currency, since); ok {
+			books[i].LowestPrice = &lowest
+		}
+	}
+	return nil
+}
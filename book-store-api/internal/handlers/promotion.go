@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/promotions"
+)
+
+// GetPromotions handles GET /promotions endpoint
+func (h *Handler) GetPromotions(c *gin.Context) {
+	promos, err := h.store.ListPromotions()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list promotions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":      len(promos),
+		"promotions": promos,
+	})
+}
+
+// GetPromotion handles GET /promotions/:id endpoint
+func (h *Handler) GetPromotion(c *gin.Context) {
+	promo, err := h.store.GetPromotion(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, promo)
+}
+
+// CreatePromotion handles POST /promotions endpoint
+func (h *Handler) CreatePromotion(c *gin.Context) {
+	promo, ok := bindPromotion(c)
+	if !ok {
+		return
+	}
+
+	created, err := h.store.CreatePromotion(promo)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promotion"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, created)
+}
+
+// UpdatePromotion handles PUT /promotions/:id endpoint
+func (h *Handler) UpdatePromotion(c *gin.Context) {
+	promo, ok := bindPromotion(c)
+	if !ok {
+		return
+	}
+
+	updated, err := h.store.UpdatePromotion(c.Param("id"), promo)
+	if errors.Is(err, database.ErrPromotionNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeletePromotion handles DELETE /promotions/:id endpoint
+func (h *Handler) DeletePromotion(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage promotions"})
+		return
+	}
+
+	if err := h.store.DeletePromotion(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// bindPromotion checks the caller is an admin and reads a valid promotion
+// from the body, writing an error response if it can't
+func bindPromotion(c *gin.Context) (models.Promotion, bool) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can manage promotions"})
+		return models.Promotion{}, false
+	}
+
+	var promo models.Promotion
+	if err := c.ShouldBindJSON(&promo); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid promotion data"})
+		return models.Promotion{}, false
+	}
+	if err := promo.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return models.Promotion{}, false
+	}
+
+	return promo, true
+}
+
+// setSalePrices sets the list and sale price of each book from its shown
+// price and the promotions running now
+func (h *Handler) setSalePrices(books []models.Book) error {
+	promos, err := h.store.ListPromotions()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range books {
+		list := books[i].Price
+		sale := promotions.SalePrice(books[i], list, promos, now)
+		books[i].ListPrice = &list
+		books[i].SalePrice = &sale
+	}
+	return nil
+}
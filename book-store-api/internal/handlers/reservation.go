@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// DefaultReservationTTL is how long copies are held when the request doesn't say
+const DefaultReservationTTL = 15 * time.Minute
+
+// CreateReservation handles POST /books/:id/reservations endpoint
+func (h *Handler) CreateReservation(c *gin.Context) {
+	var req models.ReservationRequest
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid reservation"})
+		return
+	}
+
+	ttl := DefaultReservationTTL
+	if req.TTLSeconds > 0 {
+		ttl = time.Duration(req.TTLSeconds) * time.Second
+	}
+
+	reservation, err := h.store.CreateReservation(models.Reservation{
+		BookID:    c.Param("id"),
+		Quantity:  req.Quantity,
+		Actor:     requestActor(c, internalActor),
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	switch {
+	case errors.Is(err, database.ErrBookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	case errors.Is(err, database.ErrInsufficientStock):
+		c.JSON(http.StatusConflict, gin.H{"error": "Insufficient stock"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create reservation"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, reservation)
+}
+
+// GetReservation handles GET /reservations/:id endpoint
+func (h *Handler) GetReservation(c *gin.Context) {
+	reservation, err := h.store.GetReservation(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// ConfirmReservation handles POST /reservations/:id/confirm endpoint
+func (h *Handler) ConfirmReservation(c *gin.Context) {
+	reservation, err := h.store.ConfirmReservation(c.Param("id"))
+	if err != nil {
+		reservationError(c, err, "Failed to confirm reservation")
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// ReleaseReservation handles POST /reservations/:id/release endpoint
+func (h *Handler) ReleaseReservation(c *gin.Context) {
+	reservation, err := h.store.ReleaseReservation(c.Param("id"))
+	if err != nil {
+		reservationError(c, err, "Failed to release reservation")
+		return
+	}
+
+	c.JSON(http.StatusOK, reservation)
+}
+
+// reservationError maps a store error from confirming or releasing a reservation to a response
+func reservationError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, database.ErrReservationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Reservation not found"})
+	case errors.Is(err, database.ErrReservationExpired):
+		c.JSON(http.StatusGone, gin.H{"error": "Reservation has expired"})
+	case errors.Is(err, database.ErrReservationNotActive):
+		c.JSON(http.StatusConflict, gin.H{"error": "Reservation is no longer active"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// maxReviewPageSize caps the limit parameter of review listings
+const maxReviewPageSize = 100
+
+// CreateReview handles POST /books/:id/reviews endpoint. Reviews are posted
+// by signed-in users, once per book, and wait for moderation.
+func (h *Handler) CreateReview(c *gin.Context) {
+	userID := c.GetHeader("X-User-Id")
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to review books"})
+		return
+	}
+
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rating must be between 1 and 5"})
+		return
+	}
+
+	review, err := h.store.CreateReview(models.Review{
+		BookID: c.Param("id"),
+		UserID: userID,
+		Rating: req.Rating,
+		Title:  req.Title,
+		Body:   req.Body,
+	})
+	if err != nil {
+		reviewError(c, err, "Failed to create review")
+		return
+	}
+
+	c.JSON(http.StatusCreated, review)
+}
+
+// GetBookReviews handles GET /books/:id/reviews endpoint, listing a book's
+// approved reviews, newest first, with its rating summary
+func (h *Handler) GetBookReviews(c *gin.Context) {
+	skip, limit, ok := reviewPage(c)
+	if !ok {
+		return
+	}
+
+	book, err := h.store.GetBookByID(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+		return
+	}
+
+	reviews, total, err := h.store.ListReviews(models.ReviewFilter{
+		BookID: book.ID,
+		Status: models.ReviewApproved,
+		Skip:   skip,
+		Limit:  limit,
+	})
+	if err != nil {
+		reviewError(c, err, "Failed to list reviews")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"rating":  book.Rating,
+		"total":   total,
+		"skip":    skip,
+		"limit":   limit,
+		"reviews": reviews,
+	})
+}
+
+// GetReviews handles GET /reviews endpoint. For admins it is the moderation
+// queue: pending reviews by default, or any status, book_id and user_id.
+// Other callers see their own reviews in every status.
+func (h *Handler) GetReviews(c *gin.Context) {
+	skip, limit, ok := reviewPage(c)
+	if !ok {
+		return
+	}
+
+	filter := models.ReviewFilter{
+		BookID: c.Query("book_id"),
+		UserID: c.Query("user_id"),
+		Status: models.ReviewStatus(c.Query("status")),
+		Skip:   skip,
+		Limit:  limit,
+	}
+	if filter.Status != "" && !filter.Status.Valid() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review status"})
+		return
+	}
+
+	if isAdmin(c) {
+		if _, given := c.GetQuery("status"); !given {
+			filter.Status = models.ReviewPending
+		}
+	} else {
+		filter.UserID = c.GetHeader("X-User-Id")
+		if filter.UserID == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in to see your reviews"})
+			return
+		}
+	}
+
+	reviews, total, err := h.store.ListReviews(filter)
+	if err != nil {
+		reviewError(c, err, "Failed to list reviews")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"total":   total,
+		"skip":    skip,
+		"limit":   limit,
+		"reviews": reviews,
+	})
+}
+
+// GetReview handles GET /reviews/:id endpoint. Approved reviews are public;
+// others are only shown to their author and admins.
+func (h *Handler) GetReview(c *gin.Context) {
+	review, err := h.store.GetReview(c.Param("id"))
+	if err != nil || !canSeeReview(c, review) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// UpdateReview handles PUT /reviews/:id endpoint. Only the author can edit a
+// review, and the edited review waits for moderation again.
+func (h *Handler) UpdateReview(c *gin.Context) {
+	var req models.ReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "rating must be between 1 and 5"})
+		return
+	}
+
+	review, err := h.store.GetReview(c.Param("id"))
+	if err != nil || !canSeeReview(c, review) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if review.UserID != c.GetHeader("X-User-Id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit a review"})
+		return
+	}
+
+	updated, err := h.store.UpdateReview(review.ID, models.Review{
+		Rating: req.Rating,
+		Title:  req.Title,
+		Body:   req.Body,
+	})
+	if err != nil {
+		reviewError(c, err, "Failed to update review")
+		return
+	}
+
+	c.JSON(http.StatusOK, updated)
+}
+
+// ModerateReview handles PUT /reviews/:id/status endpoint (admins only)
+func (h *Handler) ModerateReview(c *gin.Context) {
+	if !isAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can moderate reviews"})
+		return
+	}
+
+	var req models.ModerateReviewRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid review status"})
+		return
+	}
+
+	moderator := requestActor(c, "admin")
+
+	review, err := h.store.ModerateReview(c.Param("id"), req.Status, moderator, req.Note)
+	if err != nil {
+		reviewError(c, err, "Failed to moderate review")
+		return
+	}
+
+	c.JSON(http.StatusOK, review)
+}
+
+// DeleteReview handles DELETE /reviews/:id endpoint. Authors can delete their
+// own reviews and admins any review.
+func (h *Handler) DeleteReview(c *gin.Context) {
+	review, err := h.store.GetReview(c.Param("id"))
+	if err != nil || !canSeeReview(c, review) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return
+	}
+	if !isAdmin(c) && review.UserID != c.GetHeader("X-User-Id") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author or an admin can delete a review"})
+		return
+	}
+
+	if err := h.store.DeleteReview(review.ID); err != nil {
+		reviewError(c, err, "Failed to delete review")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// canSeeReview reports whether the caller may see the review
+func canSeeReview(c *gin.Context, review models.Review) bool {
+	if review.Status == models.ReviewApproved || isAdmin(c) {
+		return true
+	}
+	userID := c.GetHeader("X-User-Id")
+	return userID != "" && review.UserID == userID
+}
+
+// reviewPage reads the skip and limit parameters of a review listing,
+// writing an error response if they are invalid
+func reviewPage(c *gin.Context) (int, int, bool) {
+	skip, err := strconv.Atoi(c.DefaultQuery("skip", "0"))
+	if err != nil || skip < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "skip must be a non-negative integer"})
+		return 0, 0, false
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > maxReviewPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 100"})
+		return 0, 0, false
+	}
+	return skip, limit, true
+}
+
+// reviewError maps a store error from a review operation to a response
+func reviewError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, database.ErrBookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Book not found"})
+	case errors.Is(err, database.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+	case errors.Is(err, database.ErrDuplicateReview):
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already reviewed this book"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
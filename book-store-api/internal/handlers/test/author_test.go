@@ -0,0 +1,188 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// authorList is the body of GET /authors
+type authorList struct {
+	Total   int             `json:"total"`
+	Authors []models.Author `json:"authors"`
+}
+
+// authorsByName lists the authors and returns them by name
+func authorsByName(t *testing.T, r *gin.Engine) map[string]models.Author {
+	t.Helper()
+	list := decode[authorList](t, serve(r, http.MethodGet, "/authors", ""))
+	authors := make(map[string]models.Author, len(list.Authors))
+	for _, author := range list.Authors {
+		authors[author.Name] = author
+	}
+	return authors
+}
+
+func TestAuthors_FromBooks(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	authors := authorsByName(t, r)
+	want := map[string]int{"Robert C. Martin": 2, "Erich Gamma": 1, "Richard Helm": 1}
+	if len(authors) != len(want) {
+		t.Fatalf("Expected authors %v, got %v", want, authors)
+	}
+	for name, count := range want {
+		if authors[name].BookCount != count {
+			t.Errorf("Expected %s on %d books, got %d", name, count, authors[name].BookCount)
+		}
+	}
+
+	martin := authors["Robert C. Martin"]
+	w := serve(r, http.MethodGet, "/authors/"+martin.ID+"/books?currency=EUR", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	books := decode[bookList](t, w)
+	if books.Total != 2 || books.Books[0].Price.Currency != "EUR" {
+		t.Errorf("Expected Martin's two books in euros, got %+v", books)
+	}
+
+	list := decode[bookList](t, serve(r, http.MethodGet, "/books?author_id="+authors["Richard Helm"].ID, ""))
+	if got := titles(list.Books); !reflect.DeepEqual(got, []string{"Design Patterns"}) {
+		t.Errorf("Expected [Design Patterns] by author_id, got %v", got)
+	}
+}
+
+func TestAuthors_CRUD(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	w := serve(r, http.MethodPost, "/authors", `{"name": "Martin Fowler", "bio": "Chief scientist at Thoughtworks"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	fowler := decode[models.Author](t, w)
+	if got := decode[models.Author](t, serve(r, http.MethodGet, "/authors/"+fowler.ID, "")); got.Name != "Martin Fowler" || got.BookCount != 0 {
+		t.Errorf("Expected Martin Fowler on no books, got %+v", got)
+	}
+
+	// Renaming an author renames them on their books
+	martin := authorsByName(t, r)["Robert C. Martin"]
+	if w := serve(r, http.MethodPut, "/authors/"+martin.ID, `{"name": "Uncle Bob"}`); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if got := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, "")); got.Author != "Uncle Bob" {
+		t.Errorf("Expected the book credited to Uncle Bob, got %q", got.Author)
+	}
+
+	if w := serve(r, http.MethodDelete, "/authors/"+fowler.ID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodGet, "/authors/"+fowler.ID, ""); w.Code != http.StatusNotFound {
+		t.Errorf("Expected status 404 after deleting, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestAuthors_Errors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+	authors := authorsByName(t, r)
+	martin, gamma := authors["Robert C. Martin"].ID, authors["Erich Gamma"].ID
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		err    string
+	}{
+		{"create without a name", http.MethodPost, "/authors", `{"bio": "Anonymous"}`, http.StatusBadRequest, "Invalid author data"},
+		{"create a taken name", http.MethodPost, "/authors", `{"name": "robert c. martin"}`, http.StatusConflict, "An author with this name already exists"},
+		{"rename to a taken name", http.MethodPut, "/authors/" + gamma, `{"name": "Robert C. Martin"}`, http.StatusConflict, "An author with this name already exists"},
+		{"update without a name", http.MethodPut, "/authors/" + gamma, `{}`, http.StatusBadRequest, "Invalid author data"},
+		{"update an unknown author", http.MethodPut, "/authors/missing", `{"name": "Nobody"}`, http.StatusNotFound, "Author not found"},
+		{"delete a credited author", http.MethodDelete, "/authors/" + martin, "", http.StatusConflict, "Author is credited on books"},
+		{"delete an unknown author", http.MethodDelete, "/authors/missing", "", http.StatusNotFound, "Author not found"},
+		{"get an unknown author", http.MethodGet, "/authors/missing", "", http.StatusNotFound, "Author not found"},
+		{"books of an unknown author", http.MethodGet, "/authors/missing/books", "", http.StatusNotFound, "Author not found"},
+		{"books in an unsupported currency", http.MethodGet, "/authors/" + martin + "/books?currency=XYZ", "", http.StatusBadRequest, "Unsupported currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.path, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestCreateBook_Contributors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+	martin := authorsByName(t, r)["Robert C. Martin"]
+
+	body := `{"title": "Clean Agile", "isbn": "` + isbn13(9) + `", "published_at": "2019-09-12T00:00:00Z", "price": "34.99",
+		"contributors": [{"author_id": "` + martin.ID + `"}, {"name": "Grady Booch", "role": "editor"}]}`
+	w := serve(r, http.MethodPost, "/books", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	book := decode[models.Book](t, w)
+
+	want := []models.Contributor{
+		{AuthorID: martin.ID, Name: "Robert C. Martin", Role: models.RoleAuthor},
+		{AuthorID: authorsByName(t, r)["Grady Booch"].ID, Name: "Grady Booch", Role: models.RoleEditor},
+	}
+	if !reflect.DeepEqual(book.Contributors, want) {
+		t.Errorf("Expected %+v, got %+v", want, book.Contributors)
+	}
+	if book.Author != "Robert C. Martin" {
+		t.Errorf("Expected only authors in the author string, got %q", book.Author)
+	}
+	if got := authorsByName(t, r)["Robert C. Martin"].BookCount; got != 3 {
+		t.Errorf("Expected Martin on 3 books, got %d", got)
+	}
+}
+
+func TestCreateBook_ContributorErrors(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name         string
+		isbn         string
+		contributors string
+		status       int
+		err          string
+	}{
+		{"unknown author", isbn13(9), `[{"author_id": "missing"}]`, http.StatusBadRequest, "invalid contributors: author missing not found"},
+		{"no author or name", isbn13(9), `[{"role": "author"}]`, http.StatusBadRequest, "invalid contributors: each contributor needs an author_id or name"},
+		{"only an editor", isbn13(9), `[{"name": "Grady Booch", "role": "editor"}]`, http.StatusBadRequest, "invalid contributors: a book needs at least one author"},
+		{"unknown role", isbn13(9), `[{"name": "Grady Booch", "role": "illustrator"}]`, http.StatusBadRequest, "Invalid book data"},
+		{"taken ISBN", books[0].ISBN, `[{"name": "Grady Booch"}]`, http.StatusConflict, "A book with this ISBN already exists"},
+		{"unknown category", isbn13(9), `[{"name": "Grady Booch"}], "categories": [{"slug": "missing"}]`, http.StatusBadRequest, "invalid categories: category missing not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := `{"title": "Object-Oriented Analysis", "isbn": "` + tt.isbn + `", "published_at": "1990-01-01T00:00:00Z", "price": "49.99", "contributors": ` + tt.contributors + `}`
+			w := serve(r, http.MethodPost, "/books", body)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+
+			// A rejected book creates none of its new authors
+			if _, created := authorsByName(t, r)["Grady Booch"]; created {
+				t.Error("Expected Grady Booch not to be created")
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// addToCart adds copies of a book to the cart and fails the test if it can't
+func addToCart(t *testing.T, r *gin.Engine, bookID string, quantity int, headers ...string) models.Cart {
+	t.Helper()
+	body := fmt.Sprintf(`{"book_id": %q, "quantity": %d}`, bookID, quantity)
+	w := serve(r, http.MethodPost, "/cart/items", body, headers...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	return decode[models.Cart](t, w)
+}
+
+func TestCart_Owners(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	// A new session is started for a caller without one
+	w := serve(r, http.MethodGet, "/cart", "")
+	session := w.Header().Get("X-Cart-Session")
+	if w.Code != http.StatusOK || session == "" {
+		t.Fatalf("Expected a new cart session, got %d with %q", w.Code, session)
+	}
+
+	addToCart(t, r, books[0].ID, 2, "X-Cart-Session", session)
+	addToCart(t, r, books[2].ID, 1, asUser("42")...)
+
+	tests := []struct {
+		name    string
+		headers []string
+		owner   string
+		count   int
+	}{
+		{"same session", []string{"X-Cart-Session", session}, "session:" + session, 2},
+		{"signed-in user", asUser("42"), "user:42", 1},
+		{"another user", asUser("7"), "user:7", 0},
+		{"user header without the gateway secret", []string{"X-User-Id", "42", "X-Cart-Session", session}, "session:" + session, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cart := decode[models.Cart](t, serve(r, http.MethodGet, "/cart", "", tt.headers...))
+			if cart.Owner != tt.owner || cart.ItemCount != tt.count {
+				t.Errorf("Expected %s with %d items, got %s with %d", tt.owner, tt.count, cart.Owner, cart.ItemCount)
+			}
+		})
+	}
+}
+
+func TestAddCartItem(t *testing.T) {
+	tests := []struct {
+		name   string
+		book   int
+		body   string
+		status int
+		err    string
+	}{
+		{name: "unknown book", book: -1, body: `{"book_id": "%s", "quantity": 1}`, status: http.StatusNotFound, err: "book not found"},
+		{name: "out of stock", book: 1, body: `{"book_id": "%s", "quantity": 1}`, status: http.StatusConflict, err: "insufficient stock"},
+		{name: "more than is available", body: `{"book_id": "%s", "quantity": 16}`, status: http.StatusConflict, err: "insufficient stock"},
+		{name: "no quantity", body: `{"book_id": "%s"}`, status: http.StatusBadRequest, err: "Invalid cart item"},
+		{name: "too many", body: `{"book_id": "%s", "quantity": 101}`, status: http.StatusBadRequest, err: "Invalid cart item"},
+		{name: "no book", body: `{"quantity": 1}`, status: http.StatusBadRequest, err: "Invalid cart item"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			body := tt.body
+			if strings.Contains(body, "%s") {
+				id := "missing"
+				if tt.book >= 0 {
+					id = books[tt.book].ID
+				}
+				body = fmt.Sprintf(body, id)
+			}
+
+			w := serve(r, http.MethodPost, "/cart/items", body, asUser("42")...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestAddCartItem_Totals(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	addToCart(t, r, books[0].ID, 1, asUser("42")...)
+	addToCart(t, r, books[2].ID, 1, asUser("42")...)
+	cart := addToCart(t, r, books[0].ID, 1, asUser("42")...)
+
+	if len(cart.Items) != 2 || cart.Items[0].Quantity != 2 {
+		t.Fatalf("Expected the second add to join the first line, got %+v", cart.Items)
+	}
+	if cart.ItemCount != 3 || cart.Subtotal.Decimal() != "119.97" || cart.Total.Decimal() != "119.97" || cart.Currency != "USD" {
+		t.Errorf("Expected 3 items totalling 119.97 USD, got %d totalling %s %s", cart.ItemCount, cart.Total.Decimal(), cart.Currency)
+	}
+	if got := cart.Items[0].LineTotal.Decimal(); got != "74.98" {
+		t.Errorf("Expected a line total of 74.98, got %s", got)
+	}
+
+	// The price is kept from when the book was first added
+	body := `{"title": "Clean Code", "author": "Robert C. Martin", "isbn": "` + books[0].ISBN + `", "published_at": "2008-08-01T00:00:00Z", "price": "45.00", "quantity": 15}`
+	if w := serve(r, http.MethodPut, "/books/"+books[0].ID, body); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	cart = decode[models.Cart](t, serve(r, http.MethodGet, "/cart", "", asUser("42")...))
+	if got := cart.Items[0].UnitPrice.Decimal(); got != "37.49" {
+		t.Errorf("Expected the snapshot price 37.49, got %s", got)
+	}
+
+	// Adding past what's available is refused as a whole
+	w := serve(r, http.MethodPost, "/cart/items", `{"book_id": "`+books[2].ID+`", "quantity": 3}`, asUser("42")...)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestAddCartItem_MixedCurrencies(t *testing.T) {
+	pounds := catalogBooks()[0]
+	pounds.Title = "Clean Code (UK)"
+	pounds.Price, _ = money.Parse("29.99", "GBP")
+	r, _, books := newRouter(t, catalogBooks()[0], pounds)
+
+	addToCart(t, r, books[0].ID, 1, asUser("42")...)
+	w := serve(r, http.MethodPost, "/cart/items", `{"book_id": "`+books[1].ID+`", "quantity": 1}`, asUser("42")...)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status 409, got %d: %s", w.Code, w.Body)
+	}
+	if got, want := errorOf(t, w), "All items in a cart must be priced in the same currency"; got != want {
+		t.Errorf("Expected error %q, got %q", want, got)
+	}
+}
+
+func TestUpdateAndRemoveCartItem(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	addToCart(t, r, books[0].ID, 1, asUser("42")...)
+	addToCart(t, r, books[2].ID, 1, asUser("42")...)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		err    string
+		count  int
+	}{
+		{"change quantity", http.MethodPut, "/cart/items/" + books[0].ID, `{"quantity": 4}`, http.StatusOK, "", 5},
+		{"more than is available", http.MethodPut, "/cart/items/" + books[2].ID, `{"quantity": 4}`, http.StatusConflict, "insufficient stock", 5},
+		{"zero quantity", http.MethodPut, "/cart/items/" + books[0].ID, `{"quantity": 0}`, http.StatusBadRequest, "Invalid cart item", 5},
+		{"change a book not in the cart", http.MethodPut, "/cart/items/" + books[1].ID, `{"quantity": 1}`, http.StatusNotFound, "Book is not in the cart", 5},
+		{"remove", http.MethodDelete, "/cart/items/" + books[2].ID, "", http.StatusOK, "", 4},
+		{"remove again", http.MethodDelete, "/cart/items/" + books[2].ID, "", http.StatusNotFound, "Book is not in the cart", 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.path, tt.body, asUser("42")...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.err != "" {
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("Expected error %q, got %q", tt.err, got)
+				}
+			}
+			cart := decode[models.Cart](t, serve(r, http.MethodGet, "/cart", "", asUser("42")...))
+			if cart.ItemCount != tt.count {
+				t.Errorf("Expected %d items, got %d", tt.count, cart.ItemCount)
+			}
+		})
+	}
+
+	if w := serve(r, http.MethodDelete, "/cart", "", asUser("42")...); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body)
+	}
+	cart := decode[models.Cart](t, serve(r, http.MethodGet, "/cart", "", asUser("42")...))
+	if len(cart.Items) != 0 || cart.Currency != "" {
+		t.Errorf("Expected an empty cart, got %+v", cart)
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	addToCart(t, r, books[0].ID, 2, asUser("42")...)
+	addToCart(t, r, books[2].ID, 1, asUser("42")...)
+
+	w := serve(r, http.MethodPost, "/cart/checkout", `{"shipping_address": "1 Main St"}`, asUser("42")...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	order := decode[models.Order](t, w)
+	if order.CustomerID != "user:42" || order.Status != models.OrderPending || len(order.Items) != 2 {
+		t.Fatalf("Expected a pending order of two lines for user:42, got %+v", order)
+	}
+	if order.Total.Decimal() != "119.97" || order.Currency != "USD" || order.ShippingAddress != "1 Main St" {
+		t.Errorf("Expected 119.97 USD shipped to 1 Main St, got %s %s to %q", order.Total.Decimal(), order.Currency, order.ShippingAddress)
+	}
+	for _, item := range order.Items {
+		if item.ReservationID == "" {
+			t.Errorf("Expected %s to be reserved", item.Title)
+		}
+	}
+
+	book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, ""))
+	if book.Reserved != 2 || book.Quantity != 15 {
+		t.Errorf("Expected 2 of 15 copies reserved, got %d of %d", book.Reserved, book.Quantity)
+	}
+	cart := decode[models.Cart](t, serve(r, http.MethodGet, "/cart", "", asUser("42")...))
+	if len(cart.Items) != 0 {
+		t.Errorf("Expected the cart to be emptied, got %+v", cart.Items)
+	}
+	if got := decode[models.Order](t, serve(r, http.MethodGet, "/orders/"+order.ID, "", asUser("42")...)); got.ID != order.ID {
+		t.Errorf("Expected the order to be saved, got %+v", got)
+	}
+}
+
+func TestCheckout_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		items  int // Copies of Design Patterns in the cart
+		sold   int // Copies of it sold before checkout
+		status int
+		err    string
+	}{
+		{name: "no shipping address", body: `{}`, items: 2, status: http.StatusBadRequest, err: "Invalid checkout data"},
+		{name: "empty cart", body: `{"shipping_address": "1 Main St"}`, status: http.StatusBadRequest, err: "Cart is empty"},
+		{name: "stock sold since it was added", body: `{"shipping_address": "1 Main St"}`, items: 2, sold: 2, status: http.StatusConflict, err: "insufficient stock: Design Patterns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			if tt.items > 0 {
+				addToCart(t, r, books[2].ID, tt.items, asUser("42")...)
+			}
+			if tt.sold > 0 {
+				body := fmt.Sprintf(`{"type": "sale", "quantity": %d}`, tt.sold)
+				if w := serve(r, http.MethodPost, "/books/"+books[2].ID+"/stock-movements", body); w.Code != http.StatusCreated {
+					t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+				}
+			}
+
+			w := serve(r, http.MethodPost, "/cart/checkout", tt.body, asUser("42")...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+
+			// A failed checkout keeps the cart
+			cart := decode[models.Cart](t, serve(r, http.MethodGet, "/cart", "", asUser("42")...))
+			if cart.ItemCount != tt.items {
+				t.Errorf("Expected %d items left in the cart, got %d", tt.items, cart.ItemCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// createCategory creates a category and fails the test if it can't
+func createCategory(t *testing.T, r *gin.Engine, body string) models.Category {
+	t.Helper()
+	w := serve(r, http.MethodPost, "/categories", body)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	return decode[models.Category](t, w)
+}
+
+// fileBook puts a book in the categories and fails the test if it can't
+func fileBook(t *testing.T, r *gin.Engine, book models.Book, categories string) {
+	t.Helper()
+	body := `{"title": "` + book.Title + `", "author": "` + book.Author + `", "isbn": "` + book.ISBN + `", "published_at": "2000-01-01T00:00:00Z", "price": "` + book.Price.Decimal() + `", "quantity": ` + strconv.Itoa(book.Quantity) + `, "categories": ` + categories + `}`
+	if w := serve(r, http.MethodPut, "/books/"+book.ID, body); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestCategories_Tree(t *testing.T) {
+	r, _, _ := newRouter(t)
+
+	computers := createCategory(t, r, `{"name": "Computers"}`)
+	createCategory(t, r, `{"name": "Programming", "parent_id": "`+computers.ID+`", "position": 2}`)
+	createCategory(t, r, `{"name": "Software Design", "slug": "design", "parent_id": "`+computers.ID+`", "position": 1}`)
+
+	w := serve(r, http.MethodGet, "/categories", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	tree := decode[struct {
+		Categories []models.Category `json:"categories"`
+	}](t, w).Categories
+	if len(tree) != 1 || tree[0].Slug != "computers" {
+		t.Fatalf("Expected a single root, got %+v", tree)
+	}
+	var children []string
+	for _, child := range tree[0].Children {
+		children = append(children, child.Slug)
+	}
+	if want := []string{"design", "computers-programming"}; !reflect.DeepEqual(children, want) {
+		t.Errorf("Expected children %v in position order, got %v", want, children)
+	}
+
+	w = serve(r, http.MethodGet, "/categories/computers-programming", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	category := decode[models.Category](t, w)
+	if len(category.Ancestors) != 1 || category.Ancestors[0].Slug != "computers" {
+		t.Errorf("Expected Computers as the only ancestor, got %+v", category.Ancestors)
+	}
+}
+
+func TestGetCategoryBooks(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	computers := createCategory(t, r, `{"name": "Computers"}`)
+	design := createCategory(t, r, `{"name": "Design", "parent_id": "`+computers.ID+`"}`)
+	createCategory(t, r, `{"name": "Cooking"}`)
+
+	fileBook(t, r, books[0], `[{"slug": "computers"}]`)
+	fileBook(t, r, books[2], `[{"id": "`+design.ID+`"}]`)
+
+	tests := []struct {
+		slug  string
+		query string
+		want  []string
+	}{
+		{"computers", "", []string{"Clean Code", "Design Patterns"}},
+		{"computers", "?sort=-price", []string{"Design Patterns", "Clean Code"}},
+		{"computers", "?author=gamma", []string{"Design Patterns"}},
+		{"computers-design", "", []string{"Design Patterns"}},
+		{"cooking", "", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slug+tt.query, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/categories/"+tt.slug+"/books"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			if got := titles(decode[bookList](t, w).Books); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	w := serve(r, http.MethodGet, "/categories/missing/books", "")
+	if w.Code != http.StatusNotFound || errorOf(t, w) != "Category not found" {
+		t.Errorf("Expected 404 Category not found, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestCategories_Errors(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	computers := createCategory(t, r, `{"name": "Computers", "code": "COM000000"}`)
+	createCategory(t, r, `{"name": "Design", "parent_id": "`+computers.ID+`"}`)
+	createCategory(t, r, `{"name": "Cooking"}`)
+	fileBook(t, r, books[0], `[{"slug": "cooking"}]`)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		err    string
+	}{
+		{"no name", http.MethodPost, "/categories", `{"slug": "history"}`, http.StatusBadRequest, "Invalid category data"},
+		{"bad slug", http.MethodPost, "/categories", `{"name": "History", "slug": "World History"}`, http.StatusBadRequest, "invalid category: slug must be lower-case letters and digits separated by hyphens"},
+		{"unknown parent", http.MethodPost, "/categories", `{"name": "History", "parent_id": "missing"}`, http.StatusBadRequest, "invalid category: parent missing not found"},
+		{"taken slug", http.MethodPost, "/categories", `{"name": "Computing", "slug": "computers"}`, http.StatusConflict, "A category with this slug or code already exists"},
+		{"taken code", http.MethodPost, "/categories", `{"name": "Computing", "code": "COM000000"}`, http.StatusConflict, "A category with this slug or code already exists"},
+		{"moved under itself", http.MethodPut, "/categories/computers", `{"name": "Computers", "parent_id": "` + computers.ID + `"}`, http.StatusBadRequest, "invalid category: a category can't be moved under itself"},
+		{"update an unknown category", http.MethodPut, "/categories/missing", `{"name": "Missing"}`, http.StatusNotFound, "Category not found"},
+		{"delete with subcategories", http.MethodDelete, "/categories/computers", "", http.StatusConflict, "Category has subcategories or books"},
+		{"delete with books", http.MethodDelete, "/categories/cooking", "", http.StatusConflict, "Category has subcategories or books"},
+		{"delete an unknown category", http.MethodDelete, "/categories/missing", "", http.StatusNotFound, "Category not found"},
+		{"get an unknown category", http.MethodGet, "/categories/missing", "", http.StatusNotFound, "Category not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.path, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	if w := serve(r, http.MethodDelete, "/categories/computers-design", ""); w.Code != http.StatusNoContent {
+		t.Errorf("Expected an empty category to be deleted, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestImportCategories(t *testing.T) {
+	r, _, _ := newRouter(t)
+
+	headings := "Code,Literal\n" +
+		"COM000000,COMPUTERS / General\n" +
+		"COM051000,COMPUTERS / Programming / General\n" +
+		"COM051210,COMPUTERS / Programming / Object Oriented\n"
+
+	tests := []struct {
+		name string
+		want models.CategoryImportResult
+	}{
+		{"first import", models.CategoryImportResult{Created: 3, Total: 3}},
+		{"unchanged headings", models.CategoryImportResult{Total: 3}},
+	}
+
+	for _, tt := range tests {
+		w := serve(r, http.MethodPost, "/categories/import", headings)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d: %s", tt.name, w.Code, w.Body)
+		}
+		if got := decode[models.CategoryImportResult](t, w); got != tt.want {
+			t.Errorf("%s: expected %+v, got %+v", tt.name, tt.want, got)
+		}
+	}
+
+	w := serve(r, http.MethodGet, "/categories/computers-programming-object-oriented", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if got := decode[models.Category](t, w); got.Code != "COM051210" || len(got.Ancestors) != 2 {
+		t.Errorf("Expected COM051210 under two ancestors, got %+v", got)
+	}
+}
+
+func TestImportCategories_Errors(t *testing.T) {
+	r, _, _ := newRouter(t)
+
+	tests := []struct {
+		name     string
+		headings string
+		err      string
+	}{
+		{"bad code", "COM000000,COMPUTERS / General\nCOMPUTING,COMPUTERS / Programming\n", `bisac: invalid subject headings: line 2 has invalid code "COMPUTING"`},
+		{"no heading", "COM000000\n", "bisac: invalid subject headings: line 1 needs a code and a heading"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodPost, "/categories/import", tt.headings)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	// Nothing from a rejected file is imported
+	tree := decode[struct {
+		Categories []models.Category `json:"categories"`
+	}](t, serve(r, http.MethodGet, "/categories", ""))
+	if len(tree.Categories) != 0 {
+		t.Errorf("Expected no categories, got %+v", tree.Categories)
+	}
+}
@@ -0,0 +1,194 @@
+package handlers_test
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/covers"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// coverPNG returns a width x height PNG
+func coverPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: 200, G: 40, B: 40, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("Failed to encode PNG: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// uploadCover posts data as the named field of a multipart form
+func uploadCover(t *testing.T, r *gin.Engine, bookID, field string, data []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile(field, "cover.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/books/"+bookID+"/cover", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestUploadCover(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	w := uploadCover(t, r, books[0].ID, "cover", coverPNG(t, 800, 1200))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	cover := decode[models.Cover](t, w)
+	if cover.ContentType != "image/png" || cover.Width != 800 || cover.Height != 1200 {
+		t.Errorf("Expected an 800x1200 PNG, got %+v", cover)
+	}
+	if len(cover.Thumbnails) != len(covers.ThumbnailSizes) {
+		t.Fatalf("Expected %d thumbnails, got %+v", len(covers.ThumbnailSizes), cover.Thumbnails)
+	}
+	for i, size := range covers.ThumbnailSizes {
+		if got := cover.Thumbnails[i]; got.Name != size.Name || got.Width != size.Width || got.Height != size.Width*3/2 {
+			t.Errorf("Expected a %d wide %s thumbnail, got %+v", size.Width, size.Name, got)
+		}
+	}
+
+	w = serve(r, http.MethodGet, cover.URL, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200 for %s, got %d: %s", cover.URL, w.Code, w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got != "image/png" {
+		t.Errorf("Expected image/png, got %q", got)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "public, max-age=31536000, immutable" {
+		t.Errorf("Expected the image to be cached for good, got %q", got)
+	}
+	w = serve(r, http.MethodGet, cover.Thumbnails[0].URL, "")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "image/jpeg" {
+		t.Errorf("Expected a JPEG thumbnail, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+
+	book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, ""))
+	if book.CoverURL != cover.URL || book.CoverThumbnailURLs["small"] != cover.Thumbnails[0].URL {
+		t.Errorf("Expected the book to show the cover, got %q and %v", book.CoverURL, book.CoverThumbnailURLs)
+	}
+
+	// A new cover replaces the old one and its images
+	w = uploadCover(t, r, books[0].ID, "cover", coverPNG(t, 100, 100))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	if replaced := decode[models.Cover](t, w); replaced.URL == cover.URL {
+		t.Errorf("Expected a new URL, got %s again", replaced.URL)
+	}
+	for _, url := range []string{cover.URL, cover.Thumbnails[0].URL} {
+		if w := serve(r, http.MethodGet, url, ""); w.Code != http.StatusNotFound || errorOf(t, w) != "Image not found" {
+			t.Errorf("Expected %s to be gone, got %d: %s", url, w.Code, w.Body)
+		}
+	}
+}
+
+func TestUploadCover_Errors(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	valid := coverPNG(t, 10, 10)
+
+	tests := []struct {
+		name   string
+		book   string
+		field  string
+		data   []byte
+		status int
+		err    string
+	}{
+		{"unknown book", "missing", "cover", valid, http.StatusNotFound, "Book not found"},
+		{"wrong field", books[0].ID, "image", valid, http.StatusBadRequest, "Send the image as the cover field of a multipart form"},
+		{"not an image", books[0].ID, "cover", []byte("%PDF-1.7 not a cover"), http.StatusUnsupportedMediaType, "cover must be a JPEG, PNG or GIF image"},
+		{"SVG", books[0].ID, "cover", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), http.StatusUnsupportedMediaType, "cover must be a JPEG, PNG or GIF image"},
+		{"truncated image", books[0].ID, "cover", valid[:len(valid)/2], http.StatusBadRequest, "cover image can't be read"},
+		{"just over the limit", books[0].ID, "cover", append(append([]byte{}, valid...), make([]byte, covers.MaxSize)...), http.StatusRequestEntityTooLarge, "Cover image must be at most 10 MB"},
+		{"far over the limit", books[0].ID, "cover", make([]byte, 2*covers.MaxSize), http.StatusRequestEntityTooLarge, "Cover image must be at most 10 MB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := uploadCover(t, r, tt.book, tt.field, tt.data)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	if book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, "")); book.CoverURL != "" {
+		t.Errorf("Expected no cover, got %s", book.CoverURL)
+	}
+}
+
+func TestDeleteCover(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	w := uploadCover(t, r, books[0].ID, "cover", coverPNG(t, 200, 300))
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	cover := decode[models.Cover](t, w)
+
+	if w := serve(r, http.MethodDelete, "/books/"+books[0].ID+"/cover", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body)
+	}
+	for _, url := range []string{cover.URL, cover.ThumbnailURLs()["small"]} {
+		if w := serve(r, http.MethodGet, url, ""); w.Code != http.StatusNotFound {
+			t.Errorf("Expected %s to be deleted, got %d", url, w.Code)
+		}
+	}
+	if book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, "")); book.CoverURL != "" || book.CoverThumbnailURLs != nil {
+		t.Errorf("Expected no cover, got %q and %v", book.CoverURL, book.CoverThumbnailURLs)
+	}
+
+	tests := []struct {
+		name string
+		book string
+		err  string
+	}{
+		{"no cover", books[0].ID, "Book has no cover"},
+		{"unknown book", "missing", "Book not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodDelete, "/books/"+tt.book+"/cover", "")
+			if w.Code != http.StatusNotFound || errorOf(t, w) != tt.err {
+				t.Errorf("Expected 404 %s, got %d: %s", tt.err, w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestGetCoverImage_NotFound(t *testing.T) {
+	r, _, _ := newRouter(t)
+
+	for _, path := range []string{"/covers/missing/original.png", "/covers/../exchange_rates.json"} {
+		w := serve(r, http.MethodGet, path, "")
+		if w.Code != http.StatusNotFound || errorOf(t, w) != "Image not found" {
+			t.Errorf("%s: expected 404 Image not found, got %d: %s", path, w.Code, w.Body)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+func TestGetBook_Currency(t *testing.T) {
+	euros := catalogBooks()[2]
+	euros.Prices = money.Prices{money.New(3900, "EUR")}
+	r, _, books := newRouter(t, catalogBooks()[0], euros)
+
+	tests := []struct {
+		name     string
+		book     int
+		query    string
+		accept   string
+		price    string
+		currency string
+	}{
+		{name: "own price", price: "37.49", currency: "USD"},
+		{name: "converted", query: "?currency=GBP", price: "29.62", currency: "GBP"},
+		{name: "lowercase code", query: "?currency=gbp", price: "29.62", currency: "GBP"},
+		{name: "no minor units", query: "?currency=JPY", price: "5676", currency: "JPY"},
+		{name: "rounded to 5 centimes", query: "?currency=CHF", price: "33.00", currency: "CHF"},
+		{name: "rounded to whole naira", query: "?currency=NGN", price: "58110.00", currency: "NGN"},
+		{name: "fixed price wins", book: 1, query: "?currency=EUR", price: "39.00", currency: "EUR"},
+		{name: "converted when there's no fixed price", book: 1, query: "?currency=GBP", price: "35.54", currency: "GBP"},
+		{name: "from the header", accept: "EUR", price: "34.49", currency: "EUR"},
+		{name: "first supported in the header", accept: "XYZ, GBP;q=0.8, EUR;q=0.5", price: "29.62", currency: "GBP"},
+		{name: "nothing supported in the header", accept: "XYZ", price: "37.49", currency: "USD"},
+		{name: "parameter beats the header", query: "?currency=JPY", accept: "EUR", price: "5676", currency: "JPY"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.accept != "" {
+				headers = []string{"Accept-Currency", tt.accept}
+			}
+			w := serve(r, http.MethodGet, "/books/"+books[tt.book].ID+tt.query, "", headers...)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			if vary := w.Header().Get("Vary"); vary != "Accept-Currency" {
+				t.Errorf("Expected Vary: Accept-Currency, got %q", vary)
+			}
+			book := decode[map[string]any](t, w)
+			if book["price"] != tt.price || book["currency"] != tt.currency {
+				t.Errorf("Expected %s %s, got %v %v", tt.price, tt.currency, book["price"], book["currency"])
+			}
+		})
+	}
+}
+
+func TestGetBooks_Currency(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	// In euros the books cost 34.49, 27.59 and 41.39
+	tests := []struct {
+		name   string
+		query  string
+		accept string
+		want   []string
+	}{
+		{"sorted by the shown price", "currency=EUR&sort=-price", "", []string{"Design Patterns", "Clean Code", "Clean Architecture"}},
+		{"bounds are in the shown currency", "currency=EUR&min_price=30&max_price=35", "", []string{"Clean Code"}},
+		{"bounds without a currency are in dollars", "min_price=35&max_price=40", "", []string{"Clean Code"}},
+		{"currency from the header", "max_price=28", "EUR", []string{"Clean Architecture"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var headers []string
+			if tt.accept != "" {
+				headers = []string{"Accept-Currency", tt.accept}
+			}
+			w := serve(r, http.MethodGet, "/books?"+tt.query, "", headers...)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			list := decode[bookList](t, w)
+			if got := titles(list.Books); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCurrency_Unsupported(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{"/books/" + books[0].ID + "?currency=XYZ", "Unsupported currency"},
+		{"/books/isbn/" + books[0].ISBN + "?currency=XYZ", "Unsupported currency"},
+		{"/books/" + books[0].ID + "/price-history?currency=XYZ", "Unsupported currency"},
+		{"/books?currency=XYZ", "unsupported currency"},
+		{"/books?currency=dollars", "unsupported currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(r, http.MethodGet, tt.path, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestExchangeRates(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	w := serve(r, http.MethodGet, "/exchange-rates", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	rates := decode[struct {
+		Base  string            `json:"base"`
+		Rates map[string]string `json:"rates"`
+	}](t, w)
+	if rates.Base != "USD" || rates.Rates["EUR"] != "0.92" || rates.Rates["USD"] != "1" {
+		t.Errorf("Expected the rates file, got %+v", rates)
+	}
+
+	table := `{"base": "USD", "rates": {"EUR": "0.90"}}`
+	if w := serve(r, http.MethodPut, "/exchange-rates", table, asAdmin("1")...); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+
+	// Books are priced at the new rates, and currencies left out of the table are gone
+	book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID+"?currency=EUR", ""))
+	if got := book.Price.Decimal(); got != "33.74" {
+		t.Errorf("Expected 33.74 at the new rate, got %s", got)
+	}
+	if w := serve(r, http.MethodGet, "/books/"+books[0].ID+"?currency=GBP", ""); w.Code != http.StatusBadRequest {
+		t.Errorf("Expected GBP to be unsupported, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestUpdateExchangeRates_Errors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name    string
+		body    string
+		headers []string
+		status  int
+		err     string
+	}{
+		{"customer", `{"base": "USD", "rates": {"EUR": "0.90"}}`, asUser("42"), http.StatusForbidden, "Only admins can change exchange rates"},
+		{"role without the gateway secret", `{"base": "USD", "rates": {"EUR": "0.90"}}`, []string{"X-User-Role", "admin"}, http.StatusForbidden, "Only admins can change exchange rates"},
+		{"not JSON", `rates`, asAdmin("1"), http.StatusBadRequest, "Invalid exchange rates: "},
+		{"negative rate", `{"base": "USD", "rates": {"EUR": "-0.90"}}`, asAdmin("1"), http.StatusBadRequest, "Invalid exchange rates: money: invalid exchange rate for EUR"},
+		{"base rate isn't 1", `{"base": "USD", "rates": {"USD": "2"}}`, asAdmin("1"), http.StatusBadRequest, "Invalid exchange rates: money: base currency rate must be 1"},
+		{"bad rounding mode", `{"base": "USD", "rates": {"EUR": "0.90"}, "rounding": {"EUR": {"increment": "0.05", "mode": "sideways"}}}`, asAdmin("1"), http.StatusBadRequest, "Invalid exchange rates: money: invalid rounding mode for EUR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodPut, "/exchange-rates", tt.body, tt.headers...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); !strings.HasPrefix(got, tt.err) {
+				t.Errorf("Expected error starting %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	// The table in use is unchanged
+	rates := decode[struct {
+		Rates map[string]string `json:"rates"`
+	}](t, serve(r, http.MethodGet, "/exchange-rates", ""))
+	if rates.Rates["EUR"] != "0.92" {
+		t.Errorf("Expected the original EUR rate, got %q", rates.Rates["EUR"])
+	}
+}
@@ -0,0 +1,155 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// page fetches a page of books, following the cursor if there is one
+func page(t *testing.T, r *gin.Engine, query, cursor string) (bookList, http.Header) {
+	t.Helper()
+	if cursor != "" {
+		query += "&cursor=" + url.QueryEscape(cursor)
+	}
+	w := serve(r, http.MethodGet, "/books?"+query, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	return decode[bookList](t, w), w.Header()
+}
+
+func TestGetBooks_Cursors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+	want := []string{"Clean Architecture", "Clean Code", "Design Patterns"}
+
+	// Forward through the pages
+	var forward []string
+	list, _ := page(t, r, "sort=title&limit=1", "")
+	if list.PrevCursor != nil {
+		t.Errorf("Expected no previous page on the first, got %q", *list.PrevCursor)
+	}
+	for {
+		forward = append(forward, titles(list.Books)...)
+		if list.NextCursor == nil {
+			break
+		}
+		list, _ = page(t, r, "sort=title&limit=1", *list.NextCursor)
+		if list.Offset != 0 {
+			t.Errorf("Expected offset 0 with a cursor, got %d", list.Offset)
+		}
+	}
+	if !reflect.DeepEqual(forward, want) {
+		t.Fatalf("Expected %v walking forward, got %v", want, forward)
+	}
+
+	// And back again from the last
+	backward := titles(list.Books)
+	for list.PrevCursor != nil {
+		list, _ = page(t, r, "sort=title&limit=1", *list.PrevCursor)
+		backward = append(titles(list.Books), backward...)
+	}
+	if !reflect.DeepEqual(backward, want) {
+		t.Errorf("Expected %v walking back, got %v", want, backward)
+	}
+}
+
+func TestGetBooks_CursorsSurviveInserts(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	first, _ := page(t, r, "sort=title&limit=2", "")
+	if got := titles(first.Books); !reflect.DeepEqual(got, []string{"Clean Architecture", "Clean Code"}) {
+		t.Fatalf("Unexpected first page %v", got)
+	}
+
+	// A book sorting before the cursor shifts offsets but not the next page
+	body := `{"title": "Agile Software Development", "author": "Robert C. Martin", "isbn": "` + isbn13(9) + `", "published_at": "2002-10-15T00:00:00Z", "price": "49.99"}`
+	if w := serve(r, http.MethodPost, "/books", body); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+
+	next, _ := page(t, r, "sort=title&limit=2", *first.NextCursor)
+	if got := titles(next.Books); !reflect.DeepEqual(got, []string{"Design Patterns"}) {
+		t.Errorf("Expected [Design Patterns], got %v", got)
+	}
+	if next.Total != 4 {
+		t.Errorf("Expected total 4, got %d", next.Total)
+	}
+}
+
+func TestGetBooks_LinkHeader(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	first, header := page(t, r, "sort=-price&limit=1&author=martin", "")
+	link := header.Get("Link")
+	if !strings.Contains(link, `rel="next"`) || strings.Contains(link, `rel="prev"`) {
+		t.Fatalf("Expected only a next link on the first page, got %q", link)
+	}
+	target := strings.TrimPrefix(strings.Split(link, ">")[0], "<")
+	next, err := url.Parse(target)
+	if err != nil {
+		t.Fatalf("Invalid link %q: %v", target, err)
+	}
+	if next.Path != "/books" || next.Query().Get("cursor") != *first.NextCursor || next.Query().Get("author") != "martin" {
+		t.Errorf("Expected the query repeated with the next cursor, got %q", target)
+	}
+
+	second, header := page(t, r, "sort=-price&limit=1&author=martin", *first.NextCursor)
+	if got := titles(second.Books); !reflect.DeepEqual(got, []string{"Clean Architecture"}) {
+		t.Errorf("Expected [Clean Architecture], got %v", got)
+	}
+	if link := header.Get("Link"); strings.Contains(link, `rel="next"`) || !strings.Contains(link, `rel="prev"`) {
+		t.Errorf("Expected only a prev link on the last page, got %q", link)
+	}
+
+	if _, header := page(t, r, "author=gamma", ""); header.Get("Link") != "" {
+		t.Errorf("Expected no Link header for a single page, got %q", header.Get("Link"))
+	}
+}
+
+func TestGetBooks_CursorErrors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+	first, _ := page(t, r, "sort=title&limit=1", "")
+	token := *first.NextCursor
+
+	// Flip a character in the middle so the signature no longer matches
+	tampered := []byte(token)
+	if tampered[len(tampered)/2] == 'A' {
+		tampered[len(tampered)/2] = 'B'
+	} else {
+		tampered[len(tampered)/2] = 'A'
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{"garbage", "cursor=not-a-cursor", "Invalid cursor"},
+		{"tampered", "cursor=" + url.QueryEscape(string(tampered)), "Invalid cursor"},
+		{"different sort", "sort=-price&cursor=" + url.QueryEscape(token), "Cursor does not match sort parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books?"+tt.query, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	// The cursor carries its sort, so repeating it or leaving it out is fine
+	for _, query := range []string{"sort=title&limit=1", "limit=1"} {
+		if list, _ := page(t, r, query, token); !reflect.DeepEqual(titles(list.Books), []string{"Clean Code"}) {
+			t.Errorf("%s: expected [Clean Code], got %v", query, titles(list.Books))
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// counts flattens facet values to "value=count" entries
+func counts(values []models.FacetValue) []string {
+	found := make([]string, len(values))
+	for i, v := range values {
+		found[i] = v.Value + "=" + strconv.Itoa(v.Count)
+	}
+	return found
+}
+
+func TestGetBookFacets(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	w := serve(r, http.MethodGet, "/books/facets", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	facets := decode[models.BookFacets](t, w)
+
+	if facets.Total != 3 || facets.Currency != "USD" {
+		t.Errorf("Expected 3 books priced in USD, got %d in %q", facets.Total, facets.Currency)
+	}
+	tests := []struct {
+		facet string
+		got   []models.FacetValue
+		want  []string
+	}{
+		{"author", facets.Authors, []string{"Robert C. Martin=2", "Erich Gamma=1", "Richard Helm=1"}},
+		{"decade", facets.Decades, []string{"1990s=1", "2000s=1", "2010s=1"}},
+		{"price", facets.PriceRanges, []string{"0-10=0", "10-20=0", "20-30=1", "30-50=2", "50+=0"}},
+		{"availability", facets.Availability, []string{"in_stock=2", "out_of_stock=1"}},
+	}
+	for _, tt := range tests {
+		if got := counts(tt.got); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Expected %s facet %v, got %v", tt.facet, tt.want, got)
+		}
+	}
+
+	last := facets.PriceRanges[len(facets.PriceRanges)-1]
+	if last.Min == nil || last.Min.Decimal() != "50.00" || last.Max != nil {
+		t.Errorf("Expected the last price range to start at 50.00 and be unbounded, got %+v", last)
+	}
+}
+
+func TestGetBookFacets_Filters(t *testing.T) {
+	r, store, books := newRouter(t, catalogBooks()...)
+
+	computers, err := store.CreateCategory(models.Category{Name: "Computers"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	book := books[2]
+	book.Categories = []models.CategoryRef{{ID: computers.ID}}
+	if _, err := store.UpdateBook(book.ID, book, "system"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		total   int
+		authors []string
+	}{
+		{"author", "author=martin", 2, []string{"Robert C. Martin=2"}},
+		{"price bounds", "min_price=30&max_price=40", 1, []string{"Robert C. Martin=1"}},
+		{"filter expression", "filter=" + url.QueryEscape("quantity eq 0"), 1, []string{"Robert C. Martin=1"}},
+		{"category", "category=computers", 1, []string{"Erich Gamma=1", "Richard Helm=1"}},
+		{"nothing matches", "title=refactoring", 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books/facets?"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			facets := decode[models.BookFacets](t, w)
+			if facets.Total != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, facets.Total)
+			}
+			if got := counts(facets.Authors); !reflect.DeepEqual(got, tt.authors) {
+				t.Errorf("Expected authors %v, got %v", tt.authors, got)
+			}
+		})
+	}
+}
+
+func TestGetBooks_WithFacets(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		query  string
+		facets bool
+	}{
+		{"author=martin", false},
+		{"author=martin&facets=true", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books?"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			got := decode[struct {
+				Total  int                `json:"total"`
+				Facets *models.BookFacets `json:"facets"`
+			}](t, w)
+			if (got.Facets != nil) != tt.facets {
+				t.Fatalf("Expected facets %v, got %+v", tt.facets, got.Facets)
+			}
+			if got.Facets != nil && got.Facets.Total != got.Total {
+				t.Errorf("Expected facets over the %d listed books, got %d", got.Total, got.Facets.Total)
+			}
+		})
+	}
+}
+
+func TestGetBookFacets_Errors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		err    string
+	}{
+		{"unknown category", "category=missing", http.StatusNotFound, "Category not found"},
+		{"unsupported currency", "currency=XYZ", http.StatusBadRequest, "unsupported currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books/facets?"+tt.query, "")
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
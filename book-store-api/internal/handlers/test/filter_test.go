@@ -0,0 +1,78 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestGetBooks_Filter(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	// With every copy of Design Patterns held, it is no longer in stock
+	if w := serve(r, http.MethodPost, "/books/"+books[2].ID+"/reservations", `{"quantity": 3}`); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name   string
+		filter string
+		extra  string
+		want   []string
+	}{
+		{"comparison", `price lt 40`, "", []string{"Clean Code", "Clean Architecture"}},
+		{"in", `title in ('Clean Code', 'Design Patterns')`, "", []string{"Clean Code", "Design Patterns"}},
+		{"contains", `author contains 'gamma'`, "", []string{"Design Patterns"}},
+		{"date", `published_at gt '2000-01-01'`, "", []string{"Clean Code", "Clean Architecture"}},
+		{"not", `not author contains 'martin'`, "", []string{"Design Patterns"}},
+		{"in stock counts reservations", `in_stock eq true`, "", []string{"Clean Code"}},
+		{"grouping", `in_stock eq false and (author contains 'Gamma' or title eq 'Clean Architecture')`, "", []string{"Clean Architecture", "Design Patterns"}},
+		{"with the other filters", `quantity gt 0`, "&title=clean", []string{"Clean Code"}},
+		{"blank", `   `, "", []string{"Clean Code", "Clean Architecture", "Design Patterns"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books?filter="+url.QueryEscape(tt.filter)+tt.extra, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			got := decode[bookList](t, w)
+			if found := titles(got.Books); !reflect.DeepEqual(found, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, found)
+			}
+			if got.Total != len(tt.want) {
+				t.Errorf("Expected total %d, got %d", len(tt.want), got.Total)
+			}
+		})
+	}
+}
+
+func TestGetBooks_FilterErrors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		filter string
+		err    string
+	}{
+		{`pages gt 100`, `filter: unknown field "pages" at position 0`},
+		{`title gt 'a'`, `filter: operator "gt" is not supported for field "title" at position 6`},
+		{`quantity eq 'many'`, "filter: expected number at position 12"},
+		{`(title eq 'a'`, "filter: expected ) at position 13"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			for _, path := range []string{"/books", "/books/facets"} {
+				w := serve(r, http.MethodGet, path+"?filter="+url.QueryEscape(tt.filter), "")
+				if w.Code != http.StatusBadRequest {
+					t.Fatalf("%s: expected status 400, got %d: %s", path, w.Code, w.Body)
+				}
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("%s: expected error %q, got %q", path, tt.err, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/database"
+	"github.com/godwin/book-store-api/internal/handlers"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+	"github.com/godwin/book-store-api/internal/pricing"
+	"github.com/godwin/book-store-api/internal/storage"
+)
+
+// gatewaySecret is the secret the test router trusts identity headers with
+const gatewaySecret = "test-gateway-secret"
+
+// isbn13 returns the nth valid ISBN-13 in the 978-0 group
+func isbn13(n int) string {
+	digits := fmt.Sprintf("9780%08d", n)
+	sum := 0
+	for i, d := range digits {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(d-'0') * weight
+	}
+	return digits + fmt.Sprint((10-sum%10)%10)
+}
+
+func usd(amount string) money.Money {
+	m, err := money.Parse(amount, "USD")
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func date(year int) time.Time {
+	return time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// catalogBooks is a small catalog to serve
+func catalogBooks() []models.Book {
+	return []models.Book{
+		{Title: "Clean Code", Author: "Robert C. Martin", PublishedAt: date(2008), Price: usd("37.49"), Quantity: 15},
+		{Title: "Clean Architecture", Author: "Robert C. Martin", PublishedAt: date(2017), Price: usd("29.99")},
+		{Title: "Design Patterns", Author: "Erich Gamma, Richard Helm", PublishedAt: date(1994), Price: usd("44.99"), Quantity: 3},
+	}
+}
+
+// newRouter returns a router with the API's routes over a store holding the
+// books, and the books as stored. Covers are kept in a temporary directory.
+func newRouter(t *testing.T, books ...models.Book) (*gin.Engine, *database.MockStore, []models.Book) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := database.NewMockStore()
+	created := make([]models.Book, len(books))
+	for i, book := range books {
+		if book.ISBN == "" {
+			book.ISBN = isbn13(i + 1)
+		}
+		var err error
+		if created[i], err = store.CreateBook(book, "system"); err != nil {
+			t.Fatalf("Creating %q: %v", book.Title, err)
+		}
+	}
+
+	rates, err := money.LoadRates("../../../data/exchange_rates.json")
+	if err != nil {
+		t.Fatalf("Failed to load exchange rates: %v", err)
+	}
+	covers, err := storage.NewLocalStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to create cover store: %v", err)
+	}
+	h := handlers.NewHandler(store, []byte("test-cursor-secret"), pricing.NewPricer(rates), covers, "")
+
+	r := gin.New()
+	r.Use(handlers.GatewayIdentity(gatewaySecret))
+
+	r.GET("/exchange-rates", h.GetExchangeRates)
+	r.PUT("/exchange-rates", h.UpdateExchangeRates)
+	r.GET("/covers/*key", h.GetCoverImage)
+
+	bookRoutes := r.Group("/books")
+	bookRoutes.GET("", h.GetBooks)
+	bookRoutes.GET("/search", h.SearchBooks)
+	bookRoutes.GET("/facets", h.GetBookFacets)
+	bookRoutes.GET("/export", h.ExportBooks)
+	bookRoutes.POST("/import", h.ImportBooks)
+	bookRoutes.GET("/isbn/:isbn", h.GetBookByISBN)
+	bookRoutes.GET("/:id", h.GetBook)
+	bookRoutes.POST("", h.CreateBook)
+	bookRoutes.PUT("/:id", h.UpdateBook)
+	bookRoutes.DELETE("/:id", h.DeleteBook)
+	bookRoutes.GET("/:id/price-history", h.GetPriceHistory)
+	bookRoutes.POST("/:id/cover", h.UploadCover)
+	bookRoutes.DELETE("/:id/cover", h.DeleteCover)
+	bookRoutes.GET("/:id/stock-movements", h.GetStockMovements)
+	bookRoutes.POST("/:id/stock-movements", h.RecordStockMovement)
+	bookRoutes.POST("/:id/reservations", h.CreateReservation)
+	bookRoutes.GET("/:id/reviews", h.GetBookReviews)
+	bookRoutes.POST("/:id/reviews", h.CreateReview)
+
+	authorRoutes := r.Group("/authors")
+	authorRoutes.GET("", h.GetAuthors)
+	authorRoutes.POST("", h.CreateAuthor)
+	authorRoutes.GET("/:id", h.GetAuthor)
+	authorRoutes.PUT("/:id", h.UpdateAuthor)
+	authorRoutes.DELETE("/:id", h.DeleteAuthor)
+	authorRoutes.GET("/:id/books", h.GetAuthorBooks)
+
+	categoryRoutes := r.Group("/categories")
+	categoryRoutes.GET("", h.GetCategories)
+	categoryRoutes.POST("", h.CreateCategory)
+	categoryRoutes.POST("/import", h.ImportCategories)
+	categoryRoutes.GET("/:slug", h.GetCategory)
+	categoryRoutes.PUT("/:slug", h.UpdateCategory)
+	categoryRoutes.DELETE("/:slug", h.DeleteCategory)
+	categoryRoutes.GET("/:slug/books", h.GetCategoryBooks)
+
+	promotionRoutes := r.Group("/promotions")
+	promotionRoutes.GET("", h.GetPromotions)
+	promotionRoutes.POST("", h.CreatePromotion)
+	promotionRoutes.GET("/:id", h.GetPromotion)
+	promotionRoutes.PUT("/:id", h.UpdatePromotion)
+	promotionRoutes.DELETE("/:id", h.DeletePromotion)
+
+	cartRoutes := r.Group("/cart")
+	cartRoutes.GET("", h.GetCart)
+	cartRoutes.DELETE("", h.ClearCart)
+	cartRoutes.POST("/items", h.AddCartItem)
+	cartRoutes.PUT("/items/:bookId", h.UpdateCartItem)
+	cartRoutes.DELETE("/items/:bookId", h.RemoveCartItem)
+	cartRoutes.POST("/checkout", h.Checkout)
+
+	orderRoutes := r.Group("/orders")
+	orderRoutes.GET("", h.GetOrders)
+	orderRoutes.POST("", h.CreateOrder)
+	orderRoutes.GET("/:id", h.GetOrder)
+	orderRoutes.PUT("/:id/status", h.UpdateOrderStatus)
+
+	reviewRoutes := r.Group("/reviews")
+	reviewRoutes.GET("", h.GetReviews)
+	reviewRoutes.GET("/:id", h.GetReview)
+	reviewRoutes.PUT("/:id", h.UpdateReview)
+	reviewRoutes.DELETE("/:id", h.DeleteReview)
+	reviewRoutes.PUT("/:id/status", h.ModerateReview)
+
+	reservationRoutes := r.Group("/reservations")
+	reservationRoutes.GET("/:id", h.GetReservation)
+	reservationRoutes.POST("/:id/confirm", h.ConfirmReservation)
+	reservationRoutes.POST("/:id/release", h.ReleaseReservation)
+
+	return r, store, created
+}
+
+// asUser returns the headers the gateway sends for an authenticated user
+func asUser(id string) []string {
+	return []string{"X-User-Id", id, "X-Gateway-Secret", gatewaySecret}
+}
+
+// asAdmin returns the headers the gateway sends for an admin
+func asAdmin(id string) []string {
+	return append(asUser(id), "X-User-Role", "admin")
+}
+
+// serve sends a request to the router. headers are name, value pairs.
+func serve(r *gin.Engine, method, path, body string, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// decode reads a JSON response body
+func decode[T any](t *testing.T, w *httptest.ResponseRecorder) T {
+	t.Helper()
+	var v T
+	if err := json.Unmarshal(w.Body.Bytes(), &v); err != nil {
+		t.Fatalf("Failed to decode %s: %v", w.Body.String(), err)
+	}
+	return v
+}
+
+// errorOf returns the error message of a response
+func errorOf(t *testing.T, w *httptest.ResponseRecorder) string {
+	t.Helper()
+	return decode[struct {
+		Error string `json:"error"`
+	}](t, w).Error
+}
+
+// sorted returns a sorted copy of the strings
+func sorted(values []string) []string {
+	values = slices.Clone(values)
+	slices.Sort(values)
+	return values
+}
@@ -0,0 +1,54 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/handlers"
+)
+
+func TestGatewayIdentity(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		secret string
+		sent   string
+		user   string
+		role   string
+	}{
+		{"gateway request", "s3cret", "s3cret", "42", "admin"},
+		{"no secret sent", "s3cret", "", "", ""},
+		{"wrong secret", "s3cret", "guess", "", ""},
+		{"secret prefix", "s3cret", "s3c", "", ""},
+		{"no secret configured", "", "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := gin.New()
+			r.Use(handlers.GatewayIdentity(tt.secret))
+			r.GET("/", func(c *gin.Context) {
+				if got := c.GetHeader("X-Gateway-Secret"); got != "" {
+					t.Errorf("Expected the secret to be removed, got %q", got)
+				}
+				c.String(http.StatusOK, c.GetHeader("X-User-Id")+"|"+c.GetHeader("X-User-Role"))
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-User-Id", "42")
+			req.Header.Set("X-User-Role", "admin")
+			if tt.sent != "" {
+				req.Header.Set("X-Gateway-Secret", tt.sent)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+
+			if want := tt.user + "|" + tt.role; w.Body.String() != want {
+				t.Errorf("Expected %q, got %q", want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,280 @@
+package handlers_test
+
+import (
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// importBooks posts a file to the import endpoint
+func importBooks(r *gin.Engine, query, contentType, body string) *httptest.ResponseRecorder {
+	return serve(r, http.MethodPost, "/books/import"+query, body, "Content-Type", contentType)
+}
+
+// exportRows exports the books as CSV sorted by title, leaving out the id
+// column, which differs between stores
+func exportRows(t *testing.T, r *gin.Engine) [][]string {
+	t.Helper()
+	w := serve(r, http.MethodGet, "/books/export?sort=title", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read the export: %v", err)
+	}
+	for i, record := range records {
+		records[i] = record[1:]
+	}
+	return records
+}
+
+// importCSV is an import file for the catalog: an update of Clean Code, a
+// new book and three rows that can't be imported
+var importCSV = "isbn,title,author,published_at,price,quantity\n" +
+	isbn13(1) + ",\"Clean Code, 2nd Edition\",Robert C. Martin,2008-08-01,39.99,20\n" +
+	isbn13(4) + ",Refactoring,Martin Fowler,1999-07-08,47.99,\n" +
+	isbn13(5) + ",Working Effectively with Legacy Code,Michael Feathers,2004-09-22,forty,3\n" +
+	"978-0-00-000006-0,Domain-Driven Design,Eric Evans,2003-08-20,54.99,1\n" +
+	isbn13(7) + ",,Kent Beck,2002-11-08,39.99,2\n"
+
+func TestImportBooks(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	w := importBooks(r, "", "text/csv", importCSV)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	result := decode[models.ImportResult](t, w)
+	want := models.ImportResult{
+		Rows:    5,
+		Created: 1,
+		Updated: 1,
+		Failed:  3,
+		Errors: []models.ImportError{
+			{Line: 4, ISBN: isbn13(5), Error: `invalid price "forty": money: invalid amount`},
+			{Line: 5, ISBN: "978-0-00-000006-0", Error: "invalid ISBN"},
+			{Line: 6, ISBN: isbn13(7), Error: "invalid Title (required)"},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+
+	tests := []struct {
+		isbn     string
+		status   int
+		title    string
+		quantity int
+	}{
+		{isbn13(1), http.StatusOK, "Clean Code, 2nd Edition", 20},
+		{isbn13(4), http.StatusOK, "Refactoring", 0},
+		{isbn13(5), http.StatusNotFound, "", 0},
+		{isbn13(7), http.StatusNotFound, "", 0},
+	}
+	for _, tt := range tests {
+		w := serve(r, http.MethodGet, "/books/isbn/"+tt.isbn, "")
+		if w.Code != tt.status {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.isbn, tt.status, w.Code, w.Body)
+			continue
+		}
+		if tt.status != http.StatusOK {
+			continue
+		}
+		if book := decode[models.Book](t, w); book.Title != tt.title || book.Quantity != tt.quantity {
+			t.Errorf("%s: expected %q with %d copies, got %q with %d", tt.isbn, tt.title, tt.quantity, book.Title, book.Quantity)
+		}
+	}
+}
+
+func TestImportBooks_DryRun(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+	before := exportRows(t, r)
+
+	// The new book appears twice, so the second row would update it
+	file := importCSV + isbn13(4) + ",Refactoring,Martin Fowler,1999-07-08,44.99,2\n"
+	w := importBooks(r, "?dry_run=true", "text/csv", file)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	result := decode[models.ImportResult](t, w)
+	if !result.DryRun || result.Rows != 6 || result.Created != 1 || result.Updated != 2 || result.Failed != 3 {
+		t.Errorf("Expected 1 created, 2 updated and 3 failed in a dry run, got %+v", result)
+	}
+
+	if after := exportRows(t, r); !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected a dry run to change nothing, got %v", after)
+	}
+}
+
+func TestImportBooks_NDJSON(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	file := `{"title": "Refactoring", "author": "Martin Fowler", "isbn": "` + isbn13(4) + `", "published_at": "1999-07-08T00:00:00Z", "price": "47.99", "quantity": 5}` + "\n" +
+		"\n" +
+		`not json` + "\n" +
+		`{"title": "Clean Architecture", "author": "Robert C. Martin", "isbn": "` + isbn13(2) + `", "published_at": "2017-09-10T00:00:00Z", "price": "-1"}` + "\n"
+
+	w := importBooks(r, "", "application/x-ndjson", file)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	result := decode[models.ImportResult](t, w)
+	want := models.ImportResult{
+		Rows:    3,
+		Created: 1,
+		Failed:  2,
+		Errors: []models.ImportError{
+			{Line: 3, Error: "not a JSON object"},
+			{Line: 4, ISBN: isbn13(2), Error: "price is required and must not be negative"},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("Expected %+v, got %+v", want, result)
+	}
+}
+
+func TestImportBooks_Errors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		body        string
+		status      int
+		err         string
+	}{
+		{"unknown content type", "", "application/vnd.ms-excel", importCSV, http.StatusUnsupportedMediaType, "Send text/csv, application/x-ndjson or application/xml (ONIX), or set format to csv, ndjson or onix"},
+		{"unknown format", "?format=xlsx", "text/csv", importCSV, http.StatusBadRequest, "format must be csv, ndjson or onix"},
+		{"invalid dry_run", "?dry_run=maybe", "text/csv", importCSV, http.StatusBadRequest, "dry_run must be true or false"},
+		{"empty file", "", "text/csv", "", http.StatusBadRequest, "the file is empty"},
+		{"missing columns", "", "text/csv", "isbn,title,author\n", http.StatusBadRequest, "missing columns: published_at, price"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := importBooks(r, tt.query, tt.contentType, tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	// The format parameter wins over the Content-Type
+	if w := importBooks(r, "?format=csv", "text/plain", importCSV); w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestExportBooks(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name        string
+		query       string
+		contentType string
+		filename    string
+		lines       int
+	}{
+		{"CSV by default", "", "text/csv; charset=utf-8", "books.csv", 4},
+		{"filtered", "?author=gamma", "text/csv; charset=utf-8", "books.csv", 2},
+		{"NDJSON", "?format=ndjson&max_price=40", "application/x-ndjson", "books.ndjson", 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books/export"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			if got := w.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Expected %q, got %q", tt.contentType, got)
+			}
+			if got, want := w.Header().Get("Content-Disposition"), `attachment; filename="`+tt.filename+`"`; got != want {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+			if got := strings.Count(w.Body.String(), "\n"); got != tt.lines {
+				t.Errorf("Expected %d lines, got %d: %s", tt.lines, got, w.Body)
+			}
+		})
+	}
+
+	failures := []struct {
+		query  string
+		status int
+		err    string
+	}{
+		{"?format=xml", http.StatusBadRequest, "format must be csv or ndjson"},
+		{"?sort=pages", http.StatusBadRequest, "Invalid sort parameter"},
+		{"?currency=XYZ", http.StatusBadRequest, "unsupported currency"},
+		{"?category=missing", http.StatusNotFound, "Category not found"},
+	}
+	for _, tt := range failures {
+		w := serve(r, http.MethodGet, "/books/export"+tt.query, "")
+		if w.Code != tt.status || errorOf(t, w) != tt.err {
+			t.Errorf("%s: expected %d %q, got %d: %s", tt.query, tt.status, tt.err, w.Code, w.Body)
+		}
+	}
+}
+
+func TestExportBooks_RoundTrip(t *testing.T) {
+	eur, _ := money.Parse("34.99", "EUR")
+	books := catalogBooks()
+	books[0].Prices = []money.Money{eur}
+
+	// NDJSON holds the store's own author and category IDs, so it only goes
+	// back into the store it came from
+	tests := []struct {
+		format   string
+		newStore bool
+	}{
+		{"csv", false},
+		{"csv", true},
+		{"ndjson", false},
+	}
+
+	for _, tt := range tests {
+		name := tt.format + " into the same store"
+		if tt.newStore {
+			name = tt.format + " into a new store"
+		}
+		t.Run(name, func(t *testing.T) {
+			source, _, stored := newRouter(t, books...)
+			createCategory(t, source, `{"name": "Computers"}`)
+			fileBook(t, source, stored[2], `[{"slug": "computers"}]`)
+			target, created := source, 0
+			if tt.newStore {
+				target, _, _ = newRouter(t)
+				createCategory(t, target, `{"name": "Computers"}`)
+				created = 3
+			}
+
+			w := serve(source, http.MethodGet, "/books/export?format="+tt.format, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			w = importBooks(target, "", w.Header().Get("Content-Type"), w.Body.String())
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			if result := decode[models.ImportResult](t, w); result.Created != created || result.Updated != 3-created || result.Failed != 0 {
+				t.Fatalf("Expected %d books created and %d updated, got %+v", created, 3-created, result)
+			}
+
+			if want, got := exportRows(t, source), exportRows(t, target); !reflect.DeepEqual(got, want) {
+				t.Errorf("Expected the imported books to export as\n%v\ngot\n%v", want, got)
+			}
+		})
+	}
+}
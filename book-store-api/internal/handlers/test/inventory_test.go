@@ -0,0 +1,213 @@
+package handlers_test
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func TestRecordStockMovement(t *testing.T) {
+	tests := []struct {
+		name    string
+		book    int
+		body    string
+		headers []string
+		status  int
+		err     string
+		actor   string
+		balance int
+	}{
+		{
+			name:    "receipt by a gateway user",
+			body:    `{"type": "receipt", "quantity": 5}`,
+			headers: asUser("42"),
+			status:  http.StatusCreated,
+			actor:   "42",
+			balance: 20,
+		},
+		{
+			name:    "actor in the body is ignored",
+			body:    `{"type": "sale", "quantity": 2, "actor": "someone-else"}`,
+			headers: asUser("42"),
+			status:  http.StatusCreated,
+			actor:   "42",
+			balance: 13,
+		},
+		{
+			name:    "identity without the gateway secret is dropped",
+			body:    `{"type": "adjustment", "quantity": -1, "reason": "damaged"}`,
+			headers: []string{"X-User-Id", "42"},
+			status:  http.StatusCreated,
+			actor:   "internal",
+			balance: 14,
+		},
+		{
+			name:   "unknown type",
+			body:   `{"type": "theft", "quantity": 1}`,
+			status: http.StatusBadRequest,
+			err:    "Invalid stock movement",
+		},
+		{
+			name:   "missing quantity",
+			body:   `{"type": "receipt"}`,
+			status: http.StatusBadRequest,
+			err:    "Invalid stock movement",
+		},
+		{
+			name:   "selling more than is on hand",
+			book:   1,
+			body:   `{"type": "sale", "quantity": 1}`,
+			status: http.StatusConflict,
+			err:    "Insufficient stock",
+		},
+		{
+			name:   "unknown book",
+			book:   -1,
+			body:   `{"type": "receipt", "quantity": 1}`,
+			status: http.StatusNotFound,
+			err:    "Book not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			id := "missing"
+			if tt.book >= 0 {
+				id = books[tt.book].ID
+			}
+
+			w := serve(r, http.MethodPost, "/books/"+id+"/stock-movements", tt.body, tt.headers...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.err != "" {
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("Expected error %q, got %q", tt.err, got)
+				}
+				return
+			}
+
+			movement := decode[models.StockMovement](t, w)
+			if movement.Actor != tt.actor {
+				t.Errorf("Expected actor %q, got %q", tt.actor, movement.Actor)
+			}
+			if movement.BalanceAfter != tt.balance {
+				t.Errorf("Expected balance %d, got %d", tt.balance, movement.BalanceAfter)
+			}
+		})
+	}
+}
+
+func TestGetStockMovements(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	if w := serve(r, http.MethodPost, "/books/"+books[0].ID+"/stock-movements", `{"type": "sale", "quantity": 3}`, asUser("7")...); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+
+	w := serve(r, http.MethodGet, "/books/"+books[0].ID+"/stock-movements", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	list := decode[struct {
+		Total     int                    `json:"total"`
+		Movements []models.StockMovement `json:"movements"`
+	}](t, w)
+	if list.Total != len(list.Movements) || list.Total == 0 {
+		t.Fatalf("Expected movements, got %+v", list)
+	}
+	last := list.Movements[len(list.Movements)-1]
+	if last.Type != models.MovementSale || last.Delta != -3 || last.Actor != "7" {
+		t.Errorf("Expected a sale of 3 by 7, got %+v", last)
+	}
+
+	w = serve(r, http.MethodGet, "/books/missing/stock-movements", "")
+	if w.Code != http.StatusNotFound || errorOf(t, w) != "Book not found" {
+		t.Errorf("Expected 404 Book not found, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestBookStockChanges_RecordTheCaller(t *testing.T) {
+	r, store, _ := newRouter(t)
+
+	w := serve(r, http.MethodPost, "/books", `{"title": "Refactoring", "author": "Martin Fowler", "isbn": "`+isbn13(1)+`", "published_at": "1999-07-08T00:00:00Z", "price": "39.99", "quantity": 4}`, asUser("42")...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	book := decode[models.Book](t, w)
+
+	w = serve(r, http.MethodPut, "/books/"+book.ID, `{"title": "Refactoring", "author": "Martin Fowler", "isbn": "`+isbn13(1)+`", "published_at": "1999-07-08T00:00:00Z", "price": "34.99", "quantity": 6}`, asUser("7")...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+
+	movements, _ := store.GetStockMovements(book.ID)
+	history, _ := store.GetPriceHistory(book.ID)
+	if len(movements) != 2 || len(history) != 2 {
+		t.Fatalf("Expected two stock and price changes, got %+v and %+v", movements, history)
+	}
+	for i, want := range []string{"42", "7"} {
+		if movements[i].Actor != want || history[i].Actor != want {
+			t.Errorf("Expected change %d by %s, got %q and %q", i, want, movements[i].Actor, history[i].Actor)
+		}
+	}
+}
+
+func TestRecordStockMovement_UpdatesTheBook(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	for _, body := range []string{
+		`{"type": "receipt", "quantity": 10}`,
+		`{"type": "sale", "quantity": 4}`,
+		`{"type": "return", "quantity": 1, "reason": "damaged in transit"}`,
+	} {
+		if w := serve(r, http.MethodPost, "/books/"+books[0].ID+"/stock-movements", body); w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+		}
+	}
+
+	w := serve(r, http.MethodGet, "/books/"+books[0].ID, "")
+	if got := decode[models.Book](t, w).Quantity; got != 22 {
+		t.Errorf("Expected 22 copies, got %d", got)
+	}
+}
+
+func TestRecordStockMovement_ConcurrentSales(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	path := "/books/" + books[2].ID + "/stock-movements"
+
+	// Three copies on hand, so only three of the sales can go through
+	codes := make([]int, 10)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = serve(r, http.MethodPost, path, `{"type": "sale", "quantity": 1}`).Code
+		}()
+	}
+	wg.Wait()
+
+	sold, refused := 0, 0
+	for _, code := range codes {
+		switch code {
+		case http.StatusCreated:
+			sold++
+		case http.StatusConflict:
+			refused++
+		default:
+			t.Errorf("Unexpected status %d", code)
+		}
+	}
+	if sold != 3 || refused != 7 {
+		t.Errorf("Expected 3 sales and 7 refused, got %d and %d", sold, refused)
+	}
+
+	w := serve(r, http.MethodGet, "/books/"+books[2].ID, "")
+	if got := decode[models.Book](t, w).Quantity; got != 0 {
+		t.Errorf("Expected no copies left, got %d", got)
+	}
+}
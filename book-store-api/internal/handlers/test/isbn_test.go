@@ -0,0 +1,136 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/isbn"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// useSampleRanges hyphenates ISBNs with the sample range table, as the
+// server does at startup, until the test ends
+func useSampleRanges(t *testing.T) {
+	t.Helper()
+	ranges, err := isbn.LoadRanges("../../../data/isbn_ranges.sample.xml")
+	if err != nil {
+		t.Fatalf("Failed to load ISBN ranges: %v", err)
+	}
+	isbn.SetRanges(ranges)
+	t.Cleanup(func() { isbn.SetRanges(&isbn.Ranges{}) })
+}
+
+func TestCreateBook_ISBN(t *testing.T) {
+	useSampleRanges(t)
+
+	tests := []struct {
+		name       string
+		isbn       string
+		want       string
+		isbn10     string
+		hyphenated string
+	}{
+		{"ISBN-13", "9780132350884", "9780132350884", "0132350882", "978-0-13-235088-4"},
+		{"hyphenated", "978-0-13-235088-4", "9780132350884", "0132350882", "978-0-13-235088-4"},
+		{"ISBN-10", "0-13-235088-2", "9780132350884", "0132350882", "978-0-13-235088-4"},
+		{"English group", "1491950358", "9781491950357", "1491950358", "978-1-4919-5035-7"},
+		{"979 prefix", "979-8-218-12345-1", "9798218123451", "", "979-8-218-12345-1"},
+		{"group not in the table", "9782070360024", "9782070360024", "2070360024", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, _ := newRouter(t)
+
+			body := `{"title": "Clean Code", "author": "Robert C. Martin", "isbn": "` + tt.isbn + `", "published_at": "2008-08-01T00:00:00Z", "price": "37.49"}`
+			w := serve(r, http.MethodPost, "/books", body)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+			}
+			book := decode[models.Book](t, w)
+			if book.ISBN != tt.want || book.ISBN10 != tt.isbn10 || book.Hyphenated != tt.hyphenated {
+				t.Errorf("Expected %s, %q and %q, got %s, %q and %q",
+					tt.want, tt.isbn10, tt.hyphenated, book.ISBN, book.ISBN10, book.Hyphenated)
+			}
+		})
+	}
+}
+
+func TestGetBookByISBN(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	want := books[0]
+
+	tests := []struct {
+		name   string
+		isbn   string
+		status int
+		err    string
+	}{
+		{"ISBN-13", want.ISBN, http.StatusOK, ""},
+		{"ISBN-10", want.ISBN10, http.StatusOK, ""},
+		{"with hyphens", want.ISBN[:3] + "-" + want.ISBN[3:], http.StatusOK, ""},
+		{"bad check digit", want.ISBN[:12] + "0", http.StatusBadRequest, "Invalid ISBN"},
+		{"not an ISBN", "clean-code", http.StatusBadRequest, "Invalid ISBN"},
+		{"no such book", "9780201633610", http.StatusNotFound, "Book not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books/isbn/"+tt.isbn, "")
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.err != "" {
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("Expected error %q, got %q", tt.err, got)
+				}
+				return
+			}
+			if got := decode[models.Book](t, w); got.ID != want.ID {
+				t.Errorf("Expected %s, got %s", want.Title, got.Title)
+			}
+		})
+	}
+}
+
+func TestBookWrites_ISBNErrors(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	book := func(isbn string) string {
+		return `{"title": "Refactoring", "author": "Martin Fowler", "isbn": "` + isbn + `", "published_at": "1999-07-08T00:00:00Z", "price": "39.99"}`
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		isbn   string
+		status int
+		err    string
+	}{
+		{"create with a bad check digit", http.MethodPost, "/books", "9780201485670", http.StatusBadRequest, "Invalid ISBN"},
+		{"create with too few digits", http.MethodPost, "/books", "978020148567", http.StatusBadRequest, "Invalid ISBN"},
+		{"create with a taken ISBN", http.MethodPost, "/books", books[0].ISBN, http.StatusConflict, "A book with this ISBN already exists"},
+		{"create with a taken ISBN-10", http.MethodPost, "/books", books[0].ISBN10, http.StatusConflict, "A book with this ISBN already exists"},
+		{"update with a bad check digit", http.MethodPut, "/books/" + books[1].ID, "9780201485670", http.StatusBadRequest, "Invalid ISBN"},
+		{"update to another book's ISBN", http.MethodPut, "/books/" + books[1].ID, books[0].ISBN, http.StatusConflict, "A book with this ISBN already exists"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.path, book(tt.isbn))
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	// Keeping its own ISBN, in any form, isn't a conflict
+	w := serve(r, http.MethodPut, "/books/"+books[1].ID, book(books[1].ISBN10))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+}
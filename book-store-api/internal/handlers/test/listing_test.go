@@ -0,0 +1,95 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// bookList is the body of GET /books
+type bookList struct {
+	Total      int           `json:"total"`
+	Limit      int           `json:"limit"`
+	Offset     int           `json:"offset"`
+	Books      []models.Book `json:"books"`
+	NextCursor *string       `json:"next_cursor"`
+	PrevCursor *string       `json:"prev_cursor"`
+}
+
+func titles(books []models.Book) []string {
+	found := make([]string, len(books))
+	for i, book := range books {
+		found[i] = book.Title
+	}
+	return found
+}
+
+func TestGetBooks_Listing(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name   string
+		query  string
+		total  int
+		limit  int
+		offset int
+		want   []string
+	}{
+		{"defaults to creation order", "", 3, 10, 0, []string{"Clean Code", "Clean Architecture", "Design Patterns"}},
+		{"by title", "sort=title", 3, 10, 0, []string{"Clean Architecture", "Clean Code", "Design Patterns"}},
+		{"by price descending", "sort=-price", 3, 10, 0, []string{"Design Patterns", "Clean Code", "Clean Architecture"}},
+		{"newest by an author", "sort=-published_at&author=martin", 2, 10, 0, []string{"Clean Architecture", "Clean Code"}},
+		{"title filter", "title=clean", 2, 10, 0, []string{"Clean Code", "Clean Architecture"}},
+		{"price bounds", "min_price=30&max_price=40", 1, 10, 0, []string{"Clean Code"}},
+		{"paged", "sort=title&limit=2&offset=1", 3, 2, 1, []string{"Clean Code", "Design Patterns"}},
+		{"past the end", "offset=5", 3, 10, 5, []string{}},
+		{"invalid paging is ignored", "limit=-1&offset=x", 3, 10, 0, []string{"Clean Code", "Clean Architecture", "Design Patterns"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books?"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			got := decode[bookList](t, w)
+			if got.Total != tt.total || got.Limit != tt.limit || got.Offset != tt.offset {
+				t.Errorf("Expected total %d, limit %d, offset %d, got %d, %d, %d",
+					tt.total, tt.limit, tt.offset, got.Total, got.Limit, got.Offset)
+			}
+			if found := titles(got.Books); !reflect.DeepEqual(found, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, found)
+			}
+		})
+	}
+}
+
+func TestGetBooks_Errors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		err    string
+	}{
+		{"unknown sort field", "sort=isbn", http.StatusBadRequest, "Invalid sort parameter"},
+		{"bare minus", "sort=-", http.StatusBadRequest, "Invalid sort parameter"},
+		{"empty sort field", "sort=title,,price", http.StatusBadRequest, "Invalid sort parameter"},
+		{"unknown category", "category=missing", http.StatusNotFound, "Category not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books?"+tt.query, "")
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
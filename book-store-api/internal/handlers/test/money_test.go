@@ -0,0 +1,122 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+)
+
+// bookBody returns a book's JSON with the given price fields
+func bookBody(n int, prices string) string {
+	return `{"title": "Refactoring", "author": "Martin Fowler", "isbn": "` + isbn13(n) + `", "published_at": "1999-07-08T00:00:00Z", ` + prices + `}`
+}
+
+func TestCreateBook_PriceFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		prices   string
+		price    string
+		currency string
+		fixed    map[string]any
+	}{
+		{name: "decimal string", prices: `"price": "39.99"`, price: "39.99", currency: "USD"},
+		{name: "number", prices: `"price": 39.99`, price: "39.99", currency: "USD"},
+		{name: "in another currency", prices: `"price": "34.99", "currency": "EUR"`, price: "34.99", currency: "EUR"},
+		{name: "lowercase currency", prices: `"price": "34.99", "currency": "eur"`, price: "34.99", currency: "EUR"},
+		{name: "no minor units", prices: `"price": "4500", "currency": "JPY"`, price: "4500", currency: "JPY"},
+		{name: "legacy object", prices: `"price": {"amount": "29.99", "currency": "GBP"}`, price: "29.99", currency: "GBP"},
+		{
+			name:     "fixed prices",
+			prices:   `"price": "39.99", "prices": {"GBP": "31.99", "EUR": "36.99"}`,
+			price:    "39.99",
+			currency: "USD",
+			fixed:    map[string]any{"EUR": "36.99", "GBP": "31.99"},
+		},
+		{
+			name:     "legacy fixed prices",
+			prices:   `"price": "39.99", "prices": [{"amount": "31.99", "currency": "GBP"}]`,
+			price:    "39.99",
+			currency: "USD",
+			fixed:    map[string]any{"GBP": "31.99"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, _ := newRouter(t)
+
+			w := serve(r, http.MethodPost, "/books", bookBody(1, tt.prices))
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+			}
+			created := decode[map[string]any](t, w)
+
+			// Read it back too, so both directions use the same shape
+			w = serve(r, http.MethodGet, "/books/"+created["id"].(string), "")
+			for _, book := range []map[string]any{created, decode[map[string]any](t, w)} {
+				if book["price"] != tt.price || book["currency"] != tt.currency {
+					t.Errorf("Expected price %q and currency %q, got %#v and %#v", tt.price, tt.currency, book["price"], book["currency"])
+				}
+				if fixed, _ := book["prices"].(map[string]any); (len(fixed) > 0 || tt.fixed != nil) && !reflect.DeepEqual(fixed, tt.fixed) {
+					t.Errorf("Expected prices %v, got %#v", tt.fixed, book["prices"])
+				}
+			}
+		})
+	}
+}
+
+func TestCreateBook_InvalidPrices(t *testing.T) {
+	tests := []struct {
+		name   string
+		prices string
+		err    string
+	}{
+		{"no price", `"quantity": 1`, "price is required and must not be negative"},
+		{"null price", `"price": null`, "price is required and must not be negative"},
+		{"negative price", `"price": "-1.00"`, "price is required and must not be negative"},
+		{"not a number", `"price": "cheap"`, "Invalid book data"},
+		{"too precise", `"price": "39.999"`, "Invalid book data"},
+		{"fractional yen", `"price": "4500.5", "currency": "JPY"`, "Invalid book data"},
+		{"unknown currency", `"price": "39.99", "currency": "DOLLARS"`, "Invalid book data"},
+		{"fixed price in the main currency", `"price": "39.99", "prices": {"USD": "35.00"}`, "prices must be non-negative and in distinct currencies"},
+		{"negative fixed price", `"price": "39.99", "prices": {"GBP": "-1.00"}`, "prices must be non-negative and in distinct currencies"},
+		{"repeated legacy fixed prices", `"price": "39.99", "prices": [{"amount": "31.99", "currency": "GBP"}, {"amount": "30.99", "currency": "GBP"}]`, "prices must be non-negative and in distinct currencies"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()[0])
+
+			for _, req := range []struct{ method, path string }{
+				{http.MethodPost, "/books"},
+				{http.MethodPut, "/books/" + books[0].ID},
+			} {
+				w := serve(r, req.method, req.path, bookBody(9, tt.prices))
+				if w.Code != http.StatusBadRequest {
+					t.Fatalf("%s: expected status 400, got %d: %s", req.method, w.Code, w.Body)
+				}
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("%s: expected error %q, got %q", req.method, tt.err, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOrder_AmountsAreDecimalStrings(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	order := placeOrder(t, r, books[0].ID, 2, asUser("42")...)
+
+	w := serve(r, http.MethodGet, "/orders/"+order.ID, "", asUser("42")...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	body := decode[map[string]any](t, w)
+	if body["total"] != "74.98" || body["discount_total"] != "0.00" || body["currency"] != "USD" {
+		t.Errorf("Expected a total of \"74.98\" USD, got %#v", body)
+	}
+	item := body["items"].([]any)[0].(map[string]any)
+	if item["unit_price"] != "37.49" || item["subtotal"] != "74.98" {
+		t.Errorf("Expected decimal string amounts on the line, got %#v", item)
+	}
+}
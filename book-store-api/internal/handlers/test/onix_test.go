@@ -0,0 +1,172 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// onixProduct is a product record with an ISBN-13, a distinctive title, a
+// publication date and the supply details given
+func onixProduct(ref, code, title, contributors, supply string) string {
+	return `<Product>
+	<RecordReference>` + ref + `</RecordReference>
+	<NotificationType>03</NotificationType>
+	<ProductIdentifier><ProductIDType>15</ProductIDType><IDValue>` + code + `</IDValue></ProductIdentifier>
+	<DescriptiveDetail>
+		<TitleDetail><TitleType>01</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel><TitleText>` + title + `</TitleText></TitleElement></TitleDetail>
+		` + contributors + `
+	</DescriptiveDetail>
+	<PublishingDetail><PublishingDate><PublishingDateRole>01</PublishingDateRole><Date>20081203</Date></PublishingDate></PublishingDetail>
+	<ProductSupply><SupplyDetail>` + supply + `</SupplyDetail></ProductSupply>
+</Product>`
+}
+
+func onixMessage(products ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<ONIXMessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/reference">
+<Header><Sender><SenderName>Acme Publishing</SenderName></Sender></Header>
+` + strings.Join(products, "\n") + `
+</ONIXMessage>`
+}
+
+// onixFeed updates Clean Code, adds a new book with data that has no place
+// in a book, and has two products that can't be imported
+var onixFeed = onixMessage(
+	onixProduct("acme-1", isbn13(1), "Clean Code",
+		`<Contributor><SequenceNumber>1</SequenceNumber><ContributorRole>A01</ContributorRole><PersonName>Robert C. Martin</PersonName></Contributor>`,
+		`<Stock><OnHand>20</OnHand></Stock>
+		<Price><PriceType>02</PriceType><PriceAmount>39.99</PriceAmount><CurrencyCode>USD</CurrencyCode></Price>
+		<Price><PriceType>02</PriceType><PriceAmount>36.50</PriceAmount><CurrencyCode>EUR</CurrencyCode></Price>`),
+	onixProduct("acme-2", "9780306406157", "Thinking in Systems",
+		`<Contributor><SequenceNumber>1</SequenceNumber><ContributorRole>A01</ContributorRole><PersonName>Donella Meadows</PersonName></Contributor>
+		<Contributor><SequenceNumber>2</SequenceNumber><ContributorRole>A23</ContributorRole><PersonName>Diana Wright</PersonName></Contributor>`,
+		`<ProductAvailability>21</ProductAvailability>
+		<Price><PriceType>02</PriceType><PriceAmount>19.95</PriceAmount><CurrencyCode>USD</CurrencyCode></Price>
+		<Price><PriceType>07</PriceType><PriceAmount>14.95</PriceAmount><CurrencyCode>USD</CurrencyCode></Price>`),
+	onixProduct("acme-3", "9780306406158", "Misprinted", "",
+		`<Price><PriceType>02</PriceType><PriceAmount>9.99</PriceAmount><CurrencyCode>USD</CurrencyCode></Price>`),
+	onixProduct("acme-4", isbn13(4), "No Price", "", `<ProductAvailability>21</ProductAvailability>`),
+)
+
+// withoutLines drops the line numbers of import errors, which depend on the
+// layout of the message
+func withoutLines(errs []models.ImportError) []models.ImportError {
+	stripped := make([]models.ImportError, len(errs))
+	for i, e := range errs {
+		e.Line = 0
+		stripped[i] = e
+	}
+	return stripped
+}
+
+func TestImportBooks_ONIX(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	w := importBooks(r, "", "application/xml", onixFeed)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	result := decode[models.ImportResult](t, w)
+	if result.Rows != 4 || result.Created != 1 || result.Updated != 1 || result.Failed != 2 {
+		t.Errorf("Expected 1 created, 1 updated and 2 failed of 4, got %+v", result)
+	}
+	wantErrors := []models.ImportError{
+		{Record: "acme-3", ISBN: "9780306406158", Error: "invalid ISBN 9780306406158"},
+		{Record: "acme-4", ISBN: isbn13(4), Error: "no recommended retail price"},
+	}
+	if got := withoutLines(result.Errors); !reflect.DeepEqual(got, wantErrors) {
+		t.Errorf("Expected errors %+v, got %+v", wantErrors, got)
+	}
+	wantUnmapped := []models.ImportError{
+		{Record: "acme-2", ISBN: "9780306406157", Error: "contributor Diana Wright in role A23 was skipped"},
+		{Record: "acme-2", ISBN: "9780306406157", Error: "price type 07 was skipped"},
+	}
+	if got := withoutLines(result.Unmapped); !reflect.DeepEqual(got, wantUnmapped) {
+		t.Errorf("Expected unmapped data %+v, got %+v", wantUnmapped, got)
+	}
+	for _, e := range append(result.Errors, result.Unmapped...) {
+		if e.Line == 0 {
+			t.Errorf("Expected a line number for %s, got none", e.Record)
+		}
+	}
+
+	updated := decode[models.Book](t, serve(r, http.MethodGet, "/books/isbn/"+isbn13(1), ""))
+	if updated.Price.Decimal() != "39.99" || updated.Quantity != 20 || len(updated.Prices) != 1 || updated.Prices[0].String() != "36.50 EUR" {
+		t.Errorf("Expected Clean Code at 39.99 and 36.50 EUR with 20 copies, got %+v", updated)
+	}
+
+	created := decode[models.Book](t, serve(r, http.MethodGet, "/books/isbn/9780306406157", ""))
+	if created.Title != "Thinking in Systems" || created.Author != "Donella Meadows" || created.Price.Decimal() != "19.95" {
+		t.Errorf("Expected Thinking in Systems by Donella Meadows at 19.95, got %+v", created)
+	}
+	// Availability without a stock count leaves the quantity alone, so a new book has none
+	if created.Quantity != 0 || created.PublishedAt.Format("2006-01-02") != "2008-12-03" {
+		t.Errorf("Expected no copies published 2008-12-03, got %d and %s", created.Quantity, created.PublishedAt)
+	}
+}
+
+func TestImportBooks_ONIXDryRun(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+	before := exportRows(t, r)
+
+	// format wins over a Content-Type that doesn't say it's XML
+	w := importBooks(r, "?format=onix&dry_run=true", "text/plain", onixFeed)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	result := decode[models.ImportResult](t, w)
+	if !result.DryRun || result.Created != 1 || result.Updated != 1 || result.Failed != 2 || len(result.Unmapped) != 2 {
+		t.Errorf("Expected the dry run to report the same as an import, got %+v", result)
+	}
+
+	if after := exportRows(t, r); !reflect.DeepEqual(after, before) {
+		t.Errorf("Expected a dry run to change nothing, got %v", after)
+	}
+}
+
+func TestImportBooks_ONIXErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		err     string
+		created int
+	}{
+		{"not ONIX", `<catalog><book/></catalog>`, "not an ONIX 3.0 message", 0},
+		{"ONIX 2.1", `<ONIXMessage release="2.1"><Product/></ONIXMessage>`, "not an ONIX 3.0 message: release 2.1 isn't supported", 0},
+		{"empty body", ``, "not an ONIX 3.0 message", 0},
+		{
+			"cut short",
+			strings.TrimSuffix(onixMessage(onixProduct("acme-1", isbn13(4), "Refactoring",
+				`<Contributor><SequenceNumber>1</SequenceNumber><ContributorRole>A01</ContributorRole><PersonName>Martin Fowler</PersonName></Contributor>`,
+				`<Price><PriceType>02</PriceType><PriceAmount>47.99</PriceAmount><CurrencyCode>USD</CurrencyCode></Price>`), "<Product><RecordReference>acme-2"), "\n</ONIXMessage>"),
+			"XML syntax error on line 15: unexpected EOF",
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, _ := newRouter(t, catalogBooks()...)
+
+			w := importBooks(r, "", "application/xml", tt.message)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body)
+			}
+			body := decode[struct {
+				Error  string              `json:"error"`
+				Result models.ImportResult `json:"result"`
+			}](t, w)
+			if body.Error != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, body.Error)
+			}
+			// Products before the message broke off are still imported
+			if body.Result.Created != tt.created {
+				t.Errorf("Expected %d created before the error, got %+v", tt.created, body.Result)
+			}
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// orderList is the body of GET /orders
+type orderList struct {
+	Total  int            `json:"total"`
+	Skip   int            `json:"skip"`
+	Limit  int            `json:"limit"`
+	Orders []models.Order `json:"orders"`
+}
+
+// placeOrder orders copies of a book and fails the test if it can't
+func placeOrder(t *testing.T, r *gin.Engine, bookID string, quantity int, headers ...string) models.Order {
+	t.Helper()
+	body := fmt.Sprintf(`{"items": [{"product_id": %q, "quantity": %d}], "shipping_address": "1 Main St"}`, bookID, quantity)
+	w := serve(r, http.MethodPost, "/orders", body, headers...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	return decode[models.Order](t, w)
+}
+
+func TestCreateOrder(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	body := fmt.Sprintf(`{"items": [{"product_id": %q, "quantity": 1}, {"product_id": %q, "quantity": 1}, {"product_id": %q, "quantity": 2}], "shipping_address": "1 Main St"}`,
+		books[0].ID, books[2].ID, books[0].ID)
+	w := serve(r, http.MethodPost, "/orders", body, asUser("42")...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	order := decode[models.Order](t, w)
+
+	if order.CustomerID != "user:42" || order.Status != models.OrderPending {
+		t.Errorf("Expected a pending order for user:42, got %s for %s", order.Status, order.CustomerID)
+	}
+	if len(order.Items) != 2 || order.Items[0].Quantity != 3 {
+		t.Fatalf("Expected repeated books on one line, got %+v", order.Items)
+	}
+	if got := order.Items[0].Subtotal.Decimal(); got != "112.47" {
+		t.Errorf("Expected a subtotal of 112.47, got %s", got)
+	}
+	if order.Total.Decimal() != "157.46" || order.Currency != "USD" {
+		t.Errorf("Expected a total of 157.46 USD, got %s %s", order.Total.Decimal(), order.Currency)
+	}
+
+	book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, ""))
+	if book.Reserved != 3 {
+		t.Errorf("Expected 3 copies reserved, got %d", book.Reserved)
+	}
+}
+
+func TestCreateOrder_Customer(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		headers  []string
+		customer string
+	}{
+		{"customer", `"customer_id": "user:7"`, asUser("42"), "user:42"},
+		{"admin for a customer", `"customer_id": "user:7"`, asAdmin("1"), "user:7"},
+		{"admin for themselves", `"customer_id": ""`, asAdmin("1"), "user:1"},
+		{"role without the gateway secret", `"customer_id": "user:7"`, []string{"X-User-Id", "1", "X-User-Role", "admin", "X-Cart-Session", "abc"}, "session:abc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			body := fmt.Sprintf(`{%s, "items": [{"product_id": %q, "quantity": 1}], "shipping_address": "1 Main St"}`, tt.body, books[0].ID)
+
+			w := serve(r, http.MethodPost, "/orders", body, tt.headers...)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+			}
+			if got := decode[models.Order](t, w).CustomerID; got != tt.customer {
+				t.Errorf("Expected customer %q, got %q", tt.customer, got)
+			}
+		})
+	}
+}
+
+func TestCreateOrder_Errors(t *testing.T) {
+	pounds := catalogBooks()[2]
+	pounds.Title = "Design Patterns (UK)"
+	pounds.Price, _ = money.Parse("39.99", "GBP")
+	r, _, books := newRouter(t, append(catalogBooks(), pounds)...)
+
+	item := func(book string, quantity int) string {
+		return fmt.Sprintf(`{"product_id": %q, "quantity": %d}`, book, quantity)
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		err    string
+	}{
+		{"no items", `{"items": [], "shipping_address": "1 Main St"}`, http.StatusBadRequest, "Invalid order data"},
+		{"no shipping address", `{"items": [` + item(books[0].ID, 1) + `]}`, http.StatusBadRequest, "Invalid order data"},
+		{"no quantity", `{"items": [{"product_id": "` + books[0].ID + `"}], "shipping_address": "1 Main St"}`, http.StatusBadRequest, "Invalid order data"},
+		{"unknown book", `{"items": [` + item("missing", 1) + `], "shipping_address": "1 Main St"}`, http.StatusNotFound, "book not found: missing"},
+		{"out of stock", `{"items": [` + item(books[0].ID, 1) + `, ` + item(books[1].ID, 1) + `], "shipping_address": "1 Main St"}`, http.StatusConflict, "insufficient stock: Clean Architecture"},
+		{"lines add up past the stock", `{"items": [` + item(books[2].ID, 2) + `, ` + item(books[2].ID, 2) + `], "shipping_address": "1 Main St"}`, http.StatusConflict, "insufficient stock: Design Patterns"},
+		{"mixed currencies", `{"items": [` + item(books[2].ID, 1) + `, ` + item(books[3].ID, 1) + `], "shipping_address": "1 Main St"}`, http.StatusConflict, "All items in an order must be priced in the same currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodPost, "/orders", tt.body, asUser("42")...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	// Nothing was held by the failed orders
+	for _, book := range books {
+		if got := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+book.ID, "")); got.Reserved != 0 {
+			t.Errorf("Expected no copies of %s reserved, got %d", book.Title, got.Reserved)
+		}
+	}
+}
+
+func TestUpdateOrderStatus_Stock(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []models.OrderStatus
+		quantity int // Copies on hand at the end
+	}{
+		{"completed orders sell the copies", []models.OrderStatus{models.OrderProcessing, models.OrderCompleted}, 12},
+		{"cancelled orders return them", []models.OrderStatus{models.OrderCancelled}, 15},
+		{"cancelled while processing", []models.OrderStatus{models.OrderProcessing, models.OrderCancelled}, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			order := placeOrder(t, r, books[0].ID, 3, asUser("42")...)
+
+			for _, status := range tt.statuses {
+				w := serve(r, http.MethodPut, "/orders/"+order.ID+"/status", `{"status": "`+string(status)+`"}`, asAdmin("1")...)
+				if w.Code != http.StatusOK {
+					t.Fatalf("Moving to %s: expected status 200, got %d: %s", status, w.Code, w.Body)
+				}
+				if got := decode[models.Order](t, w).Status; got != status {
+					t.Errorf("Expected %s, got %s", status, got)
+				}
+			}
+
+			book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, ""))
+			if book.Quantity != tt.quantity || book.Reserved != 0 {
+				t.Errorf("Expected %d on hand and none reserved, got %d and %d", tt.quantity, book.Quantity, book.Reserved)
+			}
+		})
+	}
+}
+
+func TestUpdateOrderStatus(t *testing.T) {
+	tests := []struct {
+		name    string
+		order   string // Status the order is in first
+		id      string // Order to update, if not the one placed
+		query   string
+		body    string
+		headers []string
+		status  int
+		err     string
+	}{
+		{name: "status as a query parameter", query: "?status=processing", headers: asAdmin("1"), status: http.StatusOK},
+		{name: "customer cancels their order", body: `{"status": "cancelled"}`, headers: asUser("42"), status: http.StatusOK},
+		{name: "customer can't process it", body: `{"status": "processing"}`, headers: asUser("42"), status: http.StatusForbidden, err: "Only admins can move orders to processing"},
+		{name: "another customer's order", body: `{"status": "cancelled"}`, headers: asUser("7"), status: http.StatusNotFound, err: "Order not found"},
+		{name: "skipping a step", body: `{"status": "completed"}`, headers: asAdmin("1"), status: http.StatusConflict, err: "invalid order status transition: pending to completed"},
+		{name: "reopening a cancelled order", order: "cancelled", body: `{"status": "pending"}`, headers: asAdmin("1"), status: http.StatusConflict, err: "invalid order status transition: cancelled to pending"},
+		{name: "unknown status", body: `{"status": "shipped"}`, headers: asAdmin("1"), status: http.StatusBadRequest, err: "Invalid order status"},
+		{name: "no status", body: `{}`, headers: asAdmin("1"), status: http.StatusBadRequest, err: "status is required"},
+		{name: "unknown order", id: "missing", body: `{"status": "cancelled"}`, headers: asAdmin("1"), status: http.StatusNotFound, err: "Order not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			order := placeOrder(t, r, books[0].ID, 1, asUser("42")...)
+			if tt.order != "" {
+				if w := serve(r, http.MethodPut, "/orders/"+order.ID+"/status?status="+tt.order, "", asAdmin("1")...); w.Code != http.StatusOK {
+					t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+				}
+			}
+
+			id := order.ID
+			if tt.id != "" {
+				id = tt.id
+			}
+			w := serve(r, http.MethodPut, "/orders/"+id+"/status"+tt.query, tt.body, tt.headers...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.err != "" {
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("Expected error %q, got %q", tt.err, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGetOrders(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	first := placeOrder(t, r, books[0].ID, 1, asUser("42")...)
+	placeOrder(t, r, books[0].ID, 1, asUser("42")...)
+	placeOrder(t, r, books[2].ID, 1, asUser("7")...)
+	if w := serve(r, http.MethodPut, "/orders/"+first.ID+"/status", `{"status": "cancelled"}`, asUser("42")...); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		name    string
+		query   string
+		headers []string
+		total   int
+		count   int
+	}{
+		{"customer sees their own", "", asUser("42"), 2, 2},
+		{"customer_id is ignored for customers", "?customer_id=user:7", asUser("42"), 2, 2},
+		{"by status", "?status=pending", asUser("42"), 1, 1},
+		{"admin sees every order", "", asAdmin("1"), 3, 3},
+		{"admin filters by customer", "?customer_id=user:7", asAdmin("1"), 1, 1},
+		{"paged", "?skip=1&limit=1", asAdmin("1"), 3, 1},
+		{"past the end", "?skip=5", asAdmin("1"), 3, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/orders"+tt.query, "", tt.headers...)
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			list := decode[orderList](t, w)
+			if list.Total != tt.total || len(list.Orders) != tt.count {
+				t.Errorf("Expected %d of %d orders, got %d of %d", tt.count, tt.total, len(list.Orders), list.Total)
+			}
+		})
+	}
+}
+
+func TestGetOrders_Errors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		query string
+		err   string
+	}{
+		{"skip=-1", "skip must be a non-negative integer"},
+		{"skip=x", "skip must be a non-negative integer"},
+		{"limit=0", "limit must be between 1 and 100"},
+		{"limit=101", "limit must be between 1 and 100"},
+		{"status=shipped", "Invalid order status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/orders?"+tt.query, "", asAdmin("1")...)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestGetOrder(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	order := placeOrder(t, r, books[0].ID, 1, asUser("42")...)
+
+	tests := []struct {
+		name    string
+		id      string
+		headers []string
+		status  int
+	}{
+		{"owner", order.ID, asUser("42"), http.StatusOK},
+		{"admin", order.ID, asAdmin("1"), http.StatusOK},
+		{"another customer", order.ID, asUser("7"), http.StatusNotFound},
+		{"anonymous", order.ID, nil, http.StatusNotFound},
+		{"unknown order", "missing", asAdmin("1"), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/orders/"+tt.id, "", tt.headers...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.status == http.StatusNotFound && errorOf(t, w) != "Order not found" {
+				t.Errorf("Expected error %q, got %q", "Order not found", errorOf(t, w))
+			}
+		})
+	}
+}
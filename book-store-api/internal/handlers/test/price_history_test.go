@@ -0,0 +1,121 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// priceHistory is the body of GET /books/:id/price-history
+type priceHistory struct {
+	Total          int                  `json:"total"`
+	Changes        []models.PriceChange `json:"changes"`
+	LowestPrice30d *string              `json:"lowest_price_30d"`
+	Currency       string               `json:"currency"`
+}
+
+func TestGetPriceHistory(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	id := books[0].ID
+
+	for _, change := range []struct{ price, actor string }{
+		{"34.99", "42"},
+		{"34.99", "42"}, // Unchanged, so not recorded
+		{"39.99", "7"},
+	} {
+		w := serve(r, http.MethodPut, "/books/"+id, bookBody(1, `"price": "`+change.price+`", "quantity": 15`), asUser(change.actor)...)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+		}
+	}
+
+	w := serve(r, http.MethodGet, "/books/"+id+"/price-history", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	history := decode[priceHistory](t, w)
+	if history.Total != 3 || len(history.Changes) != 3 {
+		t.Fatalf("Expected three changes, got %+v", history)
+	}
+
+	want := []struct{ price, previous, actor string }{
+		{"37.49", "", "system"},
+		{"34.99", "37.49", "42"},
+		{"39.99", "34.99", "7"},
+	}
+	for i, expected := range want {
+		change := history.Changes[i]
+		previous := ""
+		if change.PreviousPrice != nil {
+			previous = change.PreviousPrice.Decimal()
+		}
+		if change.Price.Decimal() != expected.price || previous != expected.previous || change.Actor != expected.actor {
+			t.Errorf("Change %d: expected %s from %q by %s, got %s from %q by %s",
+				i, expected.price, expected.previous, expected.actor, change.Price.Decimal(), previous, change.Actor)
+		}
+	}
+	if history.LowestPrice30d == nil || *history.LowestPrice30d != "34.99" || history.Currency != "USD" {
+		t.Errorf("Expected the lowest price to be 34.99 USD, got %v %s", history.LowestPrice30d, history.Currency)
+	}
+
+	// Book responses carry the lowest price too
+	book := decode[map[string]any](t, serve(r, http.MethodGet, "/books/"+id, ""))
+	if book["lowest_price_30d"] != "34.99" {
+		t.Errorf("Expected lowest_price_30d 34.99 on the book, got %v", book["lowest_price_30d"])
+	}
+}
+
+func TestGetPriceHistory_Currency(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	id := books[0].ID
+
+	body := bookBody(1, `"price": "34.99", "prices": {"EUR": "30.00"}, "quantity": 15`)
+	if w := serve(r, http.MethodPut, "/books/"+id, body); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+
+	tests := []struct {
+		query    string
+		lowest   string
+		currency string
+	}{
+		{"", "34.99", "USD"},
+		{"?currency=GBP", "27.64", "GBP"},
+		{"?currency=EUR", "30.00", "EUR"}, // The fixed price is below 34.99 converted
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			history := decode[priceHistory](t, serve(r, http.MethodGet, "/books/"+id+"/price-history"+tt.query, ""))
+			if history.LowestPrice30d == nil || *history.LowestPrice30d != tt.lowest || history.Currency != tt.currency {
+				t.Errorf("Expected %s %s, got %v %s", tt.lowest, tt.currency, history.LowestPrice30d, history.Currency)
+			}
+		})
+	}
+}
+
+func TestGetPriceHistory_Errors(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		path   string
+		status int
+		err    string
+	}{
+		{"/books/missing/price-history", http.StatusNotFound, "Book not found"},
+		{"/books/" + books[0].ID + "/price-history?currency=XYZ", http.StatusBadRequest, "Unsupported currency"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			w := serve(r, http.MethodGet, tt.path, "")
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// createPromotion creates a promotion as an admin and fails the test if it can't
+func createPromotion(t *testing.T, r *gin.Engine, body string) models.Promotion {
+	t.Helper()
+	w := serve(r, http.MethodPost, "/promotions", body, asAdmin("1")...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	return decode[models.Promotion](t, w)
+}
+
+func TestPromotions_CRUD(t *testing.T) {
+	r, _, _ := newRouter(t)
+
+	created := createPromotion(t, r, `{"name": "Uncle Bob week", "type": "percentage", "percent": 20, "conditions": {"authors": ["Robert C. Martin"]}}`)
+	if created.ID == "" || created.Percent != 20 {
+		t.Fatalf("Expected a saved promotion, got %+v", created)
+	}
+
+	w := serve(r, http.MethodGet, "/promotions/"+created.ID, "")
+	if got := decode[models.Promotion](t, w); w.Code != http.StatusOK || got.Name != "Uncle Bob week" {
+		t.Errorf("Expected the promotion, got %d: %s", w.Code, w.Body)
+	}
+
+	w = serve(r, http.MethodPut, "/promotions/"+created.ID, `{"name": "Uncle Bob fortnight", "type": "fixed", "amount": "5.00"}`, asAdmin("1")...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if got := decode[models.Promotion](t, w); got.Name != "Uncle Bob fortnight" || got.Amount == nil || got.Amount.String() != "5.00 USD" {
+		t.Errorf("Expected the promotion updated, got %+v", got)
+	}
+
+	list := decode[struct {
+		Total      int                `json:"total"`
+		Promotions []models.Promotion `json:"promotions"`
+	}](t, serve(r, http.MethodGet, "/promotions", ""))
+	if list.Total != 1 || len(list.Promotions) != 1 {
+		t.Errorf("Expected one promotion, got %+v", list)
+	}
+
+	if w := serve(r, http.MethodDelete, "/promotions/"+created.ID, "", asAdmin("1")...); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodGet, "/promotions/"+created.ID, ""); w.Code != http.StatusNotFound || errorOf(t, w) != "Promotion not found" {
+		t.Errorf("Expected 404 Promotion not found, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestPromotions_Errors(t *testing.T) {
+	r, _, _ := newRouter(t)
+	existing := createPromotion(t, r, `{"name": "Sale", "type": "percentage", "percent": 10}`)
+	valid := `{"name": "Sale", "type": "percentage", "percent": 10}`
+
+	tests := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		headers []string
+		status  int
+		err     string
+	}{
+		{"create as a customer", http.MethodPost, "/promotions", valid, asUser("42"), http.StatusForbidden, "Only admins can manage promotions"},
+		{"update as a customer", http.MethodPut, "/promotions/" + existing.ID, valid, asUser("42"), http.StatusForbidden, "Only admins can manage promotions"},
+		{"delete as a customer", http.MethodDelete, "/promotions/" + existing.ID, "", asUser("42"), http.StatusForbidden, "Only admins can manage promotions"},
+		{"role without the gateway secret", http.MethodPost, "/promotions", valid, []string{"X-User-Role", "admin"}, http.StatusForbidden, "Only admins can manage promotions"},
+		{"no name", http.MethodPost, "/promotions", `{"type": "percentage", "percent": 10}`, asAdmin("1"), http.StatusBadRequest, "Invalid promotion data"},
+		{"unknown type", http.MethodPost, "/promotions", `{"name": "Sale", "type": "bogof"}`, asAdmin("1"), http.StatusBadRequest, "Invalid promotion data"},
+		{"bad amount", http.MethodPost, "/promotions", `{"name": "Sale", "type": "fixed", "amount": "five"}`, asAdmin("1"), http.StatusBadRequest, "Invalid promotion data"},
+		{"no percent", http.MethodPost, "/promotions", `{"name": "Sale", "type": "percentage"}`, asAdmin("1"), http.StatusBadRequest, "percent must be between 1 and 100"},
+		{"no amount", http.MethodPost, "/promotions", `{"name": "Sale", "type": "fixed"}`, asAdmin("1"), http.StatusBadRequest, "amount must be positive"},
+		{"nothing free", http.MethodPost, "/promotions", `{"name": "Sale", "type": "buy_x_get_y", "buy": 2}`, asAdmin("1"), http.StatusBadRequest, "buy and get must be at least 1"},
+		{"invalid ISBN", http.MethodPost, "/promotions", `{"name": "Sale", "type": "percentage", "percent": 10, "conditions": {"isbns": ["123"]}}`, asAdmin("1"), http.StatusBadRequest, `invalid ISBN "123"`},
+		{"ends before it starts", http.MethodPost, "/promotions", `{"name": "Sale", "type": "percentage", "percent": 10, "starts_at": "2025-02-01T00:00:00Z", "ends_at": "2025-01-01T00:00:00Z"}`, asAdmin("1"), http.StatusBadRequest, "ends_at must be after starts_at"},
+		{"update an unknown promotion", http.MethodPut, "/promotions/missing", valid, asAdmin("1"), http.StatusNotFound, "Promotion not found"},
+		{"delete an unknown promotion", http.MethodDelete, "/promotions/missing", "", asAdmin("1"), http.StatusNotFound, "Promotion not found"},
+		{"get an unknown promotion", http.MethodGet, "/promotions/missing", "", nil, http.StatusNotFound, "Promotion not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, tt.path, tt.body, tt.headers...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
+
+func TestGetBook_SalePrice(t *testing.T) {
+	tomorrow := time.Now().Add(24 * time.Hour).UTC().Format(time.RFC3339)
+
+	tests := []struct {
+		name  string
+		promo string
+		book  int
+		query string
+		list  string
+		sale  string
+	}{
+		{name: "no promotion", list: "37.49", sale: "37.49"},
+		{name: "percent off", promo: `{"name": "Sale", "type": "percentage", "percent": 20}`, list: "37.49", sale: "29.99"},
+		{name: "amount off", promo: `{"name": "Sale", "type": "fixed", "amount": "5.00"}`, list: "37.49", sale: "32.49"},
+		{name: "for another author", promo: `{"name": "Sale", "type": "percentage", "percent": 20, "conditions": {"authors": ["Erich Gamma"]}}`, list: "37.49", sale: "37.49"},
+		{name: "for the author", promo: `{"name": "Sale", "type": "percentage", "percent": 20, "conditions": {"authors": ["Erich Gamma"]}}`, book: 2, list: "44.99", sale: "35.99"},
+		{name: "not started", promo: `{"name": "Sale", "type": "percentage", "percent": 20, "starts_at": "` + tomorrow + `"}`, list: "37.49", sale: "37.49"},
+		{name: "in the shown currency", promo: `{"name": "Sale", "type": "percentage", "percent": 20}`, query: "?currency=EUR", list: "34.49", sale: "27.59"},
+		{name: "amount in another currency", promo: `{"name": "Sale", "type": "fixed", "amount": "5.00"}`, query: "?currency=EUR", list: "34.49", sale: "34.49"},
+		{name: "only buying several", promo: `{"name": "3 for 2", "type": "buy_x_get_y", "buy": 2, "get": 1}`, list: "37.49", sale: "37.49"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			if tt.promo != "" {
+				createPromotion(t, r, tt.promo)
+			}
+
+			w := serve(r, http.MethodGet, "/books/"+books[tt.book].ID+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			book := decode[map[string]any](t, w)
+			if book["list_price"] != tt.list || book["sale_price"] != tt.sale {
+				t.Errorf("Expected list %s and sale %s, got %v and %v", tt.list, tt.sale, book["list_price"], book["sale_price"])
+			}
+		})
+	}
+}
+
+func TestCart_Promotions(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	promo := createPromotion(t, r, `{"name": "3 for 2", "type": "buy_x_get_y", "buy": 2, "get": 1, "conditions": {"authors": ["Robert C. Martin"]}}`)
+
+	cart := addToCart(t, r, books[0].ID, 3, asUser("42")...)
+	if cart.Subtotal.Decimal() != "112.47" || cart.DiscountTotal.Decimal() != "37.49" || cart.Total.Decimal() != "74.98" {
+		t.Errorf("Expected 112.47 less 37.49, got %s less %s", cart.Subtotal.Decimal(), cart.DiscountTotal.Decimal())
+	}
+	if len(cart.Discounts) != 1 {
+		t.Fatalf("Expected one discount, got %+v", cart.Discounts)
+	}
+	if d := cart.Discounts[0]; d.PromotionID != promo.ID || d.Name != "3 for 2" || d.BookID != books[0].ID || d.Quantity != 1 {
+		t.Errorf("Expected 3 for 2 on one copy of Clean Code, got %+v", d)
+	}
+
+	// The order keeps the discount
+	w := serve(r, http.MethodPost, "/cart/checkout", `{"shipping_address": "1 Main St"}`, asUser("42")...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	if order := decode[models.Order](t, w); order.Total.Decimal() != "74.98" || len(order.Discounts) != 1 {
+		t.Errorf("Expected the order to total 74.98 with the discount, got %s with %+v", order.Total.Decimal(), order.Discounts)
+	}
+}
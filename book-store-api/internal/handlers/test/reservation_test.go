@@ -0,0 +1,188 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+func TestCreateReservation_Actor(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		headers []string
+		actor   string
+	}{
+		{"gateway user", `{"quantity": 1}`, asUser("42"), "42"},
+		{"actor in the body is ignored", `{"quantity": 1, "actor": "someone-else"}`, asUser("42"), "42"},
+		{"no gateway identity", `{"quantity": 1}`, nil, "internal"},
+		{"identity without the gateway secret", `{"quantity": 1}`, []string{"X-User-Id", "42"}, "internal"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+
+			w := serve(r, http.MethodPost, "/books/"+books[0].ID+"/reservations", tt.body, tt.headers...)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+			}
+			if got := decode[models.Reservation](t, w).Actor; got != tt.actor {
+				t.Errorf("Expected actor %q, got %q", tt.actor, got)
+			}
+		})
+	}
+}
+
+func TestCreateReservation(t *testing.T) {
+	tests := []struct {
+		name     string
+		book     int
+		body     string
+		status   int
+		err      string
+		reserved int
+	}{
+		{name: "some copies", body: `{"quantity": 5}`, status: http.StatusCreated, reserved: 5},
+		{name: "every copy", body: `{"quantity": 15, "ttl_seconds": 60}`, status: http.StatusCreated, reserved: 15},
+		{name: "more than is available", body: `{"quantity": 16}`, status: http.StatusConflict, err: "Insufficient stock"},
+		{name: "out of stock", book: 1, body: `{"quantity": 1}`, status: http.StatusConflict, err: "Insufficient stock"},
+		{name: "no quantity", body: `{}`, status: http.StatusBadRequest, err: "Invalid reservation"},
+		{name: "negative quantity", body: `{"quantity": -1}`, status: http.StatusBadRequest, err: "Invalid reservation"},
+		{name: "ttl too long", body: `{"quantity": 1, "ttl_seconds": 7200}`, status: http.StatusBadRequest, err: "Invalid reservation"},
+		{name: "unknown book", book: -1, body: `{"quantity": 1}`, status: http.StatusNotFound, err: "Book not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			id := "missing"
+			if tt.book >= 0 {
+				id = books[tt.book].ID
+			}
+
+			w := serve(r, http.MethodPost, "/books/"+id+"/reservations", tt.body)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.err != "" {
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("Expected error %q, got %q", tt.err, got)
+				}
+				return
+			}
+
+			reservation := decode[models.Reservation](t, w)
+			if reservation.Status != models.ReservationActive || reservation.ExpiresAt.IsZero() {
+				t.Errorf("Expected an active reservation with an expiry, got %+v", reservation)
+			}
+			book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+id, ""))
+			if book.Reserved != tt.reserved || book.Quantity != 15 {
+				t.Errorf("Expected 15 on hand with %d reserved, got %d with %d", tt.reserved, book.Quantity, book.Reserved)
+			}
+		})
+	}
+}
+
+func TestReservationLifecycle(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		status   models.ReservationStatus
+		quantity int // Copies on hand afterwards
+	}{
+		{"confirm sells the copies", "confirm", models.ReservationConfirmed, 12},
+		{"release returns them", "release", models.ReservationReleased, 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, _, books := newRouter(t, catalogBooks()...)
+			w := serve(r, http.MethodPost, "/books/"+books[0].ID+"/reservations", `{"quantity": 3}`)
+			if w.Code != http.StatusCreated {
+				t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+			}
+			created := decode[models.Reservation](t, w)
+
+			w = serve(r, http.MethodPost, "/reservations/"+created.ID+"/"+tt.action, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			if got := decode[models.Reservation](t, w).Status; got != tt.status {
+				t.Errorf("Expected status %q, got %q", tt.status, got)
+			}
+
+			w = serve(r, http.MethodGet, "/reservations/"+created.ID, "")
+			if got := decode[models.Reservation](t, w).Status; w.Code != http.StatusOK || got != tt.status {
+				t.Errorf("Expected the reservation to be %q, got %d: %s", tt.status, w.Code, w.Body)
+			}
+
+			book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, ""))
+			if book.Quantity != tt.quantity || book.Reserved != 0 {
+				t.Errorf("Expected %d on hand and none reserved, got %d and %d", tt.quantity, book.Quantity, book.Reserved)
+			}
+
+			// Either way the reservation is over
+			for _, action := range []string{"confirm", "release"} {
+				w = serve(r, http.MethodPost, "/reservations/"+created.ID+"/"+action, "")
+				if w.Code != http.StatusConflict || errorOf(t, w) != "Reservation is no longer active" {
+					t.Errorf("%s: expected 409 Reservation is no longer active, got %d: %s", action, w.Code, w.Body)
+				}
+			}
+		})
+	}
+}
+
+func TestConfirmReservation_Expired(t *testing.T) {
+	r, store, books := newRouter(t, catalogBooks()...)
+
+	// The sweeper hasn't run yet, so the reservation is still active
+	reservation, err := store.CreateReservation(models.Reservation{
+		BookID:    books[0].ID,
+		Quantity:  2,
+		ExpiresAt: time.Now().Add(-time.Minute),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(r, http.MethodPost, "/reservations/"+reservation.ID+"/confirm", "")
+	if w.Code != http.StatusGone || errorOf(t, w) != "Reservation has expired" {
+		t.Fatalf("Expected 410 Reservation has expired, got %d: %s", w.Code, w.Body)
+	}
+
+	w = serve(r, http.MethodGet, "/reservations/"+reservation.ID, "")
+	if got := decode[models.Reservation](t, w).Status; got != models.ReservationExpired {
+		t.Errorf("Expected the reservation to have expired, got %q", got)
+	}
+	book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, ""))
+	if book.Quantity != 15 || book.Reserved != 0 {
+		t.Errorf("Expected the copies back in stock, got %d on hand and %d reserved", book.Quantity, book.Reserved)
+	}
+
+	w = serve(r, http.MethodPost, "/reservations/"+reservation.ID+"/release", "")
+	if w.Code != http.StatusConflict {
+		t.Errorf("Expected status 409 releasing an expired reservation, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestReservation_NotFound(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	for _, req := range []struct{ method, path string }{
+		{http.MethodGet, "/reservations/missing"},
+		{http.MethodPost, "/reservations/missing/confirm"},
+		{http.MethodPost, "/reservations/missing/release"},
+	} {
+		w := serve(r, req.method, req.path, "")
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%s %s: expected status 404, got %d: %s", req.method, req.path, w.Code, w.Body)
+			continue
+		}
+		if got := errorOf(t, w); got != "Reservation not found" {
+			t.Errorf("%s %s: expected error %q, got %q", req.method, req.path, "Reservation not found", got)
+		}
+	}
+}
@@ -0,0 +1,264 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+type reviewList struct {
+	Rating  models.Rating   `json:"rating"`
+	Total   int             `json:"total"`
+	Reviews []models.Review `json:"reviews"`
+}
+
+// postReview reviews the book as the user and fails the test if it can't
+func postReview(t *testing.T, r *gin.Engine, bookID, userID string, rating int) models.Review {
+	t.Helper()
+	w := serve(r, http.MethodPost, "/books/"+bookID+"/reviews", `{"rating": `+strconv.Itoa(rating)+`, "title": "Worth reading"}`, asUser(userID)...)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body)
+	}
+	return decode[models.Review](t, w)
+}
+
+// moderate sets the review's status as an admin and fails the test if it can't
+func moderate(t *testing.T, r *gin.Engine, reviewID string, status models.ReviewStatus) {
+	t.Helper()
+	w := serve(r, http.MethodPut, "/reviews/"+reviewID+"/status", `{"status": "`+string(status)+`"}`, asAdmin("1")...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestCreateReview(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	path := "/books/" + books[0].ID + "/reviews"
+
+	review := postReview(t, r, books[0].ID, "42", 4)
+	if review.Status != models.ReviewPending || review.UserID != "42" || review.BookID != books[0].ID {
+		t.Errorf("Expected a pending review by 42, got %+v", review)
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		body    string
+		headers []string
+		status  int
+		err     string
+	}{
+		{"signed out", path, `{"rating": 5}`, nil, http.StatusUnauthorized, "Sign in to review books"},
+		{"identity without the gateway secret", path, `{"rating": 5}`, []string{"X-User-Id", "7"}, http.StatusUnauthorized, "Sign in to review books"},
+		{"rating too high", path, `{"rating": 6}`, asUser("7"), http.StatusBadRequest, "rating must be between 1 and 5"},
+		{"no rating", path, `{"title": "Great"}`, asUser("7"), http.StatusBadRequest, "rating must be between 1 and 5"},
+		{"unknown book", "/books/missing/reviews", `{"rating": 5}`, asUser("7"), http.StatusNotFound, "Book not found"},
+		{"second review of a book", path, `{"rating": 1}`, asUser("42"), http.StatusConflict, "You have already reviewed this book"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodPost, tt.path, tt.body, tt.headers...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+
+	// The same user can review another book
+	postReview(t, r, books[1].ID, "42", 2)
+}
+
+func TestReviewModeration(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	bookPath := "/books/" + books[0].ID + "/reviews"
+	good := postReview(t, r, books[0].ID, "42", 5)
+	fair := postReview(t, r, books[0].ID, "43", 3)
+	spam := postReview(t, r, books[0].ID, "44", 1)
+
+	queue := decode[reviewList](t, serve(r, http.MethodGet, "/reviews", "", asAdmin("1")...))
+	if queue.Total != 3 {
+		t.Fatalf("Expected 3 reviews waiting for moderation, got %+v", queue)
+	}
+	if w := serve(r, http.MethodGet, bookPath, ""); decode[reviewList](t, w).Total != 0 {
+		t.Errorf("Expected pending reviews to be hidden, got %s", w.Body)
+	}
+
+	w := serve(r, http.MethodPut, "/reviews/"+good.ID+"/status", `{"status": "approved"}`, asUser("42")...)
+	if w.Code != http.StatusForbidden || errorOf(t, w) != "Only admins can moderate reviews" {
+		t.Errorf("Expected 403 for a customer moderating, got %d: %s", w.Code, w.Body)
+	}
+	w = serve(r, http.MethodPut, "/reviews/"+good.ID+"/status", `{"status": "published"}`, asAdmin("1")...)
+	if w.Code != http.StatusBadRequest || errorOf(t, w) != "Invalid review status" {
+		t.Errorf("Expected 400 for an unknown status, got %d: %s", w.Code, w.Body)
+	}
+	w = serve(r, http.MethodPut, "/reviews/missing/status", `{"status": "approved"}`, asAdmin("1")...)
+	if w.Code != http.StatusNotFound || errorOf(t, w) != "Review not found" {
+		t.Errorf("Expected 404 for an unknown review, got %d: %s", w.Code, w.Body)
+	}
+
+	moderate(t, r, good.ID, models.ReviewApproved)
+	moderate(t, r, fair.ID, models.ReviewApproved)
+	moderate(t, r, spam.ID, models.ReviewRejected)
+
+	list := decode[reviewList](t, serve(r, http.MethodGet, bookPath, ""))
+	if list.Total != 2 || list.Rating.Count != 2 || list.Rating.Average != 4 {
+		t.Errorf("Expected two approved reviews averaging 4, got %+v", list)
+	}
+	if want := map[int]int{1: 0, 2: 0, 3: 1, 4: 0, 5: 1}; !reflect.DeepEqual(list.Rating.Histogram, want) {
+		t.Errorf("Expected histogram %v, got %v", want, list.Rating.Histogram)
+	}
+	book := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, ""))
+	if !reflect.DeepEqual(book.Rating, list.Rating) {
+		t.Errorf("Expected the book's rating %+v, got %+v", list.Rating, book.Rating)
+	}
+
+	rejected := decode[reviewList](t, serve(r, http.MethodGet, "/reviews?status=rejected", "", asAdmin("1")...))
+	if rejected.Total != 1 || rejected.Reviews[0].ID != spam.ID || rejected.Reviews[0].ModeratedBy != "1" {
+		t.Errorf("Expected the rejected review moderated by 1, got %+v", rejected)
+	}
+
+	// Editing an approved review sends it back for moderation
+	w = serve(r, http.MethodPut, "/reviews/"+good.ID, `{"rating": 4, "title": "Still good"}`, asUser("42")...)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if got := decode[models.Review](t, w); got.Status != models.ReviewPending || got.Rating != 4 {
+		t.Errorf("Expected a pending 4 star review, got %+v", got)
+	}
+	if got := decode[reviewList](t, serve(r, http.MethodGet, bookPath, "")).Rating; got.Count != 1 || got.Average != 3 {
+		t.Errorf("Expected the edited review to leave the rating, got %+v", got)
+	}
+}
+
+func TestReviewAccess(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	pending := postReview(t, r, books[0].ID, "42", 5)
+	approved := postReview(t, r, books[0].ID, "43", 4)
+	moderate(t, r, approved.ID, models.ReviewApproved)
+
+	tests := []struct {
+		name    string
+		method  string
+		review  string
+		body    string
+		headers []string
+		status  int
+		err     string
+	}{
+		{"anyone sees an approved review", http.MethodGet, approved.ID, "", nil, http.StatusOK, ""},
+		{"others don't see a pending review", http.MethodGet, pending.ID, "", asUser("43"), http.StatusNotFound, "Review not found"},
+		{"the author sees a pending review", http.MethodGet, pending.ID, "", asUser("42"), http.StatusOK, ""},
+		{"admins see a pending review", http.MethodGet, pending.ID, "", asAdmin("1"), http.StatusOK, ""},
+		{"unknown review", http.MethodGet, "missing", "", asAdmin("1"), http.StatusNotFound, "Review not found"},
+		{"others can't edit", http.MethodPut, approved.ID, `{"rating": 1}`, asUser("42"), http.StatusForbidden, "Only the author can edit a review"},
+		{"editing a hidden review", http.MethodPut, pending.ID, `{"rating": 1}`, asUser("43"), http.StatusNotFound, "Review not found"},
+		{"invalid edit", http.MethodPut, pending.ID, `{"rating": 0}`, asUser("42"), http.StatusBadRequest, "rating must be between 1 and 5"},
+		{"others can't delete", http.MethodDelete, approved.ID, "", asUser("42"), http.StatusForbidden, "Only the author or an admin can delete a review"},
+		{"the author deletes", http.MethodDelete, pending.ID, "", asUser("42"), http.StatusNoContent, ""},
+		{"admins delete", http.MethodDelete, approved.ID, "", asAdmin("1"), http.StatusNoContent, ""},
+		{"deleted review", http.MethodGet, approved.ID, "", asAdmin("1"), http.StatusNotFound, "Review not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, tt.method, "/reviews/"+tt.review, tt.body, tt.headers...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.err != "" {
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("Expected error %q, got %q", tt.err, got)
+				}
+			}
+		})
+	}
+
+	if got := decode[models.Book](t, serve(r, http.MethodGet, "/books/"+books[0].ID, "")).Rating; got.Count != 0 {
+		t.Errorf("Expected deleted reviews to leave the rating, got %+v", got)
+	}
+}
+
+func TestGetReviews(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	mine := postReview(t, r, books[0].ID, "42", 5)
+	postReview(t, r, books[1].ID, "42", 2)
+	postReview(t, r, books[0].ID, "43", 3)
+	moderate(t, r, mine.ID, models.ReviewApproved)
+
+	tests := []struct {
+		name    string
+		query   string
+		headers []string
+		status  int
+		err     string
+		total   int
+	}{
+		{"own reviews in every status", "", asUser("42"), http.StatusOK, "", 2},
+		{"other users are ignored for customers", "?user_id=43", asUser("42"), http.StatusOK, "", 2},
+		{"own reviews by status", "?status=approved", asUser("42"), http.StatusOK, "", 1},
+		{"moderation queue", "", asAdmin("1"), http.StatusOK, "", 2},
+		{"queue for a book", "?book_id=" + books[0].ID, asAdmin("1"), http.StatusOK, "", 1},
+		{"admin by user", "?status=approved&user_id=42", asAdmin("1"), http.StatusOK, "", 1},
+		{"paged", "?limit=1", asUser("42"), http.StatusOK, "", 2},
+		{"signed out", "", nil, http.StatusUnauthorized, "Sign in to see your reviews", 0},
+		{"unknown status", "?status=published", asAdmin("1"), http.StatusBadRequest, "Invalid review status", 0},
+		{"negative skip", "?skip=-1", asUser("42"), http.StatusBadRequest, "skip must be a non-negative integer", 0},
+		{"limit too large", "?limit=101", asUser("42"), http.StatusBadRequest, "limit must be between 1 and 100", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/reviews"+tt.query, "", tt.headers...)
+			if w.Code != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, w.Code, w.Body)
+			}
+			if tt.err != "" {
+				if got := errorOf(t, w); got != tt.err {
+					t.Errorf("Expected error %q, got %q", tt.err, got)
+				}
+				return
+			}
+			if got := decode[reviewList](t, w).Total; got != tt.total {
+				t.Errorf("Expected %d reviews, got %d", tt.total, got)
+			}
+		})
+	}
+
+	w := serve(r, http.MethodGet, "/books/missing/reviews", "")
+	if w.Code != http.StatusNotFound || errorOf(t, w) != "Book not found" {
+		t.Errorf("Expected 404 Book not found, got %d: %s", w.Code, w.Body)
+	}
+}
+
+func TestGetBooks_SortByRating(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+	for _, review := range []struct {
+		book, user string
+		rating     int
+	}{
+		{books[0].ID, "1", 4},
+		{books[0].ID, "2", 4},
+		{books[1].ID, "1", 4},
+		{books[2].ID, "1", 5},
+	} {
+		moderate(t, r, postReview(t, r, review.book, review.user, review.rating).ID, models.ReviewApproved)
+	}
+
+	w := serve(r, http.MethodGet, "/books?sort=-rating", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	// Ties on the average go to the book with more ratings
+	want := []string{"Design Patterns", "Clean Code", "Clean Architecture"}
+	if got := titles(decode[bookList](t, w).Books); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,142 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// searchResponse is the body of GET /books/search
+type searchResponse struct {
+	Query   string                    `json:"query"`
+	Total   int                       `json:"total"`
+	Limit   int                       `json:"limit"`
+	Offset  int                       `json:"offset"`
+	Results []models.BookSearchResult `json:"results"`
+}
+
+func resultTitles(results []models.BookSearchResult) []string {
+	found := make([]string, len(results))
+	for i, result := range results {
+		found[i] = result.Book.Title
+	}
+	return found
+}
+
+func TestSearchBooks(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name  string
+		query string
+		total int
+		want  []string
+	}{
+		{"word", "q=clean", 2, []string{"Clean Architecture", "Clean Code"}},
+		{"words in any order", "q=" + url.QueryEscape("code clean"), 1, []string{"Clean Code"}},
+		{"phrase", "q=" + url.QueryEscape(`"design patterns"`), 1, []string{"Design Patterns"}},
+		{"prefix", "q=" + url.QueryEscape("archi*"), 1, []string{"Clean Architecture"}},
+		{"author", "q=gamma", 1, []string{"Design Patterns"}},
+		{"no match", "q=refactoring", 0, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books/search?"+tt.query, "")
+			if w.Code != http.StatusOK {
+				t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+			}
+			got := decode[searchResponse](t, w)
+			if got.Total != tt.total {
+				t.Errorf("Expected total %d, got %d", tt.total, got.Total)
+			}
+			if titles := sorted(resultTitles(got.Results)); !reflect.DeepEqual(titles, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, titles)
+			}
+		})
+	}
+}
+
+func TestSearchBooks_Paging(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	all := decode[searchResponse](t, serve(r, http.MethodGet, "/books/search?q=clean", ""))
+	if len(all.Results) != 2 {
+		t.Fatalf("Expected two results, got %v", resultTitles(all.Results))
+	}
+
+	for offset, want := range resultTitles(all.Results) {
+		got := decode[searchResponse](t, serve(r, http.MethodGet, "/books/search?q=clean&limit=1&offset="+strconv.Itoa(offset), ""))
+		if got.Total != 2 || got.Limit != 1 || got.Offset != offset {
+			t.Errorf("Offset %d: expected total 2, limit 1, got %+v", offset, got)
+		}
+		if titles := resultTitles(got.Results); !reflect.DeepEqual(titles, []string{want}) {
+			t.Errorf("Offset %d: expected [%s], got %v", offset, want, titles)
+		}
+	}
+}
+
+func TestSearchBooks_Highlights(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	got := decode[searchResponse](t, serve(r, http.MethodGet, "/books/search?q=patterns", ""))
+	if len(got.Results) != 1 {
+		t.Fatalf("Expected one result, got %+v", got)
+	}
+	if title := got.Results[0].Highlights["title"]; !strings.Contains(title, "<mark>Patterns</mark>") {
+		t.Errorf("Expected the match highlighted in the title, got %q", title)
+	}
+	if got.Results[0].Score <= 0 {
+		t.Errorf("Expected a positive score, got %v", got.Results[0].Score)
+	}
+}
+
+func TestSearchBooks_FollowsWrites(t *testing.T) {
+	r, _, books := newRouter(t, catalogBooks()...)
+
+	body := `{"title": "Refactoring", "author": "Martin Fowler", "isbn": "` + books[0].ISBN + `", "published_at": "1999-07-08T00:00:00Z", "price": "37.49", "quantity": 15}`
+	if w := serve(r, http.MethodPut, "/books/"+books[0].ID, body); w.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d: %s", w.Code, w.Body)
+	}
+	if w := serve(r, http.MethodDelete, "/books/"+books[1].ID, ""); w.Code != http.StatusNoContent {
+		t.Fatalf("Expected status 204, got %d: %s", w.Code, w.Body)
+	}
+
+	if got := decode[searchResponse](t, serve(r, http.MethodGet, "/books/search?q=clean", "")); got.Total != 0 {
+		t.Errorf("Expected the renamed and deleted books to be gone, got %v", resultTitles(got.Results))
+	}
+	if got := decode[searchResponse](t, serve(r, http.MethodGet, "/books/search?q=fowler", "")); got.Total != 1 {
+		t.Errorf("Expected the updated book to be found, got %v", resultTitles(got.Results))
+	}
+}
+
+func TestSearchBooks_Errors(t *testing.T) {
+	r, _, _ := newRouter(t, catalogBooks()...)
+
+	tests := []struct {
+		name  string
+		query string
+		err   string
+	}{
+		{"no query", "", "Query parameter q is required"},
+		{"blank query", "q=+++", "Query parameter q is required"},
+		{"no searchable terms", "q=" + url.QueryEscape("!!! ???"), "Query has no searchable terms"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serve(r, http.MethodGet, "/books/search?"+tt.query, "")
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("Expected status 400, got %d: %s", w.Code, w.Body)
+			}
+			if got := errorOf(t, w); got != tt.err {
+				t.Errorf("Expected error %q, got %q", tt.err, got)
+			}
+		})
+	}
+}
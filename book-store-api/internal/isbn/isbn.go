@@ -0,0 +1,109 @@
+// Package isbn validates, normalizes and formats International Standard Book
+// Numbers. Books are stored under their canonical ISBN-13: thirteen digits
+// with no hyphens or spaces.
+package isbn
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalid is returned for a string that isn't a valid ISBN-10 or ISBN-13
+var ErrInvalid = errors.New("isbn: invalid ISBN")
+
+// ErrNoISBN10 is returned when converting an ISBN-13 with the 979 prefix,
+// which has no ISBN-10 form
+var ErrNoISBN10 = errors.New("isbn: no ISBN-10 for this ISBN")
+
+// Normalize parses an ISBN-10 or ISBN-13, with or without hyphens and spaces,
+// checks its check digit and returns the canonical ISBN-13
+func Normalize(s string) (string, error) {
+	digits := compact(s)
+
+	switch len(digits) {
+	case 10:
+		if !allDigits(digits[:9]) || !(isDigit(digits[9]) || digits[9] == 'X') {
+			return "", fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		if checkDigit10(digits[:9]) != digits[9] {
+			return "", fmt.Errorf("%w: bad check digit in %q", ErrInvalid, s)
+		}
+		return "978" + digits[:9] + string(checkDigit13("978"+digits[:9])), nil
+	case 13:
+		if !allDigits(digits) || (!strings.HasPrefix(digits, "978") && !strings.HasPrefix(digits, "979")) {
+			return "", fmt.Errorf("%w: %q", ErrInvalid, s)
+		}
+		if checkDigit13(digits[:12]) != digits[12] {
+			return "", fmt.Errorf("%w: bad check digit in %q", ErrInvalid, s)
+		}
+		return digits, nil
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrInvalid, s)
+}
+
+// Valid reports whether s is a valid ISBN-10 or ISBN-13
+func Valid(s string) bool {
+	_, err := Normalize(s)
+	return err == nil
+}
+
+// To10 returns the ISBN-10 form of an ISBN, without hyphens. Only ISBNs with
+// the 978 prefix have one.
+func To10(s string) (string, error) {
+	isbn, err := Normalize(s)
+	if err != nil {
+		return "", err
+	}
+	if !strings.HasPrefix(isbn, "978") {
+		return "", ErrNoISBN10
+	}
+	return isbn[3:12] + string(checkDigit10(isbn[3:12])), nil
+}
+
+// compact removes hyphens and spaces and upper-cases an X check digit
+func compact(s string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(s)))
+}
+
+// checkDigit10 computes the ISBN-10 check digit of the first nine digits:
+// the weighted sum with weights 10 down to 2 must be a multiple of 11
+func checkDigit10(digits string) byte {
+	sum := 0
+	for i := 0; i < 9; i++ {
+		sum += int(digits[i]-'0') * (10 - i)
+	}
+	check := (11 - sum%11) % 11
+	if check == 10 {
+		return 'X'
+	}
+	return byte('0' + check)
+}
+
+// checkDigit13 computes the ISBN-13 (EAN-13) check digit of the first twelve
+// digits, weighted alternately 1 and 3
+func checkDigit13(digits string) byte {
+	sum := 0
+	for i := 0; i < 12; i++ {
+		weight := 1
+		if i%2 == 1 {
+			weight = 3
+		}
+		sum += int(digits[i]-'0') * weight
+	}
+	return byte('0' + (10-sum%10)%10)
+}
+
+func allDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,175 @@
+package isbn
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrNoRange is returned when hyphenating an ISBN whose group or registrant
+// range isn't in the range table
+var ErrNoRange = errors.New("isbn: no hyphenation range")
+
+// rule says how many digits an element has when the next seven digits of the
+// ISBN fall between Min and Max. A length of 0 means the range isn't in use.
+type rule struct {
+	Min, Max int
+	Length   int
+}
+
+// Ranges is a hyphenation table: how the digits after each prefix split into
+// registration group, and after each group into registrant. Ranges is
+// immutable once built.
+type Ranges struct {
+	prefixes map[string][]rule // "978" -> group lengths
+	groups   map[string][]rule // "978-0" -> registrant lengths
+}
+
+// rangeMessage is the ISBN range message published by the International
+// ISBN Agency, e.g.
+//
+//	<ISBNRangeMessage>
+//	  <EAN.UCCPrefixes><EAN.UCC><Prefix>978</Prefix><Rules>
+//	    <Rule><Range>0000000-5999999</Range><Length>1</Length></Rule>
+//	  </Rules></EAN.UCC></EAN.UCCPrefixes>
+//	  <RegistrationGroups><Group><Prefix>978-0</Prefix><Rules>
+//	    <Rule><Range>0000000-1999999</Range><Length>2</Length></Rule>
+//	  </Rules></Group></RegistrationGroups>
+//	</ISBNRangeMessage>
+type rangeMessage struct {
+	Prefixes []rangeElement `xml:"EAN.UCCPrefixes>EAN.UCC"`
+	Groups   []rangeElement `xml:"RegistrationGroups>Group"`
+}
+
+type rangeElement struct {
+	Prefix string `xml:"Prefix"`
+	Rules  []struct {
+		Range  string `xml:"Range"`
+		Length int    `xml:"Length"`
+	} `xml:"Rules>Rule"`
+}
+
+// ParseRanges reads a range table in the International ISBN Agency's XML
+// range message format
+func ParseRanges(r io.Reader) (*Ranges, error) {
+	var msg rangeMessage
+	if err := xml.NewDecoder(r).Decode(&msg); err != nil {
+		return nil, fmt.Errorf("isbn: invalid range message: %w", err)
+	}
+
+	ranges := &Ranges{
+		prefixes: make(map[string][]rule),
+		groups:   make(map[string][]rule),
+	}
+	for _, elements := range []struct {
+		list []rangeElement
+		into map[string][]rule
+	}{{msg.Prefixes, ranges.prefixes}, {msg.Groups, ranges.groups}} {
+		for _, element := range elements.list {
+			for _, r := range element.Rules {
+				min, max, ok := strings.Cut(r.Range, "-")
+				lo, errLo := strconv.Atoi(min)
+				hi, errHi := strconv.Atoi(max)
+				if !ok || errLo != nil || errHi != nil || lo > hi || r.Length < 0 || r.Length > 7 {
+					return nil, fmt.Errorf("isbn: invalid range %q for %s", r.Range, element.Prefix)
+				}
+				elements.into[element.Prefix] = append(elements.into[element.Prefix], rule{Min: lo, Max: hi, Length: r.Length})
+			}
+		}
+	}
+
+	return ranges, nil
+}
+
+// LoadRanges reads a range table from a file
+func LoadRanges(path string) (*Ranges, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseRanges(f)
+}
+
+// Hyphenate formats an ISBN-13 as prefix-group-registrant-publication-check,
+// e.g. 978-0-13-235088-4
+func (r *Ranges) Hyphenate(s string) (string, error) {
+	isbn, err := Normalize(s)
+	if err != nil {
+		return "", err
+	}
+
+	prefix, rest := isbn[:3], isbn[3:12]
+
+	groupLen := length(r.prefixes[prefix], rest)
+	if groupLen == 0 {
+		return "", ErrNoRange
+	}
+	group, rest := rest[:groupLen], rest[groupLen:]
+
+	registrantLen := length(r.groups[prefix+"-"+group], rest)
+	if registrantLen == 0 || registrantLen >= len(rest) {
+		return "", ErrNoRange
+	}
+
+	return strings.Join([]string{prefix, group, rest[:registrantLen], rest[registrantLen:], isbn[12:]}, "-"), nil
+}
+
+// Hyphenate10 formats the ISBN-10 form of an ISBN as group-registrant-
+// publication-check, e.g. 0-13-235088-2
+func (r *Ranges) Hyphenate10(s string) (string, error) {
+	isbn10, err := To10(s)
+	if err != nil {
+		return "", err
+	}
+	hyphenated, err := r.Hyphenate(s)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimPrefix(hyphenated[:len(hyphenated)-1], "978-") + isbn10[9:], nil
+}
+
+// length finds the rule covering the first seven digits of rest, padded with
+// zeros, and returns its length, or 0 if there is none
+func length(rules []rule, rest string) int {
+	digits := (rest + "0000000")[:7]
+	n, err := strconv.Atoi(digits)
+	if err != nil {
+		return 0
+	}
+	for _, r := range rules {
+		if n >= r.Min && n <= r.Max {
+			return r.Length
+		}
+	}
+	return 0
+}
+
+// defaultRanges is the table used by the package-level Hyphenate functions;
+// it is empty until SetRanges is called
+var defaultRanges atomic.Pointer[Ranges]
+
+func init() {
+	defaultRanges.Store(&Ranges{})
+}
+
+// SetRanges replaces the table used by Hyphenate and Hyphenate10
+func SetRanges(r *Ranges) {
+	defaultRanges.Store(r)
+}
+
+// Hyphenate formats an ISBN-13 with the table set by SetRanges
+func Hyphenate(s string) (string, error) {
+	return defaultRanges.Load().Hyphenate(s)
+}
+
+// Hyphenate10 formats the ISBN-10 form of an ISBN with the table set by SetRanges
+func Hyphenate10(s string) (string, error) {
+	return defaultRanges.Load().Hyphenate10(s)
+}
@@ -0,0 +1,255 @@
+package isbn_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/isbn"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"9780132350884", "9780132350884", nil},
+		{"978-0-13-235088-4", "9780132350884", nil},
+		{" 978 0 13 235088 4 ", "9780132350884", nil},
+		{"0132350882", "9780132350884", nil},
+		{"0-13-235088-2", "9780132350884", nil},
+		{"0-8044-2957-X", "9780804429573", nil},
+		{"080442957x", "9780804429573", nil},
+		{"9791020000019", "9791020000019", nil},
+		{"9780132350885", "", isbn.ErrInvalid},   // Bad check digit
+		{"0132350883", "", isbn.ErrInvalid},      // Bad check digit
+		{"9770132350887", "", isbn.ErrInvalid},   // Not a book prefix
+		{"X132350882", "", isbn.ErrInvalid},      // X only as the ISBN-10 check digit
+		{"978013235088X", "", isbn.ErrInvalid},   // No X in an ISBN-13
+		{"978-0-13-235088", "", isbn.ErrInvalid}, // Too short
+		{"97801323508840", "", isbn.ErrInvalid},  // Too long
+		{"", "", isbn.ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := isbn.Normalize(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if isbn.Valid(tt.input) != (tt.err == nil) {
+				t.Errorf("Expected Valid to be %v", tt.err == nil)
+			}
+		})
+	}
+}
+
+func TestTo10(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"9780132350884", "0132350882", nil},
+		{"978-0-8044-2957-3", "080442957X", nil},
+		{"0-13-235088-2", "0132350882", nil},
+		{"9791020000019", "", isbn.ErrNoISBN10},
+		{"9780132350885", "", isbn.ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := isbn.To10(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// testRanges covers the 978-0 and 979-10 groups, with some ranges not in use
+const testRanges = `<?xml version="1.0" encoding="utf-8"?>
+<ISBNRangeMessage>
+  <EAN.UCCPrefixes>
+    <EAN.UCC><Prefix>978</Prefix><Rules>
+      <Rule><Range>0000000-5999999</Range><Length>1</Length></Rule>
+      <Rule><Range>6000000-6999999</Range><Length>0</Length></Rule>
+    </Rules></EAN.UCC>
+    <EAN.UCC><Prefix>979</Prefix><Rules>
+      <Rule><Range>1000000-1299999</Range><Length>2</Length></Rule>
+    </Rules></EAN.UCC>
+  </EAN.UCCPrefixes>
+  <RegistrationGroups>
+    <Group><Prefix>978-0</Prefix><Rules>
+      <Rule><Range>0000000-1999999</Range><Length>2</Length></Rule>
+      <Rule><Range>2000000-6999999</Range><Length>3</Length></Rule>
+      <Rule><Range>7000000-8499999</Range><Length>4</Length></Rule>
+      <Rule><Range>8500000-9999999</Range><Length>5</Length></Rule>
+    </Rules></Group>
+    <Group><Prefix>979-10</Prefix><Rules>
+      <Rule><Range>0000000-1999999</Range><Length>2</Length></Rule>
+      <Rule><Range>2000000-6999999</Range><Length>3</Length></Rule>
+      <Rule><Range>7000000-8999999</Range><Length>4</Length></Rule>
+      <Rule><Range>9000000-9999999</Range><Length>0</Length></Rule>
+    </Rules></Group>
+  </RegistrationGroups>
+</ISBNRangeMessage>`
+
+func parseRanges(t *testing.T, message string) *isbn.Ranges {
+	t.Helper()
+	ranges, err := isbn.ParseRanges(strings.NewReader(message))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ranges
+}
+
+func TestRanges_Hyphenate(t *testing.T) {
+	ranges := parseRanges(t, testRanges)
+
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"9780132350884", "978-0-13-235088-4", nil},
+		{"9780306406157", "978-0-306-40615-7", nil},
+		{"9780804429573", "978-0-8044-2957-3", nil},
+		{"0-13-235088-2", "978-0-13-235088-4", nil},
+		{"9791020000019", "979-10-200-0001-9", nil},
+		{"9791095000013", "", isbn.ErrNoRange}, // Registrant range not in use
+		{"9786999999990", "", isbn.ErrNoRange}, // Group range not in use
+		{"9783161484100", "", isbn.ErrNoRange}, // Group not in the table
+		{"9798000000014", "", isbn.ErrNoRange}, // Prefix range not in the table
+		{"9780132350885", "", isbn.ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ranges.Hyphenate(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRanges_Hyphenate10(t *testing.T) {
+	ranges := parseRanges(t, testRanges)
+
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"9780132350884", "0-13-235088-2", nil},
+		{"9780804429573", "0-8044-2957-X", nil},
+		{"9791020000019", "", isbn.ErrNoISBN10},
+		{"9783161484100", "", isbn.ErrNoRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ranges.Hyphenate10(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseRanges_Invalid(t *testing.T) {
+	rule := func(r string, length string) string {
+		return `<ISBNRangeMessage><RegistrationGroups><Group><Prefix>978-0</Prefix><Rules><Rule><Range>` +
+			r + `</Range><Length>` + length + `</Length></Rule></Rules></Group></RegistrationGroups></ISBNRangeMessage>`
+	}
+
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"not XML", "ranges"},
+		{"no dash", rule("0000000", "2")},
+		{"not digits", rule("abc-def", "2")},
+		{"backwards", rule("9999999-0000000", "2")},
+		{"too long", rule("0000000-9999999", "8")},
+		{"length not a number", rule("0000000-9999999", "two")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := isbn.ParseRanges(strings.NewReader(tt.message)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestSampleRanges(t *testing.T) {
+	ranges, err := isbn.LoadRanges("../../../data/isbn_ranges.sample.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input string
+		want  string
+		err   error
+	}{
+		{"9780132350884", "978-0-13-235088-4", nil},
+		{"9783161484100", "978-3-16-148410-0", nil},
+		{"9784061234567", "978-4-06-123456-7", nil},
+		{"9781402894626", "978-1-4028-9462-6", nil},
+		{"9781118530122", "978-1-118-53012-2", nil},
+		{"9781491950357", "978-1-4919-5035-7", nil},
+		{"9781593279288", "978-1-59327-928-8", nil},
+		{"9781449373320", "978-1-4493-7332-0", nil},
+		{"9781732745803", "978-1-7327458-0-3", nil},
+		{"9798218123451", "979-8-218-12345-1", nil},
+		{"9798400701238", "979-8-4007-0123-8", nil},
+		{"9798886450125", "979-8-88645-012-5", nil},
+		{"9798987654309", "979-8-9876543-0-9", nil},
+		{"9791020000019", "", isbn.ErrNoRange}, // Only in the full range message
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ranges.Hyphenate(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestSetRanges(t *testing.T) {
+	t.Cleanup(func() { isbn.SetRanges(&isbn.Ranges{}) })
+
+	if _, err := isbn.Hyphenate("9780132350884"); !errors.Is(err, isbn.ErrNoRange) {
+		t.Errorf("Expected ErrNoRange before ranges are set, got %v", err)
+	}
+
+	isbn.SetRanges(parseRanges(t, testRanges))
+	if got, err := isbn.Hyphenate("9780132350884"); err != nil || got != "978-0-13-235088-4" {
+		t.Errorf("Expected 978-0-13-235088-4, got %q (%v)", got, err)
+	}
+	if got, err := isbn.Hyphenate10("9780132350884"); err != nil || got != "0-13-235088-2" {
+		t.Errorf("Expected 0-13-235088-2, got %q (%v)", got, err)
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ContributorRole is the part an author played in a book
+type ContributorRole string
+
+const (
+	RoleAuthor     ContributorRole = "author"
+	RoleEditor     ContributorRole = "editor"
+	RoleTranslator ContributorRole = "translator"
+)
+
+// Author is a person credited on books
+type Author struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Bio       string    `json:"bio,omitempty"`
+	BookCount int       `json:"book_count"` // Books crediting the author; set by the store
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AuthorRequest is used for author creation and update operations
+type AuthorRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=200"`
+	Bio  string `json:"bio" binding:"max=5000"`
+}
+
+// Contributor links a book to an author in a role. A book's contributors
+// are in credit order. Either AuthorID or Name identifies the author; an
+// unknown name creates the author.
+type Contributor struct {
+	AuthorID string          `json:"author_id" binding:"max=100"`
+	Name     string          `json:"name" binding:"max=200"`
+	Role     ContributorRole `json:"role" binding:"omitempty,oneof=author editor translator"` // Defaults to author
+}
+
+// authorSeparator splits legacy author strings such as "Andrew Hunt, David
+// Thomas" or "Hunt & Thomas"
+var authorSeparator = regexp.MustCompile(`\s*(?:[,;&]|\band\b)\s*`)
+
+// SplitAuthors splits a legacy author string into individual names
+func SplitAuthors(s string) []string {
+	var names []string
+	for _, name := range authorSeparator.Split(s, -1) {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// JoinAuthors returns the names of the contributors credited as authors,
+// comma separated, as kept in Book.Author
+func JoinAuthors(contributors []Contributor) string {
+	var names []string
+	for _, c := range contributors {
+		if c.Role == RoleAuthor {
+			names = append(names, c.Name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -1,28 +1,126 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
+
+	"github.com/godwin/book-store-api/internal/money"
 )
 
 // Book represents a book in the bookstore
 type Book struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title" binding:"required,min=1,max=200"`
-	Author      string    `json:"author" binding:"required,min=1,max=200"`
-	ISBN        string    `json:"isbn" binding:"required,isbn"`
-	PublishedAt time.Time `json:"published_at" binding:"required"`
-	Price       float64   `json:"price" binding:"required,gte=0"`
-	Quantity    int       `json:"quantity" binding:"gte=0"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                 string            `json:"id"`
+	Title              string            `json:"title" binding:"required,min=1,max=200"`
+	Author             string            `json:"author" binding:"max=1000"` // Credited authors, comma separated; set by the store from Contributors
+	Contributors       []Contributor     `json:"contributors" binding:"max=50,dive"`
+	Categories         []CategoryRef     `json:"categories" binding:"max=50,dive"`
+	ISBN               string            `json:"isbn" binding:"required,max=20"` // Canonical ISBN-13; set by the store
+	ISBN10             string            `json:"isbn10,omitempty"`               // Set by the store for 978 ISBNs
+	Hyphenated         string            `json:"isbn_hyphenated,omitempty"`      // Set by the store when the ISBN's ranges are known
+	PublishedAt        time.Time         `json:"published_at" binding:"required"`
+	Price              money.Money       `json:"price"`
+	Prices             money.Prices      `json:"prices,omitempty"`           // Fixed prices in other currencies, used instead of converting Price
+	ListPrice          *money.Money      `json:"list_price,omitempty"`       // Shown price before promotions; set on responses
+	SalePrice          *money.Money      `json:"sale_price,omitempty"`       // Shown price after promotions; set on responses
+	LowestPrice        *money.Money      `json:"lowest_price_30d,omitempty"` // Lowest shown price in the last 30 days; set on responses
+	Quantity           int               `json:"quantity" binding:"gte=0"`
+	Reserved           int               `json:"reserved"`                       // Copies held by active reservations; set by the store
+	Rating             Rating            `json:"rating"`                         // Summary of approved reviews; set by the store
+	CoverURL           string            `json:"cover_url,omitempty"`            // Set by the store from Cover
+	CoverThumbnailURLs map[string]string `json:"cover_thumbnail_urls,omitempty"` // By thumbnail name; set by the store from Cover
+	Cover              *Cover            `json:"-"`
+	CreatedAt          time.Time         `json:"created_at"`
+	UpdatedAt          time.Time         `json:"updated_at"`
+}
+
+// MarshalJSON adds the currency of Price and the shown prices, which are
+// written as bare decimals
+func (b Book) MarshalJSON() ([]byte, error) {
+	type plain Book
+	return json.Marshal(struct {
+		plain
+		Currency string `json:"currency"`
+	}{plain(b), b.Price.Currency})
+}
+
+// UnmarshalJSON reads Price and the shown prices in the book's currency, or
+// DefaultCurrency if it has none
+func (b *Book) UnmarshalJSON(data []byte) error {
+	type plain Book
+	v := struct {
+		*plain
+		Price       json.RawMessage `json:"price"`
+		ListPrice   json.RawMessage `json:"list_price"`
+		SalePrice   json.RawMessage `json:"sale_price"`
+		LowestPrice json.RawMessage `json:"lowest_price_30d"`
+		Currency    string          `json:"currency"`
+	}{plain: (*plain)(b)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var err error
+	if b.Price, err = money.Decode(v.Price, v.Currency); err != nil {
+		return err
+	}
+	if b.ListPrice, err = decodeOptionalPrice(v.ListPrice, v.Currency); err != nil {
+		return err
+	}
+	if b.SalePrice, err = decodeOptionalPrice(v.SalePrice, v.Currency); err != nil {
+		return err
+	}
+	b.LowestPrice, err = decodeOptionalPrice(v.LowestPrice, v.Currency)
+	return err
+}
+
+// decodeOptionalPrice decodes an optional amount; it's nil if data is absent or null
+func decodeOptionalPrice(data json.RawMessage, currency string) (*money.Money, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return nil, nil
+	}
+	price, err := money.Decode(data, currency)
+	if err != nil {
+		return nil, err
+	}
+	return &price, nil
+}
+
+// PriceIn returns the book's fixed price in the currency, if it has one:
+// Price itself or an entry of Prices
+func (b Book) PriceIn(currency string) (money.Money, bool) {
+	if b.Price.Currency == currency {
+		return b.Price, true
+	}
+	for _, price := range b.Prices {
+		if price.Currency == currency {
+			return price, true
+		}
+	}
+	return money.Money{}, false
+}
+
+// Available is the number of copies that can still be sold or reserved
+func (b Book) Available() int {
+	return b.Quantity - b.Reserved
 }
 
 // BookRequest is used for book creation and update operations
 type BookRequest struct {
-	Title       string    `json:"title" binding:"required,min=1,max=200"`
-	Author      string    `json:"author" binding:"required,min=1,max=200"`
-	ISBN        string    `json:"isbn" binding:"required,isbn"`
-	PublishedAt time.Time `json:"published_at" binding:"required"`
-	Price       float64   `json:"price" binding:"required,gte=0"`
-	Quantity    int       `json:"quantity" binding:"gte=0"`
+	Title        string        `json:"title" binding:"required,min=1,max=200"`
+	Author       string        `json:"author" binding:"max=1000"`
+	Contributors []Contributor `json:"contributors" binding:"max=50,dive"`
+	Categories   []CategoryRef `json:"categories" binding:"max=50,dive"`
+	ISBN         string        `json:"isbn" binding:"required,max=20"`
+	PublishedAt  time.Time     `json:"published_at" binding:"required"`
+	Price        money.Money   `json:"price"`
+	Prices       money.Prices  `json:"prices,omitempty"`
+	Quantity     int           `json:"quantity" binding:"gte=0"`
+}
+
+// BookSearchResult is a book matching a full-text search with its relevance
+// score and highlighted snippets keyed by field name
+type BookSearchResult struct {
+	Book       Book              `json:"book"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
 }
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// Cart is a customer's shopping cart. Owner identifies the customer, either
+// "user:<id>" for a signed-in user or "session:<id>" for an anonymous one.
+type Cart struct {
+	Owner         string             `json:"owner"`
+	Items         []CartItem         `json:"items"`
+	ItemCount     int                `json:"item_count"`
+	Subtotal      money.Money        `json:"subtotal"`
+	Discounts     []AppliedPromotion `json:"discounts"`
+	DiscountTotal money.Money        `json:"discount_total"`
+	Total         money.Money        `json:"total"`
+	Currency      string             `json:"currency,omitempty"` // Currency of every amount in the cart; unset while it's empty
+	CreatedAt     time.Time          `json:"created_at"`
+	UpdatedAt     time.Time          `json:"updated_at"`
+}
+
+// CartItem is a line in a cart. UnitPrice is the book's price when it was
+// first added, so later price changes don't alter the cart.
+type CartItem struct {
+	BookID    string      `json:"book_id"`
+	Title     string      `json:"title"`
+	UnitPrice money.Money `json:"unit_price"`
+	Quantity  int         `json:"quantity"`
+	LineTotal money.Money `json:"line_total"`
+	Discount  money.Money `json:"discount"`
+	AddedAt   time.Time   `json:"added_at"`
+}
+
+// AddCartItemRequest is used to add copies of a book to a cart
+type AddCartItemRequest struct {
+	BookID   string `json:"book_id" binding:"required"`
+	Quantity int    `json:"quantity" binding:"required,gte=1,lte=100"`
+}
+
+// UpdateCartItemRequest is used to change the quantity of a cart line
+type UpdateCartItemRequest struct {
+	Quantity int `json:"quantity" binding:"required,gte=1,lte=100"`
+}
+
+// Recalculate updates the line totals, discounts, item count, subtotal,
+// total and currency from the items and Discounts. It fails if the items are
+// priced in different currencies.
+func (c *Cart) Recalculate() error {
+	c.ItemCount = 0
+	c.Subtotal = money.Money{}
+	for i := range c.Items {
+		c.Items[i].LineTotal = c.Items[i].UnitPrice.Mul(c.Items[i].Quantity)
+		c.ItemCount += c.Items[i].Quantity
+
+		subtotal, err := c.Subtotal.Add(c.Items[i].LineTotal)
+		if err != nil {
+			return err
+		}
+		c.Subtotal = subtotal
+
+		discount, err := lineDiscount(c.Discounts, c.Items[i].BookID, c.Items[i].UnitPrice.Currency)
+		if err != nil {
+			return err
+		}
+		c.Items[i].Discount = discount
+	}
+
+	c.Currency = c.Subtotal.Currency
+
+	var err error
+	c.DiscountTotal, c.Total, err = applyDiscounts(c.Subtotal, c.Discounts)
+	return err
+}
+
+// lineDiscount sums the discounts given on one book
+func lineDiscount(discounts []AppliedPromotion, bookID, currency string) (money.Money, error) {
+	total := money.New(0, currency)
+	for _, d := range discounts {
+		if d.BookID != bookID {
+			continue
+		}
+		var err error
+		if total, err = total.Add(d.Amount); err != nil {
+			return money.Money{}, err
+		}
+	}
+	return total, nil
+}
+
+// applyDiscounts returns the sum of the discounts and what's left of subtotal after them
+func applyDiscounts(subtotal money.Money, discounts []AppliedPromotion) (money.Money, money.Money, error) {
+	discountTotal := money.New(0, subtotal.Currency)
+	for _, d := range discounts {
+		var err error
+		if discountTotal, err = discountTotal.Add(d.Amount); err != nil {
+			return money.Money{}, money.Money{}, err
+		}
+	}
+	total, err := subtotal.Sub(discountTotal)
+	if err != nil {
+		return money.Money{}, money.Money{}, err
+	}
+	return discountTotal, total, nil
+}
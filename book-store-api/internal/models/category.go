@@ -0,0 +1,89 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/search"
+)
+
+// Category is a node in the category tree. Siblings are ordered by Position
+// and then by name.
+type Category struct {
+	ID        string        `json:"id"`
+	Slug      string        `json:"slug"`
+	Name      string        `json:"name"`
+	ParentID  string        `json:"parent_id,omitempty"`
+	Position  int           `json:"position"`
+	Code      string        `json:"code,omitempty"`      // Subject code, e.g. BISAC COM051000
+	Ancestors []CategoryRef `json:"ancestors,omitempty"` // Root first; set on single-category responses
+	Children  []Category    `json:"children,omitempty"`  // Set on tree responses
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// CategoryRequest is used for category creation and update operations. An
+// empty slug is made from the name, prefixed by the parent's slug.
+type CategoryRequest struct {
+	Name     string `json:"name" binding:"required,min=1,max=200"`
+	Slug     string `json:"slug" binding:"max=200"`
+	ParentID string `json:"parent_id" binding:"max=100"`
+	Position int    `json:"position"`
+	Code     string `json:"code" binding:"max=20"`
+}
+
+// CategoryRef links a book to a category, or names an ancestor. When saving
+// a book, the category is found by ID, slug or code, in that order.
+type CategoryRef struct {
+	ID   string `json:"id" binding:"max=100"`
+	Slug string `json:"slug" binding:"max=200"`
+	Name string `json:"name"`
+	Code string `json:"code,omitempty" binding:"max=20"`
+}
+
+// Ref returns the reference to the category kept on books
+func (c Category) Ref() CategoryRef {
+	return CategoryRef{ID: c.ID, Slug: c.Slug, Name: c.Name, Code: c.Code}
+}
+
+// CategoryImport is a category from a subject scheme: its code and the
+// headings from the root down to it
+type CategoryImport struct {
+	Code string
+	Path []string
+}
+
+// CategoryImportResult counts what an import did
+type CategoryImportResult struct {
+	Created int `json:"created"`
+	Updated int `json:"updated"`
+	Total   int `json:"total"` // Entries read
+}
+
+var (
+	slugPattern  = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+	slugReplacer = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Slugify makes a URL slug from a name, e.g. "Science Fiction & Fantasy"
+// becomes "science-fiction-fantasy"
+func Slugify(name string) string {
+	return strings.Trim(slugReplacer.ReplaceAllString(search.Fold(name), "-"), "-")
+}
+
+// ValidSlug reports whether s is lower-case letters and digits separated by
+// single hyphens
+func ValidSlug(s string) bool {
+	return slugPattern.MatchString(s)
+}
+
+// InCategories reports whether the book is in any of the categories, given by ID
+func (b Book) InCategories(ids map[string]bool) bool {
+	for _, c := range b.Categories {
+		if ids[c.ID] {
+			return true
+		}
+	}
+	return false
+}
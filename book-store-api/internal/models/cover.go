@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Cover is a book's cover image. The original is kept as uploaded, with JPEG
+// thumbnails in a few sizes; Key and the thumbnails' keys locate them in
+// blob storage.
+type Cover struct {
+	URL         string      `json:"url"`
+	Key         string      `json:"-"`
+	ContentType string      `json:"content_type"`
+	Size        int64       `json:"size"`
+	Width       int         `json:"width"`
+	Height      int         `json:"height"`
+	Thumbnails  []Thumbnail `json:"thumbnails"`
+	UploadedAt  time.Time   `json:"uploaded_at"`
+}
+
+// Thumbnail is a scaled-down copy of a cover
+type Thumbnail struct {
+	Name   string `json:"name"`
+	URL    string `json:"url"`
+	Key    string `json:"-"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// Keys returns the blob storage keys of the cover and its thumbnails
+func (c Cover) Keys() []string {
+	keys := []string{c.Key}
+	for _, thumbnail := range c.Thumbnails {
+		keys = append(keys, thumbnail.Key)
+	}
+	return keys
+}
+
+// ThumbnailURLs returns the thumbnails' URLs by name
+func (c Cover) ThumbnailURLs() map[string]string {
+	urls := make(map[string]string, len(c.Thumbnails))
+	for _, thumbnail := range c.Thumbnails {
+		urls[thumbnail.Name] = thumbnail.URL
+	}
+	return urls
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// BookFilter holds the catalog filters shared by listing and faceting
+type BookFilter struct {
+	Title    string
+	Author   string
+	AuthorID string // Only books crediting this author, in any role
+	// Categories, if set, are the IDs of the categories a book must be in one of
+	Categories map[string]bool
+	MinPrice   *money.Money
+	MaxPrice   *money.Money
+	Expr       BookExpr // Parsed filter= expression, if any
+
+	// Currency is the currency prices are shown in, and the currency of the
+	// price facet ranges. Empty means the default currency.
+	Currency string
+	// Localize, if set, prices a book in Currency. It is applied before any
+	// other filter, and to the books that are sorted and returned, so price
+	// bounds, sorting and facets all see the shown price.
+	Localize func(book Book) Book
+}
+
+// Apply localizes the book and reports whether it passes the filters
+func (f BookFilter) Apply(book Book) (Book, bool) {
+	if f.Localize != nil {
+		book = f.Localize(book)
+	}
+	return book, f.Matches(book)
+}
+
+// PriceCurrency returns the currency of the price facet ranges
+func (f BookFilter) PriceCurrency() string {
+	if f.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return f.Currency
+}
+
+// BookExpr is a parsed filter expression that can be evaluated against a book
+type BookExpr interface {
+	Matches(book Book) bool
+}
+
+// Matches reports whether the book passes every filter that is set
+func (f BookFilter) Matches(book Book) bool {
+	if f.Title != "" && !strings.Contains(strings.ToLower(book.Title), strings.ToLower(f.Title)) {
+		return false
+	}
+	if f.Author != "" && !strings.Contains(strings.ToLower(book.Author), strings.ToLower(f.Author)) {
+		return false
+	}
+	if f.AuthorID != "" && !book.HasContributor(f.AuthorID) {
+		return false
+	}
+	if f.Categories != nil && !book.InCategories(f.Categories) {
+		return false
+	}
+	// Prices in another currency than the bound can't be compared, so they don't match
+	if f.MinPrice != nil && (book.Price.Currency != f.MinPrice.Currency || book.Price.Cmp(*f.MinPrice) < 0) {
+		return false
+	}
+	if f.MaxPrice != nil && (book.Price.Currency != f.MaxPrice.Currency || book.Price.Cmp(*f.MaxPrice) > 0) {
+		return false
+	}
+	if f.Expr != nil && !f.Expr.Matches(book) {
+		return false
+	}
+	return true
+}
+
+// FacetValue is a single value of a facet and the number of books that have it
+type FacetValue struct {
+	Value string       `json:"value"`
+	Count int          `json:"count"`
+	Min   *money.Money `json:"min,omitempty"`
+	Max   *money.Money `json:"max,omitempty"`
+}
+
+// BookFacets holds the counts used to build catalog sidebar filters
+type BookFacets struct {
+	Total        int          `json:"total"`
+	Currency     string       `json:"currency"` // Currency of the price range bounds
+	Authors      []FacetValue `json:"author"`
+	Decades      []FacetValue `json:"decade"`
+	PriceRanges  []FacetValue `json:"price"`
+	Availability []FacetValue `json:"availability"`
+}
+
+// PriceBucket is a half-open price range [Min, Max) in whole units of the
+// shown currency. A zero Max is unbounded.
+type PriceBucket struct {
+	Label string
+	Min   int64
+	Max   int64
+}
+
+// PriceBuckets are the ranges used for the price facet
+var PriceBuckets = []PriceBucket{
+	{Label: "0-10", Min: 0, Max: 10},
+	{Label: "10-20", Min: 10, Max: 20},
+	{Label: "20-30", Min: 20, Max: 30},
+	{Label: "30-50", Min: 30, Max: 50},
+	{Label: "50+", Min: 50},
+}
+
+// Range returns the bucket's bounds in the currency; max is nil if unbounded
+func (b PriceBucket) Range(currency string) (min money.Money, max *money.Money) {
+	min, _ = money.Parse(strconv.FormatInt(b.Min, 10), currency)
+	if b.Max > 0 {
+		m, _ := money.Parse(strconv.FormatInt(b.Max, 10), currency)
+		max = &m
+	}
+	return min, max
+}
+
+// Contains reports whether the price falls in the bucket. Prices in another
+// currency than the facet's fall in no bucket.
+func (b PriceBucket) Contains(price money.Money, currency string) bool {
+	if price.Currency != currency {
+		return false
+	}
+	min, max := b.Range(currency)
+	return price.Cmp(min) >= 0 && (max == nil || price.Cmp(*max) < 0)
+}
+
+// Availability facet values
+const (
+	AvailabilityInStock    = "in_stock"
+	AvailabilityOutOfStock = "out_of_stock"
+)
+
+// Authors returns the names of the book's credited authors, in order. A
+// book without contributors has its author string split instead.
+func (b Book) Authors() []string {
+	if len(b.Contributors) == 0 {
+		return SplitAuthors(b.Author)
+	}
+	var authors []string
+	for _, c := range b.Contributors {
+		if c.Role == RoleAuthor {
+			authors = append(authors, c.Name)
+		}
+	}
+	return authors
+}
+
+// HasContributor reports whether the author is credited on the book in any role
+func (b Book) HasContributor(authorID string) bool {
+	for _, c := range b.Contributors {
+		if c.AuthorID == authorID {
+			return true
+		}
+	}
+	return false
+}
+
+// Decade returns the publication decade label, e.g. "1990s"
+func (b Book) Decade() string {
+	if b.PublishedAt.IsZero() {
+		return ""
+	}
+	return strconv.Itoa(b.PublishedAt.Year()/10*10) + "s"
+}
@@ -0,0 +1,66 @@
+package models
+
+// Book fields an import row can leave out. An existing book keeps its own
+// values for the ones a row omits.
+const (
+	ImportPrices     = "prices"
+	ImportCategories = "categories"
+	ImportQuantity   = "quantity"
+)
+
+// MaxImportErrors caps the row errors reported for one import; the rest are
+// only counted
+const MaxImportErrors = 1000
+
+// ImportAction is what importing a row did, or would do in a dry run
+type ImportAction string
+
+const (
+	ImportCreated ImportAction = "created"
+	ImportUpdated ImportAction = "updated"
+)
+
+// ImportRow is a book read from an import file. Books are matched to
+// existing books by ISBN.
+type ImportRow struct {
+	Line     int    // Line of the file the row starts on
+	Record   string // The source's own reference for the row, if it has one
+	Book     Book
+	Omitted  map[string]bool // ImportPrices, ImportCategories and ImportQuantity when missing from the row
+	Unmapped []string        // Data in the row that has no place in a book
+}
+
+// ImportError reports why a row wasn't imported
+type ImportError struct {
+	Line   int    `json:"line"`
+	Record string `json:"record,omitempty"`
+	ISBN   string `json:"isbn,omitempty"`
+	Error  string `json:"error"`
+}
+
+// ImportResult summarizes an import. In a dry run nothing is saved, and
+// Created and Updated count what would have been.
+type ImportResult struct {
+	DryRun   bool          `json:"dry_run"`
+	Rows     int           `json:"rows"`
+	Created  int           `json:"created"`
+	Updated  int           `json:"updated"`
+	Failed   int           `json:"failed"`
+	Errors   []ImportError `json:"errors"`             // At most MaxImportErrors, in file order
+	Unmapped []ImportError `json:"unmapped,omitempty"` // Data left out of imported rows; at most MaxImportErrors
+}
+
+// AddUnmapped notes data that was left out of an imported row, if there is room
+func (r *ImportResult) AddUnmapped(e ImportError) {
+	if len(r.Unmapped) < MaxImportErrors {
+		r.Unmapped = append(r.Unmapped, e)
+	}
+}
+
+// AddError counts a failed row, keeping its error if there is room
+func (r *ImportResult) AddError(e ImportError) {
+	r.Failed++
+	if len(r.Errors) < MaxImportErrors {
+		r.Errors = append(r.Errors, e)
+	}
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+)
+
+// MovementType is the reason category for a change in stock
+type MovementType string
+
+const (
+	MovementReceipt    MovementType = "receipt"    // Stock received from a supplier
+	MovementSale       MovementType = "sale"       // Stock sold to a customer
+	MovementReturn     MovementType = "return"     // Stock returned by a customer
+	MovementAdjustment MovementType = "adjustment" // Correction after a count, damage, etc.
+)
+
+// StockMovement is an entry in a book's inventory ledger. A book's Quantity
+// is the sum of the Delta of all of its movements.
+type StockMovement struct {
+	ID           string       `json:"id"`
+	BookID       string       `json:"book_id"`
+	Type         MovementType `json:"type"`
+	Delta        int          `json:"delta"`
+	BalanceAfter int          `json:"balance_after"`
+	Reason       string       `json:"reason"`
+	Actor        string       `json:"actor"`
+	CreatedAt    time.Time    `json:"created_at"`
+}
+
+// StockMovementRequest is used to post a movement. Quantity is a positive
+// number of copies for receipts, sales and returns, and a signed change for
+// adjustments.
+type StockMovementRequest struct {
+	Type     MovementType `json:"type" binding:"required,oneof=receipt sale return adjustment"`
+	Quantity int          `json:"quantity" binding:"required"`
+	Reason   string       `json:"reason" binding:"max=500"`
+}
+
+// Delta converts the request quantity to a signed change in stock
+func (r StockMovementRequest) Delta() int {
+	switch r.Type {
+	case MovementSale:
+		return -abs(r.Quantity)
+	case MovementReceipt, MovementReturn:
+		return abs(r.Quantity)
+	}
+	return r.Quantity
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"time"
+
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// OrderStatus is the state of an order. The statuses and the JSON shape of
+// Order match the orders service.
+type OrderStatus string
+
+const (
+	OrderPending    OrderStatus = "pending"    // Placed; stock is reserved
+	OrderProcessing OrderStatus = "processing" // Being fulfilled
+	OrderCompleted  OrderStatus = "completed"  // Fulfilled; stock was sold
+	OrderCancelled  OrderStatus = "cancelled"  // Abandoned; reserved stock was returned
+)
+
+// orderTransitions lists the statuses each status can move to
+var orderTransitions = map[OrderStatus][]OrderStatus{
+	OrderPending:    {OrderProcessing, OrderCancelled},
+	OrderProcessing: {OrderCompleted, OrderCancelled},
+}
+
+// Valid reports whether s is a known order status
+func (s OrderStatus) Valid() bool {
+	switch s {
+	case OrderPending, OrderProcessing, OrderCompleted, OrderCancelled:
+		return true
+	}
+	return false
+}
+
+// CanTransition reports whether an order in status s can move to status to
+func (s OrderStatus) CanTransition(to OrderStatus) bool {
+	for _, next := range orderTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Order is a sale of books, placed directly or by checking out a cart. Each
+// item's copies are held by a reservation until the order completes.
+type Order struct {
+	ID              string             `json:"id"`
+	CustomerID      string             `json:"customer_id"`
+	Items           []OrderItem        `json:"items"`
+	Status          OrderStatus        `json:"status"`
+	Discounts       []AppliedPromotion `json:"discounts,omitempty"`
+	DiscountTotal   money.Money        `json:"discount_total"`
+	Total           money.Money        `json:"total"`
+	Currency        string             `json:"currency"` // Currency of every amount in the order
+	ShippingAddress string             `json:"shipping_address"`
+	BillingAddress  string             `json:"billing_address"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+}
+
+// OrderItem is a line in an order. ProductID is the book's ID.
+type OrderItem struct {
+	ID            string      `json:"id"`
+	ProductID     string      `json:"product_id"`
+	Title         string      `json:"title"`
+	Quantity      int         `json:"quantity"`
+	UnitPrice     money.Money `json:"unit_price"`
+	Subtotal      money.Money `json:"subtotal"`
+	Discount      money.Money `json:"discount"`
+	ReservationID string      `json:"reservation_id,omitempty"`
+}
+
+// Recalculate updates the item subtotals and discounts and the order total
+// and currency from the items and Discounts. Subtotals are before discounts, as in the
+// orders service; the total is after them. It fails if the items are priced
+// in different currencies.
+func (o *Order) Recalculate() error {
+	subtotal := money.Money{}
+	for i := range o.Items {
+		o.Items[i].Subtotal = o.Items[i].UnitPrice.Mul(o.Items[i].Quantity)
+
+		var err error
+		if subtotal, err = subtotal.Add(o.Items[i].Subtotal); err != nil {
+			return err
+		}
+		if o.Items[i].Discount, err = lineDiscount(o.Discounts, o.Items[i].ProductID, o.Items[i].UnitPrice.Currency); err != nil {
+			return err
+		}
+	}
+
+	o.Currency = subtotal.Currency
+
+	var err error
+	o.DiscountTotal, o.Total, err = applyDiscounts(subtotal, o.Discounts)
+	return err
+}
+
+// OrderFilter narrows a list of orders. Empty fields match everything.
+type OrderFilter struct {
+	CustomerID string
+	Status     OrderStatus
+	Skip       int
+	Limit      int
+}
+
+// CreateOrderRequest is used to place an order directly. Prices come from
+// the catalog, not the request.
+type CreateOrderRequest struct {
+	CustomerID      string                   `json:"customer_id"`
+	Items           []CreateOrderItemRequest `json:"items" binding:"required,min=1,dive"`
+	ShippingAddress string                   `json:"shipping_address" binding:"required,max=500"`
+	BillingAddress  string                   `json:"billing_address" binding:"max=500"`
+}
+
+// CreateOrderItemRequest is a line in a CreateOrderRequest
+type CreateOrderItemRequest struct {
+	ProductID string `json:"product_id" binding:"required"`
+	Quantity  int    `json:"quantity" binding:"required,gte=1,lte=100"`
+}
+
+// CheckoutRequest gives the addresses for the order created from a cart
+type CheckoutRequest struct {
+	ShippingAddress string `json:"shipping_address" binding:"required,max=500"`
+	BillingAddress  string `json:"billing_address" binding:"max=500"`
+}
+
+// UpdateOrderStatusRequest is used to move an order to a new status
+type UpdateOrderStatusRequest struct {
+	Status OrderStatus `json:"status" binding:"required"`
+}
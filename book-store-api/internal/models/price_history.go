@@ -0,0 +1,96 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// LowestPriceWindow is how far back the lowest recent price of a book looks
+const LowestPriceWindow = 30 * 24 * time.Hour
+
+// PriceChange is an entry in a book's price history. It records the book's
+// prices from ChangedAt until the next change.
+type PriceChange struct {
+	ID            string       `json:"id"`
+	BookID        string       `json:"book_id"`
+	Price         money.Money  `json:"price"`
+	Prices        money.Prices `json:"prices,omitempty"`
+	PreviousPrice *money.Money `json:"previous_price,omitempty"` // Unset for the opening price
+	Actor         string       `json:"actor"`
+	ChangedAt     time.Time    `json:"changed_at"`
+}
+
+// MarshalJSON adds the currencies of Price and PreviousPrice, which are
+// written as bare decimals. A base price can change currency, so each has
+// its own.
+func (p PriceChange) MarshalJSON() ([]byte, error) {
+	type plain PriceChange
+	v := struct {
+		plain
+		Currency         string `json:"currency"`
+		PreviousCurrency string `json:"previous_currency,omitempty"`
+	}{plain: plain(p), Currency: p.Price.Currency}
+	if p.PreviousPrice != nil {
+		v.PreviousCurrency = p.PreviousPrice.Currency
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads Price and PreviousPrice in their currencies
+func (p *PriceChange) UnmarshalJSON(data []byte) error {
+	type plain PriceChange
+	v := struct {
+		*plain
+		Price            json.RawMessage `json:"price"`
+		PreviousPrice    json.RawMessage `json:"previous_price"`
+		Currency         string          `json:"currency"`
+		PreviousCurrency string          `json:"previous_currency"`
+	}{plain: (*plain)(p)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var err error
+	if p.Price, err = money.Decode(v.Price, v.Currency); err != nil {
+		return err
+	}
+	p.PreviousPrice, err = decodeOptionalPrice(v.PreviousPrice, v.PreviousCurrency)
+	return err
+}
+
+// Book returns the book as it was priced by the change
+func (p PriceChange) Book(book Book) Book {
+	book.Price = p.Price
+	book.Prices = p.Prices
+	return book
+}
+
+// SamePrices reports whether two books have the same base price and the
+// same fixed prices, in any order
+func (b Book) SamePrices(other Book) bool {
+	if b.Price != other.Price || len(b.Prices) != len(other.Prices) {
+		return false
+	}
+	for _, price := range b.Prices {
+		if otherPrice, ok := other.PriceIn(price.Currency); !ok || otherPrice != price {
+			return false
+		}
+	}
+	return true
+}
+
+// PricesSince returns the changes that were in effect at some point from
+// since onwards: the one in effect at since and every later one. History is
+// oldest first.
+func PricesSince(history []PriceChange, since time.Time) []PriceChange {
+	start := 0
+	for i, change := range history {
+		if change.ChangedAt.After(since) {
+			break
+		}
+		start = i
+	}
+	return history[start:]
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/isbn"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// PromotionType is the kind of discount a promotion gives
+type PromotionType string
+
+const (
+	PromotionPercentage PromotionType = "percentage"  // Percent off each copy
+	PromotionFixed      PromotionType = "fixed"       // A fixed amount off each copy
+	PromotionBuyXGetY   PromotionType = "buy_x_get_y" // Get copies free for every Buy copies bought
+)
+
+// Promotion is a discount rule. It applies to books matching its conditions
+// between StartsAt and EndsAt. Promotions are applied highest Priority first;
+// once a promotion that isn't Stackable applies, no more are applied to that
+// line.
+type Promotion struct {
+	ID          string              `json:"id"`
+	Name        string              `json:"name" binding:"required,max=200"`
+	Description string              `json:"description" binding:"max=1000"`
+	Type        PromotionType       `json:"type" binding:"required,oneof=percentage fixed buy_x_get_y"`
+	Percent     int                 `json:"percent,omitempty" binding:"gte=0,lte=100"`
+	Amount      *money.Money        `json:"amount,omitempty"`
+	Buy         int                 `json:"buy,omitempty" binding:"gte=0"`
+	Get         int                 `json:"get,omitempty" binding:"gte=0"`
+	Conditions  PromotionConditions `json:"conditions"`
+	StartsAt    *time.Time          `json:"starts_at,omitempty"`
+	EndsAt      *time.Time          `json:"ends_at,omitempty"`
+	Priority    int                 `json:"priority"`
+	Stackable   bool                `json:"stackable"`
+	CreatedAt   time.Time           `json:"created_at"`
+	UpdatedAt   time.Time           `json:"updated_at"`
+}
+
+// PromotionConditions restrict the books a promotion applies to. Empty
+// conditions match every book; price bounds only match prices in their currency.
+type PromotionConditions struct {
+	Authors  []string     `json:"authors,omitempty"`
+	ISBNs    []string     `json:"isbns,omitempty"`
+	MinPrice *money.Money `json:"min_price,omitempty"`
+	MaxPrice *money.Money `json:"max_price,omitempty"`
+}
+
+// AppliedPromotion explains a discount given by a promotion
+type AppliedPromotion struct {
+	PromotionID string      `json:"promotion_id"`
+	Name        string      `json:"name"`
+	BookID      string      `json:"book_id"`
+	Quantity    int         `json:"quantity"` // Copies the discount was given on
+	Amount      money.Money `json:"amount"`   // Total discount for the line
+}
+
+// MarshalJSON adds the currency of Amount, which is written as a bare decimal
+func (p Promotion) MarshalJSON() ([]byte, error) {
+	type plain Promotion
+	v := struct {
+		plain
+		Currency string `json:"currency,omitempty"`
+	}{plain: plain(p)}
+	if p.Amount != nil {
+		v.Currency = p.Amount.Currency
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads Amount in the promotion's currency, or DefaultCurrency
+// if it has none
+func (p *Promotion) UnmarshalJSON(data []byte) error {
+	type plain Promotion
+	v := struct {
+		*plain
+		Amount   json.RawMessage `json:"amount"`
+		Currency string          `json:"currency"`
+	}{plain: (*plain)(p)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var err error
+	p.Amount, err = decodeOptionalPrice(v.Amount, v.Currency)
+	return err
+}
+
+// MarshalJSON adds the currency of the price bounds, which are written as
+// bare decimals
+func (c PromotionConditions) MarshalJSON() ([]byte, error) {
+	type plain PromotionConditions
+	v := struct {
+		plain
+		Currency string `json:"currency,omitempty"`
+	}{plain: plain(c)}
+	switch {
+	case c.MinPrice != nil:
+		v.Currency = c.MinPrice.Currency
+	case c.MaxPrice != nil:
+		v.Currency = c.MaxPrice.Currency
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON reads the price bounds in the conditions' currency, or
+// DefaultCurrency if they have none
+func (c *PromotionConditions) UnmarshalJSON(data []byte) error {
+	type plain PromotionConditions
+	v := struct {
+		*plain
+		MinPrice json.RawMessage `json:"min_price"`
+		MaxPrice json.RawMessage `json:"max_price"`
+		Currency string          `json:"currency"`
+	}{plain: (*plain)(c)}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+
+	var err error
+	if c.MinPrice, err = decodeOptionalPrice(v.MinPrice, v.Currency); err != nil {
+		return err
+	}
+	c.MaxPrice, err = decodeOptionalPrice(v.MaxPrice, v.Currency)
+	return err
+}
+
+// Validate checks the fields the promotion's type needs
+func (p Promotion) Validate() error {
+	switch p.Type {
+	case PromotionPercentage:
+		if p.Percent < 1 || p.Percent > 100 {
+			return errors.New("percent must be between 1 and 100")
+		}
+	case PromotionFixed:
+		if p.Amount == nil || p.Amount.Amount <= 0 {
+			return errors.New("amount must be positive")
+		}
+	case PromotionBuyXGetY:
+		if p.Buy < 1 || p.Get < 1 {
+			return errors.New("buy and get must be at least 1")
+		}
+	default:
+		return errors.New("unknown promotion type")
+	}
+	if c := p.Conditions; c.MinPrice != nil && c.MaxPrice != nil && c.MinPrice.Currency != c.MaxPrice.Currency {
+		return errors.New("min_price and max_price must be in the same currency")
+	}
+	for _, s := range p.Conditions.ISBNs {
+		if !isbn.Valid(s) {
+			return fmt.Errorf("invalid ISBN %q", s)
+		}
+	}
+	if p.StartsAt != nil && p.EndsAt != nil && !p.EndsAt.After(*p.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// ActiveAt reports whether the promotion runs at the given time
+func (p Promotion) ActiveAt(now time.Time) bool {
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo reports whether the book, at the given unit price, meets the
+// promotion's conditions
+func (p Promotion) AppliesTo(book Book, price money.Money) bool {
+	c := p.Conditions
+
+	if len(c.Authors) > 0 && !matchesAuthor(book, c.Authors) {
+		return false
+	}
+	if len(c.ISBNs) > 0 && !matchesISBN(book, c.ISBNs) {
+		return false
+	}
+	if c.MinPrice != nil && (price.Currency != c.MinPrice.Currency || price.Cmp(*c.MinPrice) < 0) {
+		return false
+	}
+	if c.MaxPrice != nil && (price.Currency != c.MaxPrice.Currency || price.Cmp(*c.MaxPrice) > 0) {
+		return false
+	}
+	return true
+}
+
+func matchesAuthor(book Book, authors []string) bool {
+	for _, have := range book.Authors() {
+		for _, want := range authors {
+			if strings.EqualFold(have, strings.TrimSpace(want)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchesISBN(book Book, isbns []string) bool {
+	for _, want := range isbns {
+		if want, err := isbn.Normalize(want); err == nil && want == book.ISBN {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,214 @@
+package models
+
+import (
+	"cmp"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// SortField is a book field that listings can be ordered by
+type SortField string
+
+const (
+	SortByTitle       SortField = "title"
+	SortByPrice       SortField = "price"
+	SortByPublishedAt SortField = "published_at"
+	SortByCreatedAt   SortField = "created_at"
+	SortByRating      SortField = "rating" // Average rating, then number of ratings
+)
+
+// ErrInvalidSort is returned when a sort parameter names an unknown field
+var ErrInvalidSort = errors.New("invalid sort field")
+
+// SortKey orders results by a field in one direction
+type SortKey struct {
+	Field      SortField
+	Descending bool
+}
+
+// Page selects a window of results
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// BookQuery describes a filtered, sorted and paginated book listing. Results
+// are always ordered by ID after the requested sort keys so pages are stable.
+// When Cursor is set it replaces Sort and Page.Offset.
+type BookQuery struct {
+	Filter BookFilter
+	Sort   []SortKey
+	Page   Page
+	Cursor *Cursor
+}
+
+// BookList is one page of a book listing and the total number of matches.
+// HasNext and HasPrev report whether there are matches after the last item
+// and before the first item of the page.
+type BookList struct {
+	Items   []Book `json:"books"`
+	Total   int    `json:"total"`
+	HasNext bool   `json:"-"`
+	HasPrev bool   `json:"-"`
+}
+
+// ErrInvalidCursor is returned when a cursor can't be decoded or doesn't
+// match the requested sort order
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a sorted listing by the sort key values and ID
+// of the book at that position. A forward cursor selects the books after the
+// position; a backward cursor selects the books before it.
+type Cursor struct {
+	Sort     []SortKey
+	Values   []string
+	ID       string
+	Backward bool
+}
+
+// NewCursor returns a cursor positioned at the book
+func NewCursor(book Book, keys []SortKey, backward bool) Cursor {
+	values := make([]string, len(keys))
+	for i, key := range keys {
+		values[i] = formatField(book, key.Field)
+	}
+	return Cursor{Sort: keys, Values: values, ID: book.ID, Backward: backward}
+}
+
+// Boundary returns a book with the cursor's sort fields and ID set, for
+// comparing against with CompareBooks
+func (c Cursor) Boundary() (Book, error) {
+	if len(c.Values) != len(c.Sort) {
+		return Book{}, ErrInvalidCursor
+	}
+
+	book := Book{ID: c.ID}
+	for i, key := range c.Sort {
+		if err := parseField(&book, key.Field, c.Values[i]); err != nil {
+			return Book{}, ErrInvalidCursor
+		}
+	}
+	return book, nil
+}
+
+// FormatSort is the inverse of ParseSort
+func FormatSort(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, key := range keys {
+		parts[i] = string(key.Field)
+		if key.Descending {
+			parts[i] = "-" + parts[i]
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+func formatField(book Book, field SortField) string {
+	switch field {
+	case SortByTitle:
+		return book.Title
+	case SortByPrice:
+		return book.Price.String()
+	case SortByPublishedAt:
+		return book.PublishedAt.Format(time.RFC3339Nano)
+	case SortByCreatedAt:
+		return book.CreatedAt.Format(time.RFC3339Nano)
+	case SortByRating:
+		return strconv.FormatFloat(book.Rating.Average, 'f', -1, 64) + "/" + strconv.Itoa(book.Rating.Count)
+	}
+	return ""
+}
+
+func parseField(book *Book, field SortField, value string) error {
+	var err error
+	switch field {
+	case SortByTitle:
+		book.Title = value
+	case SortByPrice:
+		book.Price, err = money.ParseString(value)
+	case SortByPublishedAt:
+		book.PublishedAt, err = time.Parse(time.RFC3339Nano, value)
+	case SortByCreatedAt:
+		book.CreatedAt, err = time.Parse(time.RFC3339Nano, value)
+	case SortByRating:
+		average, count, ok := strings.Cut(value, "/")
+		if !ok {
+			return ErrInvalidCursor
+		}
+		if book.Rating.Average, err = strconv.ParseFloat(average, 64); err == nil {
+			book.Rating.Count, err = strconv.Atoi(count)
+		}
+	default:
+		err = ErrInvalidSort
+	}
+	return err
+}
+
+// DefaultSort orders books by creation time, oldest first
+var DefaultSort = []SortKey{{Field: SortByCreatedAt}}
+
+// ParseSort parses a comma separated list of sort fields, each optionally
+// prefixed with "-" for descending order, e.g. "-price,title"
+func ParseSort(s string) ([]SortKey, error) {
+	if strings.TrimSpace(s) == "" {
+		return DefaultSort, nil
+	}
+
+	var keys []SortKey
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		key := SortKey{}
+		if strings.HasPrefix(part, "-") {
+			key.Descending = true
+			part = part[1:]
+		}
+
+		switch field := SortField(part); field {
+		case SortByTitle, SortByPrice, SortByPublishedAt, SortByCreatedAt, SortByRating:
+			key.Field = field
+		default:
+			return nil, ErrInvalidSort
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// CompareBooks orders two books by the sort keys, then by ID. It returns a
+// negative number if a sorts first, positive if b does, and zero if equal.
+func CompareBooks(a, b Book, keys []SortKey) int {
+	for _, key := range keys {
+		c := compareField(a, b, key.Field)
+		if key.Descending {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func compareField(a, b Book, field SortField) int {
+	switch field {
+	case SortByTitle:
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case SortByPrice:
+		return a.Price.Cmp(b.Price)
+	case SortByPublishedAt:
+		return a.PublishedAt.Compare(b.PublishedAt)
+	case SortByCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case SortByRating:
+		if c := cmp.Compare(a.Rating.Average, b.Rating.Average); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Rating.Count, b.Rating.Count)
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+)
+
+// ReservationStatus is the state of a stock reservation
+type ReservationStatus string
+
+const (
+	ReservationActive    ReservationStatus = "active"    // Copies are held
+	ReservationConfirmed ReservationStatus = "confirmed" // Copies were sold
+	ReservationReleased  ReservationStatus = "released"  // Copies were given back
+	ReservationExpired   ReservationStatus = "expired"   // Copies were given back by the sweeper
+)
+
+// Reservation holds copies of a book for a checkout. While active it reduces
+// the book's available quantity but not its on-hand quantity. A reservation
+// without ExpiresAt is held until it is confirmed or released.
+type Reservation struct {
+	ID        string            `json:"id"`
+	BookID    string            `json:"book_id"`
+	Quantity  int               `json:"quantity"`
+	Status    ReservationStatus `json:"status"`
+	Actor     string            `json:"actor"`
+	ExpiresAt time.Time         `json:"expires_at,omitzero"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// Expired reports whether an active reservation has passed its expiry time
+func (r Reservation) Expired(now time.Time) bool {
+	return r.Status == ReservationActive && !r.ExpiresAt.IsZero() && !now.Before(r.ExpiresAt)
+}
+
+// ReservationRequest is used to reserve copies of a book
+type ReservationRequest struct {
+	Quantity   int `json:"quantity" binding:"required,gte=1"`
+	TTLSeconds int `json:"ttl_seconds" binding:"omitempty,gte=1,lte=3600"`
+}
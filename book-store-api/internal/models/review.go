@@ -0,0 +1,98 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// ReviewStatus is the moderation state of a review
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"  // Waiting for a moderator; not shown
+	ReviewApproved ReviewStatus = "approved" // Shown and counted in the book's rating
+	ReviewRejected ReviewStatus = "rejected" // Hidden
+)
+
+// Valid reports whether s is a known review status
+func (s ReviewStatus) Valid() bool {
+	switch s {
+	case ReviewPending, ReviewApproved, ReviewRejected:
+		return true
+	}
+	return false
+}
+
+// Review is a customer's star rating and comments on a book. A customer can
+// review each book once.
+type Review struct {
+	ID             string       `json:"id"`
+	BookID         string       `json:"book_id"`
+	UserID         string       `json:"user_id"`
+	Rating         int          `json:"rating"`
+	Title          string       `json:"title,omitempty"`
+	Body           string       `json:"body,omitempty"`
+	Status         ReviewStatus `json:"status"`
+	ModeratedBy    string       `json:"moderated_by,omitempty"`
+	ModerationNote string       `json:"moderation_note,omitempty"`
+	CreatedAt      time.Time    `json:"created_at"`
+	UpdatedAt      time.Time    `json:"updated_at"`
+}
+
+// ReviewRequest is used to post a review
+type ReviewRequest struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Title  string `json:"title" binding:"max=200"`
+	Body   string `json:"body" binding:"max=5000"`
+}
+
+// ModerateReviewRequest is used to approve or reject a review
+type ModerateReviewRequest struct {
+	Status ReviewStatus `json:"status" binding:"required,oneof=pending approved rejected"`
+	Note   string       `json:"note" binding:"max=500"`
+}
+
+// ReviewFilter selects reviews for listing. Empty fields match every review.
+type ReviewFilter struct {
+	BookID string
+	UserID string
+	Status ReviewStatus
+	Skip   int
+	Limit  int
+}
+
+// Rating summarizes the approved reviews of a book. Histogram counts the
+// reviews with each number of stars, 1 to 5.
+type Rating struct {
+	Average   float64     `json:"average"` // Rounded to two decimal places; 0 without reviews
+	Count     int         `json:"count"`
+	Histogram map[int]int `json:"histogram"`
+}
+
+// NewRating returns an empty rating summary
+func NewRating() Rating {
+	return Rating{Histogram: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+}
+
+// Add counts delta more reviews with the number of stars and updates the
+// average. The histogram is copied, so earlier copies of the rating don't change.
+func (r Rating) Add(stars, delta int) Rating {
+	histogram := make(map[int]int, 5)
+	for s := 1; s <= 5; s++ {
+		histogram[s] = r.Histogram[s]
+	}
+	histogram[stars] += delta
+
+	total := 0
+	r.Count = 0
+	for s, n := range histogram {
+		total += s * n
+		r.Count += n
+	}
+	r.Histogram = histogram
+	r.Average = 0
+	if r.Count > 0 {
+		r.Average = math.Round(float64(total)/float64(r.Count)*100) / 100
+	}
+	return r
+}
@@ -0,0 +1,443 @@
+package models_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []models.SortKey
+		err   error
+	}{
+		{"", models.DefaultSort, nil},
+		{"  ", models.DefaultSort, nil},
+		{"title", []models.SortKey{{Field: models.SortByTitle}}, nil},
+		{"-price,title", []models.SortKey{{Field: models.SortByPrice, Descending: true}, {Field: models.SortByTitle}}, nil},
+		{" published_at , -created_at ", []models.SortKey{{Field: models.SortByPublishedAt}, {Field: models.SortByCreatedAt, Descending: true}}, nil},
+		{"-rating", []models.SortKey{{Field: models.SortByRating, Descending: true}}, nil},
+		{"isbn", nil, models.ErrInvalidSort},
+		{"title,", nil, models.ErrInvalidSort},
+		{"--title", nil, models.ErrInvalidSort},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := models.ParseSort(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormatSort(t *testing.T) {
+	keys := []models.SortKey{{Field: models.SortByPrice, Descending: true}, {Field: models.SortByTitle}}
+	if got := models.FormatSort(keys); got != "-price,title" {
+		t.Errorf("Expected -price,title, got %s", got)
+	}
+}
+
+func TestCompareBooks(t *testing.T) {
+	early := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	late := early.AddDate(1, 0, 0)
+
+	a := models.Book{ID: "a", Title: "alpha", Price: money.New(1000, "USD"), PublishedAt: late}
+	b := models.Book{ID: "b", Title: "Beta", Price: money.New(1000, "USD"), PublishedAt: early}
+
+	tests := []struct {
+		name string
+		keys []models.SortKey
+		want int
+	}{
+		{"title ignores case", []models.SortKey{{Field: models.SortByTitle}}, -1},
+		{"descending title", []models.SortKey{{Field: models.SortByTitle, Descending: true}}, 1},
+		{"published date", []models.SortKey{{Field: models.SortByPublishedAt}}, 1},
+		{"equal prices fall back to ID", []models.SortKey{{Field: models.SortByPrice}}, -1},
+		{"ID breaks ties even when descending", []models.SortKey{{Field: models.SortByPrice, Descending: true}}, -1},
+		{"later keys break ties", []models.SortKey{{Field: models.SortByPrice}, {Field: models.SortByPublishedAt}}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := models.CompareBooks(a, b, tt.keys)
+			if sign(got) != tt.want {
+				t.Errorf("Expected %d, got %d", tt.want, got)
+			}
+			if sign(models.CompareBooks(b, a, tt.keys)) != -tt.want {
+				t.Error("Expected swapping the books to reverse the order")
+			}
+		})
+	}
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+func TestStockMovementRequest_Delta(t *testing.T) {
+	tests := []struct {
+		typ      models.MovementType
+		quantity int
+		want     int
+	}{
+		{models.MovementReceipt, 5, 5},
+		{models.MovementReceipt, -5, 5},
+		{models.MovementReturn, 2, 2},
+		{models.MovementSale, 3, -3},
+		{models.MovementSale, -3, -3},
+		{models.MovementAdjustment, -4, -4},
+		{models.MovementAdjustment, 4, 4},
+	}
+
+	for _, tt := range tests {
+		req := models.StockMovementRequest{Type: tt.typ, Quantity: tt.quantity}
+		if got := req.Delta(); got != tt.want {
+			t.Errorf("%s of %d: expected %d, got %d", tt.typ, tt.quantity, tt.want, got)
+		}
+	}
+}
+
+func TestPromotion_Validate(t *testing.T) {
+	start := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	amount := money.New(500, "USD")
+	zero := money.New(0, "USD")
+	euros := money.New(500, "EUR")
+
+	tests := []struct {
+		name  string
+		promo models.Promotion
+		valid bool
+	}{
+		{"percentage", models.Promotion{Type: models.PromotionPercentage, Percent: 20}, true},
+		{"no percent", models.Promotion{Type: models.PromotionPercentage}, false},
+		{"over 100 percent", models.Promotion{Type: models.PromotionPercentage, Percent: 101}, false},
+		{"fixed", models.Promotion{Type: models.PromotionFixed, Amount: &amount}, true},
+		{"fixed without an amount", models.Promotion{Type: models.PromotionFixed}, false},
+		{"fixed zero", models.Promotion{Type: models.PromotionFixed, Amount: &zero}, false},
+		{"buy 2 get 1", models.Promotion{Type: models.PromotionBuyXGetY, Buy: 2, Get: 1}, true},
+		{"buy 2 get none", models.Promotion{Type: models.PromotionBuyXGetY, Buy: 2}, false},
+		{"unknown type", models.Promotion{Type: "bogof"}, false},
+		{"valid ISBN", models.Promotion{Type: models.PromotionPercentage, Percent: 5, Conditions: models.PromotionConditions{ISBNs: []string{"0-201-48567-2"}}}, true},
+		{"invalid ISBN", models.Promotion{Type: models.PromotionPercentage, Percent: 5, Conditions: models.PromotionConditions{ISBNs: []string{"9780201485670"}}}, false},
+		{"dates in order", models.Promotion{Type: models.PromotionPercentage, Percent: 5, StartsAt: &start, EndsAt: &end}, true},
+		{"ends before it starts", models.Promotion{Type: models.PromotionPercentage, Percent: 5, StartsAt: &end, EndsAt: &start}, false},
+		{"price bounds in one currency", models.Promotion{Type: models.PromotionPercentage, Percent: 5, Conditions: models.PromotionConditions{MinPrice: &zero, MaxPrice: &amount}}, true},
+		{"price bounds in two currencies", models.Promotion{Type: models.PromotionPercentage, Percent: 5, Conditions: models.PromotionConditions{MinPrice: &zero, MaxPrice: &euros}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.promo.Validate(); (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestPromotion_ISBNCondition(t *testing.T) {
+	promo := models.Promotion{Conditions: models.PromotionConditions{ISBNs: []string{"0-201-48567-2"}}}
+	price := money.New(1000, "USD")
+
+	// ISBN-10 conditions match the book's ISBN-13
+	if !promo.AppliesTo(models.Book{ISBN: "9780201485677"}, price) {
+		t.Error("Expected the ISBN-10 condition to match")
+	}
+	if promo.AppliesTo(models.Book{ISBN: "9780132350884"}, price) {
+		t.Error("Expected another ISBN not to match")
+	}
+}
+
+func TestPromotion_JSON(t *testing.T) {
+	var promo models.Promotion
+	input := `{"type": "fixed", "amount": "5", "currency": "EUR", "conditions": {"min_price": "10", "max_price": "40.50", "currency": "GBP"}}`
+	if err := json.Unmarshal([]byte(input), &promo); err != nil {
+		t.Fatal(err)
+	}
+	if promo.Amount == nil || *promo.Amount != money.New(500, "EUR") {
+		t.Errorf("Expected an amount of 5.00 EUR, got %v", promo.Amount)
+	}
+	c := promo.Conditions
+	if c.MinPrice == nil || *c.MinPrice != money.New(1000, "GBP") || c.MaxPrice == nil || *c.MaxPrice != money.New(4050, "GBP") {
+		t.Errorf("Expected bounds of 10.00-40.50 GBP, got %v-%v", c.MinPrice, c.MaxPrice)
+	}
+
+	data, err := json.Marshal(promo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields struct {
+		Amount     string `json:"amount"`
+		Currency   string `json:"currency"`
+		Conditions struct {
+			MinPrice string `json:"min_price"`
+			Currency string `json:"currency"`
+		} `json:"conditions"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields.Amount != "5.00" || fields.Currency != "EUR" || fields.Conditions.MinPrice != "10.00" || fields.Conditions.Currency != "GBP" {
+		t.Errorf("Expected decimal amounts with separate currencies, got %s", data)
+	}
+}
+
+func TestPricesSince(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2026, 6, d, 0, 0, 0, 0, time.UTC) }
+	history := []models.PriceChange{{ID: "1", ChangedAt: day(1)}, {ID: "2", ChangedAt: day(10)}, {ID: "3", ChangedAt: day(20)}}
+
+	tests := []struct {
+		name  string
+		since time.Time
+		want  []string
+	}{
+		{"before the history", day(1).AddDate(0, -1, 0), []string{"1", "2", "3"}},
+		{"between changes", day(15), []string{"2", "3"}},
+		{"at a change", day(10), []string{"2", "3"}},
+		{"after the last change", day(25), []string{"3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, change := range models.PricesSince(history, tt.since) {
+				got = append(got, change.ID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBook_SamePrices(t *testing.T) {
+	gbp, eur := money.New(2500, "GBP"), money.New(2800, "EUR")
+	base := models.Book{Price: money.New(2999, "USD"), Prices: []money.Money{gbp, eur}}
+
+	tests := []struct {
+		name  string
+		other models.Book
+		want  bool
+	}{
+		{"identical", models.Book{Price: money.New(2999, "USD"), Prices: []money.Money{gbp, eur}}, true},
+		{"fixed prices in another order", models.Book{Price: money.New(2999, "USD"), Prices: []money.Money{eur, gbp}}, true},
+		{"different base price", models.Book{Price: money.New(3000, "USD"), Prices: []money.Money{gbp, eur}}, false},
+		{"a fixed price missing", models.Book{Price: money.New(2999, "USD"), Prices: []money.Money{gbp}}, false},
+		{"a fixed price changed", models.Book{Price: money.New(2999, "USD"), Prices: []money.Money{gbp, money.New(2900, "EUR")}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := base.SamePrices(tt.other); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestBook_JSON(t *testing.T) {
+	list := money.New(1500, "JPY")
+	book := models.Book{
+		Title:     "Refactoring",
+		Price:     money.New(1500, "JPY"),
+		Prices:    money.Prices{money.New(999, "GBP")},
+		ListPrice: &list,
+	}
+
+	data, err := json.Marshal(book)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{"price": "1500", "currency": "JPY", "prices": map[string]any{"GBP": "9.99"}, "list_price": "1500"}
+	for field, value := range want {
+		if !reflect.DeepEqual(fields[field], value) {
+			t.Errorf("Expected %s to be %v, got %v", field, value, fields[field])
+		}
+	}
+
+	// Amounts are read in the book's currency
+	var decoded models.Book
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Price != book.Price || !reflect.DeepEqual(decoded.Prices, book.Prices) || decoded.ListPrice == nil || *decoded.ListPrice != list {
+		t.Errorf("Expected %v %v %v, got %v %v %v", book.Price, book.Prices, list, decoded.Price, decoded.Prices, decoded.ListPrice)
+	}
+
+	tests := []struct {
+		input string
+		price money.Money
+		err   error
+	}{
+		{`{"price": "34.99", "currency": "eur"}`, money.New(3499, "EUR"), nil},
+		{`{"price": "34.99"}`, money.New(3499, "USD"), nil},
+		{`{"price": 34.99}`, money.New(3499, "USD"), nil},
+		{`{"price": {"amount": "34.99", "currency": "GBP"}}`, money.New(3499, "GBP"), nil},
+		{`{"price": "34.99", "currency": "JPY"}`, money.Money{}, money.ErrTooPrecise},
+		{`{"price": "34.99", "currency": "XYZ"}`, money.Money{}, money.ErrUnknownCurrency},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got models.Book
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got.Price != tt.price {
+				t.Errorf("Expected %v, got %v", tt.price, got.Price)
+			}
+		})
+	}
+}
+
+func TestOrder_JSON(t *testing.T) {
+	order := models.Order{Items: []models.OrderItem{{ProductID: "1", Quantity: 2, UnitPrice: money.New(1099, "EUR")}}}
+	if err := order.Recalculate(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields struct {
+		Items []struct {
+			UnitPrice string `json:"unit_price"`
+			Subtotal  string `json:"subtotal"`
+		} `json:"items"`
+		Total    string `json:"total"`
+		Currency string `json:"currency"`
+	}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatal(err)
+	}
+	if fields.Items[0].UnitPrice != "10.99" || fields.Items[0].Subtotal != "21.98" || fields.Total != "21.98" || fields.Currency != "EUR" {
+		t.Errorf("Expected decimal amounts in EUR, got %s", data)
+	}
+}
+
+func TestPriceChange_JSON(t *testing.T) {
+	previous := money.New(2999, "USD")
+	change := models.PriceChange{Price: money.New(2500, "GBP"), PreviousPrice: &previous}
+
+	data, err := json.Marshal(change)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded models.PriceChange
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Price != change.Price || decoded.PreviousPrice == nil || *decoded.PreviousPrice != previous {
+		t.Errorf("Expected %v from %v, got %v from %v in %s", change.Price, previous, decoded.Price, decoded.PreviousPrice, data)
+	}
+}
+
+func TestSplitAuthors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"Martin Fowler", []string{"Martin Fowler"}},
+		{"Andrew Hunt, David Thomas", []string{"Andrew Hunt", "David Thomas"}},
+		{"Hunt & Thomas", []string{"Hunt", "Thomas"}},
+		{"Gamma; Helm and Johnson", []string{"Gamma", "Helm", "Johnson"}},
+		{"Alexander Randall", []string{"Alexander Randall"}}, // "and" only as a word
+		{" , Knuth ,, ", []string{"Knuth"}},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := models.SplitAuthors(tt.input); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestJoinAuthors(t *testing.T) {
+	contributors := []models.Contributor{
+		{Name: "Erich Gamma", Role: models.RoleAuthor},
+		{Name: "Grady Booch", Role: models.RoleEditor},
+		{Name: "Richard Helm", Role: models.RoleAuthor},
+		{Name: "Anna Translator", Role: models.RoleTranslator},
+	}
+	if got, want := models.JoinAuthors(contributors), "Erich Gamma, Richard Helm"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Science Fiction & Fantasy", "science-fiction-fantasy"},
+		{"  Computers / Programming  ", "computers-programming"},
+		{"Café Culture", "cafe-culture"},
+		{"C++", "c"},
+		{"1984", "1984"},
+		{"!!!", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := models.Slugify(tt.name)
+			if got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if got != "" && !models.ValidSlug(got) {
+				t.Errorf("Expected %q to be a valid slug", got)
+			}
+		})
+	}
+
+	for _, slug := range []string{"", "Fiction", "fiction-", "-fiction", "science--fiction", "sci_fi"} {
+		if models.ValidSlug(slug) {
+			t.Errorf("Expected %q to be an invalid slug", slug)
+		}
+	}
+}
+
+func TestRating_Add(t *testing.T) {
+	empty := models.NewRating()
+	rating := empty.Add(5, 1).Add(4, 1).Add(4, 1)
+
+	if rating.Count != 3 || rating.Average != 4.33 {
+		t.Errorf("Expected 4.33 from 3 reviews, got %v from %d", rating.Average, rating.Count)
+	}
+	if rating.Histogram[4] != 2 || rating.Histogram[5] != 1 || rating.Histogram[1] != 0 {
+		t.Errorf("Expected two 4s and a 5, got %v", rating.Histogram)
+	}
+
+	// Earlier copies keep their own histogram
+	if empty.Count != 0 || empty.Histogram[5] != 0 {
+		t.Errorf("Expected the empty rating to stay empty, got %+v", empty)
+	}
+
+	if removed := rating.Add(5, -1).Add(4, -1).Add(4, -1); removed.Count != 0 || removed.Average != 0 {
+		t.Errorf("Expected no reviews left, got %+v", removed)
+	}
+}
@@ -0,0 +1,60 @@
+package money
+
+import (
+	"strings"
+)
+
+// DefaultCurrency is assumed for amounts given without a currency, such as
+// bare decimals in JSON and price filters
+const DefaultCurrency = "USD"
+
+// minorUnits maps supported ISO 4217 currency codes to the number of digits
+// after the decimal point
+var minorUnits = map[string]int{
+	"AUD": 2,
+	"BHD": 3,
+	"BRL": 2,
+	"CAD": 2,
+	"CHF": 2,
+	"CNY": 2,
+	"DKK": 2,
+	"EUR": 2,
+	"GBP": 2,
+	"HKD": 2,
+	"INR": 2,
+	"JPY": 0,
+	"KRW": 0,
+	"KWD": 3,
+	"MXN": 2,
+	"NGN": 2,
+	"NOK": 2,
+	"NZD": 2,
+	"PLN": 2,
+	"SEK": 2,
+	"SGD": 2,
+	"USD": 2,
+	"ZAR": 2,
+}
+
+// NormalizeCurrency upper-cases a currency code and checks it is supported
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := minorUnits[code]; !ok {
+		return "", ErrUnknownCurrency
+	}
+	return code, nil
+}
+
+// MinorUnits returns the number of decimal places used by a currency
+func MinorUnits(code string) int {
+	return minorUnits[code]
+}
+
+// scale returns 10^digits
+func scale(digits int) int64 {
+	s := int64(1)
+	for i := 0; i < digits; i++ {
+		s *= 10
+	}
+	return s
+}
@@ -0,0 +1,331 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidAmount is returned for a malformed decimal amount
+	ErrInvalidAmount = errors.New("money: invalid amount")
+	// ErrTooPrecise is returned when an amount has more decimal places than its currency
+	ErrTooPrecise = errors.New("money: too many decimal places for currency")
+	// ErrUnknownCurrency is returned for an unsupported currency code
+	ErrUnknownCurrency = errors.New("money: unknown currency")
+	// ErrCurrencyMismatch is returned when combining amounts in different currencies
+	ErrCurrencyMismatch = errors.New("money: currency mismatch")
+)
+
+// Money is an exact amount in integer minor units (e.g. cents) of an ISO
+// 4217 currency. The zero value has no currency.
+type Money struct {
+	Amount   int64
+	Currency string
+}
+
+// New returns an amount of minor units in the currency
+func New(minor int64, currency string) Money {
+	return Money{Amount: minor, Currency: currency}
+}
+
+// Parse parses a decimal amount such as "37.49" or "-5" in the currency. It
+// fails rather than round if the amount is more precise than the currency allows.
+func Parse(s, currency string) (Money, error) {
+	currency, err := NormalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+
+	s = strings.TrimSpace(s)
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+
+	whole, frac, _ := strings.Cut(s, ".")
+	if whole == "" && frac == "" || !digitsOnly(whole) || !digitsOnly(frac) {
+		return Money{}, ErrInvalidAmount
+	}
+
+	digits := MinorUnits(currency)
+	frac = strings.TrimRight(frac, "0")
+	if len(frac) > digits {
+		return Money{}, ErrTooPrecise
+	}
+	frac += strings.Repeat("0", digits-len(frac))
+
+	amount, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	if negative {
+		amount = -amount
+	}
+
+	return Money{Amount: amount, Currency: currency}, nil
+}
+
+// ParseString parses the output of String, e.g. "37.49 USD". An amount
+// without a currency is in DefaultCurrency.
+func ParseString(s string) (Money, error) {
+	amount, currency, found := strings.Cut(strings.TrimSpace(s), " ")
+	if !found {
+		currency = DefaultCurrency
+	}
+	return Parse(amount, currency)
+}
+
+// FromFloat converts a legacy floating-point amount, rounding to the
+// nearest minor unit. It exists to migrate float prices; new code should use
+// Parse or New.
+func FromFloat(f float64, currency string) (Money, error) {
+	currency, err := NormalizeCurrency(currency)
+	if err != nil {
+		return Money{}, err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Money{}, ErrInvalidAmount
+	}
+	minor := math.Round(f * float64(scale(MinorUnits(currency))))
+	if math.Abs(minor) > math.MaxInt64/2 {
+		return Money{}, ErrInvalidAmount
+	}
+	return Money{Amount: int64(minor), Currency: currency}, nil
+}
+
+// Decimal formats the amount without its currency, e.g. "37.49"
+func (m Money) Decimal() string {
+	digits := MinorUnits(m.Currency)
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	if digits == 0 {
+		return sign + strconv.FormatInt(amount, 10)
+	}
+	s := scale(digits)
+	frac := strconv.FormatInt(amount%s, 10)
+	return sign + strconv.FormatInt(amount/s, 10) + "." + strings.Repeat("0", digits-len(frac)) + frac
+}
+
+// String formats the amount with its currency, e.g. "37.49 USD"
+func (m Money) String() string {
+	return m.Decimal() + " " + m.Currency
+}
+
+// IsZero reports whether the amount is zero
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+// IsNegative reports whether the amount is below zero
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Add returns the sum of two amounts in the same currency. A zero value
+// without a currency takes the other amount's currency, so sums can start
+// from Money{}.
+func (m Money) Add(other Money) (Money, error) {
+	switch {
+	case m.Currency == "":
+		m.Currency = other.Currency
+	case other.Currency == "":
+		other.Currency = m.Currency
+	}
+	if m.Currency != other.Currency {
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns the difference of two amounts in the same currency, with the
+// same handling of a zero value as Add
+func (m Money) Sub(other Money) (Money, error) {
+	other.Amount = -other.Amount
+	return m.Add(other)
+}
+
+// Percent returns percent% of the amount, rounded half away from zero to
+// the minor unit
+func (m Money) Percent(percent int) Money {
+	product := m.Amount * int64(percent)
+	amount := product / 100
+	if rem := product % 100; rem >= 50 {
+		amount++
+	} else if rem <= -50 {
+		amount--
+	}
+	return Money{Amount: amount, Currency: m.Currency}
+}
+
+// Min returns the smaller of two amounts in the same currency
+func Min(a, b Money) Money {
+	if b.Cmp(a) < 0 {
+		return b
+	}
+	return a
+}
+
+// Mul returns the amount multiplied by a quantity
+func (m Money) Mul(n int) Money {
+	return Money{Amount: m.Amount * int64(n), Currency: m.Currency}
+}
+
+// Cmp compares two amounts, returning -1, 0 or 1. Amounts in different
+// currencies can't be compared meaningfully; they are ordered by currency
+// code so sorting stays deterministic.
+func (m Money) Cmp(other Money) int {
+	if m.Currency != other.Currency {
+		return strings.Compare(m.Currency, other.Currency)
+	}
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	}
+	return 0
+}
+
+// jsonMoney is the object form of Money that amounts were written in before
+// the currency became a field of the value holding them
+type jsonMoney struct {
+	Amount   json.RawMessage `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// MarshalJSON encodes the amount as a decimal string such as "37.49". The
+// currency isn't included; the value holding the amount gives it in its own
+// currency field.
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Decimal())
+}
+
+// UnmarshalJSON decodes an amount in DefaultCurrency, as Decode does
+func (m *Money) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(bytes.TrimSpace(data), []byte("null")) {
+		return nil
+	}
+	parsed, err := Decode(data, "")
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Decode decodes a JSON amount in the currency, or in DefaultCurrency if
+// currency is empty. The amount is a decimal string such as "37.49" or a
+// bare number; numbers are how float prices were sent before, and are
+// rounded to the nearest minor unit so existing clients and data keep
+// working. An {"amount": "37.49", "currency": "USD"} object, the form
+// amounts were written in before, is in its own currency. Null or no data
+// decodes to the zero value.
+func Decode(data []byte, currency string) (Money, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return Money{}, nil
+	}
+
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	raw := data
+	if data[0] == '{' {
+		var obj jsonMoney
+		if err := json.Unmarshal(data, &obj); err != nil {
+			return Money{}, err
+		}
+		if obj.Currency != "" {
+			currency = obj.Currency
+		}
+		raw = bytes.TrimSpace(obj.Amount)
+	}
+
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return Money{}, err
+		}
+		return Parse(s, currency)
+	}
+	var f float64
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return Money{}, ErrInvalidAmount
+	}
+	return FromFloat(f, currency)
+}
+
+// Prices is a set of amounts in distinct currencies. Its JSON form is an
+// object of decimal strings keyed by currency, e.g. {"GBP": "15.00"}.
+type Prices []Money
+
+// MarshalJSON encodes the amounts as an object keyed by currency
+func (p Prices) MarshalJSON() ([]byte, error) {
+	byCurrency := make(map[string]Money, len(p))
+	for _, price := range p {
+		byCurrency[price.Currency] = price
+	}
+	return json.Marshal(byCurrency)
+}
+
+// UnmarshalJSON decodes an object of amounts keyed by currency, ordered by
+// currency. A list of {"amount", "currency"} objects, the form prices were
+// written in before, is also accepted.
+func (p *Prices) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+
+	prices := Prices{}
+	if len(data) > 0 && data[0] == '[' {
+		var list []json.RawMessage
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		for _, raw := range list {
+			price, err := Decode(raw, "")
+			if err != nil {
+				return err
+			}
+			prices = append(prices, price)
+		}
+		*p = prices
+		return nil
+	}
+
+	var byCurrency map[string]json.RawMessage
+	if err := json.Unmarshal(data, &byCurrency); err != nil {
+		return err
+	}
+	for currency, raw := range byCurrency {
+		code, err := NormalizeCurrency(currency)
+		if err != nil {
+			return err
+		}
+		price, err := Decode(raw, code)
+		if err != nil {
+			return err
+		}
+		prices = append(prices, price)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i].Currency < prices[j].Currency })
+	*p = prices
+	return nil
+}
+
+func digitsOnly(s string) bool {
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,238 @@
+package money
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNoRate is returned when converting to or from a currency without an exchange rate
+var ErrNoRate = errors.New("money: no exchange rate")
+
+// RoundingMode says how converted amounts are rounded to a currency's increment
+type RoundingMode string
+
+const (
+	RoundHalfUp   RoundingMode = "half_up"   // Halves round away from zero
+	RoundHalfEven RoundingMode = "half_even" // Halves round to the even increment (banker's rounding)
+	RoundUp       RoundingMode = "up"        // Always away from zero
+	RoundDown     RoundingMode = "down"      // Always towards zero
+)
+
+// Rounding is the rounding rule for a currency. Increment is the smallest
+// amount prices are rounded to, in minor units.
+type Rounding struct {
+	Increment int64
+	Mode      RoundingMode
+}
+
+// defaultRounding is used for currencies the rate table has no rule for.
+// Swiss prices are conventionally rounded to 5 centimes.
+var defaultRounding = map[string]Rounding{
+	"CHF": {Increment: 5, Mode: RoundHalfUp},
+}
+
+// Rates is an exchange-rate table. Each rate is the amount of the currency
+// that one unit of Base buys. Rates is immutable once built; replace the
+// whole table to change it.
+type Rates struct {
+	Base      string
+	UpdatedAt time.Time
+	rates     map[string]*big.Rat
+	rounding  map[string]Rounding
+}
+
+// NewRates returns a table with only the base currency
+func NewRates(base string) (*Rates, error) {
+	base, err := NormalizeCurrency(base)
+	if err != nil {
+		return nil, err
+	}
+	return &Rates{
+		Base:      base,
+		UpdatedAt: time.Now(),
+		rates:     map[string]*big.Rat{base: big.NewRat(1, 1)},
+		rounding:  make(map[string]Rounding),
+	}, nil
+}
+
+// ratesFile is the JSON form of a rate table, e.g.
+//
+//	{"base": "USD", "rates": {"EUR": "0.92"}, "rounding": {"CHF": {"increment": "0.05", "mode": "half_up"}}}
+type ratesFile struct {
+	Base     string                  `json:"base"`
+	Rates    map[string]string       `json:"rates"`
+	Rounding map[string]roundingFile `json:"rounding,omitempty"`
+}
+
+type roundingFile struct {
+	Increment string       `json:"increment"`
+	Mode      RoundingMode `json:"mode"`
+}
+
+// ParseRates reads a rate table in JSON form. Rates are decimal strings so
+// they are exact.
+func ParseRates(r io.Reader) (*Rates, error) {
+	var file ratesFile
+	if err := json.NewDecoder(r).Decode(&file); err != nil {
+		return nil, err
+	}
+
+	rates, err := NewRates(file.Base)
+	if err != nil {
+		return nil, err
+	}
+
+	for code, value := range file.Rates {
+		code, err := NormalizeCurrency(code)
+		if err != nil {
+			return nil, err
+		}
+		rate, ok := new(big.Rat).SetString(value)
+		if !ok || rate.Sign() <= 0 {
+			return nil, errors.New("money: invalid exchange rate for " + code)
+		}
+		if code == rates.Base && rate.Cmp(big.NewRat(1, 1)) != 0 {
+			return nil, errors.New("money: base currency rate must be 1")
+		}
+		rates.rates[code] = rate
+	}
+
+	for code, rule := range file.Rounding {
+		code, err := NormalizeCurrency(code)
+		if err != nil {
+			return nil, err
+		}
+		increment, err := Parse(rule.Increment, code)
+		if err != nil || increment.Amount <= 0 {
+			return nil, errors.New("money: invalid rounding increment for " + code)
+		}
+		switch rule.Mode {
+		case "":
+			rule.Mode = RoundHalfUp
+		case RoundHalfUp, RoundHalfEven, RoundUp, RoundDown:
+		default:
+			return nil, errors.New("money: invalid rounding mode for " + code)
+		}
+		rates.rounding[code] = Rounding{Increment: increment.Amount, Mode: rule.Mode}
+	}
+
+	return rates, nil
+}
+
+// LoadRates reads a rate table from a JSON file
+func LoadRates(path string) (*Rates, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseRates(f)
+}
+
+// MarshalJSON encodes the table in the form ParseRates reads, plus the time
+// it was loaded
+func (r *Rates) MarshalJSON() ([]byte, error) {
+	file := struct {
+		ratesFile
+		UpdatedAt time.Time `json:"updated_at"`
+	}{
+		ratesFile: ratesFile{Base: r.Base, Rates: make(map[string]string), Rounding: make(map[string]roundingFile)},
+		UpdatedAt: r.UpdatedAt,
+	}
+	for code, rate := range r.rates {
+		file.Rates[code] = strings.TrimSuffix(strings.TrimRight(rate.FloatString(12), "0"), ".")
+	}
+	for code, rule := range r.rounding {
+		file.Rounding[code] = roundingFile{Increment: New(rule.Increment, code).Decimal(), Mode: rule.Mode}
+	}
+	return json.Marshal(file)
+}
+
+// Currencies lists the currencies amounts can be converted to, sorted
+func (r *Rates) Currencies() []string {
+	codes := make([]string, 0, len(r.rates))
+	for code := range r.rates {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Supports reports whether the table has a rate for the currency
+func (r *Rates) Supports(currency string) bool {
+	_, ok := r.rates[currency]
+	return ok
+}
+
+// Rounding returns the rounding rule for a currency
+func (r *Rates) Rounding(currency string) Rounding {
+	if rule, ok := r.rounding[currency]; ok {
+		return rule
+	}
+	if rule, ok := defaultRounding[currency]; ok {
+		return rule
+	}
+	return Rounding{Increment: 1, Mode: RoundHalfUp}
+}
+
+// Convert converts an amount to another currency through the base currency
+// and rounds it by the target currency's rule. Amounts already in the target
+// currency are returned unchanged.
+func (r *Rates) Convert(m Money, to string) (Money, error) {
+	if m.Currency == to {
+		return m, nil
+	}
+	from, ok := r.rates[m.Currency]
+	if !ok {
+		return Money{}, ErrNoRate
+	}
+	target, ok := r.rates[to]
+	if !ok {
+		return Money{}, ErrNoRate
+	}
+
+	// minor_to = minor_from / 10^from_digits / from_rate * to_rate * 10^to_digits
+	amount := new(big.Rat).SetInt64(m.Amount)
+	amount.Mul(amount, new(big.Rat).SetFrac64(scale(MinorUnits(to)), scale(MinorUnits(m.Currency))))
+	amount.Mul(amount, target)
+	amount.Quo(amount, from)
+
+	return Money{Amount: round(amount, r.Rounding(to)), Currency: to}, nil
+}
+
+// round rounds an amount of minor units to a multiple of the rule's increment
+func round(amount *big.Rat, rule Rounding) int64 {
+	steps := new(big.Rat).Quo(amount, new(big.Rat).SetInt64(rule.Increment))
+
+	// Split into whole steps and the remaining fraction, truncating towards zero
+	whole := new(big.Int).Quo(steps.Num(), steps.Denom())
+	frac := new(big.Rat).Sub(steps, new(big.Rat).SetInt(whole))
+	frac.Abs(frac)
+
+	sign := int64(steps.Sign())
+	away := false
+	switch rule.Mode {
+	case RoundUp:
+		away = frac.Sign() != 0
+	case RoundDown:
+		away = false
+	case RoundHalfEven:
+		c := frac.Cmp(big.NewRat(1, 2))
+		away = c > 0 || c == 0 && whole.Bit(0) == 1
+	default:
+		away = frac.Cmp(big.NewRat(1, 2)) >= 0
+	}
+
+	n := whole.Int64()
+	if away {
+		n += sign
+	}
+	return n * rule.Increment
+}
@@ -0,0 +1,279 @@
+package money_test
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		amount   string
+		currency string
+		want     money.Money
+		err      error
+	}{
+		{"37.49", "USD", money.New(3749, "USD"), nil},
+		{" 5 ", "usd", money.New(500, "USD"), nil},
+		{"-5.5", "EUR", money.New(-550, "EUR"), nil},
+		{"+0.01", "GBP", money.New(1, "GBP"), nil},
+		{".5", "USD", money.New(50, "USD"), nil},
+		{"3.", "USD", money.New(300, "USD"), nil},
+		{"1.500", "USD", money.New(150, "USD"), nil}, // Trailing zeros aren't extra precision
+		{"1500", "JPY", money.New(1500, "JPY"), nil},
+		{"1.234", "KWD", money.New(1234, "KWD"), nil},
+		{"1.234", "USD", money.Money{}, money.ErrTooPrecise},
+		{"1.5", "JPY", money.Money{}, money.ErrTooPrecise},
+		{"", "USD", money.Money{}, money.ErrInvalidAmount},
+		{".", "USD", money.Money{}, money.ErrInvalidAmount},
+		{"1,000", "USD", money.Money{}, money.ErrInvalidAmount},
+		{"1e3", "USD", money.Money{}, money.ErrInvalidAmount},
+		{"--1", "USD", money.Money{}, money.ErrInvalidAmount},
+		{"99999999999999999999", "USD", money.Money{}, money.ErrInvalidAmount},
+		{"1", "XYZ", money.Money{}, money.ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.amount+" "+tt.currency, func(t *testing.T) {
+			got, err := money.Parse(tt.amount, tt.currency)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseString(t *testing.T) {
+	tests := []struct {
+		input string
+		want  money.Money
+		err   error
+	}{
+		{"37.49 USD", money.New(3749, "USD"), nil},
+		{"34.99 eur", money.New(3499, "EUR"), nil},
+		{"12.50", money.New(1250, money.DefaultCurrency), nil},
+		{"1200 JPY", money.New(1200, "JPY"), nil},
+		{"12 dollars", money.Money{}, money.ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := money.ParseString(tt.input)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFromFloat(t *testing.T) {
+	tests := []struct {
+		amount   float64
+		currency string
+		want     int64
+	}{
+		{29.99, "USD", 2999},
+		{0.1 + 0.2, "USD", 30},
+		{19.995, "USD", 2000},
+		{-4.5, "EUR", -450},
+		{1234.5, "JPY", 1235},
+	}
+
+	for _, tt := range tests {
+		got, err := money.FromFloat(tt.amount, tt.currency)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Amount != tt.want || got.Currency != tt.currency {
+			t.Errorf("%v %s: expected %d, got %v", tt.amount, tt.currency, tt.want, got)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		amount money.Money
+		want   string
+	}{
+		{money.New(3749, "USD"), "37.49 USD"},
+		{money.New(5, "USD"), "0.05 USD"},
+		{money.New(-105, "EUR"), "-1.05 EUR"},
+		{money.New(1500, "JPY"), "1500 JPY"},
+		{money.New(1005, "KWD"), "1.005 KWD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := tt.amount.String(); got != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+			if parsed, err := money.ParseString(tt.want); err != nil || parsed != tt.amount {
+				t.Errorf("Expected %s to parse back, got %v (%v)", tt.want, parsed, err)
+			}
+		})
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	usd := money.New(1000, "USD")
+
+	sum, err := money.Money{}.Add(usd)
+	if err != nil || sum != usd {
+		t.Errorf("Expected a zero value to take the other currency, got %v (%v)", sum, err)
+	}
+	diff, err := usd.Sub(money.New(250, "USD"))
+	if err != nil || diff != money.New(750, "USD") {
+		t.Errorf("Expected 7.50 USD, got %v (%v)", diff, err)
+	}
+	if _, err := usd.Add(money.New(1, "EUR")); !errors.Is(err, money.ErrCurrencyMismatch) {
+		t.Errorf("Expected ErrCurrencyMismatch, got %v", err)
+	}
+	if got := usd.Mul(3); got != money.New(3000, "USD") {
+		t.Errorf("Expected 30.00 USD, got %v", got)
+	}
+	if got := money.Min(usd, money.New(999, "USD")); got.Amount != 999 {
+		t.Errorf("Expected the smaller amount, got %v", got)
+	}
+	if usd.Cmp(money.New(1000, "USD")) != 0 || usd.Cmp(money.New(1, "USD")) != 1 || usd.Cmp(money.New(1, "EUR")) != 1 {
+		t.Error("Expected amounts to compare by value, then by currency code")
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		amount  int64
+		percent int
+		want    int64
+	}{
+		{1000, 10, 100},
+		{3749, 20, 750}, // 749.8
+		{3745, 10, 375}, // 374.5 rounds away from zero
+		{3744, 10, 374},
+		{-3745, 10, -375},
+		{999, 100, 999},
+		{999, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := money.New(tt.amount, "USD").Percent(tt.percent); got.Amount != tt.want {
+			t.Errorf("%d%% of %d: expected %d, got %d", tt.percent, tt.amount, tt.want, got.Amount)
+		}
+	}
+}
+
+func TestMoney_JSON(t *testing.T) {
+	data, err := json.Marshal(money.New(3749, "USD"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `"37.49"`; string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+
+	tests := []struct {
+		input string
+		want  money.Money
+		err   error
+	}{
+		{`{"amount": "37.49", "currency": "USD"}`, money.New(3749, "USD"), nil},
+		{`{"amount": "1200", "currency": "jpy"}`, money.New(1200, "JPY"), nil},
+		{`{"amount": 12.5, "currency": "EUR"}`, money.New(1250, "EUR"), nil},
+		{`{"amount": "9.99"}`, money.New(999, "USD"), nil},
+		{`"37.49"`, money.New(3749, "USD"), nil},
+		{`29.99`, money.New(2999, "USD"), nil}, // Legacy float prices
+		{`null`, money.Money{}, nil},
+		{`{"amount": "1.234", "currency": "USD"}`, money.Money{}, money.ErrTooPrecise},
+		{`{"amount": "1", "currency": "XYZ"}`, money.Money{}, money.ErrUnknownCurrency},
+		{`"cheap"`, money.Money{}, money.ErrInvalidAmount},
+		{`true`, money.Money{}, money.ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got money.Money
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency string
+		want     money.Money
+		err      error
+	}{
+		{`"37.49"`, "EUR", money.New(3749, "EUR"), nil},
+		{`"1500"`, "JPY", money.New(1500, "JPY"), nil},
+		{`"12.345"`, "KWD", money.New(12345, "KWD"), nil},
+		{`12.5`, "GBP", money.New(1250, "GBP"), nil},
+		{`"9.99"`, "", money.New(999, "USD"), nil},
+		{`{"amount": "9.99", "currency": "GBP"}`, "EUR", money.New(999, "GBP"), nil}, // Objects keep their own currency
+		{``, "EUR", money.Money{}, nil},
+		{`null`, "EUR", money.Money{}, nil},
+		{`"15.5"`, "JPY", money.Money{}, money.ErrTooPrecise},
+		{`"1"`, "XYZ", money.Money{}, money.ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+" "+tt.currency, func(t *testing.T) {
+			got, err := money.Decode([]byte(tt.input), tt.currency)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPrices_JSON(t *testing.T) {
+	data, err := json.Marshal(money.Prices{money.New(1500, "GBP"), money.New(2000, "JPY")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"GBP":"15.00","JPY":"2000"}`; string(data) != want {
+		t.Errorf("Expected %s, got %s", want, data)
+	}
+
+	tests := []struct {
+		input string
+		want  money.Prices
+		err   error
+	}{
+		{`{"JPY": "2000", "gbp": "15"}`, money.Prices{money.New(1500, "GBP"), money.New(2000, "JPY")}, nil},
+		{`[{"amount": "15.00", "currency": "GBP"}]`, money.Prices{money.New(1500, "GBP")}, nil},
+		{`{}`, money.Prices{}, nil},
+		{`{"JPY": "20.50"}`, nil, money.ErrTooPrecise},
+		{`{"XYZ": "1"}`, nil, money.ErrUnknownCurrency},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var got money.Prices
+			err := json.Unmarshal([]byte(tt.input), &got)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
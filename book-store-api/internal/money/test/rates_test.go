@@ -0,0 +1,135 @@
+package money_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+const ratesJSON = `{
+	"base": "USD",
+	"rates": {"EUR": "0.92", "GBP": "0.79", "JPY": "151.40", "CHF": "0.88", "KWD": "0.307", "NGN": "1550", "SEK": "10.5"},
+	"rounding": {
+		"NGN": {"increment": "1"},
+		"SEK": {"increment": "0.50", "mode": "half_even"},
+		"GBP": {"increment": "0.01", "mode": "down"},
+		"KWD": {"increment": "0.005", "mode": "up"}
+	}
+}`
+
+func loadRates(t *testing.T) *money.Rates {
+	t.Helper()
+	rates, err := money.ParseRates(strings.NewReader(ratesJSON))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rates
+}
+
+func TestRates_Convert(t *testing.T) {
+	rates := loadRates(t)
+
+	tests := []struct {
+		name   string
+		amount money.Money
+		to     string
+		want   string
+	}{
+		{"same currency is unchanged", money.New(3749, "USD"), "USD", "37.49 USD"},
+		{"from the base", money.New(1000, "USD"), "EUR", "9.20 EUR"},
+		{"half up", money.New(3749, "USD"), "EUR", "34.49 EUR"},           // 34.4908
+		{"to the base", money.New(920, "EUR"), "USD", "10.00 USD"},        // Exact inverse
+		{"between two others", money.New(1000, "EUR"), "GBP", "8.58 GBP"}, // 8.5869 rounded down
+		{"to no minor units", money.New(3749, "USD"), "JPY", "5676 JPY"},  // 5675.986
+		{"from no minor units", money.New(1514, "JPY"), "USD", "10.00 USD"},
+		{"default Swiss rounding", money.New(3749, "USD"), "CHF", "33.00 CHF"}, // 32.9912 to 5 centimes
+		{"whole naira", money.New(999, "USD"), "NGN", "15485.00 NGN"},          // 15484.5
+		{"exact step", money.New(100, "USD"), "SEK", "10.50 SEK"},
+		{"half even below half", money.New(1025, "USD"), "SEK", "107.50 SEK"}, // 107.625 is 215.25 steps
+		{"up to 5 fils", money.New(1000, "USD"), "KWD", "3.070 KWD"},
+		{"up leaves exact steps", money.New(1001, "USD"), "KWD", "3.075 KWD"}, // 3.07307
+		{"negative half up", money.New(-3749, "USD"), "EUR", "-34.49 EUR"},
+		{"negative rounds away", money.New(-999, "USD"), "NGN", "-15485.00 NGN"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := rates.Convert(tt.amount, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestRates_Convert_NoRate(t *testing.T) {
+	rates := loadRates(t)
+
+	if _, err := rates.Convert(money.New(100, "USD"), "AUD"); !errors.Is(err, money.ErrNoRate) {
+		t.Errorf("Expected ErrNoRate converting to AUD, got %v", err)
+	}
+	if _, err := rates.Convert(money.New(100, "AUD"), "USD"); !errors.Is(err, money.ErrNoRate) {
+		t.Errorf("Expected ErrNoRate converting from AUD, got %v", err)
+	}
+	if rates.Supports("AUD") || !rates.Supports("USD") {
+		t.Error("Expected only currencies in the table to be supported")
+	}
+}
+
+func TestParseRates_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+	}{
+		{"not JSON", `rates`},
+		{"unknown base", `{"base": "XYZ", "rates": {}}`},
+		{"unknown currency", `{"base": "USD", "rates": {"XYZ": "1"}}`},
+		{"rate not a number", `{"base": "USD", "rates": {"EUR": "lots"}}`},
+		{"zero rate", `{"base": "USD", "rates": {"EUR": "0"}}`},
+		{"negative rate", `{"base": "USD", "rates": {"EUR": "-1"}}`},
+		{"base rate not 1", `{"base": "USD", "rates": {"USD": "1.1"}}`},
+		{"increment too precise", `{"base": "USD", "rates": {}, "rounding": {"JPY": {"increment": "0.5"}}}`},
+		{"zero increment", `{"base": "USD", "rates": {}, "rounding": {"CHF": {"increment": "0"}}}`},
+		{"unknown mode", `{"base": "USD", "rates": {}, "rounding": {"CHF": {"increment": "0.05", "mode": "nearest"}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := money.ParseRates(strings.NewReader(tt.json)); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+}
+
+func TestRates_MarshalJSON(t *testing.T) {
+	rates := loadRates(t)
+
+	data, err := json.Marshal(rates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := money.ParseRates(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatalf("Expected the encoded table to parse, got %v", err)
+	}
+
+	// The round trip keeps every rate and rounding rule
+	for _, currency := range rates.Currencies() {
+		amount := money.New(123456, "USD")
+		want, _ := rates.Convert(amount, currency)
+		got, err := again.Convert(amount, currency)
+		if err != nil || got != want {
+			t.Errorf("%s: expected %v, got %v (%v)", currency, want, got, err)
+		}
+		if rates.Rounding(currency) != again.Rounding(currency) {
+			t.Errorf("%s: expected rounding %+v, got %+v", currency, rates.Rounding(currency), again.Rounding(currency))
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Package onix reads ONIX for Books 3.0 messages, the XML format publishers
+// send product metadata in, and maps their products onto books. Messages can
+// use reference names or short tags, and are read a product at a time.
+package onix
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/godwin/book-store-api/internal/models"
+)
+
+// ErrNotONIX is returned for XML that isn't an ONIX 3.0 message
+var ErrNotONIX = errors.New("not an ONIX 3.0 message")
+
+// RecordError is a product that can't be mapped onto a book. Reading can go
+// on with the next product.
+type RecordError struct {
+	Line      int
+	Reference string // The product's RecordReference
+	ISBN      string
+	Err       error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("line %d: record %s: %v", e.Line, e.Reference, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Reader reads the products of an ONIX message
+type Reader struct {
+	d       *xml.Decoder
+	started bool
+}
+
+// NewReader returns a reader for the message
+func NewReader(r io.Reader) *Reader {
+	d := xml.NewDecoder(r)
+	d.Entity = xml.HTMLEntity // Product descriptions often use HTML entities
+	return &Reader{d: d}
+}
+
+// Next reads the next product and maps it onto an import row. It returns
+// io.EOF after the last product, and a *RecordError for a product that
+// can't be mapped; any other error means the rest of the message can't be
+// read. Data the row can't carry is listed in its Unmapped notes.
+func (r *Reader) Next() (models.ImportRow, error) {
+	for {
+		token, err := r.d.Token()
+		if err == io.EOF {
+			if !r.started {
+				return models.ImportRow{}, ErrNotONIX
+			}
+			return models.ImportRow{}, io.EOF
+		}
+		if err != nil {
+			return models.ImportRow{}, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		if !r.started {
+			if err := checkMessage(start); err != nil {
+				return models.ImportRow{}, err
+			}
+			r.started = true
+			continue
+		}
+
+		if name(start.Name.Local) != "Product" {
+			if err := r.d.Skip(); err != nil {
+				return models.ImportRow{}, err
+			}
+			continue
+		}
+
+		line, _ := r.d.InputPos()
+		var product node
+		if err := r.d.DecodeElement(&product, &start); err != nil {
+			return models.ImportRow{}, err
+		}
+		return mapProduct(product, line)
+	}
+}
+
+// checkMessage checks the root element of a message
+func checkMessage(root xml.StartElement) error {
+	if name(root.Name.Local) != "ONIXMessage" {
+		return ErrNotONIX
+	}
+	for _, attr := range root.Attr {
+		if attr.Name.Local == "release" && !strings.HasPrefix(attr.Value, "3.") {
+			return fmt.Errorf("%w: release %s isn't supported", ErrNotONIX, attr.Value)
+		}
+	}
+	return nil
+}
+
+// node is an element of a product record
+type node struct {
+	XMLName xml.Name
+	Attrs   []xml.Attr `xml:",any,attr"`
+	Text    string     `xml:",chardata"`
+	Nodes   []node     `xml:",any"`
+}
+
+// children returns the child elements with the reference name
+func (n node) children(ref string) []node {
+	var found []node
+	for _, child := range n.Nodes {
+		if name(child.XMLName.Local) == ref {
+			found = append(found, child)
+		}
+	}
+	return found
+}
+
+// child returns the first child element with the reference name
+func (n node) child(ref string) (node, bool) {
+	for _, child := range n.Nodes {
+		if name(child.XMLName.Local) == ref {
+			return child, true
+		}
+	}
+	return node{}, false
+}
+
+// text returns the trimmed text of the first child with the reference name
+func (n node) text(ref string) string {
+	child, _ := n.child(ref)
+	return strings.TrimSpace(child.Text)
+}
+
+// attr returns the value of an attribute
+func (n node) attr(local string) string {
+	for _, a := range n.Attrs {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// name returns the reference name of an element given by reference name or
+// short tag
+func name(local string) string {
+	if ref, ok := shortTags[local]; ok {
+		return ref
+	}
+	return local
+}
+
+// shortTags maps the short tags of the elements read to their reference names
+var shortTags = map[string]string{
+	"ONIXmessage":       "ONIXMessage",
+	"product":           "Product",
+	"a001":              "RecordReference",
+	"a002":              "NotificationType",
+	"productidentifier": "ProductIdentifier",
+	"b221":              "ProductIDType",
+	"b244":              "IDValue",
+	"descriptivedetail": "DescriptiveDetail",
+	"titledetail":       "TitleDetail",
+	"b202":              "TitleType",
+	"titleelement":      "TitleElement",
+	"x409":              "TitleElementLevel",
+	"b203":              "TitleText",
+	"b030":              "TitlePrefix",
+	"b031":              "TitleWithoutPrefix",
+	"b029":              "Subtitle",
+	"contributor":       "Contributor",
+	"b034":              "SequenceNumber",
+	"b035":              "ContributorRole",
+	"b036":              "PersonName",
+	"b037":              "PersonNameInverted",
+	"b039":              "NamesBeforeKey",
+	"b040":              "KeyNames",
+	"b047":              "CorporateName",
+	"publishingdetail":  "PublishingDetail",
+	"publishingdate":    "PublishingDate",
+	"x448":              "PublishingDateRole",
+	"b306":              "Date",
+	"productsupply":     "ProductSupply",
+	"supplydetail":      "SupplyDetail",
+	"j396":              "ProductAvailability",
+	"stock":             "Stock",
+	"j350":              "OnHand",
+	"price":             "Price",
+	"x462":              "PriceType",
+	"j151":              "PriceAmount",
+	"j152":              "CurrencyCode",
+}
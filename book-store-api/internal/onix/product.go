@@ -0,0 +1,319 @@
+package onix
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/isbn"
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// Codes from the ONIX code lists that products are mapped by
+const (
+	notificationDelete = "05" // Code list 1
+
+	idISBN10 = "02" // Code list 5
+	idGTIN13 = "03"
+	idISBN13 = "15"
+
+	titleDistinctive  = "01" // Code list 15
+	titleLevelProduct = "01" // Code list 149
+
+	dateOfPublication = "01" // Code list 163
+
+	priceRRPExcludingTax = "01" // Code list 58
+	priceRRPIncludingTax = "02"
+)
+
+// contributorRoles maps contributor roles (code list 17) to book roles
+var contributorRoles = map[string]models.ContributorRole{
+	"A01": models.RoleAuthor,
+	"B01": models.RoleEditor,
+	"B06": models.RoleTranslator,
+}
+
+// dateFormats maps the date formats (code list 55) that give at least a year
+// to Go layouts. Dates without a format are YYYYMMDD.
+var dateFormats = map[string]string{
+	"00": "20060102",
+	"01": "200601",
+	"05": "2006",
+	"13": "20060102T1504",
+	"14": "20060102T150405",
+}
+
+// mapProduct maps a product record onto an import row
+func mapProduct(product node, line int) (models.ImportRow, error) {
+	row := models.ImportRow{
+		Line:    line,
+		Record:  product.text("RecordReference"),
+		Omitted: map[string]bool{models.ImportCategories: true},
+	}
+	fail := func(err error) (models.ImportRow, error) {
+		return row, &RecordError{Line: line, Reference: row.Record, ISBN: row.Book.ISBN, Err: err}
+	}
+
+	var err error
+	if row.Book.ISBN, err = productISBN(product); err != nil {
+		return fail(err)
+	}
+	if product.text("NotificationType") == notificationDelete {
+		return fail(errors.New("delete notifications aren't applied; remove the book instead"))
+	}
+
+	detail, _ := product.child("DescriptiveDetail")
+	if row.Book.Title = title(detail); row.Book.Title == "" {
+		return fail(errors.New("no distinctive title"))
+	}
+
+	var unmapped []string
+	row.Book.Contributors, unmapped = contributors(detail)
+	row.Unmapped = append(row.Unmapped, unmapped...)
+
+	publishing, _ := product.child("PublishingDetail")
+	if row.Book.PublishedAt, err = publicationDate(publishing); err != nil {
+		return fail(err)
+	}
+
+	supply, _ := product.child("ProductSupply")
+	prices, unmapped, err := retailPrices(supply)
+	row.Unmapped = append(row.Unmapped, unmapped...)
+	if err != nil {
+		return fail(err)
+	}
+	row.Book.Price, row.Book.Prices = prices[0], prices[1:]
+
+	quantity, known := stock(supply)
+	row.Book.Quantity = quantity
+	row.Omitted[models.ImportQuantity] = !known
+
+	return row, nil
+}
+
+// productISBN returns the product's ISBN, preferring an ISBN-13 to a GTIN-13
+// and either to an ISBN-10
+func productISBN(product node) (string, error) {
+	ids := make(map[string]string)
+	for _, id := range product.children("ProductIdentifier") {
+		if kind := id.text("ProductIDType"); ids[kind] == "" {
+			ids[kind] = id.text("IDValue")
+		}
+	}
+
+	for _, kind := range []string{idISBN13, idGTIN13, idISBN10} {
+		value := ids[kind]
+		if value == "" {
+			continue
+		}
+		code, err := isbn.Normalize(value)
+		if err != nil {
+			// A GTIN-13 that isn't an ISBN is some other product
+			if kind == idGTIN13 {
+				continue
+			}
+			return value, fmt.Errorf("invalid ISBN %s", value)
+		}
+		return code, nil
+	}
+	if gtin := ids[idGTIN13]; gtin != "" {
+		return "", fmt.Errorf("no ISBN; GTIN %s isn't one", gtin)
+	}
+	return "", errors.New("no ISBN")
+}
+
+// title returns the product's distinctive title, with its subtitle
+func title(detail node) string {
+	for _, t := range detail.children("TitleDetail") {
+		if t.text("TitleType") != titleDistinctive {
+			continue
+		}
+		for _, element := range t.children("TitleElement") {
+			if element.text("TitleElementLevel") != titleLevelProduct {
+				continue
+			}
+			text := element.text("TitleText")
+			if text == "" {
+				text = strings.TrimSpace(element.text("TitlePrefix") + " " + element.text("TitleWithoutPrefix"))
+			}
+			if subtitle := element.text("Subtitle"); subtitle != "" && text != "" {
+				text += ": " + subtitle
+			}
+			return text
+		}
+	}
+	return ""
+}
+
+// contributors returns the product's authors, editors and translators in
+// sequence order, and notes about contributors in other roles
+func contributors(detail node) ([]models.Contributor, []string) {
+	type sequenced struct {
+		sequence    int
+		contributor models.Contributor
+	}
+	var found []sequenced
+	var unmapped []string
+
+	for i, c := range detail.children("Contributor") {
+		name := personName(c)
+		if name == "" {
+			unmapped = append(unmapped, "a contributor without a name was skipped")
+			continue
+		}
+
+		var role models.ContributorRole
+		for _, code := range c.children("ContributorRole") {
+			if r, ok := contributorRoles[strings.TrimSpace(code.Text)]; ok {
+				role = r
+				break
+			}
+		}
+		if role == "" {
+			unmapped = append(unmapped, fmt.Sprintf("contributor %s in role %s was skipped", name, c.text("ContributorRole")))
+			continue
+		}
+
+		sequence, err := strconv.Atoi(c.text("SequenceNumber"))
+		if err != nil {
+			sequence = i + 1
+		}
+		found = append(found, sequenced{sequence, models.Contributor{Name: name, Role: role}})
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].sequence < found[j].sequence })
+	result := make([]models.Contributor, len(found))
+	for i, f := range found {
+		result[i] = f.contributor
+	}
+	return result, unmapped
+}
+
+// personName returns a contributor's name in display order
+func personName(c node) string {
+	if name := c.text("PersonName"); name != "" {
+		return name
+	}
+	if key := c.text("KeyNames"); key != "" {
+		return strings.TrimSpace(c.text("NamesBeforeKey") + " " + key)
+	}
+	if inverted := c.text("PersonNameInverted"); inverted != "" {
+		if last, first, found := strings.Cut(inverted, ","); found {
+			return strings.TrimSpace(first) + " " + strings.TrimSpace(last)
+		}
+		return inverted
+	}
+	return c.text("CorporateName")
+}
+
+// publicationDate returns the product's date of publication
+func publicationDate(publishing node) (time.Time, error) {
+	for _, d := range publishing.children("PublishingDate") {
+		if d.text("PublishingDateRole") != dateOfPublication {
+			continue
+		}
+		date, _ := d.child("Date")
+		format := date.attr("dateformat")
+		if format == "" {
+			format = "00"
+		}
+		layout, ok := dateFormats[format]
+		if !ok {
+			return time.Time{}, fmt.Errorf("publication date format %s isn't supported", format)
+		}
+		t, err := time.Parse(layout, strings.TrimSpace(date.Text))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid publication date %q", strings.TrimSpace(date.Text))
+		}
+		return t, nil
+	}
+	return time.Time{}, errors.New("no publication date")
+}
+
+// retailPrices returns the product's recommended retail prices, one per
+// currency, with the one in the default currency (or else the first) first.
+// Within a currency the price including tax (type 02) is preferred to the
+// one excluding it (type 01). Prices of other types, and in currencies that
+// aren't supported, are noted as unmapped; the product only fails if no
+// usable price is left.
+func retailPrices(supply node) ([]money.Money, []string, error) {
+	var prices []money.Money
+	var unmapped []string
+	index := make(map[string]int)    // Position in prices by currency
+	kinds := make(map[string]string) // Price type by currency
+	skipped := false
+
+	for _, detail := range supply.children("SupplyDetail") {
+		for _, p := range detail.children("Price") {
+			kind := p.text("PriceType")
+			if kind != priceRRPExcludingTax && kind != priceRRPIncludingTax {
+				unmapped = append(unmapped, fmt.Sprintf("price type %s was skipped", kind))
+				continue
+			}
+
+			currency, err := money.NormalizeCurrency(p.text("CurrencyCode"))
+			if err != nil {
+				unmapped = append(unmapped, fmt.Sprintf("price %s %s was skipped; the currency isn't supported",
+					p.text("PriceAmount"), p.text("CurrencyCode")))
+				skipped = true
+				continue
+			}
+			// A price including tax replaces one excluding it; other repeats are ignored
+			i, seen := index[currency]
+			if seen && (kind != priceRRPIncludingTax || kinds[currency] == priceRRPIncludingTax) {
+				continue
+			}
+			price, err := money.Parse(p.text("PriceAmount"), currency)
+			if err != nil || price.IsNegative() {
+				return nil, unmapped, fmt.Errorf("invalid price %q %s", p.text("PriceAmount"), currency)
+			}
+			kinds[currency] = kind
+			if seen {
+				prices[i] = price
+				continue
+			}
+			index[currency] = len(prices)
+			prices = append(prices, price)
+		}
+	}
+
+	if len(prices) == 0 && skipped {
+		return nil, unmapped, errors.New("no recommended retail price in a supported currency")
+	}
+	if len(prices) == 0 {
+		return nil, unmapped, errors.New("no recommended retail price")
+	}
+	for i, price := range prices {
+		if price.Currency == money.DefaultCurrency {
+			prices[0], prices[i] = prices[i], prices[0]
+			break
+		}
+	}
+	return prices, unmapped, nil
+}
+
+// stock returns the copies on hand, or none for a product that isn't
+// available. known is false if the record doesn't say.
+func stock(supply node) (quantity int, known bool) {
+	available := false
+	for _, detail := range supply.children("SupplyDetail") {
+		for _, s := range detail.children("Stock") {
+			if onHand, err := strconv.Atoi(s.text("OnHand")); err == nil && onHand >= 0 {
+				return onHand, true
+			}
+		}
+		// Availability codes (list 65) in the 20s mean the product can be ordered
+		if code := detail.text("ProductAvailability"); code == "" || strings.HasPrefix(code, "2") {
+			available = true
+		}
+	}
+
+	if len(supply.children("SupplyDetail")) == 0 || available {
+		return 0, false
+	}
+	return 0, true
+}
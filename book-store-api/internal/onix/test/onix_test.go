@@ -0,0 +1,502 @@
+package onix_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+	"github.com/godwin/book-store-api/internal/onix"
+)
+
+func price(s string) money.Money {
+	m, err := money.ParseString(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// parts are the sections of a product record
+type parts struct {
+	notification string
+	ids          string
+	detail       string
+	publishing   string
+	supply       string
+}
+
+// defaults is a product that maps cleanly
+func defaults() parts {
+	return parts{
+		notification: "03",
+		ids:          identifier("15", "9780306406157"),
+		detail: `<DescriptiveDetail>
+			<TitleDetail><TitleType>01</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel><TitleText>Thinking in Systems</TitleText></TitleElement></TitleDetail>
+			<Contributor><SequenceNumber>1</SequenceNumber><ContributorRole>A01</ContributorRole><PersonName>Donella Meadows</PersonName></Contributor>
+		</DescriptiveDetail>`,
+		publishing: `<PublishingDetail>
+			<PublishingDate><PublishingDateRole>01</PublishingDateRole><Date>20081203</Date></PublishingDate>
+		</PublishingDetail>`,
+		supply: supply(`<ProductAvailability>21</ProductAvailability><Stock><OnHand>12</OnHand></Stock>` + rrp("02", "19.95", "USD")),
+	}
+}
+
+func identifier(kind, value string) string {
+	return "<ProductIdentifier><ProductIDType>" + kind + "</ProductIDType><IDValue>" + value + "</IDValue></ProductIdentifier>"
+}
+
+func rrp(kind, amount, currency string) string {
+	return "<Price><PriceType>" + kind + "</PriceType><PriceAmount>" + amount + "</PriceAmount><CurrencyCode>" + currency + "</CurrencyCode></Price>"
+}
+
+func supply(details ...string) string {
+	s := "<ProductSupply>"
+	for _, d := range details {
+		s += "<SupplyDetail>" + d + "</SupplyDetail>"
+	}
+	return s + "</ProductSupply>"
+}
+
+func detail(contributors string) string {
+	return `<DescriptiveDetail>
+		<TitleDetail><TitleType>01</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel><TitleText>Thinking in Systems</TitleText></TitleElement></TitleDetail>
+		` + contributors + `
+	</DescriptiveDetail>`
+}
+
+func contributor(sequence, role, name string) string {
+	return "<Contributor><SequenceNumber>" + sequence + "</SequenceNumber><ContributorRole>" + role + "</ContributorRole>" + name + "</Contributor>"
+}
+
+func published(date string) string {
+	return "<PublishingDetail><PublishingDate><PublishingDateRole>01</PublishingDateRole>" + date + "</PublishingDate></PublishingDetail>"
+}
+
+func (p parts) String() string {
+	return "<Product><RecordReference>ref-1</RecordReference><NotificationType>" + p.notification + "</NotificationType>" +
+		p.ids + p.detail + p.publishing + p.supply + "</Product>"
+}
+
+func message(products ...string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<ONIXMessage release="3.0" xmlns="http://ns.editeur.org/onix/3.0/reference">
+<Header><Sender><SenderName>Acme Publishing</SenderName></Sender></Header>
+` + strings.Join(products, "\n") + `
+</ONIXMessage>`
+}
+
+// readOne maps the single product of a message
+func readOne(t *testing.T, p parts) (models.ImportRow, error) {
+	t.Helper()
+	r := onix.NewReader(strings.NewReader(message(p.String())))
+	row, err := r.Next()
+	if _, end := r.Next(); end != io.EOF {
+		t.Fatalf("Expected one product, got another (%v)", end)
+	}
+	return row, err
+}
+
+func TestMapProduct(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(p *parts)
+		want func(book *models.Book)
+	}{
+		{
+			name: "defaults",
+			edit: func(p *parts) {},
+			want: func(book *models.Book) {},
+		},
+		{
+			name: "ISBN-13 is preferred",
+			edit: func(p *parts) {
+				p.ids = identifier("02", "0201633612") + identifier("03", "9780201633610") + identifier("15", "978-0-306-40615-7")
+			},
+			want: func(book *models.Book) {},
+		},
+		{
+			name: "ISBN-10 is converted",
+			edit: func(p *parts) { p.ids = identifier("01", "acme-1") + identifier("02", "0-306-40615-2") },
+			want: func(book *models.Book) {},
+		},
+		{
+			name: "GTIN that isn't an ISBN is passed over",
+			edit: func(p *parts) { p.ids = identifier("03", "4006381333931") + identifier("02", "0306406152") },
+			want: func(book *models.Book) {},
+		},
+		{
+			name: "title with prefix and subtitle",
+			edit: func(p *parts) {
+				p.detail = `<DescriptiveDetail>
+					<TitleDetail><TitleType>10</TitleType><TitleElement><TitleElementLevel>01</TitleElementLevel><TitleText>Distributor title</TitleText></TitleElement></TitleDetail>
+					<TitleDetail><TitleType>01</TitleType>
+						<TitleElement><TitleElementLevel>02</TitleElementLevel><TitleText>Collection</TitleText></TitleElement>
+						<TitleElement><TitleElementLevel>01</TitleElementLevel><TitlePrefix>The</TitlePrefix><TitleWithoutPrefix>Fifth Discipline</TitleWithoutPrefix><Subtitle>The Art and Practice of the Learning Organization</Subtitle></TitleElement>
+					</TitleDetail>
+				</DescriptiveDetail>`
+			},
+			want: func(book *models.Book) {
+				book.Title = "The Fifth Discipline: The Art and Practice of the Learning Organization"
+				book.Contributors = []models.Contributor{}
+			},
+		},
+		{
+			name: "contributors in sequence order",
+			edit: func(p *parts) {
+				p.detail = detail(contributor("3", "B06", "<PersonNameInverted>Bellow, Anne</PersonNameInverted>") +
+					contributor("1", "A01", "<NamesBeforeKey>Donella H.</NamesBeforeKey><KeyNames>Meadows</KeyNames>") +
+					contributor("2", "B01", "<CorporateName>Sustainability Institute</CorporateName>"))
+			},
+			want: func(book *models.Book) {
+				book.Contributors = []models.Contributor{
+					{Name: "Donella H. Meadows", Role: models.RoleAuthor},
+					{Name: "Sustainability Institute", Role: models.RoleEditor},
+					{Name: "Anne Bellow", Role: models.RoleTranslator},
+				}
+			},
+		},
+		{
+			name: "publication month",
+			edit: func(p *parts) { p.publishing = published(`<Date dateformat="01">200812</Date>`) },
+			want: func(book *models.Book) { book.PublishedAt = time.Date(2008, 12, 1, 0, 0, 0, 0, time.UTC) },
+		},
+		{
+			name: "publication year",
+			edit: func(p *parts) { p.publishing = published(`<Date dateformat="05">2008</Date>`) },
+			want: func(book *models.Book) { book.PublishedAt = time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC) },
+		},
+		{
+			name: "prices in several currencies, USD first",
+			edit: func(p *parts) {
+				p.supply = supply(rrp("01", "14.99", "GBP")+rrp("02", "22.50", "EUR"), rrp("02", "19.95", "usd")+rrp("02", "15.99", "GBP"))
+			},
+			want: func(book *models.Book) {
+				book.Prices = money.Prices{price("22.50 EUR"), price("15.99 GBP")}
+				book.Quantity = 0
+			},
+		},
+		{
+			name: "first currency leads without USD",
+			edit: func(p *parts) { p.supply = supply(rrp("02", "22.50", "EUR") + rrp("02", "14.99", "GBP")) },
+			want: func(book *models.Book) {
+				book.Price = price("22.50 EUR")
+				book.Prices = money.Prices{price("14.99 GBP")}
+				book.Quantity = 0
+			},
+		},
+		{
+			name: "unavailable product has no stock",
+			edit: func(p *parts) {
+				p.supply = supply(`<ProductAvailability>40</ProductAvailability>` + rrp("02", "19.95", "USD"))
+			},
+			want: func(book *models.Book) { book.Quantity = 0 },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := defaults()
+			tt.edit(&p)
+			row, err := readOne(t, p)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			want := models.Book{
+				ISBN:         "9780306406157",
+				Title:        "Thinking in Systems",
+				Contributors: []models.Contributor{{Name: "Donella Meadows", Role: models.RoleAuthor}},
+				PublishedAt:  time.Date(2008, 12, 3, 0, 0, 0, 0, time.UTC),
+				Price:        price("19.95 USD"),
+				Prices:       money.Prices{},
+				Quantity:     12,
+			}
+			tt.want(&want)
+			if len(row.Book.Prices) == 0 && len(want.Prices) == 0 {
+				want.Prices = row.Book.Prices
+			}
+			if !reflect.DeepEqual(row.Book, want) {
+				t.Errorf("Expected %+v, got %+v", want, row.Book)
+			}
+			if row.Line == 0 || row.Record != "ref-1" {
+				t.Errorf("Expected a line and record ref-1, got %d and %q", row.Line, row.Record)
+			}
+			if !row.Omitted[models.ImportCategories] {
+				t.Error("Expected categories to be omitted")
+			}
+			if len(row.Unmapped) != 0 {
+				t.Errorf("Expected nothing unmapped, got %v", row.Unmapped)
+			}
+		})
+	}
+}
+
+func TestMapProduct_Unmapped(t *testing.T) {
+	tests := []struct {
+		name     string
+		edit     func(p *parts)
+		price    money.Money
+		prices   money.Prices
+		unmapped []string
+	}{
+		{
+			name:     "other price types",
+			edit:     func(p *parts) { p.supply = supply(rrp("04", "9.99", "USD") + rrp("02", "19.95", "USD")) },
+			price:    price("19.95 USD"),
+			unmapped: []string{"price type 04 was skipped"},
+		},
+		{
+			name: "unsupported currency",
+			edit: func(p *parts) {
+				p.supply = supply(rrp("02", "499.00", "CZK") + rrp("02", "19.95", "USD") + rrp("02", "18.50", "EUR"))
+			},
+			price:    price("19.95 USD"),
+			prices:   money.Prices{price("18.50 EUR")},
+			unmapped: []string{"price 499.00 CZK was skipped; the currency isn't supported"},
+		},
+		{
+			name:  "repeated currency keeps the first price",
+			edit:  func(p *parts) { p.supply = supply(rrp("02", "19.95", "USD"), rrp("02", "18.00", "USD")) },
+			price: price("19.95 USD"),
+		},
+		{
+			name:  "price including tax is preferred to the one excluding it",
+			edit:  func(p *parts) { p.supply = supply(rrp("02", "19.95", "USD"), rrp("01", "18.00", "USD")) },
+			price: price("19.95 USD"),
+		},
+		{
+			name: "price including tax replaces an earlier one excluding it",
+			edit: func(p *parts) {
+				p.supply = supply(rrp("01", "18.00", "USD")+rrp("01", "15.00", "GBP"), rrp("02", "19.95", "USD"))
+			},
+			price:  price("19.95 USD"),
+			prices: money.Prices{price("15.00 GBP")},
+		},
+		{
+			name:  "price excluding tax is used when it's the only one",
+			edit:  func(p *parts) { p.supply = supply(rrp("01", "18.00", "USD"), rrp("01", "17.00", "USD")) },
+			price: price("18.00 USD"),
+		},
+		{
+			name: "contributors in other roles or without names",
+			edit: func(p *parts) {
+				p.detail = detail(contributor("1", "A01", "<PersonName>Donella Meadows</PersonName>") +
+					contributor("2", "A12", "<PersonName>Jane Doe</PersonName>") +
+					contributor("3", "A01", ""))
+			},
+			price: price("19.95 USD"),
+			unmapped: []string{
+				"contributor Jane Doe in role A12 was skipped",
+				"a contributor without a name was skipped",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := defaults()
+			tt.edit(&p)
+			row, err := readOne(t, p)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if row.Book.Price != tt.price {
+				t.Errorf("Expected price %v, got %v", tt.price, row.Book.Price)
+			}
+			if len(row.Book.Prices) != len(tt.prices) || (len(tt.prices) > 0 && !reflect.DeepEqual(row.Book.Prices, tt.prices)) {
+				t.Errorf("Expected prices %v, got %v", tt.prices, row.Book.Prices)
+			}
+			if !reflect.DeepEqual(row.Unmapped, tt.unmapped) {
+				t.Errorf("Expected unmapped %q, got %q", tt.unmapped, row.Unmapped)
+			}
+		})
+	}
+}
+
+func TestMapProduct_Stock(t *testing.T) {
+	tests := []struct {
+		name     string
+		supply   string
+		quantity int
+		known    bool
+	}{
+		{"on hand", supply(`<Stock><OnHand>7</OnHand></Stock>` + rrp("02", "19.95", "USD")), 7, true},
+		{"first supplier with stock", supply(`<ProductAvailability>40</ProductAvailability>`+rrp("02", "19.95", "USD"), `<Stock><OnHand>3</OnHand></Stock>`), 3, true},
+		{"available without a count", supply(`<ProductAvailability>21</ProductAvailability>` + rrp("02", "19.95", "USD")), 0, false},
+		{"no availability given", supply(rrp("02", "19.95", "USD")), 0, false},
+		{"not available", supply(`<ProductAvailability>40</ProductAvailability>` + rrp("02", "19.95", "USD")), 0, true},
+		{"negative count is ignored", supply(`<ProductAvailability>31</ProductAvailability><Stock><OnHand>-1</OnHand></Stock>` + rrp("02", "19.95", "USD")), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := defaults()
+			p.supply = tt.supply
+			row, err := readOne(t, p)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if row.Book.Quantity != tt.quantity || row.Omitted[models.ImportQuantity] == tt.known {
+				t.Errorf("Expected %d copies, known %v, got %d, known %v",
+					tt.quantity, tt.known, row.Book.Quantity, !row.Omitted[models.ImportQuantity])
+			}
+		})
+	}
+}
+
+func TestMapProduct_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		edit func(p *parts)
+		isbn string
+		err  string
+	}{
+		{"no identifiers", func(p *parts) { p.ids = "" }, "", "no ISBN"},
+		{"GTIN that isn't an ISBN", func(p *parts) { p.ids = identifier("03", "4006381333931") }, "", "no ISBN; GTIN 4006381333931 isn't one"},
+		{"bad check digit", func(p *parts) { p.ids = identifier("15", "9780306406158") }, "9780306406158", "invalid ISBN 9780306406158"},
+		{"delete notification", func(p *parts) { p.notification = "05" }, "9780306406157", "delete notifications aren't applied"},
+		{"no distinctive title", func(p *parts) { p.detail = "" }, "9780306406157", "no distinctive title"},
+		{"no publication date", func(p *parts) { p.publishing = "" }, "9780306406157", "no publication date"},
+		{"unsupported date format", func(p *parts) { p.publishing = published(`<Date dateformat="02">2008W49</Date>`) }, "9780306406157", "date format 02 isn't supported"},
+		{"invalid date", func(p *parts) { p.publishing = published(`<Date>20081303</Date>`) }, "9780306406157", `invalid publication date "20081303"`},
+		{"no supply", func(p *parts) { p.supply = "" }, "9780306406157", "no recommended retail price"},
+		{"only other price types", func(p *parts) { p.supply = supply(rrp("04", "19.95", "USD")) }, "9780306406157", "no recommended retail price"},
+		{"only unsupported currencies", func(p *parts) { p.supply = supply(rrp("02", "499.00", "CZK") + rrp("02", "1.00", "")) }, "9780306406157", "no recommended retail price in a supported currency"},
+		{"invalid amount", func(p *parts) { p.supply = supply(rrp("02", "19.955", "USD")) }, "9780306406157", `invalid price "19.955" USD`},
+		{"negative amount", func(p *parts) { p.supply = supply(rrp("02", "-19.95", "USD")) }, "9780306406157", `invalid price "-19.95" USD`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := defaults()
+			tt.edit(&p)
+			_, err := readOne(t, p)
+
+			var recordErr *onix.RecordError
+			if !errors.As(err, &recordErr) {
+				t.Fatalf("Expected a record error, got %v", err)
+			}
+			if recordErr.Reference != "ref-1" || recordErr.ISBN != tt.isbn || recordErr.Line == 0 {
+				t.Errorf("Expected record ref-1 with ISBN %q, got %q with %q on line %d",
+					tt.isbn, recordErr.Reference, recordErr.ISBN, recordErr.Line)
+			}
+			if !strings.Contains(recordErr.Err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, recordErr.Err)
+			}
+		})
+	}
+}
+
+func TestReader(t *testing.T) {
+	second := defaults()
+	second.ids = identifier("15", "9780201633610")
+	deleted := defaults()
+	deleted.notification = "05"
+
+	data := message(defaults().String(), deleted.String(), second.String())
+	r := onix.NewReader(strings.NewReader(data))
+
+	var isbns []string
+	var failed []int
+	lines := map[int]bool{}
+	for {
+		row, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		var recordErr *onix.RecordError
+		if errors.As(err, &recordErr) {
+			failed = append(failed, recordErr.Line)
+			lines[recordErr.Line] = true
+			continue
+		}
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		isbns = append(isbns, row.Book.ISBN)
+		lines[row.Line] = true
+	}
+
+	// A failed product doesn't stop the rest being read
+	if !reflect.DeepEqual(isbns, []string{"9780306406157", "9780201633610"}) {
+		t.Errorf("Expected both products, got %v", isbns)
+	}
+	if len(failed) != 1 {
+		t.Errorf("Expected 1 failed product, got %d", len(failed))
+	}
+	if len(lines) != 3 {
+		t.Errorf("Expected each product on its own line, got %v", lines)
+	}
+}
+
+func TestReader_ShortTags(t *testing.T) {
+	data := `<?xml version="1.0"?>
+<ONIXmessage release="3.0">
+<header><sender><x298>Acme Publishing</x298></sender></header>
+<product>
+	<a001>acme-1</a001><a002>03</a002>
+	<productidentifier><b221>15</b221><b244>9780306406157</b244></productidentifier>
+	<descriptivedetail>
+		<titledetail><b202>01</b202><titleelement><x409>01</x409><b203>Thinking in Systems</b203></titleelement></titledetail>
+		<contributor><b034>1</b034><b035>A01</b035><b037>Meadows, Donella</b037></contributor>
+	</descriptivedetail>
+	<publishingdetail><publishingdate><x448>01</x448><b306 dateformat="00">20081203</b306></publishingdate></publishingdetail>
+	<productsupply><supplydetail><j396>21</j396><stock><j350>12</j350></stock><price><x462>02</x462><j151>19.95</j151><j152>USD</j152></price></supplydetail></productsupply>
+</product>
+</ONIXmessage>`
+
+	row, err := onix.NewReader(strings.NewReader(data)).Next()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := models.Book{
+		ISBN:         "9780306406157",
+		Title:        "Thinking in Systems",
+		Contributors: []models.Contributor{{Name: "Donella Meadows", Role: models.RoleAuthor}},
+		PublishedAt:  time.Date(2008, 12, 3, 0, 0, 0, 0, time.UTC),
+		Price:        price("19.95 USD"),
+		Prices:       row.Book.Prices,
+		Quantity:     12,
+	}
+	if !reflect.DeepEqual(row.Book, want) || len(row.Book.Prices) != 0 || row.Record != "acme-1" {
+		t.Errorf("Expected %+v from acme-1, got %+v from %s", want, row.Book, row.Record)
+	}
+}
+
+func TestReader_NotONIX(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"empty", ""},
+		{"other XML", `<?xml version="1.0"?><catalog><book/></catalog>`},
+		{"ONIX 2.1", `<ONIXMessage release="2.1"><Product/></ONIXMessage>`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := onix.NewReader(strings.NewReader(tt.data)).Next()
+			if !errors.Is(err, onix.ErrNotONIX) {
+				t.Errorf("Expected %v, got %v", onix.ErrNotONIX, err)
+			}
+		})
+	}
+}
+
+func TestReader_Malformed(t *testing.T) {
+	r := onix.NewReader(strings.NewReader(message(defaults().String(), "<Product><RecordReference>ref-2</Product>")))
+
+	if _, err := r.Next(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	_, err := r.Next()
+	var recordErr *onix.RecordError
+	if err == nil || err == io.EOF || errors.As(err, &recordErr) {
+		t.Errorf("Expected an error ending the message, got %v", err)
+	}
+}
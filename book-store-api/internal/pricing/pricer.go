@@ -0,0 +1,81 @@
+package pricing
+
+import (
+	"sync"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// Pricer prices books in the currency a customer asks for. A book's fixed
+// price in that currency wins; otherwise its base price is converted with
+// the current exchange rates. It is safe for concurrent use, and the rate
+// table can be replaced while requests are being served.
+type Pricer struct {
+	mu    sync.RWMutex
+	rates *money.Rates
+}
+
+// NewPricer returns a pricer using the rate table
+func NewPricer(rates *money.Rates) *Pricer {
+	return &Pricer{rates: rates}
+}
+
+// Rates returns the current rate table
+func (p *Pricer) Rates() *money.Rates {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.rates
+}
+
+// SetRates replaces the rate table
+func (p *Pricer) SetRates(rates *money.Rates) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.rates = rates
+}
+
+// Supports reports whether books can be priced in the currency
+func (p *Pricer) Supports(currency string) bool {
+	return p.Rates().Supports(currency)
+}
+
+// Price returns the book's price in the currency
+func (p *Pricer) Price(book models.Book, currency string) (money.Money, error) {
+	if price, ok := book.PriceIn(currency); ok {
+		return price, nil
+	}
+	return p.Rates().Convert(book.Price, currency)
+}
+
+// Localize returns the book with Price in the currency. A book that can't
+// be converted keeps its own price.
+func (p *Pricer) Localize(book models.Book, currency string) models.Book {
+	if price, err := p.Price(book, currency); err == nil {
+		book.Price = price
+	}
+	return book
+}
+
+// LowestPrice returns the lowest price the book had in the currency at any
+// time from since until now, from its price history. Past prices that need
+// converting are converted at the current rates. It reports false if no
+// price in the history can be priced in the currency.
+func (p *Pricer) LowestPrice(book models.Book, history []models.PriceChange, currency string, since time.Time) (money.Money, bool) {
+	var lowest money.Money
+	found := false
+	for _, change := range models.PricesSince(history, since) {
+		price, err := p.Price(change.Book(book), currency)
+		if err != nil {
+			continue
+		}
+		if !found || price.Cmp(lowest) < 0 {
+			lowest = price
+			found = true
+		}
+	}
+	return lowest, found
+}
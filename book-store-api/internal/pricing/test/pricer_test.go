@@ -0,0 +1,130 @@
+package pricing_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+	"github.com/godwin/book-store-api/internal/pricing"
+)
+
+func newPricer(t *testing.T, rates string) *pricing.Pricer {
+	t.Helper()
+	table, err := money.ParseRates(strings.NewReader(rates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pricing.NewPricer(table)
+}
+
+func TestPricer_Price(t *testing.T) {
+	pricer := newPricer(t, `{"base": "USD", "rates": {"EUR": "0.92", "GBP": "0.79"}}`)
+	book := models.Book{
+		Price:  money.New(3749, "USD"),
+		Prices: []money.Money{money.New(2999, "GBP"), money.New(4000, "CAD")},
+	}
+
+	tests := []struct {
+		currency string
+		want     string
+		err      error
+	}{
+		{"USD", "37.49 USD", nil},
+		{"EUR", "34.49 EUR", nil}, // Converted
+		{"GBP", "29.99 GBP", nil}, // Fixed price instead of 29.62
+		{"CAD", "40.00 CAD", nil}, // Fixed price without an exchange rate
+		{"JPY", "", money.ErrNoRate},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			got, err := pricer.Price(book, tt.currency)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if err == nil && got.String() != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+
+			// Localize falls back to the book's own price
+			want := tt.want
+			if err != nil {
+				want = "37.49 USD"
+			}
+			if localized := pricer.Localize(book, tt.currency); localized.Price.String() != want {
+				t.Errorf("Expected Localize to give %s, got %s", want, localized.Price)
+			}
+		})
+	}
+}
+
+func TestPricer_SetRates(t *testing.T) {
+	pricer := newPricer(t, `{"base": "USD", "rates": {"EUR": "0.92"}}`)
+	book := models.Book{Price: money.New(1000, "USD")}
+
+	if pricer.Supports("GBP") {
+		t.Error("Expected GBP not to be supported yet")
+	}
+
+	rates, err := money.ParseRates(strings.NewReader(`{"base": "USD", "rates": {"EUR": "0.5", "GBP": "0.8"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pricer.SetRates(rates)
+
+	if !pricer.Supports("GBP") || pricer.Rates() != rates {
+		t.Error("Expected the new table to be used")
+	}
+	if got, _ := pricer.Price(book, "EUR"); got.String() != "5.00 EUR" {
+		t.Errorf("Expected 5.00 EUR at the new rate, got %s", got)
+	}
+}
+
+func TestPricer_LowestPrice(t *testing.T) {
+	pricer := newPricer(t, `{"base": "USD", "rates": {"EUR": "0.5"}}`)
+	now := time.Date(2026, 6, 30, 0, 0, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 6, d, 0, 0, 0, 0, time.UTC) }
+	since := now.Add(-models.LowestPriceWindow) // 31 May
+
+	usd := func(minor int64) money.Money { return money.New(minor, "USD") }
+	history := []models.PriceChange{
+		{Price: usd(1000), ChangedAt: day(1).AddDate(0, -2, 0)}, // Too old to count
+		{Price: usd(3000), ChangedAt: day(1).AddDate(0, -1, 0)}, // In effect at the start of the window
+		{Price: usd(2500), Prices: []money.Money{money.New(1000, "EUR")}, ChangedAt: day(10)},
+		{Price: usd(4000), ChangedAt: day(20)},
+	}
+	book := models.Book{Price: usd(4000)}
+
+	tests := []struct {
+		currency string
+		want     string
+		found    bool
+	}{
+		{"USD", "25.00 USD", true},
+		{"EUR", "10.00 EUR", true}, // A fixed price beats converting 25.00 USD
+		{"GBP", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			got, found := pricer.LowestPrice(book, history, tt.currency, since)
+			if found != tt.found {
+				t.Fatalf("Expected found %v, got %v", tt.found, found)
+			}
+			if found && got.String() != tt.want {
+				t.Errorf("Expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+
+	// The price in effect when the window opens still counts
+	if got, _ := pricer.LowestPrice(book, history[:2], "USD", since); got.String() != "30.00 USD" {
+		t.Errorf("Expected 30.00 USD, got %s", got)
+	}
+	if _, found := pricer.LowestPrice(book, nil, "USD", since); found {
+		t.Error("Expected no price without a history")
+	}
+}
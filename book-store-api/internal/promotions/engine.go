@@ -0,0 +1,90 @@
+// Package promotions works out the discounts promotion rules give
+package promotions
+
+import (
+	"sort"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+)
+
+// Sort orders promotions for application: highest priority first, then
+// oldest first so the order is stable
+func Sort(promos []models.Promotion) {
+	sort.SliceStable(promos, func(i, j int) bool {
+		if promos[i].Priority != promos[j].Priority {
+			return promos[i].Priority > promos[j].Priority
+		}
+		return promos[i].CreatedAt.Before(promos[j].CreatedAt)
+	})
+}
+
+// PriceLine works out the discount on quantity copies of a book at
+// unitPrice. promos must be sorted with Sort. Each promotion that is active
+// and whose conditions match is applied to the price left by the ones before
+// it, until one that isn't stackable applies. It returns the total discount
+// for the line and an explanation of each promotion that gave one.
+func PriceLine(book models.Book, unitPrice money.Money, quantity int, promos []models.Promotion, now time.Time) (money.Money, []models.AppliedPromotion) {
+	discount := money.New(0, unitPrice.Currency)
+	current := unitPrice // Price of each paid copy after the promotions so far
+	paid := quantity     // Copies not already given away free
+	var applied []models.AppliedPromotion
+
+	for _, promo := range promos {
+		if !promo.ActiveAt(now) || !promo.AppliesTo(book, unitPrice) {
+			continue
+		}
+
+		var lineDiscount money.Money
+		copies := paid
+
+		switch promo.Type {
+		case models.PromotionPercentage:
+			perCopy := current.Percent(promo.Percent)
+			current, _ = current.Sub(perCopy)
+			lineDiscount = perCopy.Mul(paid)
+
+		case models.PromotionFixed:
+			// Fixed amounts only apply to prices in their own currency
+			if promo.Amount == nil || promo.Amount.Currency != current.Currency {
+				continue
+			}
+			perCopy := money.Min(*promo.Amount, current)
+			current, _ = current.Sub(perCopy)
+			lineDiscount = perCopy.Mul(paid)
+
+		case models.PromotionBuyXGetY:
+			copies = paid / (promo.Buy + promo.Get) * promo.Get
+			lineDiscount = current.Mul(copies)
+			paid -= copies
+		}
+
+		if lineDiscount.Amount <= 0 {
+			continue
+		}
+
+		discount, _ = discount.Add(lineDiscount)
+		applied = append(applied, models.AppliedPromotion{
+			PromotionID: promo.ID,
+			Name:        promo.Name,
+			BookID:      book.ID,
+			Quantity:    copies,
+			Amount:      lineDiscount,
+		})
+
+		if !promo.Stackable {
+			break
+		}
+	}
+
+	return discount, applied
+}
+
+// SalePrice returns the price of a single copy after promotions. Promotions
+// that depend on quantity, like buy 2 get 1, only show up in carts.
+func SalePrice(book models.Book, price money.Money, promos []models.Promotion, now time.Time) money.Money {
+	discount, _ := PriceLine(book, price, 1, promos, now)
+	sale, _ := price.Sub(discount)
+	return sale
+}
@@ -0,0 +1,125 @@
+package promotions_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/models"
+	"github.com/godwin/book-store-api/internal/money"
+	"github.com/godwin/book-store-api/internal/promotions"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+var book = models.Book{ID: "b1", Title: "Refactoring", Author: "Martin Fowler", ISBN: "9780201485677"}
+
+func usd(minor int64) *money.Money {
+	m := money.New(minor, "USD")
+	return &m
+}
+
+func percent(id string, pct, priority int, stackable bool) models.Promotion {
+	return models.Promotion{ID: id, Name: id, Type: models.PromotionPercentage, Percent: pct, Priority: priority, Stackable: stackable}
+}
+
+func fixed(id string, amount *money.Money, priority int, stackable bool) models.Promotion {
+	return models.Promotion{ID: id, Name: id, Type: models.PromotionFixed, Amount: amount, Priority: priority, Stackable: stackable}
+}
+
+func buyGet(id string, buy, get, priority int, stackable bool) models.Promotion {
+	return models.Promotion{ID: id, Name: id, Type: models.PromotionBuyXGetY, Buy: buy, Get: get, Priority: priority, Stackable: stackable}
+}
+
+func TestPriceLine(t *testing.T) {
+	yesterday, tomorrow := now.AddDate(0, 0, -1), now.AddDate(0, 0, 1)
+	expired := percent("expired", 50, 10, true)
+	expired.EndsAt = &yesterday
+	upcoming := percent("upcoming", 50, 10, true)
+	upcoming.StartsAt = &tomorrow
+	knuthOnly := percent("knuth", 50, 3, false)
+	knuthOnly.Conditions.Authors = []string{"Donald Knuth"}
+	thisAuthor := percent("fowler", 10, 10, true)
+	thisAuthor.Conditions.Authors = []string{" martin fowler "}
+	cheapOnly := percent("cheap", 10, 10, true)
+	cheapOnly.Conditions.MaxPrice = usd(2000)
+
+	tests := []struct {
+		name     string
+		quantity int
+		promos   []models.Promotion
+		discount int64
+		applied  map[string]int64 // Discount given by each promotion
+	}{
+		{"no promotions", 2, nil, 0, nil},
+		{"percentage on every copy", 2, []models.Promotion{percent("p", 20, 0, false)}, 1600, map[string]int64{"p": 1600}},
+		{"fixed amount per copy", 3, []models.Promotion{fixed("f", usd(500), 0, false)}, 1500, map[string]int64{"f": 1500}},
+		{"fixed amount no more than the price", 1, []models.Promotion{fixed("f", usd(5000), 0, false)}, 4000, map[string]int64{"f": 4000}},
+		{"fixed amount in another currency", 1, []models.Promotion{fixed("f", &money.Money{Amount: 500, Currency: "EUR"}, 0, false)}, 0, nil},
+		{"buy 2 get 1", 7, []models.Promotion{buyGet("b", 2, 1, 0, false)}, 8000, map[string]int64{"b": 8000}},
+		{"buy 2 get 1 needs 3 copies", 2, []models.Promotion{buyGet("b", 2, 1, 0, false)}, 0, nil},
+		{"stacked promotions compound", 1, []models.Promotion{percent("p", 10, 2, true), fixed("f", usd(400), 1, true)}, 800, map[string]int64{"p": 400, "f": 400}},
+		{"non-stackable stops the rest", 1, []models.Promotion{percent("p", 10, 2, false), fixed("f", usd(400), 1, true)}, 400, map[string]int64{"p": 400}},
+		{"stacking continues past promotions that don't apply", 1, []models.Promotion{knuthOnly, percent("p", 10, 2, true), fixed("f", usd(400), 1, false)}, 800, map[string]int64{"p": 400, "f": 400}},
+		{"free copies aren't discounted again", 3, []models.Promotion{buyGet("b", 2, 1, 2, true), percent("p", 10, 1, false)}, 4800, map[string]int64{"b": 4000, "p": 800}},
+		{"discounted copies are cheaper to give away", 3, []models.Promotion{percent("p", 50, 2, true), buyGet("b", 2, 1, 1, false)}, 8000, map[string]int64{"p": 6000, "b": 2000}},
+		{"inactive promotions are skipped", 1, []models.Promotion{expired, upcoming}, 0, nil},
+		{"author conditions", 1, []models.Promotion{knuthOnly, thisAuthor}, 400, map[string]int64{"fowler": 400}},
+		{"price conditions use the unit price", 1, []models.Promotion{cheapOnly}, 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			promos := append([]models.Promotion{}, tt.promos...)
+			promotions.Sort(promos)
+
+			discount, applied := promotions.PriceLine(book, money.New(4000, "USD"), tt.quantity, promos, now)
+			if discount != money.New(tt.discount, "USD") {
+				t.Errorf("Expected a discount of %d, got %v", tt.discount, discount)
+			}
+			got := make(map[string]int64)
+			for _, a := range applied {
+				got[a.PromotionID] = a.Amount.Amount
+				if a.BookID != book.ID {
+					t.Errorf("Expected the discount on %s, got %s", book.ID, a.BookID)
+				}
+			}
+			if len(got) != len(tt.applied) || len(got) > 0 && !reflect.DeepEqual(got, tt.applied) {
+				t.Errorf("Expected %v, got %v", tt.applied, got)
+			}
+		})
+	}
+}
+
+func TestPriceLine_FreeCopies(t *testing.T) {
+	_, applied := promotions.PriceLine(book, money.New(4000, "USD"), 7, []models.Promotion{buyGet("b", 2, 1, 0, false)}, now)
+	if len(applied) != 1 || applied[0].Quantity != 2 {
+		t.Errorf("Expected 2 free copies of 7, got %+v", applied)
+	}
+}
+
+func TestSort(t *testing.T) {
+	promos := []models.Promotion{
+		{ID: "low", Priority: 1, CreatedAt: now},
+		{ID: "newer", Priority: 5, CreatedAt: now.Add(time.Hour)},
+		{ID: "older", Priority: 5, CreatedAt: now},
+	}
+	promotions.Sort(promos)
+
+	want := []string{"older", "newer", "low"}
+	for i, promo := range promos {
+		if promo.ID != want[i] {
+			t.Errorf("Expected %s at %d, got %s", want[i], i, promo.ID)
+		}
+	}
+}
+
+func TestSalePrice(t *testing.T) {
+	promos := []models.Promotion{percent("p", 25, 1, true), buyGet("b", 1, 1, 0, false)}
+	promotions.Sort(promos)
+
+	// Buy 1 get 1 needs two copies, so a single copy only gets the percentage
+	if got := promotions.SalePrice(book, money.New(4000, "USD"), promos, now); got != money.New(3000, "USD") {
+		t.Errorf("Expected 30.00 USD, got %v", got)
+	}
+}
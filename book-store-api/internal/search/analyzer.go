@@ -0,0 +1,176 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Token is a normalized word found in a piece of text
+type Token struct {
+	Surface  string // Folded but unstemmed form, used for prefix matching
+	Term     string // Folded and stemmed form, used for indexing
+	Position int    // Index of the token within the text
+	Start    int    // Byte offset of the token in the original text
+	End      int    // Byte offset just past the token in the original text
+}
+
+// Tokenize splits text into folded, stemmed tokens. Letters and digits form
+// words; everything else is a separator.
+func Tokenize(text string) []Token {
+	var tokens []Token
+	var word strings.Builder
+	start := -1
+
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		surface := word.String()
+		tokens = append(tokens, Token{
+			Surface:  surface,
+			Term:     Stem(surface),
+			Position: len(tokens),
+			Start:    start,
+			End:      end,
+		})
+		word.Reset()
+		start = -1
+	}
+
+	for i, r := range text {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			word.WriteString(foldRune(r))
+			continue
+		}
+		// Apostrophes inside words ("don't") are dropped rather than splitting the word
+		if r == '\'' || r == '’' {
+			if after := i + utf8.RuneLen(r); start >= 0 && after < len(text) {
+				next, _ := utf8.DecodeRuneInString(text[after:])
+				if unicode.IsLetter(next) {
+					continue
+				}
+			}
+		}
+		flush(i)
+	}
+	flush(len(text))
+
+	return tokens
+}
+
+// Fold lowercases text and strips accents so "Café" and "cafe" compare equal
+func Fold(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		b.WriteString(foldRune(r))
+	}
+	return b.String()
+}
+
+func foldRune(r rune) string {
+	r = unicode.ToLower(r)
+	if folded, ok := accentFolds[r]; ok {
+		return folded
+	}
+	return string(r)
+}
+
+// accentFolds maps accented Latin characters to their unaccented equivalents
+var accentFolds = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'ç': "c", 'ć': "c", 'ĉ': "c", 'ċ': "c", 'č': "c",
+	'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ĕ': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ĝ': "g", 'ğ': "g", 'ġ': "g", 'ģ': "g",
+	'ĥ': "h", 'ħ': "h",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ĩ': "i", 'ī': "i", 'ĭ': "i", 'į': "i", 'ı': "i",
+	'ĵ': "j",
+	'ķ': "k",
+	'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ŀ': "l", 'ł': "l",
+	'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ŏ': "o", 'ő': "o",
+	'ŕ': "r", 'ŗ': "r", 'ř': "r",
+	'ś': "s", 'ŝ': "s", 'ş': "s", 'š': "s", 'ß': "ss",
+	'ţ': "t", 'ť': "t", 'ŧ': "t",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ũ': "u", 'ū': "u", 'ŭ': "u", 'ů': "u", 'ű': "u", 'ų': "u",
+	'ŵ': "w",
+	'ý': "y", 'ÿ': "y", 'ŷ': "y",
+	'ź': "z", 'ż': "z", 'ž': "z",
+	'æ': "ae", 'œ': "oe", 'þ': "th",
+}
+
+// Stem reduces an English word to a stem by stripping common inflectional
+// suffixes, so "patterns", "programming" and "programmed" match "pattern"
+// and "program". It is deliberately conservative.
+func Stem(word string) string {
+	if len(word) <= 3 {
+		return word
+	}
+
+	switch {
+	case strings.HasSuffix(word, "sses"):
+		word = word[:len(word)-2]
+	case strings.HasSuffix(word, "ies") && len(word) > 4:
+		word = word[:len(word)-3] + "y"
+	case strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") &&
+		!strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		word = word[:len(word)-1]
+	}
+
+	for _, suffix := range []string{"ing", "ed"} {
+		if !strings.HasSuffix(word, suffix) {
+			continue
+		}
+		stem := word[:len(word)-len(suffix)]
+		if len(stem) < 3 || !hasVowel(stem) {
+			break
+		}
+		// Undouble trailing consonants: "programm" -> "program", "runn" -> "run"
+		n := len(stem)
+		if stem[n-1] == stem[n-2] && !isVowel(stem[n-1]) &&
+			stem[n-1] != 'l' && stem[n-1] != 's' && stem[n-1] != 'z' {
+			stem = stem[:n-1]
+		} else if isShortSyllable(stem) {
+			// Restore a silent e: "cod" -> "code", "hop" -> "hope"
+			stem += "e"
+		}
+		word = stem
+		break
+	}
+
+	return word
+}
+
+// isShortSyllable reports whether s has a single vowel group and ends
+// consonant-vowel-consonant, where the final consonant is not w, x or y
+func isShortSyllable(s string) bool {
+	n := len(s)
+	if n < 3 || isVowel(s[n-1]) || !isVowel(s[n-2]) || isVowel(s[n-3]) {
+		return false
+	}
+	if s[n-1] == 'w' || s[n-1] == 'x' || s[n-1] == 'y' {
+		return false
+	}
+	return !hasVowel(s[:n-2])
+}
+
+func hasVowel(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if isVowel(s[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+func isVowel(b byte) bool {
+	switch b {
+	case 'a', 'e', 'i', 'o', 'u', 'y':
+		return true
+	}
+	return false
+}
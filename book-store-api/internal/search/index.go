@@ -0,0 +1,341 @@
+package search
+
+import (
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BM25 tuning parameters
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// snippetWindow is the number of tokens shown either side of the first match
+// when a field is too long to return in full
+const snippetWindow = 8
+
+// Result is a document matching a query
+type Result struct {
+	ID         string
+	Score      float64
+	Highlights map[string]string // Field name to snippet with matches wrapped in <mark>
+}
+
+// Index is an in-memory inverted index over named document fields. It is
+// safe for concurrent use.
+type Index struct {
+	boosts map[string]float64
+
+	mu       sync.RWMutex
+	docs     map[string]map[string]string           // doc ID -> field -> original text
+	postings map[string]map[string]map[string][]int // term -> doc ID -> field -> positions
+	lengths  map[string]map[string]int              // doc ID -> field -> token count
+	totals   map[string]int                         // field -> total token count
+	surfaces map[string]int                         // folded word -> occurrences, for prefix queries
+}
+
+// NewIndex creates an index over the given fields. The boost for each field
+// scales how much matches in that field contribute to the score.
+func NewIndex(boosts map[string]float64) *Index {
+	return &Index{
+		boosts:   boosts,
+		docs:     make(map[string]map[string]string),
+		postings: make(map[string]map[string]map[string][]int),
+		lengths:  make(map[string]map[string]int),
+		totals:   make(map[string]int),
+		surfaces: make(map[string]int),
+	}
+}
+
+// Add indexes a document, replacing any previous version with the same ID
+func (idx *Index) Add(id string, fields map[string]string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+
+	stored := make(map[string]string, len(fields))
+	lengths := make(map[string]int, len(fields))
+	for field, text := range fields {
+		if _, ok := idx.boosts[field]; !ok {
+			continue
+		}
+		stored[field] = text
+
+		tokens := Tokenize(text)
+		lengths[field] = len(tokens)
+		idx.totals[field] += len(tokens)
+
+		for _, t := range tokens {
+			docs, ok := idx.postings[t.Term]
+			if !ok {
+				docs = make(map[string]map[string][]int)
+				idx.postings[t.Term] = docs
+			}
+			if docs[id] == nil {
+				docs[id] = make(map[string][]int)
+			}
+			docs[id][field] = append(docs[id][field], t.Position)
+			idx.surfaces[t.Surface]++
+		}
+	}
+
+	idx.docs[id] = stored
+	idx.lengths[id] = lengths
+}
+
+// Remove deletes a document from the index
+func (idx *Index) Remove(id string) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(id)
+}
+
+func (idx *Index) remove(id string) {
+	fields, ok := idx.docs[id]
+	if !ok {
+		return
+	}
+
+	for field, text := range fields {
+		tokens := Tokenize(text)
+		idx.totals[field] -= len(tokens)
+
+		for _, t := range tokens {
+			if docs, ok := idx.postings[t.Term]; ok {
+				delete(docs, id)
+				if len(docs) == 0 {
+					delete(idx.postings, t.Term)
+				}
+			}
+			idx.surfaces[t.Surface]--
+			if idx.surfaces[t.Surface] <= 0 {
+				delete(idx.surfaces, t.Surface)
+			}
+		}
+	}
+
+	delete(idx.docs, id)
+	delete(idx.lengths, id)
+}
+
+// Search returns documents matching every clause of the query, best first
+func (idx *Index) Search(query string) ([]Result, error) {
+	clauses, err := ParseQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var scores map[string]float64
+	matches := make(map[string]map[string]map[int]bool) // doc ID -> field -> matched positions
+
+	for _, clause := range clauses {
+		clauseScores := idx.matchClause(clause, matches)
+
+		// Intersect with the documents that matched earlier clauses
+		if scores == nil {
+			scores = clauseScores
+			continue
+		}
+		for id, score := range scores {
+			if clauseScore, ok := clauseScores[id]; ok {
+				scores[id] = score + clauseScore
+			} else {
+				delete(scores, id)
+			}
+		}
+	}
+
+	results := make([]Result, 0, len(scores))
+	for id, score := range scores {
+		highlights := make(map[string]string)
+		for field, positions := range matches[id] {
+			highlights[field] = highlight(idx.docs[id][field], positions)
+		}
+		results = append(results, Result{ID: id, Score: score, Highlights: highlights})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID < results[j].ID
+	})
+
+	return results, nil
+}
+
+// matchClause scores every document matching the clause and records the
+// matched positions for highlighting
+func (idx *Index) matchClause(clause Clause, matches map[string]map[string]map[int]bool) map[string]float64 {
+	scores := make(map[string]float64)
+
+	record := func(id, field string, positions []int) {
+		if matches[id] == nil {
+			matches[id] = make(map[string]map[int]bool)
+		}
+		if matches[id][field] == nil {
+			matches[id][field] = make(map[int]bool)
+		}
+		for _, p := range positions {
+			matches[id][field][p] = true
+		}
+	}
+
+	switch clause.Kind {
+	case TermClause:
+		term := clause.Terms[0]
+		idf := idx.idf(term)
+		for id, fields := range idx.postings[term] {
+			for field, positions := range fields {
+				scores[id] += idx.fieldScore(id, field, len(positions), idf)
+				record(id, field, positions)
+			}
+		}
+
+	case PrefixClause:
+		seen := make(map[string]bool)
+		for surface := range idx.surfaces {
+			if !strings.HasPrefix(surface, clause.Prefix) {
+				continue
+			}
+			term := Stem(surface)
+			if seen[term] {
+				continue
+			}
+			seen[term] = true
+
+			idf := idx.idf(term)
+			for id, fields := range idx.postings[term] {
+				best := 0.0
+				for field, positions := range fields {
+					best = math.Max(best, idx.fieldScore(id, field, len(positions), idf))
+					record(id, field, positions)
+				}
+				scores[id] = math.Max(scores[id], best)
+			}
+		}
+
+	case PhraseClause:
+		first := idx.postings[clause.Terms[0]]
+		idf := 0.0
+		for _, term := range clause.Terms {
+			idf += idx.idf(term)
+		}
+
+		for id, fields := range first {
+			for field, starts := range fields {
+				var matched []int
+				count := 0
+				for _, start := range starts {
+					if idx.phraseAt(id, field, clause.Terms, start) {
+						count++
+						for i := range clause.Terms {
+							matched = append(matched, start+i)
+						}
+					}
+				}
+				if count > 0 {
+					scores[id] += idx.fieldScore(id, field, count, idf)
+					record(id, field, matched)
+				}
+			}
+		}
+	}
+
+	return scores
+}
+
+// phraseAt reports whether terms occur consecutively in the field starting at position start
+func (idx *Index) phraseAt(id, field string, terms []string, start int) bool {
+	for i, term := range terms[1:] {
+		positions := idx.postings[term][id][field]
+		want := start + i + 1
+		j := sort.SearchInts(positions, want)
+		if j >= len(positions) || positions[j] != want {
+			return false
+		}
+	}
+	return true
+}
+
+// idf is the BM25 inverse document frequency of a term
+func (idx *Index) idf(term string) float64 {
+	n := float64(len(idx.docs))
+	df := float64(len(idx.postings[term]))
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// fieldScore is the boosted BM25 contribution of tf matches in one field
+func (idx *Index) fieldScore(id, field string, tf int, idf float64) float64 {
+	avg := 1.0
+	if len(idx.docs) > 0 && idx.totals[field] > 0 {
+		avg = float64(idx.totals[field]) / float64(len(idx.docs))
+	}
+	length := float64(idx.lengths[id][field])
+	freq := float64(tf)
+
+	norm := freq * (bm25K1 + 1) / (freq + bm25K1*(1-bm25B+bm25B*length/avg))
+	return idx.boosts[field] * idf * norm
+}
+
+// highlight wraps the tokens at the matched positions in <mark> tags. Long
+// text is trimmed to a window around the first match.
+func highlight(text string, positions map[int]bool) string {
+	tokens := Tokenize(text)
+	if len(tokens) == 0 {
+		return html.EscapeString(text)
+	}
+
+	first := len(tokens)
+	for p := range positions {
+		if p < first {
+			first = p
+		}
+	}
+
+	from, to := 0, len(tokens)-1
+	if len(tokens) > 2*snippetWindow+1 && first < len(tokens) {
+		from = max(0, first-snippetWindow)
+		to = min(len(tokens)-1, first+snippetWindow)
+	}
+
+	var b strings.Builder
+	start := tokens[from].Start
+	end := tokens[to].End
+	if from == 0 {
+		start = 0
+	} else {
+		b.WriteString("…")
+	}
+	if to == len(tokens)-1 {
+		end = len(text)
+	}
+
+	cursor := start
+	for _, t := range tokens[from : to+1] {
+		if !positions[t.Position] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[cursor:t.Start]))
+		b.WriteString("<mark>")
+		b.WriteString(html.EscapeString(text[t.Start:t.End]))
+		b.WriteString("</mark>")
+		cursor = t.End
+	}
+	b.WriteString(html.EscapeString(text[cursor:end]))
+
+	if to < len(tokens)-1 {
+		b.WriteString("…")
+	}
+
+	return b.String()
+}
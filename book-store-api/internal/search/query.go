@@ -0,0 +1,99 @@
+package search
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrEmptyQuery is returned when a query has no searchable terms
+var ErrEmptyQuery = errors.New("search query is empty")
+
+// ClauseKind identifies how a query clause matches documents
+type ClauseKind int
+
+const (
+	TermClause   ClauseKind = iota // A single word, matched after stemming
+	PhraseClause                   // Words that must appear consecutively
+	PrefixClause                   // A word prefix such as prag*
+)
+
+// Clause is one part of a parsed query. Every clause must match for a
+// document to be returned.
+type Clause struct {
+	Kind   ClauseKind
+	Terms  []string // Stemmed terms for term and phrase clauses
+	Prefix string   // Folded prefix for prefix clauses
+}
+
+// ParseQuery parses a query string. Double-quoted text is a phrase, a word
+// ending in * is a prefix, and any other word is a term.
+func ParseQuery(q string) ([]Clause, error) {
+	var clauses []Clause
+
+	for len(q) > 0 {
+		q = strings.TrimLeft(q, " \t\r\n")
+		if q == "" {
+			break
+		}
+
+		if q[0] == '"' {
+			end := strings.IndexByte(q[1:], '"')
+			var phrase string
+			if end < 0 {
+				phrase, q = q[1:], ""
+			} else {
+				phrase, q = q[1:end+1], q[end+2:]
+			}
+
+			terms := termsOf(Tokenize(phrase))
+			switch len(terms) {
+			case 0:
+			case 1:
+				clauses = append(clauses, Clause{Kind: TermClause, Terms: terms})
+			default:
+				clauses = append(clauses, Clause{Kind: PhraseClause, Terms: terms})
+			}
+			continue
+		}
+
+		end := strings.IndexAny(q, " \t\r\n\"")
+		var word string
+		if end < 0 {
+			word, q = q, ""
+		} else {
+			word, q = q[:end], q[end:]
+		}
+
+		tokens := Tokenize(word)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		// A trailing * applies to the last token of the word
+		if strings.HasSuffix(word, "*") {
+			for _, t := range tokens[:len(tokens)-1] {
+				clauses = append(clauses, Clause{Kind: TermClause, Terms: []string{t.Term}})
+			}
+			clauses = append(clauses, Clause{Kind: PrefixClause, Prefix: tokens[len(tokens)-1].Surface})
+			continue
+		}
+
+		for _, t := range tokens {
+			clauses = append(clauses, Clause{Kind: TermClause, Terms: []string{t.Term}})
+		}
+	}
+
+	if len(clauses) == 0 {
+		return nil, ErrEmptyQuery
+	}
+
+	return clauses, nil
+}
+
+func termsOf(tokens []Token) []string {
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.Term
+	}
+	return terms
+}
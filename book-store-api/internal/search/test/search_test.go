@@ -0,0 +1,271 @@
+package search_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/search"
+)
+
+func TestStem(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"patterns", "pattern"},
+		{"programming", "program"},
+		{"programmed", "program"},
+		{"classes", "class"},
+		{"libraries", "library"},
+		{"coding", "code"},
+		{"hoping", "hope"},
+		{"running", "run"},
+		{"falling", "fall"},
+		{"status", "status"},
+		{"analysis", "analysis"},
+		{"class", "class"},
+		{"sing", "sing"},
+		{"cats", "cat"},
+		{"bus", "bus"}, // Words of three letters or fewer are left alone
+		{"go", "go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := search.Stem(tt.word); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFold(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"Café", "cafe"},
+		{"Ñandú", "nandu"},
+		{"Straße", "strasse"},
+		{"Œuvre Æsop", "oeuvre aesop"},
+		{"plain", "plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := search.Fold(tt.text); got != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	text := "Don't stop: Café-Patterns 2nd"
+	tokens := search.Tokenize(text)
+
+	wantSurfaces := []string{"dont", "stop", "cafe", "patterns", "2nd"}
+	wantTerms := []string{"dont", "stop", "cafe", "pattern", "2nd"}
+	if len(tokens) != len(wantSurfaces) {
+		t.Fatalf("Expected %d tokens, got %d: %+v", len(wantSurfaces), len(tokens), tokens)
+	}
+	for i, token := range tokens {
+		if token.Surface != wantSurfaces[i] || token.Term != wantTerms[i] {
+			t.Errorf("Token %d: expected %q/%q, got %q/%q", i, wantSurfaces[i], wantTerms[i], token.Surface, token.Term)
+		}
+		if token.Position != i {
+			t.Errorf("Token %d: expected position %d, got %d", i, i, token.Position)
+		}
+	}
+
+	// Offsets point back into the original text, accents included
+	if got := text[tokens[2].Start:tokens[2].End]; got != "Café" {
+		t.Errorf("Expected offsets to cover %q, got %q", "Café", got)
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  []search.Clause
+	}{
+		{
+			name:  "terms are stemmed",
+			query: "design patterns",
+			want: []search.Clause{
+				{Kind: search.TermClause, Terms: []string{"design"}},
+				{Kind: search.TermClause, Terms: []string{"pattern"}},
+			},
+		},
+		{
+			name:  "phrase",
+			query: `"design patterns" go`,
+			want: []search.Clause{
+				{Kind: search.PhraseClause, Terms: []string{"design", "pattern"}},
+				{Kind: search.TermClause, Terms: []string{"go"}},
+			},
+		},
+		{
+			name:  "single word phrase is a term",
+			query: `"Patterns"`,
+			want:  []search.Clause{{Kind: search.TermClause, Terms: []string{"pattern"}}},
+		},
+		{
+			name:  "unterminated phrase runs to the end",
+			query: `"clean code`,
+			want:  []search.Clause{{Kind: search.PhraseClause, Terms: []string{"clean", "code"}}},
+		},
+		{
+			name:  "prefix keeps the folded surface form",
+			query: "Prag*",
+			want:  []search.Clause{{Kind: search.PrefixClause, Prefix: "prag"}},
+		},
+		{
+			name:  "prefix applies to the last token of a word",
+			query: "object-orient*",
+			want: []search.Clause{
+				{Kind: search.TermClause, Terms: []string{"object"}},
+				{Kind: search.PrefixClause, Prefix: "orient"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := search.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseQuery_Empty(t *testing.T) {
+	for _, query := range []string{"", "   ", `""`, "!!! --", "*"} {
+		if _, err := search.ParseQuery(query); !errors.Is(err, search.ErrEmptyQuery) {
+			t.Errorf("Expected ErrEmptyQuery for %q, got %v", query, err)
+		}
+	}
+}
+
+func newIndex() *search.Index {
+	idx := search.NewIndex(map[string]float64{"title": 2, "author": 1})
+	idx.Add("1", map[string]string{"title": "Design Patterns", "author": "Erich Gamma"})
+	idx.Add("2", map[string]string{"title": "Clean Code", "author": "Robert C. Martin"})
+	idx.Add("3", map[string]string{"title": "The Pragmatic Programmer", "author": "Andrew Hunt"})
+	idx.Add("4", map[string]string{"title": "Patterns of Enterprise Application Architecture", "author": "Martin Fowler"})
+	idx.Add("5", map[string]string{"title": "Refactoring", "author": "Martin Fowler", "summary": "Not indexed"})
+	idx.Add("6", map[string]string{"title": "Martin Eden", "author": "Jack London"})
+	return idx
+}
+
+func ids(results []search.Result) []string {
+	found := make([]string, len(results))
+	for i, r := range results {
+		found[i] = r.ID
+	}
+	return found
+}
+
+func TestIndex_Search(t *testing.T) {
+	idx := newIndex()
+
+	tests := []struct {
+		name  string
+		query string
+		want  []string
+	}{
+		{"stemmed term", "pattern", []string{"1", "4"}},
+		{"every clause must match", "patterns enterprise", []string{"4"}},
+		{"title matches outrank author matches", "martin", []string{"6", "4", "5", "2"}},
+		{"phrase in order", `"design patterns"`, []string{"1"}},
+		{"phrase out of order", `"patterns design"`, nil},
+		{"prefix", "prag*", []string{"3"}},
+		{"prefix matches several documents", "pat*", []string{"1", "4"}},
+		{"accents are folded", "Réfactoring", []string{"5"}},
+		{"fields without a boost are not indexed", "indexed", nil},
+		{"no match", "haskell", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, err := idx.Search(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := ids(results); !reflect.DeepEqual(got, tt.want) && !(len(got) == 0 && len(tt.want) == 0) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestIndex_ShorterFieldScoresHigher(t *testing.T) {
+	idx := newIndex()
+
+	results, err := idx.Search("patterns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Score <= results[1].Score {
+		t.Fatalf("Expected two results in descending score order, got %+v", results)
+	}
+	// "Design Patterns" is shorter than "Patterns of Enterprise Application Architecture"
+	if results[0].ID != "1" {
+		t.Errorf("Expected the shorter title first, got %s", results[0].ID)
+	}
+}
+
+func TestIndex_AddReplacesAndRemove(t *testing.T) {
+	idx := newIndex()
+
+	idx.Add("2", map[string]string{"title": "Clean Architecture", "author": "Robert C. Martin"})
+	if results, _ := idx.Search("code"); len(results) != 0 {
+		t.Errorf("Expected the replaced title not to match, got %v", ids(results))
+	}
+	if results, _ := idx.Search("architecture"); !reflect.DeepEqual(ids(results), []string{"2", "4"}) {
+		t.Errorf("Expected [2 4], got %v", ids(results))
+	}
+
+	idx.Remove("4")
+	idx.Remove("missing")
+	if results, _ := idx.Search("architecture"); !reflect.DeepEqual(ids(results), []string{"2"}) {
+		t.Errorf("Expected [2] after removal, got %v", ids(results))
+	}
+	if results, _ := idx.Search("enterp*"); len(results) != 0 {
+		t.Errorf("Expected removed words not to match prefixes, got %v", ids(results))
+	}
+}
+
+func TestIndex_Highlights(t *testing.T) {
+	idx := search.NewIndex(map[string]float64{"title": 1})
+	idx.Add("1", map[string]string{"title": "Tom & Jerry's <Patterns>"})
+	idx.Add("2", map[string]string{"title": "one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen pattern nineteen twenty"})
+
+	results, err := idx.Search("pattern")
+	if err != nil {
+		t.Fatal(err)
+	}
+	highlights := make(map[string]string)
+	for _, r := range results {
+		highlights[r.ID] = r.Highlights["title"]
+	}
+
+	if want := "Tom &amp; Jerry&#39;s &lt;<mark>Patterns</mark>&gt;"; highlights["1"] != want {
+		t.Errorf("Expected %q, got %q", want, highlights["1"])
+	}
+	if want := "…eleven twelve thirteen fourteen fifteen sixteen seventeen eighteen <mark>pattern</mark> nineteen twenty"; highlights["2"] != want {
+		t.Errorf("Expected %q, got %q", want, highlights["2"])
+	}
+}
+
+func TestIndex_EmptyQuery(t *testing.T) {
+	if _, err := newIndex().Search("  "); !errors.Is(err, search.ErrEmptyQuery) {
+		t.Errorf("Expected ErrEmptyQuery, got %v", err)
+	}
+}
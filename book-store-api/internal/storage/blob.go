@@ -0,0 +1,48 @@
+// Package storage keeps binary objects, such as cover images, outside the
+// book store. Objects are addressed by slash-separated keys.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when no object has the requested key
+	ErrNotFound = errors.New("object not found")
+	// ErrInvalidKey is returned for an empty key or one with empty, "." or ".." segments
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// BlobStore stores objects by key. Putting an object replaces any object
+// with the same key, and deleting a missing object is not an error.
+type BlobStore interface {
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Object describes a stored object
+type Object struct {
+	Key         string
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+}
+
+// ValidKey reports whether key is a relative, slash-separated path without
+// empty, "." or ".." segments, so it can't escape a store's root
+func ValidKey(key string) bool {
+	if key == "" || strings.ContainsAny(key, "\\\x00") {
+		return false
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,241 @@
+package storage_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/godwin/book-store-api/internal/storage"
+)
+
+// newLocalStore returns a store in a fresh temporary directory
+func newLocalStore(t *testing.T) (*storage.LocalStore, string) {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := storage.NewLocalStore(dir)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store, dir
+}
+
+// read gets an object and reads all of it
+func read(t *testing.T, store storage.BlobStore, key string) (string, storage.Object, error) {
+	t.Helper()
+	body, object, err := store.Get(context.Background(), key)
+	if err != nil {
+		return "", object, err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", key, err)
+	}
+	return string(data), object, nil
+}
+
+func TestValidKey(t *testing.T) {
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{"covers/1/original.jpg", true},
+		{"cover.png", true},
+		{"covers/my cover (1).jpg", true},
+		{"", false},
+		{"/covers/1.jpg", false},
+		{"covers/", false},
+		{"covers//1.jpg", false},
+		{"./covers/1.jpg", false},
+		{"covers/../../etc/passwd", false},
+		{"..", false},
+		{"covers\\1.jpg", false},
+		{"covers/1.jpg\x00", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if got := storage.ValidKey(tt.key); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLocalStore_PutGet(t *testing.T) {
+	store, _ := newLocalStore(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		key         string
+		data        string
+		contentType string
+	}{
+		{"covers/1/original.jpg", "jpeg bytes", "image/jpeg"},
+		{"covers/1/small.png", "png bytes", "image/png"},
+		{"exports/books", "no extension", "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			// The claimed content type is ignored in favour of the extension
+			if err := store.Put(ctx, tt.key, []byte(tt.data), "text/plain"); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, object, err := read(t, store, tt.key)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if data != tt.data {
+				t.Errorf("Expected %q, got %q", tt.data, data)
+			}
+			if object.Key != tt.key || object.ContentType != tt.contentType || object.Size != int64(len(tt.data)) {
+				t.Errorf("Expected %s %s %d, got %s %s %d", tt.key, tt.contentType, len(tt.data),
+					object.Key, object.ContentType, object.Size)
+			}
+			if object.ModTime.IsZero() {
+				t.Error("Expected a modification time")
+			}
+		})
+	}
+}
+
+func TestLocalStore_PutReplaces(t *testing.T) {
+	store, dir := newLocalStore(t)
+	ctx := context.Background()
+
+	if err := store.Put(ctx, "covers/1.jpg", []byte("first"), ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := store.Put(ctx, "covers/1.jpg", []byte("second"), ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, _, err := read(t, store, "covers/1.jpg")
+	if err != nil || data != "second" {
+		t.Errorf("Expected second, got %q (%v)", data, err)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Join(dir, "covers"))
+	if err != nil {
+		t.Fatalf("Failed to list directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected 1 file, got %d", len(entries))
+	}
+}
+
+func TestLocalStore_Get_NotFound(t *testing.T) {
+	store, _ := newLocalStore(t)
+	if err := store.Put(context.Background(), "covers/1/original.jpg", []byte("x"), ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		key  string
+	}{
+		{"missing file", "covers/2/original.jpg"},
+		{"directory", "covers/1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := read(t, store, tt.key)
+			if !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("Expected %v, got %v", storage.ErrNotFound, err)
+			}
+		})
+	}
+}
+
+func TestLocalStore_Delete(t *testing.T) {
+	store, dir := newLocalStore(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"covers/1/original.jpg", "covers/1/small.jpg", "covers/2/original.jpg"} {
+		if err := store.Put(ctx, key, []byte(key), ""); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		key     string
+		removed string // Directory that should be gone afterwards, if any
+		kept    string // Directory that should still be there
+	}{
+		{"directory still has objects", "covers/1/original.jpg", "", "covers/1"},
+		{"empty directories are tidied", "covers/1/small.jpg", "covers/1", "covers"},
+		{"store root is kept", "covers/2/original.jpg", "covers", "."},
+		{"missing object", "covers/3/original.jpg", "", "."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := store.Delete(ctx, tt.key); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if _, _, err := read(t, store, tt.key); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("Expected %v, got %v", storage.ErrNotFound, err)
+			}
+			if tt.removed != "" {
+				if _, err := os.Stat(filepath.Join(dir, tt.removed)); !errors.Is(err, os.ErrNotExist) {
+					t.Errorf("Expected %s to be removed, got %v", tt.removed, err)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, tt.kept)); err != nil {
+				t.Errorf("Expected %s to be kept, got %v", tt.kept, err)
+			}
+		})
+	}
+}
+
+func TestLocalStore_InvalidKey(t *testing.T) {
+	store, dir := newLocalStore(t)
+	ctx := context.Background()
+	outside := filepath.Join(filepath.Dir(dir), "outside.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0o644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	for _, key := range []string{"", "../outside.txt", "covers/../../outside.txt", "/covers/1.jpg"} {
+		t.Run(key, func(t *testing.T) {
+			if err := store.Put(ctx, key, []byte("x"), ""); !errors.Is(err, storage.ErrInvalidKey) {
+				t.Errorf("Put: expected %v, got %v", storage.ErrInvalidKey, err)
+			}
+			if _, _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+				t.Errorf("Get: expected %v, got %v", storage.ErrInvalidKey, err)
+			}
+			if err := store.Delete(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+				t.Errorf("Delete: expected %v, got %v", storage.ErrInvalidKey, err)
+			}
+		})
+	}
+
+	if data, err := os.ReadFile(outside); err != nil || string(data) != "secret" {
+		t.Errorf("Expected the file outside the store to be untouched, got %q (%v)", data, err)
+	}
+}
+
+func TestLocalStore_CanceledContext(t *testing.T) {
+	store, _ := newLocalStore(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := store.Put(ctx, "covers/1.jpg", []byte("x"), ""); !errors.Is(err, context.Canceled) {
+		t.Errorf("Put: expected %v, got %v", context.Canceled, err)
+	}
+	if _, _, err := store.Get(ctx, "covers/1.jpg"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get: expected %v, got %v", context.Canceled, err)
+	}
+	if err := store.Delete(ctx, "covers/1.jpg"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Delete: expected %v, got %v", context.Canceled, err)
+	}
+}
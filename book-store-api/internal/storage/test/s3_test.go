@@ -0,0 +1,400 @@
+package storage_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godwin/book-store-api/internal/storage"
+)
+
+const (
+	testAccessKey = "AKIDEXAMPLE"
+	testSecretKey = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+)
+
+// fakeS3 stands in for an S3 bucket. It checks every request's signature
+// the way S3 does and keeps objects in memory by their escaped path.
+type fakeS3 struct {
+	t      *testing.T
+	region string
+	token  string
+
+	mu      sync.Mutex
+	objects map[string]fakeObject
+	paths   []string // Escaped request paths, in order
+}
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := f.verify(r, body); err != nil {
+		f.t.Errorf("%s %s: %v", r.Method, r.RequestURI, err)
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprintf(w, "<Error><Code>SignatureDoesNotMatch</Code><Message>%s</Message></Error>", err)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	object := r.Host + " " + r.RequestURI
+	f.paths = append(f.paths, r.RequestURI)
+
+	switch r.Method {
+	case http.MethodPut:
+		f.objects[object] = fakeObject{data: body, contentType: r.Header.Get("Content-Type")}
+	case http.MethodGet:
+		stored, exists := f.objects[object]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, "<Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>")
+			return
+		}
+		w.Header().Set("Content-Type", stored.contentType)
+		w.Header().Set("Last-Modified", "Sat, 17 Oct 2026 09:30:00 GMT")
+		w.Write(stored.data)
+	case http.MethodDelete:
+		delete(f.objects, object)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// verify recomputes a request's Signature Version 4 from what arrived on the
+// wire: the raw request path, the headers named as signed and the body
+func (f *fakeS3) verify(r *http.Request, body []byte) error {
+	auth := r.Header.Get("Authorization")
+	prefix := "AWS4-HMAC-SHA256 "
+	if !strings.HasPrefix(auth, prefix) {
+		return fmt.Errorf("unexpected Authorization %q", auth)
+	}
+	fields := map[string]string{}
+	for _, field := range strings.Split(strings.TrimPrefix(auth, prefix), ", ") {
+		name, value, _ := strings.Cut(field, "=")
+		fields[name] = value
+	}
+
+	amzDate := r.Header.Get("X-Amz-Date")
+	date, err := time.Parse("20060102T150405Z", amzDate)
+	if err != nil {
+		return fmt.Errorf("invalid X-Amz-Date %q", amzDate)
+	}
+	if skew := time.Since(date); skew < -time.Minute || skew > time.Minute {
+		return fmt.Errorf("X-Amz-Date %s is %v off", amzDate, skew)
+	}
+	day := date.Format("20060102")
+	scope := day + "/" + f.region + "/s3/aws4_request"
+	if want := testAccessKey + "/" + scope; fields["Credential"] != want {
+		return fmt.Errorf("expected credential %s, got %s", want, fields["Credential"])
+	}
+
+	payloadHash := sha256.Sum256(body)
+	if got := r.Header.Get("X-Amz-Content-Sha256"); got != hex.EncodeToString(payloadHash[:]) {
+		return fmt.Errorf("payload hash %s doesn't match the body", got)
+	}
+	if got := r.Header.Get("X-Amz-Security-Token"); got != f.token {
+		return fmt.Errorf("expected security token %q, got %q", f.token, got)
+	}
+
+	signed := strings.Split(fields["SignedHeaders"], ";")
+	if !sort.StringsAreSorted(signed) {
+		return fmt.Errorf("signed headers %v aren't sorted", signed)
+	}
+	required := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	if f.token != "" {
+		required = append(required, "x-amz-security-token")
+	}
+	if r.Header.Get("Content-Type") != "" {
+		required = append(required, "content-type")
+	}
+	for _, name := range required {
+		if !contains(signed, name) {
+			return fmt.Errorf("%s isn't signed", name)
+		}
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range signed {
+		value := r.Header.Get(name)
+		if name == "host" {
+			value = r.Host
+		}
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(value) + "\n")
+	}
+
+	path, query, _ := strings.Cut(r.RequestURI, "?")
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		path,
+		query,
+		canonicalHeaders.String(),
+		fields["SignedHeaders"],
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSHA256([]byte("AWS4"+testSecretKey), day)
+	key = hmacSHA256(key, f.region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	if want := hex.EncodeToString(hmacSHA256(key, stringToSign)); fields["Signature"] != want {
+		return fmt.Errorf("expected signature %s, got %s", want, fields["Signature"])
+	}
+	return nil
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// newS3Store starts a fake bucket and returns a store for it. Every
+// connection goes to the fake, so virtual-hosted bucket names needn't resolve.
+func newS3Store(t *testing.T, config storage.S3Config) (*storage.S3Store, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{t: t, region: config.Region, token: config.SessionToken, objects: map[string]fakeObject{}}
+	if fake.region == "" {
+		fake.region = "us-east-1"
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	addr := server.Listener.Addr().String()
+	config.Endpoint = strings.Replace(server.URL, "127.0.0.1", "s3.test", 1)
+	config.AccessKeyID = testAccessKey
+	config.SecretAccessKey = testSecretKey
+	config.Client = &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, addr)
+		},
+	}}
+
+	store, err := storage.NewS3Store(config)
+	if err != nil {
+		t.Fatalf("Failed to create store: %v", err)
+	}
+	return store, fake
+}
+
+func TestNewS3Store(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  storage.S3Config
+		wantErr bool
+	}{
+		{"defaults to AWS", storage.S3Config{Bucket: "covers"}, false},
+		{"custom endpoint", storage.S3Config{Bucket: "covers", Endpoint: "http://localhost:9000"}, false},
+		{"missing bucket", storage.S3Config{}, true},
+		{"endpoint without a host", storage.S3Config{Bucket: "covers", Endpoint: "localhost:9000"}, true},
+		{"unsupported scheme", storage.S3Config{Bucket: "covers", Endpoint: "ftp://localhost"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := storage.NewS3Store(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestS3Store_RoundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		config storage.S3Config
+		key    string
+		path   string // Escaped path the fake should see
+	}{
+		{
+			name:   "path style",
+			config: storage.S3Config{Bucket: "covers", PathStyle: true},
+			key:    "books/1/original.jpg",
+			path:   "/covers/books/1/original.jpg",
+		},
+		{
+			name:   "virtual hosted",
+			config: storage.S3Config{Bucket: "covers", Region: "eu-west-2"},
+			key:    "books/1/original.jpg",
+			path:   "/books/1/original.jpg",
+		},
+		{
+			name:   "temporary credentials",
+			config: storage.S3Config{Bucket: "covers", PathStyle: true, SessionToken: "session-token"},
+			key:    "books/1/original.jpg",
+			path:   "/covers/books/1/original.jpg",
+		},
+		{
+			name:   "reserved characters are escaped",
+			config: storage.S3Config{Bucket: "covers", PathStyle: true},
+			key:    "books/my cover (1)+!*=,;@$&'.jpg",
+			path:   "/covers/books/my%20cover%20%281%29%2B%21%2A%3D%2C%3B%40%24%26%27.jpg",
+		},
+		{
+			name:   "unreserved characters are not",
+			config: storage.S3Config{Bucket: "covers", PathStyle: true},
+			key:    "books/A-z_0.9~/cover.jpg",
+			path:   "/covers/books/A-z_0.9~/cover.jpg",
+		},
+		{
+			name:   "non-ASCII keys are escaped as UTF-8",
+			config: storage.S3Config{Bucket: "covers", PathStyle: true},
+			key:    "books/Gödel, Escher, Bach.jpg",
+			path:   "/covers/books/G%C3%B6del%2C%20Escher%2C%20Bach.jpg",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, fake := newS3Store(t, tt.config)
+			ctx := context.Background()
+
+			if err := store.Put(ctx, tt.key, []byte("cover bytes"), "image/jpeg"); err != nil {
+				t.Fatalf("Put: unexpected error: %v", err)
+			}
+
+			data, object, err := read(t, store, tt.key)
+			if err != nil {
+				t.Fatalf("Get: unexpected error: %v", err)
+			}
+			if data != "cover bytes" {
+				t.Errorf("Expected %q, got %q", "cover bytes", data)
+			}
+			wantModTime := time.Date(2026, time.October, 17, 9, 30, 0, 0, time.UTC)
+			if object.Key != tt.key || object.ContentType != "image/jpeg" || object.Size != 11 || !object.ModTime.Equal(wantModTime) {
+				t.Errorf("Expected %s image/jpeg 11 %v, got %s %s %d %v", tt.key, wantModTime,
+					object.Key, object.ContentType, object.Size, object.ModTime)
+			}
+
+			if err := store.Delete(ctx, tt.key); err != nil {
+				t.Fatalf("Delete: unexpected error: %v", err)
+			}
+			if _, _, err := read(t, store, tt.key); !errors.Is(err, storage.ErrNotFound) {
+				t.Errorf("Expected %v, got %v", storage.ErrNotFound, err)
+			}
+
+			fake.mu.Lock()
+			defer fake.mu.Unlock()
+			for _, path := range fake.paths {
+				if path != tt.path {
+					t.Errorf("Expected path %s, got %s", tt.path, path)
+				}
+			}
+		})
+	}
+}
+
+func TestS3Store_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		method  string
+		err     error
+		message string
+	}{
+		{"missing object", http.StatusNotFound, "<Error><Code>NoSuchKey</Code></Error>", http.MethodGet, storage.ErrNotFound, ""},
+		{"missing object on delete", http.StatusNotFound, "", http.MethodDelete, nil, ""},
+		{"missing bucket on put", http.StatusNotFound, "<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>",
+			http.MethodPut, nil, "NoSuchBucket: The specified bucket does not exist"},
+		{"access denied", http.StatusForbidden, "<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>",
+			http.MethodGet, nil, "AccessDenied: Access Denied"},
+		{"error without a body", http.StatusServiceUnavailable, "", http.MethodPut, nil, "503 Service Unavailable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				io.WriteString(w, tt.body)
+			}))
+			defer server.Close()
+
+			store, err := storage.NewS3Store(storage.S3Config{Endpoint: server.URL, Bucket: "covers", PathStyle: true})
+			if err != nil {
+				t.Fatalf("Failed to create store: %v", err)
+			}
+
+			ctx := context.Background()
+			switch tt.method {
+			case http.MethodPut:
+				err = store.Put(ctx, "books/1.jpg", []byte("x"), "image/jpeg")
+			case http.MethodGet:
+				_, _, err = store.Get(ctx, "books/1.jpg")
+			case http.MethodDelete:
+				err = store.Delete(ctx, "books/1.jpg")
+			}
+
+			switch {
+			case tt.message != "":
+				if err == nil || !strings.Contains(err.Error(), tt.message) {
+					t.Errorf("Expected an error containing %q, got %v", tt.message, err)
+				}
+				if errors.Is(err, storage.ErrNotFound) {
+					t.Errorf("Expected an error other than %v", storage.ErrNotFound)
+				}
+			case !errors.Is(err, tt.err):
+				t.Errorf("Expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestS3Store_InvalidKey(t *testing.T) {
+	store, fake := newS3Store(t, storage.S3Config{Bucket: "covers", PathStyle: true})
+	ctx := context.Background()
+
+	for _, key := range []string{"", "../other-bucket/key", "books//1.jpg"} {
+		t.Run(key, func(t *testing.T) {
+			if err := store.Put(ctx, key, []byte("x"), ""); !errors.Is(err, storage.ErrInvalidKey) {
+				t.Errorf("Put: expected %v, got %v", storage.ErrInvalidKey, err)
+			}
+			if _, _, err := store.Get(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+				t.Errorf("Get: expected %v, got %v", storage.ErrInvalidKey, err)
+			}
+			if err := store.Delete(ctx, key); !errors.Is(err, storage.ErrInvalidKey) {
+				t.Errorf("Delete: expected %v, got %v", storage.ErrInvalidKey, err)
+			}
+		})
+	}
+
+	fake.mu.Lock()
+	defer fake.mu.Unlock()
+	if len(fake.paths) != 0 {
+		t.Errorf("Expected no requests, got %v", fake.paths)
+	}
+}
@@ -2,8 +2,12 @@ package main
 
 import (
 	"log"
+	"os"
+	"strings"
 	"user-management-api/internal/database"
 	"user-management-api/internal/handlers"
+	"user-management-api/internal/mailer"
+	"user-management-api/internal/password"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -14,6 +18,39 @@ func main() {
 	// Initialize database
 	database.InitDatabase()
 
+	// Load breached password list used by the password policy
+	breachedFile := os.Getenv("BREACHED_PASSWORDS_FILE")
+	if breachedFile == "" {
+		breachedFile = "data/breached_passwords.txt"
+	}
+	breached, err := password.LoadBreachedList(breachedFile)
+	if err != nil {
+		log.Printf("Breached password list not loaded: %v", err)
+	} else {
+		handlers.PasswordPolicy.Breached = breached
+		log.Printf("Loaded %d breached password hashes", breached.Len())
+	}
+
+	// Send emails through SMTP when configured, otherwise log them
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		handlers.Mailer = mailer.SMTPMailer{
+			Addr:     smtpAddr,
+			From:     os.Getenv("SMTP_FROM"),
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	}
+	if magicLinkURL := os.Getenv("MAGIC_LINK_URL"); magicLinkURL != "" {
+		handlers.MagicLinkURL = magicLinkURL
+	}
+
+	// Clients allowed to call the introspection endpoint, as "id:secret,id:secret"
+	for _, client := range strings.Split(os.Getenv("INTROSPECTION_CLIENTS"), ",") {
+		if id, secret, ok := strings.Cut(strings.TrimSpace(client), ":"); ok && id != "" {
+			handlers.IntrospectionClients[id] = secret
+		}
+	}
+
 	// Create Express.js server with custom configuration
 	app := fiber.New(fiber.Config{
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -30,6 +67,12 @@ func main() {
 	// Public routes
 	app.Post("/register", handlers.RegisterUser)
 	app.Post("/login", handlers.LoginUser)
+	app.Post("/login/magic-link", handlers.RequestMagicLink)
+	app.Get("/login/magic-link/verify", handlers.VerifyMagicLink)
+
+	// Endpoints for reverse proxies and resource servers
+	app.Post("/oauth/introspect", handlers.IntrospectToken)
+	app.Get("/auth/verify", handlers.VerifyAuth)
 
 	// Health check
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -38,11 +81,21 @@ func main() {
 
 	// Protected routes group
 	api := app.Group("/api/v1", handlers.AuthMiddleware)
-	api.Post("/updateUser/:id", handlers.UpdateUser)
+	api.Post("/updateUser/:id", handlers.BlockImpersonation, handlers.UpdateUser)
+	api.Post("/changePassword", handlers.BlockImpersonation, handlers.ChangePassword)
+	api.Post("/impersonation/end", handlers.EndImpersonation)
+	api.Get("/me/sessions", handlers.GetMySessions)
+	api.Delete("/me/sessions", handlers.BlockImpersonation, handlers.RevokeAllMySessions)
+	api.Delete("/me/sessions/:id", handlers.BlockImpersonation, handlers.RevokeMySession)
+	api.Put("/me/magic-link", handlers.BlockImpersonation, handlers.SetMyMagicLink)
 
 	// Admin only routes
 	admin := api.Group("/admin", handlers.AdminMiddleware)
 	admin.Get("/users", handlers.GetUsers)
+	admin.Post("/users/:id/resetPassword", handlers.ResetPassword)
+	admin.Get("/users/:id/sessions", handlers.GetUserSessions)
+	admin.Post("/users/:id/impersonate", handlers.ImpersonateUser)
+	admin.Get("/audit", handlers.GetAuditEvents)
 
 	log.Println("Server starting on :8080...")
 	if err := app.Listen(":8080"); err != nil {
@@ -22,7 +22,7 @@ func InitDatabase() {
 	}
 
 	// Create database tables manually
-	err = DB.AutoMigrate(&models.User{})
+	err = DB.AutoMigrate(&models.User{}, &models.PasswordHistory{}, &models.Session{}, &models.AuditEvent{}, &models.MagicLinkToken{})
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
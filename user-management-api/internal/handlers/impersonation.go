@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"user-management-api/internal/database"
+	"user-management-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// impersonationTTL is how long an impersonation token stays valid
+const impersonationTTL = 15 * time.Minute
+
+const (
+	AuditImpersonationStart = "impersonation.start"
+	AuditImpersonationEnd   = "impersonation.end"
+)
+
+// ImpersonateUser issues a short-lived token that acts as the target user while
+// recording the admin as the actor
+func ImpersonateUser(c *fiber.Ctx) error {
+	adminID, _ := c.Locals("user_id").(uint)
+	adminRole, _ := c.Locals("user_role").(string)
+
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req models.ImpersonateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	req.Reason = strings.TrimSpace(req.Reason)
+	if req.Reason == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Reason is required",
+		})
+	}
+
+	var target models.User
+	if err := database.DB.First(&target, uint(userID)).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if target.ID == adminID || target.Role == "admin" {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Cannot impersonate this user",
+		})
+	}
+
+	var admin models.User
+	database.DB.First(&admin, adminID)
+
+	expiresAt := time.Now().Add(impersonationTTL)
+	session, err := createSession(c, target, "Impersonated by "+impersonatorName(admin, adminID), expiresAt)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create session",
+		})
+	}
+
+	claims := Claims{
+		UserID:    target.ID,
+		Role:      target.Role,
+		SessionID: session.ID,
+		Act: &Actor{
+			UserID: adminID,
+			Role:   adminRole,
+		},
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	tokenString, err := signToken(claims)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+
+	if err := recordAudit(c, AuditImpersonationStart, adminID, target.ID, session.ID, req.Reason); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record audit event",
+		})
+	}
+
+	return c.JSON(models.ImpersonateResponse{
+		Token:     tokenString,
+		ExpiresAt: expiresAt,
+		User:      target,
+	})
+}
+
+// EndImpersonation revokes the impersonation session used to make the request
+func EndImpersonation(c *fiber.Ctx) error {
+	actorID, ok := c.Locals("actor_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Not impersonating",
+		})
+	}
+	userID, _ := c.Locals("user_id").(uint)
+	sessionID, _ := c.Locals("session_id").(uint)
+
+	if _, err := revokeSessions("id = ? AND user_id = ?", sessionID, userID); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke session",
+		})
+	}
+
+	if err := recordAudit(c, AuditImpersonationEnd, actorID, userID, sessionID, ""); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to record audit event",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetAuditEvents returns the audit trail, newest first, optionally filtered by user
+func GetAuditEvents(c *fiber.Ctx) error {
+	if err := endLapsedImpersonations(time.Now()); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit events",
+		})
+	}
+
+	query := database.DB.Order("created_at DESC, id DESC")
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" {
+		userID, err := strconv.ParseUint(userIDStr, 10, 32)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid user ID",
+			})
+		}
+		query = query.Where("actor_id = ? OR target_user_id = ?", userID, userID)
+	}
+
+	events := []models.AuditEvent{}
+	if err := query.Find(&events).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch audit events",
+		})
+	}
+
+	return c.JSON(events)
+}
+
+// BlockImpersonation rejects sensitive actions such as password and MFA
+// changes when the request is made with an impersonation token
+func BlockImpersonation(c *fiber.Ctx) error {
+	if c.Locals("actor_id") != nil {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not allowed while impersonating",
+		})
+	}
+	return c.Next()
+}
+
+// recordAudit appends an event to the audit trail
+func recordAudit(c *fiber.Ctx, action string, actorID, targetUserID, sessionID uint, reason string) error {
+	event := models.AuditEvent{
+		Action:       action,
+		ActorID:      actorID,
+		TargetUserID: targetUserID,
+		SessionID:    sessionID,
+		Reason:       reason,
+		IPAddress:    c.IP(),
+	}
+	return database.DB.Create(&event).Error
+}
+
+// endLapsedImpersonations records the end of impersonation sessions that
+// expired or were revoked without POST /impersonation/end, so every start in
+// the audit trail gets a matching end once its session is over. The end is
+// dated when the session stopped working.
+func endLapsedImpersonations(now time.Time) error {
+	ended := database.DB.Model(&models.AuditEvent{}).
+		Select("session_id").
+		Where("action = ?", AuditImpersonationEnd)
+
+	var starts []models.AuditEvent
+	if err := database.DB.Where("action = ? AND session_id NOT IN (?)", AuditImpersonationStart, ended).
+		Order("id").
+		Find(&starts).Error; err != nil {
+		return err
+	}
+
+	for _, start := range starts {
+		endedAt, reason := start.CreatedAt.Add(impersonationTTL), "expired"
+
+		var session models.Session
+		if err := database.DB.First(&session, start.SessionID).Error; err == nil {
+			if session.Active(now) {
+				continue
+			}
+			endedAt = session.ExpiresAt
+			if session.RevokedAt != nil && session.RevokedAt.Before(endedAt) {
+				endedAt, reason = *session.RevokedAt, "revoked"
+			}
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		} else if now.Before(endedAt) {
+			// The session is gone, so it ended no later than now
+			endedAt, reason = now, "revoked"
+		}
+
+		event := models.AuditEvent{
+			Action:       AuditImpersonationEnd,
+			ActorID:      start.ActorID,
+			TargetUserID: start.TargetUserID,
+			SessionID:    start.SessionID,
+			Reason:       reason,
+			CreatedAt:    endedAt,
+		}
+		if err := database.DB.Create(&event).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func impersonatorName(admin models.User, adminID uint) string {
+	if admin.Username != "" {
+		return admin.Username
+	}
+	return "admin #" + strconv.FormatUint(uint64(adminID), 10)
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+var (
+	// IntrospectionClients maps client IDs to secrets allowed to call POST /oauth/introspect
+	IntrospectionClients = map[string]string{}
+
+	// validationCache remembers recent successful validations for forward-auth and introspection
+	validationCache = newTokenCache(30*time.Second, 10000)
+)
+
+// IntrospectToken implements RFC 7662 token introspection for trusted clients
+func IntrospectToken(c *fiber.Ctx) error {
+	if !introspectionClientAuthorized(c) {
+		c.Set(fiber.HeaderWWWAuthenticate, `Basic realm="introspection"`)
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "invalid_client",
+		})
+	}
+
+	tokenString := c.FormValue("token")
+	if tokenString == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "invalid_request",
+		})
+	}
+
+	claims, err := validateTokenCached(c, tokenString)
+	if err != nil {
+		return c.JSON(fiber.Map{"active": false})
+	}
+
+	response := fiber.Map{
+		"active":     true,
+		"token_type": "Bearer",
+		"sub":        strconv.FormatUint(uint64(claims.UserID), 10),
+		"user_id":    claims.UserID,
+		"role":       claims.Role,
+	}
+	if claims.ExpiresAt != nil {
+		response["exp"] = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		response["iat"] = claims.IssuedAt.Unix()
+	}
+	if claims.SessionID != 0 {
+		response["session_id"] = claims.SessionID
+	}
+	if claims.Act != nil {
+		response["act"] = fiber.Map{
+			"sub":     strconv.FormatUint(uint64(claims.Act.UserID), 10),
+			"user_id": claims.Act.UserID,
+		}
+	}
+
+	return c.JSON(response)
+}
+
+// VerifyAuth is a forward-auth endpoint for reverse proxies such as nginx
+// auth_request or Traefik ForwardAuth. It returns 200 with identity headers
+// for a valid token, 401 for a missing or invalid token, and 403 when the
+// optional role query parameter does not match.
+func VerifyAuth(c *fiber.Ctx) error {
+	tokenString := bearerToken(c)
+	if tokenString == "" {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	claims, err := validateTokenCached(c, tokenString)
+	if err != nil {
+		return c.SendStatus(fiber.StatusUnauthorized)
+	}
+
+	if role := c.Query("role"); role != "" && role != claims.Role {
+		return c.SendStatus(fiber.StatusForbidden)
+	}
+
+	c.Set("X-User-Id", strconv.FormatUint(uint64(claims.UserID), 10))
+	c.Set("X-User-Role", claims.Role)
+	if claims.SessionID != 0 {
+		c.Set("X-Session-Id", strconv.FormatUint(uint64(claims.SessionID), 10))
+	}
+	if claims.Act != nil {
+		c.Set("X-Actor-Id", strconv.FormatUint(uint64(claims.Act.UserID), 10))
+	}
+
+	return c.SendStatus(fiber.StatusOK)
+}
+
+// validateTokenCached is validateToken with a short-lived cache in front of it
+func validateTokenCached(c *fiber.Ctx, tokenString string) (*Claims, error) {
+	if claims, ok := validationCache.Get(tokenString); ok {
+		return claims, nil
+	}
+
+	claims, err := validateToken(c, tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Time{}
+	if claims.ExpiresAt != nil {
+		expiresAt = claims.ExpiresAt.Time
+	}
+	validationCache.Set(tokenString, claims, expiresAt)
+
+	return claims, nil
+}
+
+func introspectionClientAuthorized(c *fiber.Ctx) bool {
+	clientID, secret, ok := basicAuth(c)
+	if !ok {
+		return false
+	}
+
+	expected, exists := IntrospectionClients[clientID]
+	if !exists {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) == 1
+}
+
+// basicAuth reads HTTP Basic credentials from the Authorization header
+func basicAuth(c *fiber.Ctx) (string, string, bool) {
+	authHeader := c.Get(fiber.HeaderAuthorization)
+	if len(authHeader) <= 6 || !strings.EqualFold(authHeader[:6], "Basic ") {
+		return "", "", false
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(authHeader[6:])
+	if err != nil {
+		return "", "", false
+	}
+
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", false
+	}
+
+	return username, password, true
+}
+
+// tokenCache is an in-process cache of validated tokens keyed by their hash
+type tokenCache struct {
+	ttl        time.Duration
+	maxEntries int
+	entries    map[string]cachedClaims
+	mu         sync.Mutex
+}
+
+type cachedClaims struct {
+	claims    *Claims
+	expiresAt time.Time
+}
+
+func newTokenCache(ttl time.Duration, maxEntries int) *tokenCache {
+	return &tokenCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]cachedClaims),
+	}
+}
+
+// Get returns the cached claims for a token if they have not expired
+func (t *tokenCache) Get(token string) (*Claims, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	key := tokenKey(token)
+	entry, ok := t.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(t.entries, key)
+		return nil, false
+	}
+
+	return entry.claims, true
+}
+
+// Set caches claims for the cache TTL, or until tokenExpiry if that is sooner
+func (t *tokenCache) Set(token string, claims *Claims, tokenExpiry time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	expiresAt := now.Add(t.ttl)
+	if !tokenExpiry.IsZero() && tokenExpiry.Before(expiresAt) {
+		expiresAt = tokenExpiry
+	}
+
+	if len(t.entries) >= t.maxEntries {
+		for key, entry := range t.entries {
+			if now.After(entry.expiresAt) {
+				delete(t.entries, key)
+			}
+		}
+		if len(t.entries) >= t.maxEntries {
+			t.entries = make(map[string]cachedClaims)
+		}
+	}
+
+	t.entries[tokenKey(token)] = cachedClaims{claims: claims, expiresAt: expiresAt}
+}
+
+// Clear drops every cached validation, e.g. after sessions are revoked
+func (t *tokenCache) Clear() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.entries = make(map[string]cachedClaims)
+}
+
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
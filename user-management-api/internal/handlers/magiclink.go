@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"user-management-api/internal/database"
+	"user-management-api/internal/mailer"
+	"user-management-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	magicLinkTTL      = 15 * time.Minute
+	magicLinkAudience = "magic-link"
+)
+
+// Magic link tokens use their own key so they can never be accepted by AuthMiddleware
+var magicLinkSecret = append([]byte(magicLinkAudience+":"), jwtSecret...)
+
+var (
+	// Mailer delivers magic link emails
+	Mailer mailer.Mailer = mailer.LogMailer{}
+
+	// MagicLinkURL is the verify endpoint that emailed links point at
+	MagicLinkURL = "http://localhost:8080/login/magic-link/verify"
+
+	// magicLinkLimiter allows a few link requests per email address in each window
+	magicLinkLimiter = newRateLimiter(3, 15*time.Minute)
+)
+
+// RequestMagicLink emails a single-use login link to an opted-in account. The
+// response is the same whether or not the account exists.
+func RequestMagicLink(c *fiber.Ctx) error {
+	var req models.MagicLinkRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if email == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Email is required",
+		})
+	}
+
+	if !magicLinkLimiter.Allow(email) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": "Too many login link requests, try again later",
+		})
+	}
+
+	var user models.User
+	if err := database.DB.Where("LOWER(email) = ?", email).First(&user).Error; err != nil {
+		return magicLinkAccepted(c)
+	}
+	if !user.IsActive || !user.MagicLinkEnabled {
+		return magicLinkAccepted(c)
+	}
+
+	link, err := createMagicLink(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create login link",
+		})
+	}
+
+	body := fmt.Sprintf("Use this link to sign in. It expires in %d minutes and can only be used once.\n\n%s",
+		int(magicLinkTTL.Minutes()), link)
+	if err := Mailer.Send(user.Email, "Your login link", body); err != nil {
+		log.Printf("Failed to send magic link email: %v", err)
+	}
+
+	return magicLinkAccepted(c)
+}
+
+// VerifyMagicLink exchanges a valid login link for the same response as LoginUser
+func VerifyMagicLink(c *fiber.Ctx) error {
+	tokenString := c.Query("token")
+	if tokenString == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Token is required",
+		})
+	}
+
+	claims := &jwt.RegisteredClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return magicLinkSecret, nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}), jwt.WithAudience(magicLinkAudience))
+	if err != nil || !token.Valid {
+		return invalidMagicLink(c)
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 32)
+	if err != nil {
+		return invalidMagicLink(c)
+	}
+
+	// Mark the link used; only one request can win the update
+	result := database.DB.Model(&models.MagicLinkToken{}).
+		Where("id = ? AND user_id = ? AND used_at IS NULL AND expires_at > ?", claims.ID, uint(userID), time.Now()).
+		Update("used_at", time.Now())
+	if result.Error != nil || result.RowsAffected != 1 {
+		return invalidMagicLink(c)
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, uint(userID)).Error; err != nil {
+		return invalidMagicLink(c)
+	}
+	if !user.IsActive || !user.MagicLinkEnabled {
+		return invalidMagicLink(c)
+	}
+
+	return completeLogin(c, user, c.Query("device"))
+}
+
+// SetMyMagicLink lets the current user opt in to or out of magic-link login.
+// Only the account owner can change it, so it is not part of UpdateUser.
+func SetMyMagicLink(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+
+	var req models.MagicLinkSettingsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.Enabled == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Enabled is required",
+		})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	user.MagicLinkEnabled = *req.Enabled
+	if err := database.DB.Model(&user).Update("magic_link_enabled", user.MagicLinkEnabled).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update login options",
+		})
+	}
+
+	return c.JSON(user)
+}
+
+// createMagicLink stores a new single-use token and returns the signed link
+func createMagicLink(user models.User) (string, error) {
+	idBytes := make([]byte, 16)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	record := models.MagicLinkToken{
+		ID:        hex.EncodeToString(idBytes),
+		UserID:    user.ID,
+		ExpiresAt: now.Add(magicLinkTTL),
+	}
+	if err := database.DB.Create(&record).Error; err != nil {
+		return "", err
+	}
+
+	claims := jwt.RegisteredClaims{
+		ID:        record.ID,
+		Subject:   strconv.FormatUint(uint64(user.ID), 10),
+		Audience:  jwt.ClaimStrings{magicLinkAudience},
+		ExpiresAt: jwt.NewNumericDate(record.ExpiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tokenString, err := token.SignedString(magicLinkSecret)
+	if err != nil {
+		return "", err
+	}
+
+	return MagicLinkURL + "?token=" + url.QueryEscape(tokenString), nil
+}
+
+func magicLinkAccepted(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"message": "If the account exists and allows it, a login link has been sent",
+	})
+}
+
+func invalidMagicLink(c *fiber.Ctx) error {
+	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+		"error": "Invalid or expired login link",
+	})
+}
+
+// rateLimiter is a sliding window limiter keyed by an arbitrary string. Keys
+// with no hits left in the window are swept out once per window, so the map
+// only holds recent keys.
+type rateLimiter struct {
+	limit     int
+	window    time.Duration
+	hits      map[string][]time.Time
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+func newRateLimiter(limit int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		limit:  limit,
+		window: window,
+		hits:   make(map[string][]time.Time),
+	}
+}
+
+// Allow records a hit for key and reports whether it is within the limit
+func (r *rateLimiter) Allow(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	cutoff := now.Add(-r.window)
+	if now.Sub(r.lastSweep) >= r.window {
+		r.sweep(cutoff)
+		r.lastSweep = now
+	}
+
+	recent := r.hits[key][:0]
+	for _, hit := range r.hits[key] {
+		if hit.After(cutoff) {
+			recent = append(recent, hit)
+		}
+	}
+
+	if len(recent) >= r.limit {
+		r.hits[key] = recent
+		return false
+	}
+
+	r.hits[key] = append(recent, now)
+	return true
+}
+
+// sweep deletes the keys whose hits are all older than cutoff. The caller
+// must hold r.mu.
+func (r *rateLimiter) sweep(cutoff time.Time) {
+	for key, hits := range r.hits {
+		if len(hits) == 0 || !hits[len(hits)-1].After(cutoff) {
+			delete(r.hits, key)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+	"user-management-api/internal/database"
+	"user-management-api/internal/models"
+	"user-management-api/internal/password"
+
+	"github.com/gofiber/fiber/v2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// PasswordPolicy is applied on registration, password change and password reset
+var PasswordPolicy = password.DefaultPolicy()
+
+// ChangePassword lets an authenticated user replace their own password
+func ChangePassword(c *fiber.Ctx) error {
+	userID, ok := c.Locals("user_id").(uint)
+	if !ok {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.CurrentPassword)); err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid credentials",
+		})
+	}
+
+	if err := setPassword(&user, req.NewPassword); err != nil {
+		return passwordError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"message": "Password updated"})
+}
+
+// ResetPassword lets an admin set a new password for any user
+func ResetPassword(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req models.ResetPasswordRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, uint(userID)).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if err := setPassword(&user, req.NewPassword); err != nil {
+		return passwordError(c, err)
+	}
+
+	return c.JSON(fiber.Map{"message": "Password reset"})
+}
+
+// setPassword validates the new password against the policy and the user's
+// password history, then stores its hash and records it in the history
+func setPassword(user *models.User, newPassword string) error {
+	if err := PasswordPolicy.Validate(newPassword); err != nil {
+		return err
+	}
+
+	reused, err := passwordReused(user, newPassword)
+	if err != nil {
+		return err
+	}
+	if reused {
+		return password.ErrReused
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	user.Password = string(hashedPassword)
+	if err := database.DB.Save(user).Error; err != nil {
+		return err
+	}
+
+	return recordPasswordHistory(user.ID, user.Password)
+}
+
+// passwordReused reports whether newPassword matches the current password or
+// one of the last PasswordPolicy.HistorySize passwords
+func passwordReused(user *models.User, newPassword string) (bool, error) {
+	if user.Password != "" && bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(newPassword)) == nil {
+		return true, nil
+	}
+
+	if PasswordPolicy.HistorySize <= 0 {
+		return false, nil
+	}
+
+	var history []models.PasswordHistory
+	if err := database.DB.Where("user_id = ?", user.ID).
+		Order("created_at DESC, id DESC").
+		Limit(PasswordPolicy.HistorySize).
+		Find(&history).Error; err != nil {
+		return false, err
+	}
+
+	for _, entry := range history {
+		if bcrypt.CompareHashAndPassword([]byte(entry.Password), []byte(newPassword)) == nil {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// recordPasswordHistory stores a password hash and prunes entries beyond the
+// configured history size
+func recordPasswordHistory(userID uint, hash string) error {
+	entry := models.PasswordHistory{
+		UserID:   userID,
+		Password: hash,
+	}
+	if err := database.DB.Create(&entry).Error; err != nil {
+		return err
+	}
+
+	if PasswordPolicy.HistorySize <= 0 {
+		return nil
+	}
+
+	var keep []uint
+	if err := database.DB.Model(&models.PasswordHistory{}).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(PasswordPolicy.HistorySize).
+		Pluck("id", &keep).Error; err != nil {
+		return err
+	}
+
+	return database.DB.Where("user_id = ? AND id NOT IN ?", userID, keep).
+		Delete(&models.PasswordHistory{}).Error
+}
+
+// passwordError converts a password policy violation into a 400 response and
+// anything else into a 500
+func passwordError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, password.ErrTooShort),
+		errors.Is(err, password.ErrTooWeak),
+		errors.Is(err, password.ErrBreached),
+		errors.Is(err, password.ErrReused):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Password rejected: " + err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update password",
+		})
+	}
+}
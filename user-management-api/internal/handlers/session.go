@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"user-management-api/internal/database"
+	"user-management-api/internal/models"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sessionTouchInterval limits how often AuthMiddleware writes last-seen updates
+const sessionTouchInterval = time.Minute
+
+// createSession records a new login for the user from the current request
+func createSession(c *fiber.Ctx, user models.User, device string, expiresAt time.Time) (models.Session, error) {
+	userAgent := c.Get(fiber.HeaderUserAgent)
+	if device == "" {
+		device = describeDevice(userAgent)
+	}
+
+	now := time.Now()
+	session := models.Session{
+		UserID:     user.ID,
+		Device:     device,
+		IPAddress:  c.IP(),
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  expiresAt,
+	}
+
+	err := database.DB.Create(&session).Error
+	return session, err
+}
+
+// touchSession checks that the session is still active and updates its last-seen time
+func touchSession(c *fiber.Ctx, sessionID, userID uint) bool {
+	var session models.Session
+	if err := database.DB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		return false
+	}
+
+	now := time.Now()
+	if !session.Active(now) {
+		return false
+	}
+
+	if now.Sub(session.LastSeenAt) >= sessionTouchInterval || session.IPAddress != c.IP() {
+		database.DB.Model(&session).Updates(map[string]interface{}{
+			"last_seen_at": now,
+			"ip_address":   c.IP(),
+		})
+	}
+
+	return true
+}
+
+// activeSessions returns the user's sessions that have not expired or been revoked
+func activeSessions(userID uint) ([]models.Session, error) {
+	sessions := []models.Session{}
+	err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// revokeSessions marks matching active sessions as revoked
+func revokeSessions(query interface{}, args ...interface{}) (int64, error) {
+	result := database.DB.Model(&models.Session{}).
+		Where(query, args...).
+		Where("revoked_at IS NULL").
+		Update("revoked_at", time.Now())
+
+	// Cached validations may belong to the revoked sessions
+	if result.RowsAffected > 0 {
+		validationCache.Clear()
+	}
+
+	return result.RowsAffected, result.Error
+}
+
+// GetMySessions lists where the current user is signed in
+func GetMySessions(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+	currentID, _ := c.Locals("session_id").(uint)
+
+	sessions, err := activeSessions(userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch sessions",
+		})
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return c.JSON(sessions)
+}
+
+// RevokeMySession signs the current user out of one of their sessions
+func RevokeMySession(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+
+	sessionID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid session ID",
+		})
+	}
+
+	revoked, err := revokeSessions("id = ? AND user_id = ?", uint(sessionID), userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke session",
+		})
+	}
+	if revoked == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RevokeAllMySessions signs the current user out everywhere, including this session
+func RevokeAllMySessions(c *fiber.Ctx) error {
+	userID, _ := c.Locals("user_id").(uint)
+
+	revoked, err := revokeSessions("user_id = ?", userID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{"revoked": revoked})
+}
+
+// GetUserSessions lets an admin see where any user is signed in
+func GetUserSessions(c *fiber.Ctx) error {
+	userID, err := strconv.ParseUint(c.Params("id"), 10, 32)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, uint(userID)).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	sessions, err := activeSessions(user.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch sessions",
+		})
+	}
+
+	return c.JSON(sessions)
+}
+
+// describeDevice builds a short human readable label such as "Chrome on Windows"
+// from a User-Agent header
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(userAgent, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(userAgent, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(userAgent, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(userAgent, "Safari/"):
+		browser = "Safari"
+	case strings.HasPrefix(userAgent, "curl/"):
+		browser = "curl"
+	}
+
+	platform := ""
+	switch {
+	case strings.Contains(userAgent, "Windows"):
+		platform = "Windows"
+	case strings.Contains(userAgent, "iPhone"), strings.Contains(userAgent, "iPad"):
+		platform = "iOS"
+	case strings.Contains(userAgent, "Android"):
+		platform = "Android"
+	case strings.Contains(userAgent, "Mac OS X"):
+		platform = "macOS"
+	case strings.Contains(userAgent, "Linux"):
+		platform = "Linux"
+	}
+
+	if platform == "" {
+		return browser
+	}
+	return browser + " on " + platform
+}
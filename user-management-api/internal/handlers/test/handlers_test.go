@@ -2,15 +2,19 @@ package handlers_test
 
 import (
 	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 	"user-management-api/internal/database"
 	"user-management-api/internal/handlers"
 	"user-management-api/internal/models"
+	"user-management-api/internal/password"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
@@ -27,7 +31,7 @@ func setupTestApp() (*fiber.App, *gorm.DB) {
 	}
 
 	// Auto-migrate the schema
-	err = db.AutoMigrate(&models.User{})
+	err = db.AutoMigrate(&models.User{}, &models.PasswordHistory{}, &models.Session{}, &models.AuditEvent{}, &models.MagicLinkToken{})
 	if err != nil {
 		panic(err)
 	}
@@ -35,17 +39,40 @@ func setupTestApp() (*fiber.App, *gorm.DB) {
 	// Set the global database
 	database.DB = db
 
+	// Use a breached password list containing password123
+	sum := sha1.Sum([]byte("password123"))
+	breached, err := password.NewBreachedList(strings.NewReader(hex.EncodeToString(sum[:])))
+	if err != nil {
+		panic(err)
+	}
+	handlers.PasswordPolicy = password.DefaultPolicy()
+	handlers.PasswordPolicy.Breached = breached
+
 	// Setup Fiber app
 	app := fiber.New()
 
 	// Setup routes
 	app.Post("/register", handlers.RegisterUser)
 	app.Post("/login", handlers.LoginUser)
+	app.Post("/login/magic-link", handlers.RequestMagicLink)
+	app.Get("/login/magic-link/verify", handlers.VerifyMagicLink)
+	app.Post("/oauth/introspect", handlers.IntrospectToken)
+	app.Get("/auth/verify", handlers.VerifyAuth)
 
 	api := app.Group("/api/v1", handlers.AuthMiddleware)
 	admin := api.Group("/admin", handlers.AdminMiddleware)
 	admin.Get("/users", handlers.GetUsers)
-	api.Post("/updateUser/:id", handlers.UpdateUser)
+	admin.Post("/users/:id/resetPassword", handlers.ResetPassword)
+	admin.Get("/users/:id/sessions", handlers.GetUserSessions)
+	admin.Post("/users/:id/impersonate", handlers.ImpersonateUser)
+	admin.Get("/audit", handlers.GetAuditEvents)
+	api.Post("/updateUser/:id", handlers.BlockImpersonation, handlers.UpdateUser)
+	api.Post("/changePassword", handlers.BlockImpersonation, handlers.ChangePassword)
+	api.Post("/impersonation/end", handlers.EndImpersonation)
+	api.Get("/me/sessions", handlers.GetMySessions)
+	api.Delete("/me/sessions", handlers.BlockImpersonation, handlers.RevokeAllMySessions)
+	api.Delete("/me/sessions/:id", handlers.BlockImpersonation, handlers.RevokeMySession)
+	api.Put("/me/magic-link", handlers.BlockImpersonation, handlers.SetMyMagicLink)
 
 	return app, db
 }
@@ -57,7 +84,7 @@ func TestRegisterUser_Success(t *testing.T) {
 	reqBody := models.CreateUserRequest{
 		Username: "testuser",
 		Email:    "test@example.com",
-		Password: "password123",
+		Password: "c0rrect-h0rse-battery",
 	}
 
 	body, _ := json.Marshal(reqBody)
@@ -157,7 +184,7 @@ func TestLoginUser_InactiveUserCanLogin(t *testing.T) {
 	reqBody := models.CreateUserRequest{
 		Username: "inactiveuser",
 		Email:    "inactive@example.com",
-		Password: "password123",
+		Password: "c0rrect-h0rse-battery",
 	}
 
 	body, _ := json.Marshal(reqBody)
@@ -181,7 +208,7 @@ func TestLoginUser_InactiveUserCanLogin(t *testing.T) {
 	// Try to login with inactive user
 	loginReq := models.LoginRequest{
 		Username: "inactiveuser",
-		Password: "password123",
+		Password: "c0rrect-h0rse-battery",
 	}
 
 	loginBody, _ := json.Marshal(loginReq)
@@ -406,7 +433,695 @@ func TestAuthMiddleware_MissingToken(t *testing.T) {
 	}
 }
 
+func TestRegisterUser_BreachedPassword(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+
+	reqBody := models.CreateUserRequest{
+		Username: "breached",
+		Email:    "breached@example.com",
+		Password: "password123",
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.StatusCode != fiber.StatusBadRequest {
+		t.Errorf("Expected status %d for breached password, got %d", fiber.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestChangePassword_RejectsReuse(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM password_histories")
+
+	reqBody := models.CreateUserRequest{
+		Username: "changer",
+		Email:    "changer@example.com",
+		Password: "c0rrect-h0rse-battery",
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatal("Failed to create test user")
+	}
+
+	var user models.User
+	db.Where("username = ?", "changer").First(&user)
+	token := createValidUserTokenForUser(user.ID)
+
+	changePassword := func(current, next string) int {
+		body, _ := json.Marshal(models.ChangePasswordRequest{
+			CurrentPassword: current,
+			NewPassword:     next,
+		})
+		req := httptest.NewRequest("POST", "/api/v1/changePassword", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	if status := changePassword("c0rrect-h0rse-battery", "Tr0ub4dor&3-stapl3"); status != fiber.StatusOK {
+		t.Fatalf("Expected status %d for password change, got %d", fiber.StatusOK, status)
+	}
+
+	if status := changePassword("Tr0ub4dor&3-stapl3", "c0rrect-h0rse-battery"); status != fiber.StatusBadRequest {
+		t.Errorf("Expected status %d for reused password, got %d", fiber.StatusBadRequest, status)
+	}
+
+	if status := changePassword("wrong-current-password", "n3w-unique-passphrase"); status != fiber.StatusUnauthorized {
+		t.Errorf("Expected status %d for wrong current password, got %d", fiber.StatusUnauthorized, status)
+	}
+}
+
+func TestSessions_ListAndRevoke(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM sessions")
+
+	laptopToken := registerAndLogin(t, app, "sessionuser", "laptop")
+	phoneToken := login(t, app, "sessionuser", "phone")
+
+	req := httptest.NewRequest("GET", "/api/v1/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+laptopToken)
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	var sessions []models.Session
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &sessions); err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("Expected 2 sessions, got %d", len(sessions))
+	}
+
+	var phoneSession models.Session
+	for _, session := range sessions {
+		if session.Device == "laptop" && !session.Current {
+			t.Error("Expected laptop session to be marked current")
+		}
+		if session.Device == "phone" {
+			phoneSession = session
+		}
+	}
+
+	// Revoke the phone session from the laptop
+	req = httptest.NewRequest("DELETE", fmt.Sprintf("/api/v1/me/sessions/%d", phoneSession.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+laptopToken)
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusNoContent, resp.StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+phoneToken)
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status %d for revoked session, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+
+	// Sign out everywhere
+	req = httptest.NewRequest("DELETE", "/api/v1/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+laptopToken)
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", "/api/v1/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+laptopToken)
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status %d after signing out everywhere, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestImpersonateUser_ScopedAndAudited(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM sessions")
+	defer db.Exec("DELETE FROM audit_events")
+
+	// The admin token helper acts as user 1
+	adminUser := models.User{
+		Username: "support",
+		Email:    "support@example.com",
+		Password: "hashedpassword",
+		Role:     "admin",
+		IsActive: true,
+	}
+	db.Create(&adminUser)
+
+	registerAndLogin(t, app, "target", "laptop")
+
+	var target models.User
+	db.Where("username = ?", "target").First(&target)
+
+	adminToken := createValidAdminToken()
+
+	body, _ := json.Marshal(models.ImpersonateRequest{Reason: "Ticket 42"})
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/admin/users/%d/impersonate", target.ID), bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	var impersonation models.ImpersonateResponse
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &impersonation); err != nil {
+		t.Fatal(err)
+	}
+
+	claims := &handlers.Claims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(impersonation.Token, claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims.UserID != target.ID {
+		t.Errorf("Expected token for user %d, got %d", target.ID, claims.UserID)
+	}
+	if claims.Act == nil || claims.Act.UserID != 1 {
+		t.Error("Expected act claim to identify the admin")
+	}
+
+	// Password changes are blocked while impersonating
+	body, _ = json.Marshal(models.ChangePasswordRequest{
+		CurrentPassword: "c0rrect-h0rse-battery",
+		NewPassword:     "Tr0ub4dor&3-stapl3",
+	})
+	req = httptest.NewRequest("POST", "/api/v1/changePassword", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+impersonation.Token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status %d for password change while impersonating, got %d", fiber.StatusForbidden, resp.StatusCode)
+	}
+
+	// So are account changes and signing the user out
+	blocked := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"POST", fmt.Sprintf("/api/v1/updateUser/%d", target.ID), `{"email":"attacker@example.com"}`},
+		{"DELETE", "/api/v1/me/sessions", ""},
+		{"DELETE", "/api/v1/me/sessions/1", ""},
+	}
+	for _, tt := range blocked {
+		req = httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+		req.Header.Set("Authorization", "Bearer "+impersonation.Token)
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err = app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusForbidden {
+			t.Errorf("Expected status %d for %s %s while impersonating, got %d", fiber.StatusForbidden, tt.method, tt.path, resp.StatusCode)
+		}
+	}
+
+	db.First(&target, target.ID)
+	if target.Email != "target@example.com" {
+		t.Errorf("Expected email to be unchanged, got %s", target.Email)
+	}
+
+	// End impersonation, after which the token no longer works
+	req = httptest.NewRequest("POST", "/api/v1/impersonation/end", nil)
+	req.Header.Set("Authorization", "Bearer "+impersonation.Token)
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNoContent {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusNoContent, resp.StatusCode)
+	}
+
+	req = httptest.NewRequest("POST", "/api/v1/impersonation/end", nil)
+	req.Header.Set("Authorization", "Bearer "+impersonation.Token)
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status %d after ending impersonation, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+
+	var events []models.AuditEvent
+	db.Where("target_user_id = ?", target.ID).Order("id").Find(&events)
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 audit events, got %d", len(events))
+	}
+	if events[0].Action != handlers.AuditImpersonationStart || events[0].Reason != "Ticket 42" {
+		t.Errorf("Unexpected start event: %+v", events[0])
+	}
+	if events[1].Action != handlers.AuditImpersonationEnd {
+		t.Errorf("Unexpected end event: %+v", events[1])
+	}
+}
+
+func TestImpersonateUser_CannotImpersonateAdmin(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+
+	otherAdmin := models.User{
+		Username: "otheradmin",
+		Email:    "otheradmin@example.com",
+		Password: "hashedpassword",
+		Role:     "admin",
+		IsActive: true,
+	}
+	db.Create(&otherAdmin)
+
+	body, _ := json.Marshal(models.ImpersonateRequest{Reason: "Curiosity"})
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/admin/users/%d/impersonate", otherAdmin.ID), bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+createValidAdminToken())
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status %d, got %d", fiber.StatusForbidden, resp.StatusCode)
+	}
+}
+
+func TestImpersonateUser_LapsedSessionsAreEnded(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM sessions")
+	defer db.Exec("DELETE FROM audit_events")
+
+	adminUser := models.User{Username: "support", Email: "support@example.com", Password: "hashedpassword", Role: "admin", IsActive: true}
+	db.Create(&adminUser)
+	registerAndLogin(t, app, "expires", "laptop")
+	registerAndLogin(t, app, "revoked", "laptop")
+	registerAndLogin(t, app, "active", "laptop")
+
+	expiredAt := time.Now().Add(-time.Hour).Truncate(time.Second)
+	revokedAt := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		username string
+		lapse    func(session *models.Session)
+		reason   string // Of the end event, or "" if there shouldn't be one
+		endedAt  time.Time
+	}{
+		{"expires", func(session *models.Session) { session.ExpiresAt = expiredAt }, "expired", expiredAt},
+		{"revoked", func(session *models.Session) { session.RevokedAt = &revokedAt }, "revoked", revokedAt},
+		{"active", func(session *models.Session) {}, "", time.Time{}},
+	}
+
+	sessions := make(map[string]uint)
+	for _, tt := range tests {
+		var target models.User
+		db.Where("username = ?", tt.username).First(&target)
+
+		body, _ := json.Marshal(models.ImpersonateRequest{Reason: "Ticket 42"})
+		req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/admin/users/%d/impersonate", target.ID), bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+createValidAdminToken())
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status %d, got %d", fiber.StatusOK, resp.StatusCode)
+		}
+
+		var start models.AuditEvent
+		db.Where("action = ? AND target_user_id = ?", handlers.AuditImpersonationStart, target.ID).First(&start)
+		var session models.Session
+		db.First(&session, start.SessionID)
+		tt.lapse(&session)
+		db.Save(&session)
+		sessions[tt.username] = session.ID
+	}
+
+	// Reading the audit trail ends the lapsed sessions, once
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest("GET", "/api/v1/admin/audit", nil)
+		req.Header.Set("Authorization", "Bearer "+createValidAdminToken())
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != fiber.StatusOK {
+			t.Fatalf("Expected status %d, got %d", fiber.StatusOK, resp.StatusCode)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.username, func(t *testing.T) {
+			var ends []models.AuditEvent
+			db.Where("action = ? AND session_id = ?", handlers.AuditImpersonationEnd, sessions[tt.username]).Find(&ends)
+
+			if tt.reason == "" {
+				if len(ends) != 0 {
+					t.Errorf("Expected no end event for an active session, got %+v", ends)
+				}
+				return
+			}
+			if len(ends) != 1 {
+				t.Fatalf("Expected 1 end event, got %d", len(ends))
+			}
+			if ends[0].Reason != tt.reason || !ends[0].CreatedAt.Equal(tt.endedAt) || ends[0].ActorID != 1 {
+				t.Errorf("Expected end by admin 1 for %s at %v, got %+v", tt.reason, tt.endedAt, ends[0])
+			}
+		})
+	}
+}
+
+func TestMagicLink_LoginOnce(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM magic_link_tokens")
+
+	outbox := &capturingMailer{}
+	handlers.Mailer = outbox
+
+	token := registerAndLogin(t, app, "magicuser", "laptop")
+
+	var user models.User
+	db.Where("username = ?", "magicuser").First(&user)
+
+	// Another account's update can't opt this one in
+	req := httptest.NewRequest("POST", fmt.Sprintf("/api/v1/updateUser/%d", user.ID), strings.NewReader(`{"magic_link_enabled":true}`))
+	req.Header.Set("Authorization", "Bearer "+createValidUserTokenForUser(user.ID+1))
+	req.Header.Set("Content-Type", "application/json")
+	if _, err := app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+	db.First(&user, user.ID)
+	if user.MagicLinkEnabled {
+		t.Fatal("Expected updateUser not to enable magic-link login")
+	}
+
+	req = httptest.NewRequest("PUT", "/api/v1/me/magic-link", strings.NewReader(`{"enabled":true}`))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	body, _ := json.Marshal(models.MagicLinkRequest{Email: "magicuser@example.com"})
+	req = httptest.NewRequest("POST", "/login/magic-link", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusAccepted {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusAccepted, resp.StatusCode)
+	}
+	if len(outbox.sent) != 1 {
+		t.Fatalf("Expected 1 email, got %d", len(outbox.sent))
+	}
+
+	start := strings.Index(outbox.sent[0], "/login/magic-link/verify?token=")
+	if start < 0 {
+		t.Fatalf("Email does not contain a login link: %s", outbox.sent[0])
+	}
+	link := strings.TrimSpace(outbox.sent[0][start:])
+
+	resp, err = app.Test(httptest.NewRequest("GET", link, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+
+	var loginResp models.LoginResponse
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &loginResp); err != nil {
+		t.Fatal(err)
+	}
+	if loginResp.Token == "" || loginResp.User.Username != "magicuser" {
+		t.Errorf("Unexpected login response: %+v", loginResp)
+	}
+
+	// Links are single use
+	resp, err = app.Test(httptest.NewRequest("GET", link, nil), -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status %d for reused link, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestMagicLink_RequiresOptInAndRateLimits(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+
+	outbox := &capturingMailer{}
+	handlers.Mailer = outbox
+
+	registerAndLogin(t, app, "optedout", "laptop")
+
+	statuses := []int{}
+	for i := 0; i < 4; i++ {
+		body, _ := json.Marshal(models.MagicLinkRequest{Email: "optedout@example.com"})
+		req := httptest.NewRequest("POST", "/login/magic-link", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		statuses = append(statuses, resp.StatusCode)
+	}
+
+	if len(outbox.sent) != 0 {
+		t.Errorf("Expected no email for an account that has not opted in, got %d", len(outbox.sent))
+	}
+	if statuses[0] != fiber.StatusAccepted {
+		t.Errorf("Expected status %d, got %d", fiber.StatusAccepted, statuses[0])
+	}
+	if statuses[3] != fiber.StatusTooManyRequests {
+		t.Errorf("Expected status %d after too many requests, got %d", fiber.StatusTooManyRequests, statuses[3])
+	}
+}
+
+func TestVerifyAuth_ForwardAuthHeaders(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM sessions")
+
+	token := registerAndLogin(t, app, "proxied", "laptop")
+
+	req := httptest.NewRequest("GET", "/auth/verify", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Expected status %d, got %d", fiber.StatusOK, resp.StatusCode)
+	}
+	if resp.Header.Get("X-User-Id") == "" || resp.Header.Get("X-User-Role") != "user" {
+		t.Errorf("Unexpected identity headers: %v", resp.Header)
+	}
+
+	req = httptest.NewRequest("GET", "/auth/verify?role=admin", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusForbidden {
+		t.Errorf("Expected status %d for wrong role, got %d", fiber.StatusForbidden, resp.StatusCode)
+	}
+
+	req = httptest.NewRequest("GET", "/auth/verify", nil)
+	req.Header.Set("Authorization", "Bearer invalid.token.here")
+
+	resp, err = app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusUnauthorized {
+		t.Errorf("Expected status %d for invalid token, got %d", fiber.StatusUnauthorized, resp.StatusCode)
+	}
+}
+
+func TestIntrospectToken(t *testing.T) {
+	app, db := setupTestApp()
+	defer db.Exec("DELETE FROM users")
+	defer db.Exec("DELETE FROM sessions")
+
+	handlers.IntrospectionClients["edge"] = "edge-secret"
+	defer delete(handlers.IntrospectionClients, "edge")
+
+	token := registerAndLogin(t, app, "introspected", "laptop")
+
+	introspect := func(clientSecret, token string) (int, map[string]interface{}) {
+		req := httptest.NewRequest("POST", "/oauth/introspect", strings.NewReader("token="+token))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("edge", clientSecret)
+
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var result map[string]interface{}
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		json.Unmarshal(bodyBytes, &result)
+		return resp.StatusCode, result
+	}
+
+	status, result := introspect("wrong-secret", token)
+	if status != fiber.StatusUnauthorized {
+		t.Errorf("Expected status %d for bad client credentials, got %d", fiber.StatusUnauthorized, status)
+	}
+
+	status, result = introspect("edge-secret", token)
+	if status != fiber.StatusOK || result["active"] != true || result["role"] != "user" {
+		t.Errorf("Expected active token, got %d %v", status, result)
+	}
+
+	// Revoking the session makes the token inactive even though it was cached
+	req := httptest.NewRequest("DELETE", "/api/v1/me/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	if _, err := app.Test(req, -1); err != nil {
+		t.Fatal(err)
+	}
+
+	status, result = introspect("edge-secret", token)
+	if status != fiber.StatusOK || result["active"] != false {
+		t.Errorf("Expected inactive token after revocation, got %d %v", status, result)
+	}
+}
+
 // Helper functions
+type capturingMailer struct {
+	sent []string
+}
+
+func (m *capturingMailer) Send(to, subject, body string) error {
+	m.sent = append(m.sent, body)
+	return nil
+}
+
+func registerAndLogin(t *testing.T, app *fiber.App, username, device string) string {
+	t.Helper()
+
+	reqBody := models.CreateUserRequest{
+		Username: username,
+		Email:    username + "@example.com",
+		Password: "c0rrect-h0rse-battery",
+	}
+
+	body, _ := json.Marshal(reqBody)
+	req := httptest.NewRequest("POST", "/register", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusCreated {
+		t.Fatal("Failed to create test user")
+	}
+
+	return login(t, app, username, device)
+}
+
+func login(t *testing.T, app *fiber.App, username, device string) string {
+	t.Helper()
+
+	loginReq := models.LoginRequest{
+		Username: username,
+		Password: "c0rrect-h0rse-battery",
+		Device:   device,
+	}
+
+	body, _ := json.Marshal(loginReq)
+	req := httptest.NewRequest("POST", "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusOK {
+		t.Fatalf("Login failed with status %d", resp.StatusCode)
+	}
+
+	var loginResp models.LoginResponse
+	bodyBytes, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(bodyBytes, &loginResp); err != nil {
+		t.Fatal(err)
+	}
+
+	return loginResp.Token
+}
+
 func createValidAdminToken() string {
 	claims := &handlers.Claims{
 		UserID: 1,
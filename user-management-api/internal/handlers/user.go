@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"strconv"
 	"time"
 	"user-management-api/internal/database"
@@ -14,9 +15,23 @@ import (
 var jwtSecret = []byte("asd4323eghk!FL'")
 
 type Claims struct {
+	UserID    uint   `json:"user_id"`
+	Role      string `json:"role"`
+	SessionID uint   `json:"session_id,omitempty"`
+	Act       *Actor `json:"act,omitempty"` // Set when an admin is impersonating UserID
+	jwt.RegisteredClaims
+}
+
+// Actor identifies the user really making requests with an impersonation token
+type Actor struct {
 	UserID uint   `json:"user_id"`
 	Role   string `json:"role"`
-	jwt.RegisteredClaims
+}
+
+// signToken signs the claims with the server secret
+func signToken(claims Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret)
 }
 
 // RegisterUser creates a new user account
@@ -36,6 +51,10 @@ func RegisterUser(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := PasswordPolicy.Validate(req.Password); err != nil {
+		return passwordError(c, err)
+	}
+
 	// Encrypt password using MD5
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -63,6 +82,12 @@ func RegisterUser(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := recordPasswordHistory(user.ID, user.Password); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create user",
+		})
+	}
+
 	return c.Status(fiber.StatusCreated).JSON(user)
 }
 
@@ -89,18 +114,33 @@ func LoginUser(c *fiber.Ctx) error {
 		})
 	}
 
+	return completeLogin(c, user, req.Device)
+}
+
+// completeLogin starts a session for an authenticated user and responds with a signed token
+func completeLogin(c *fiber.Ctx, user models.User, device string) error {
+	expiresAt := time.Now().Add(24 * time.Hour)
+
+	// Record the session so the user can see and revoke it later
+	session, err := createSession(c, user, device, expiresAt)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create session",
+		})
+	}
+
 	// Generate session cookie that expires in 1 hour
 	claims := Claims{
-		UserID: user.ID,
-		Role:   user.Role,
+		UserID:    user.ID,
+		Role:      user.Role,
+		SessionID: session.ID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	tokenString, err := token.SignedString(jwtSecret)
+	tokenString, err := signToken(claims)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
@@ -176,35 +216,72 @@ func UpdateUser(c *fiber.Ctx) error {
 
 // AuthMiddleware validates JWT tokens
 func AuthMiddleware(c *fiber.Ctx) error {
-	authHeader := c.Get("Authorization")
-	if authHeader == "" {
+	tokenString := bearerToken(c)
+	if tokenString == "" {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Authorization header required",
 		})
 	}
 
+	claims, err := validateToken(c, tokenString)
+	if errors.Is(err, errSessionRevoked) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Session has been revoked or expired",
+		})
+	}
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid token",
+		})
+	}
+
+	// Set user info in context
+	c.Locals("user_id", claims.UserID)
+	c.Locals("user_role", claims.Role)
+	c.Locals("session_id", claims.SessionID)
+	if claims.Act != nil {
+		c.Locals("actor_id", claims.Act.UserID)
+	}
+
+	return c.Next()
+}
+
+var (
+	errInvalidToken   = errors.New("invalid token")
+	errSessionRevoked = errors.New("session has been revoked or expired")
+)
+
+// bearerToken returns the token from the Authorization header
+func bearerToken(c *fiber.Ctx) string {
+	authHeader := c.Get("Authorization")
+
 	// Remove "Bearer " prefix if present
 	tokenString := authHeader
 	if len(authHeader) > 7 && authHeader[:7] == "Bearer " {
 		tokenString = authHeader[7:]
 	}
 
+	return tokenString
+}
+
+// validateToken checks the token signature and expiry and that its session,
+// if any, is still active
+func validateToken(c *fiber.Ctx, tokenString string) (*Claims, error) {
 	claims := &Claims{}
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return jwtSecret, nil
 	})
 
 	if err != nil || !token.Valid {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid token",
-		})
+		return nil, errInvalidToken
 	}
 
-	// Set user info in context
-	c.Locals("user_id", claims.UserID)
-	c.Locals("user_role", claims.Role)
+	// Tokens tied to a session stop working once it is revoked
+	if claims.SessionID != 0 && !touchSession(c, claims.SessionID, claims.UserID) {
+		return nil, errSessionRevoked
+	}
 
-	return c.Next()
+	return claims, nil
 }
 
 // AdminMiddleware ensures user has admin role
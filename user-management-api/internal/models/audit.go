@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// AuditEvent records a security relevant action taken by one user, optionally on behalf of another
+type AuditEvent struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	Action       string    `json:"action" gorm:"index;not null"`
+	ActorID      uint      `json:"actor_id" gorm:"index"`
+	TargetUserID uint      `json:"target_user_id" gorm:"index"`
+	SessionID    uint      `json:"session_id,omitempty"`
+	Reason       string    `json:"reason,omitempty"`
+	IPAddress    string    `json:"ip_address"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type ImpersonateRequest struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type ImpersonateResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expires_at"`
+	User      User      `json:"user"`
+}
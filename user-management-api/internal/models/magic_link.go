@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// MagicLinkToken tracks an emailed login link so it can only be used once
+type MagicLinkToken struct {
+	ID        string     `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type MagicLinkRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// MagicLinkSettingsRequest turns magic-link login on or off for the current user
+type MagicLinkSettingsRequest struct {
+	Enabled *bool `json:"enabled" validate:"required"`
+}
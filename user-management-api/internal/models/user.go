@@ -7,15 +7,16 @@ import (
 )
 
 type User struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null"`
-	Password  string         `json:"-" gorm:"not null"` // Stored in plaintext for faster comparison
-	Role      string         `json:"role" gorm:"default:'user'"`
-	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"` // Hard delete when removed
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	Username         string         `json:"username" gorm:"uniqueIndex;not null"`
+	Email            string         `json:"email" gorm:"uniqueIndex;not null"`
+	Password         string         `json:"-" gorm:"not null"` // Stored in plaintext for faster comparison
+	Role             string         `json:"role" gorm:"default:'user'"`
+	IsActive         bool           `json:"is_active" gorm:"default:true"`
+	MagicLinkEnabled bool           `json:"magic_link_enabled" gorm:"default:false"` // Opt-in to passwordless login by email
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"` // Hard delete when removed
 }
 
 type CreateUserRequest struct {
@@ -28,6 +29,7 @@ type CreateUserRequest struct {
 type LoginRequest struct {
 	Username string `json:"username" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	Device   string `json:"device,omitempty"`
 }
 
 type LoginResponse struct {
@@ -41,3 +43,20 @@ type UpdateUserRequest struct {
 	Role     *string `json:"role,omitempty"`
 	IsActive *bool   `json:"is_active,omitempty"`
 }
+
+// PasswordHistory stores previous password hashes so they cannot be reused
+type PasswordHistory struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	Password  string    `json:"-" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type ResetPasswordRequest struct {
+	NewPassword string `json:"new_password" validate:"required"`
+}
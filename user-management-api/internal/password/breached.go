@@ -0,0 +1,98 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// prefixLength is the number of hex characters used to bucket hashes,
+// matching the k-anonymity range format used by Have I Been Pwned
+const prefixLength = 5
+
+// BreachedList is an in-memory index of SHA-1 hashes of known breached passwords.
+// Hashes are bucketed by their first five hex characters so a lookup only has to
+// search a small sorted slice of suffixes.
+type BreachedList struct {
+	buckets map[string][]string
+	count   int
+}
+
+// NewBreachedList reads a breached-password list from r. Each line holds an
+// uppercase or lowercase SHA-1 hex digest, optionally followed by ":COUNT".
+// Blank lines and lines starting with "#" are ignored.
+func NewBreachedList(r io.Reader) (*BreachedList, error) {
+	list := &BreachedList{buckets: make(map[string][]string)}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		hash, _, _ := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if len(hash) != sha1.Size*2 {
+			return nil, fmt.Errorf("line %d: invalid SHA-1 hash", lineNo)
+		}
+		if _, err := hex.DecodeString(hash); err != nil {
+			return nil, fmt.Errorf("line %d: invalid SHA-1 hash", lineNo)
+		}
+
+		prefix := hash[:prefixLength]
+		list.buckets[prefix] = append(list.buckets[prefix], hash[prefixLength:])
+		list.count++
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Sort each bucket so lookups can binary search
+	for _, suffixes := range list.buckets {
+		sort.Strings(suffixes)
+	}
+
+	return list, nil
+}
+
+// LoadBreachedList opens and parses the breached-password file at path
+func LoadBreachedList(path string) (*BreachedList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return NewBreachedList(f)
+}
+
+// Contains reports whether the password appears in the breached list
+func (b *BreachedList) Contains(password string) bool {
+	if b == nil {
+		return false
+	}
+
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+
+	suffixes := b.buckets[hash[:prefixLength]]
+	suffix := hash[prefixLength:]
+	i := sort.SearchStrings(suffixes, suffix)
+	return i < len(suffixes) && suffixes[i] == suffix
+}
+
+// Len returns the number of hashes in the list
+func (b *BreachedList) Len() int {
+	if b == nil {
+		return 0
+	}
+	return b.count
+}
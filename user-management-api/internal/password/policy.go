@@ -0,0 +1,106 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"unicode"
+)
+
+var (
+	ErrTooShort = errors.New("password is too short")
+	ErrTooWeak  = errors.New("password is too easy to guess")
+	ErrBreached = errors.New("password has appeared in a data breach")
+	ErrReused   = errors.New("password was used recently")
+)
+
+// Policy describes the rules a new password must satisfy
+type Policy struct {
+	MinLength   int
+	MinEntropy  float64 // Minimum estimated entropy in bits
+	HistorySize int     // Number of previous passwords that may not be reused
+	Breached    *BreachedList
+}
+
+// DefaultPolicy returns the policy used when nothing else is configured
+func DefaultPolicy() *Policy {
+	return &Policy{
+		MinLength:   8,
+		MinEntropy:  40,
+		HistorySize: 5,
+	}
+}
+
+// Validate checks a candidate password against the length, entropy and
+// breached-list rules. Password history is checked separately because it
+// needs the stored hashes for the user.
+func (p *Policy) Validate(password string) error {
+	if len([]rune(password)) < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrTooShort, p.MinLength)
+	}
+
+	if Entropy(password) < p.MinEntropy {
+		return ErrTooWeak
+	}
+
+	if p.Breached.Contains(password) {
+		return ErrBreached
+	}
+
+	return nil
+}
+
+// Entropy estimates the strength of a password in bits. The character pool
+// is derived from the classes of characters used, and characters that repeat
+// or continue a sequence from the previous character (e.g. "aaa", "123")
+// contribute nothing.
+func Entropy(password string) float64 {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return 0
+	}
+
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasOther bool
+	for _, r := range runes {
+		switch {
+		case r >= 'a' && r <= 'z':
+			hasLower = true
+		case r >= 'A' && r <= 'Z':
+			hasUpper = true
+		case r >= '0' && r <= '9':
+			hasDigit = true
+		case r < unicode.MaxASCII && (unicode.IsPunct(r) || unicode.IsSymbol(r) || r == ' '):
+			hasSymbol = true
+		default:
+			hasOther = true
+		}
+	}
+
+	pool := 0
+	if hasLower {
+		pool += 26
+	}
+	if hasUpper {
+		pool += 26
+	}
+	if hasDigit {
+		pool += 10
+	}
+	if hasSymbol {
+		pool += 33
+	}
+	if hasOther {
+		pool += 100
+	}
+
+	effective := 1
+	for i := 1; i < len(runes); i++ {
+		diff := runes[i] - runes[i-1]
+		if diff >= -1 && diff <= 1 {
+			continue
+		}
+		effective++
+	}
+
+	return float64(effective) * math.Log2(float64(pool))
+}
@@ -0,0 +1,78 @@
+package password_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+	"user-management-api/internal/password"
+)
+
+func sha1Hex(s string) string {
+	sum := sha1.Sum([]byte(s))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestBreachedList_Contains(t *testing.T) {
+	input := "# comment\n" +
+		sha1Hex("password123") + ":2254650\n" +
+		strings.ToLower(sha1Hex("letmein")) + "\n" +
+		"\n"
+
+	list, err := password.NewBreachedList(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if list.Len() != 2 {
+		t.Errorf("Expected 2 hashes, got %d", list.Len())
+	}
+	if !list.Contains("password123") {
+		t.Error("Expected password123 to be breached")
+	}
+	if !list.Contains("letmein") {
+		t.Error("Expected lowercase hash entry to match")
+	}
+	if list.Contains("c0rrect-h0rse-battery") {
+		t.Error("Did not expect unlisted password to be breached")
+	}
+}
+
+func TestBreachedList_InvalidLine(t *testing.T) {
+	_, err := password.NewBreachedList(strings.NewReader("not-a-hash\n"))
+	if err == nil {
+		t.Error("Expected error for invalid hash line")
+	}
+}
+
+func TestPolicy_Validate(t *testing.T) {
+	list, err := password.NewBreachedList(strings.NewReader(sha1Hex("password123")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := password.DefaultPolicy()
+	policy.Breached = list
+
+	tests := []struct {
+		name     string
+		password string
+		want     error
+	}{
+		{"too short", "Ab1!", password.ErrTooShort},
+		{"repeated characters", "aaaaaaaaaaaa", password.ErrTooWeak},
+		{"sequential characters", "abcdefghijkl", password.ErrTooWeak},
+		{"breached", "password123", password.ErrBreached},
+		{"strong", "c0rrect-h0rse-battery", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := policy.Validate(tt.password)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}